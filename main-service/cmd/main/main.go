@@ -6,6 +6,7 @@ import (
 	"github.com/kokweikhong/calvary-admin-system/main-service/internal/config"
 	"github.com/kokweikhong/calvary-admin-system/main-service/internal/db"
 	"github.com/kokweikhong/calvary-admin-system/main-service/internal/routes"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/services"
 )
 
 func main() {
@@ -20,6 +21,8 @@ func main() {
 		panic(err)
 	}
 
+	services.StartAlertWorker(config.Cfg.AlertEvaluationInterval)
+
 	router := routes.Init()
 	routes.Run(router, config.Cfg.ServerPort)
 
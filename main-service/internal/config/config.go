@@ -3,8 +3,13 @@ package config
 import (
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -17,6 +22,29 @@ type Config struct {
 	PostgresDBName   string
 	ServerPort       string
 	JWTSecret        string
+	AllocationRule   string
+
+	// AdjustmentApprovalThreshold is the absolute value above which a stock
+	// adjustment waits for approval before it moves stock. Left unset, no
+	// adjustment waits.
+	AdjustmentApprovalThreshold float64
+
+	// StandardQuantityTolerance is the fraction a client supplied standard
+	// quantity may differ from the one measured from incoming dimensions.
+	StandardQuantityTolerance float64
+
+	// AlertEvaluationInterval is how often the alert worker checks every
+	// product, on top of checking a product whenever its stock moves.
+	AlertEvaluationInterval time.Duration
+
+	// ExpiryAlertHorizons are the days ahead, in ascending order, at which
+	// the alert worker warns about lots running out of date.
+	ExpiryAlertHorizons []int
+
+	// Company details printed in the header of generated documents.
+	CompanyName    string
+	CompanyAddress string
+	CompanyPhone   string
 }
 
 var Cfg = new(Config)
@@ -45,6 +73,59 @@ func Init() error {
 
 	Cfg.JWTSecret = os.Getenv("JWT_SECRET")
 
+	Cfg.AllocationRule = os.Getenv("INVENTORY_ALLOCATION_RULE")
+
+	Cfg.AdjustmentApprovalThreshold = math.Inf(1)
+	if threshold := os.Getenv("ADJUSTMENT_APPROVAL_THRESHOLD"); threshold != "" {
+		Cfg.AdjustmentApprovalThreshold, err = strconv.ParseFloat(threshold, 64)
+		if err != nil {
+			slog.Error("Error parsing ADJUSTMENT_APPROVAL_THRESHOLD", "error", err)
+			return err
+		}
+	}
+
+	Cfg.StandardQuantityTolerance = 0.001
+	if tolerance := os.Getenv("STANDARD_QUANTITY_TOLERANCE"); tolerance != "" {
+		Cfg.StandardQuantityTolerance, err = strconv.ParseFloat(tolerance, 64)
+		if err != nil {
+			slog.Error("Error parsing STANDARD_QUANTITY_TOLERANCE", "error", err)
+			return err
+		}
+	}
+
+	Cfg.AlertEvaluationInterval = time.Hour
+	if interval := os.Getenv("ALERT_EVALUATION_INTERVAL"); interval != "" {
+		Cfg.AlertEvaluationInterval, err = time.ParseDuration(interval)
+		if err != nil {
+			slog.Error("Error parsing ALERT_EVALUATION_INTERVAL", "error", err)
+			return err
+		}
+		if Cfg.AlertEvaluationInterval <= 0 {
+			return fmt.Errorf("ALERT_EVALUATION_INTERVAL must be positive, got %s", interval)
+		}
+	}
+
+	Cfg.ExpiryAlertHorizons = []int{30, 60, 90}
+	if horizons := os.Getenv("EXPIRY_ALERT_HORIZONS"); horizons != "" {
+		Cfg.ExpiryAlertHorizons = nil
+		for _, value := range strings.Split(horizons, ",") {
+			days, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				slog.Error("Error parsing EXPIRY_ALERT_HORIZONS", "error", err)
+				return err
+			}
+			if days <= 0 {
+				return fmt.Errorf("EXPIRY_ALERT_HORIZONS must be positive days, got %s", horizons)
+			}
+			Cfg.ExpiryAlertHorizons = append(Cfg.ExpiryAlertHorizons, days)
+		}
+		sort.Ints(Cfg.ExpiryAlertHorizons)
+	}
+
+	Cfg.CompanyName = os.Getenv("COMPANY_NAME")
+	Cfg.CompanyAddress = os.Getenv("COMPANY_ADDRESS")
+	Cfg.CompanyPhone = os.Getenv("COMPANY_PHONE")
+
 	slog.Info("Config loaded successfully", "config", Cfg)
 
 	return nil
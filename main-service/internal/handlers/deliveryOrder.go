@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/services"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/utils"
+)
+
+type DeliveryOrderHandler interface {
+	GetDeliveryOrders(w http.ResponseWriter, r *http.Request)
+	GetDeliveryOrder(w http.ResponseWriter, r *http.Request)
+	CreateDeliveryOrder(w http.ResponseWriter, r *http.Request)
+	RenderDeliveryOrder(w http.ResponseWriter, r *http.Request)
+	DeleteDeliveryOrder(w http.ResponseWriter, r *http.Request)
+}
+
+type deliveryOrderHandler struct {
+	jsonH   utils.JSONHandler
+	service services.DeliveryOrderService
+}
+
+func NewDeliveryOrderHandler() DeliveryOrderHandler {
+	return &deliveryOrderHandler{
+		jsonH:   utils.NewJSONHandler(),
+		service: services.NewDeliveryOrderService(),
+	}
+}
+
+func (h *deliveryOrderHandler) GetDeliveryOrders(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetDeliveryOrders Hit")
+	orders, err := h.service.GetDeliveryOrders()
+	if err != nil {
+		slog.Error("Error getting delivery orders", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, orders)
+}
+
+func (h *deliveryOrderHandler) GetDeliveryOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetDeliveryOrder Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.GetDeliveryOrder(id)
+	if err != nil {
+		slog.Error("Error getting delivery order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, order)
+}
+
+func (h *deliveryOrderHandler) CreateDeliveryOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateDeliveryOrder Hit")
+	order := new(models.DeliveryOrder)
+	if err := h.jsonH.ReadJSON(w, r, order); err != nil {
+		slog.Error("Error reading delivery order", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.CreateDeliveryOrder(order)
+	if err != nil {
+		slog.Error("Error creating delivery order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, order)
+}
+
+func (h *deliveryOrderHandler) RenderDeliveryOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("RenderDeliveryOrder Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		By string `json:"by"`
+	}
+	if err := h.jsonH.ReadJSON(w, r, &body); err != nil {
+		slog.Error("Error reading render request", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.RenderDeliveryOrder(id, body.By)
+	if err != nil {
+		slog.Error("Error rendering delivery order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, order)
+}
+
+func (h *deliveryOrderHandler) DeleteDeliveryOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteDeliveryOrder Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteDeliveryOrder(id); err != nil {
+		slog.Error("Error deleting delivery order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/services"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/utils"
+)
+
+// writeServiceError answers 409 with the available quantity when the service
+// refused a request for lack of stock, 400 when it rejected the input, 404
+// when the record does not exist and 500 for anything else.
+func writeServiceError(jsonH utils.JSONHandler, w http.ResponseWriter, err error) {
+	var stockErr *services.InsufficientStockError
+	if errors.As(err, &stockErr) {
+		jsonH.WriteJSON(w, http.StatusConflict, &utils.JSONPayload{
+			Error:   true,
+			Message: stockErr.Error(),
+			Data:    stockErr,
+		})
+		return
+	}
+
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonH.ErrorJSON(w, errors.New("record not found"), http.StatusNotFound)
+		return
+	}
+
+	jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
@@ -17,6 +19,25 @@ type InventoryHandler interface {
 	CreateProduct(w http.ResponseWriter, r *http.Request)
 	UpdateProduct(w http.ResponseWriter, r *http.Request)
 	DeleteProduct(w http.ResponseWriter, r *http.Request)
+	GetProductTags(w http.ResponseWriter, r *http.Request)
+	GetProductVariants(w http.ResponseWriter, r *http.Request)
+	LinkProductVariant(w http.ResponseWriter, r *http.Request)
+	UnlinkProductVariant(w http.ResponseWriter, r *http.Request)
+	GetProductMedia(w http.ResponseWriter, r *http.Request)
+	AddProductMedia(w http.ResponseWriter, r *http.Request)
+	ReorderProductMedia(w http.ResponseWriter, r *http.Request)
+	SetPrimaryProductMedia(w http.ResponseWriter, r *http.Request)
+	DeleteProductMedia(w http.ResponseWriter, r *http.Request)
+
+	GetCategories(w http.ResponseWriter, r *http.Request)
+	GetCategoryTree(w http.ResponseWriter, r *http.Request)
+	GetCategory(w http.ResponseWriter, r *http.Request)
+	CreateCategory(w http.ResponseWriter, r *http.Request)
+	UpdateCategory(w http.ResponseWriter, r *http.Request)
+	DeleteCategory(w http.ResponseWriter, r *http.Request)
+	CreateCategoryAttribute(w http.ResponseWriter, r *http.Request)
+	UpdateCategoryAttribute(w http.ResponseWriter, r *http.Request)
+	DeleteCategoryAttribute(w http.ResponseWriter, r *http.Request)
 	GetProductSummary(w http.ResponseWriter, r *http.Request)
 
 	GetIncomings(w http.ResponseWriter, r *http.Request)
@@ -24,12 +45,53 @@ type InventoryHandler interface {
 	CreateIncoming(w http.ResponseWriter, r *http.Request)
 	UpdateIncoming(w http.ResponseWriter, r *http.Request)
 	DeleteIncoming(w http.ResponseWriter, r *http.Request)
+	GetIncomingStatusHistory(w http.ResponseWriter, r *http.Request)
+	TransitionIncoming(w http.ResponseWriter, r *http.Request)
+	TransitionShipment(w http.ResponseWriter, r *http.Request)
 
 	GetOutgoings(w http.ResponseWriter, r *http.Request)
 	GetOutgoing(w http.ResponseWriter, r *http.Request)
 	CreateOutgoing(w http.ResponseWriter, r *http.Request)
 	UpdateOutgoing(w http.ResponseWriter, r *http.Request)
 	DeleteOutgoing(w http.ResponseWriter, r *http.Request)
+	AllocateOutgoings(w http.ResponseWriter, r *http.Request)
+
+	GetReservations(w http.ResponseWriter, r *http.Request)
+	GetReservation(w http.ResponseWriter, r *http.Request)
+	CreateReservation(w http.ResponseWriter, r *http.Request)
+	UpdateReservation(w http.ResponseWriter, r *http.Request)
+	ReleaseReservation(w http.ResponseWriter, r *http.Request)
+	DeleteReservation(w http.ResponseWriter, r *http.Request)
+
+	GetValuationReport(w http.ResponseWriter, r *http.Request)
+	GetProductLedger(w http.ResponseWriter, r *http.Request)
+	GetAgingReport(w http.ResponseWriter, r *http.Request)
+	GetExpiryReport(w http.ResponseWriter, r *http.Request)
+
+	GetTransfers(w http.ResponseWriter, r *http.Request)
+	GetTransfer(w http.ResponseWriter, r *http.Request)
+	CreateTransfer(w http.ResponseWriter, r *http.Request)
+
+	GetReturnReasons(w http.ResponseWriter, r *http.Request)
+	GetReturns(w http.ResponseWriter, r *http.Request)
+	GetReturn(w http.ResponseWriter, r *http.Request)
+	CreateReturn(w http.ResponseWriter, r *http.Request)
+
+	TraceLot(w http.ResponseWriter, r *http.Request)
+
+	GetAdjustmentReasons(w http.ResponseWriter, r *http.Request)
+	GetAdjustments(w http.ResponseWriter, r *http.Request)
+	GetAdjustment(w http.ResponseWriter, r *http.Request)
+	CreateAdjustment(w http.ResponseWriter, r *http.Request)
+	ApproveAdjustment(w http.ResponseWriter, r *http.Request)
+	RejectAdjustment(w http.ResponseWriter, r *http.Request)
+
+	GetCycleCounts(w http.ResponseWriter, r *http.Request)
+	GetCycleCount(w http.ResponseWriter, r *http.Request)
+	CreateCycleCount(w http.ResponseWriter, r *http.Request)
+	UpdateCycleCountLines(w http.ResponseWriter, r *http.Request)
+	PostCycleCount(w http.ResponseWriter, r *http.Request)
+	CancelCycleCount(w http.ResponseWriter, r *http.Request)
 }
 
 type inventoryHandler struct {
@@ -46,7 +108,14 @@ func NewInventoryHandler() InventoryHandler {
 
 func (h *inventoryHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 	slog.Info("GetProducts Hit")
-	products, err := h.service.GetProducts()
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		slog.Error("Error parsing product filter", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	products, err := h.service.GetProducts(filter)
 	if err != nil {
 		slog.Error("Error getting products", "error", err)
 		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
@@ -89,7 +158,7 @@ func (h *inventoryHandler) CreateProduct(w http.ResponseWriter, r *http.Request)
 	product, err = h.service.CreateProduct(product)
 	if err != nil {
 		slog.Error("Error creating product", "error", err)
-		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		writeServiceError(h.jsonH, w, err)
 		return
 	}
 
@@ -117,7 +186,7 @@ func (h *inventoryHandler) UpdateProduct(w http.ResponseWriter, r *http.Request)
 	product, err = h.service.UpdateProduct(id, product)
 	if err != nil {
 		slog.Error("Error updating product", "error", err)
-		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		writeServiceError(h.jsonH, w, err)
 		return
 	}
 
@@ -137,7 +206,7 @@ func (h *inventoryHandler) DeleteProduct(w http.ResponseWriter, r *http.Request)
 	err = h.service.DeleteProduct(id)
 	if err != nil {
 		slog.Error("Error deleting product", "error", err)
-		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		writeServiceError(h.jsonH, w, err)
 		return
 	}
 
@@ -146,7 +215,27 @@ func (h *inventoryHandler) DeleteProduct(w http.ResponseWriter, r *http.Request)
 
 func (h *inventoryHandler) GetProductSummary(w http.ResponseWriter, r *http.Request) {
 	slog.Info("GetProductSummary Hit")
-	productSummaries, err := h.service.GetProductSummary()
+	asOf, err := parseDateParam(r, "as_of", "asOf")
+	if err != nil {
+		slog.Error("Error parsing as_of", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		slog.Error("Error parsing product filter", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// rollUp nests variants under their parent
+	getSummary := h.service.GetProductSummary
+	if r.URL.Query().Get("rollUp") == "true" {
+		getSummary = h.service.GetProductRollup
+	}
+
+	productSummaries, err := getSummary(asOf, filter)
 	if err != nil {
 		slog.Error("Error getting product summaries", "error", err)
 		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
@@ -202,7 +291,7 @@ func (h *inventoryHandler) CreateIncoming(w http.ResponseWriter, r *http.Request
 	incoming, err = h.service.CreateIncoming(incoming)
 	if err != nil {
 		slog.Error("Error creating incoming", "error", err)
-		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		writeServiceError(h.jsonH, w, err)
 		return
 	}
 
@@ -230,7 +319,7 @@ func (h *inventoryHandler) UpdateIncoming(w http.ResponseWriter, r *http.Request
 	incoming, err = h.service.UpdateIncoming(id, incoming)
 	if err != nil {
 		slog.Error("Error updating incoming", "error", err)
-		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		writeServiceError(h.jsonH, w, err)
 		return
 	}
 
@@ -250,7 +339,7 @@ func (h *inventoryHandler) DeleteIncoming(w http.ResponseWriter, r *http.Request
 	err = h.service.DeleteIncoming(id)
 	if err != nil {
 		slog.Error("Error deleting incoming", "error", err)
-		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		writeServiceError(h.jsonH, w, err)
 		return
 	}
 
@@ -303,7 +392,7 @@ func (h *inventoryHandler) CreateOutgoing(w http.ResponseWriter, r *http.Request
 	outgoing, err = h.service.CreateOutgoing(outgoing)
 	if err != nil {
 		slog.Error("Error creating outgoing", "error", err)
-		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		writeServiceError(h.jsonH, w, err)
 		return
 	}
 
@@ -331,7 +420,7 @@ func (h *inventoryHandler) UpdateOutgoing(w http.ResponseWriter, r *http.Request
 	outgoing, err = h.service.UpdateOutgoing(id, outgoing)
 	if err != nil {
 		slog.Error("Error updating outgoing", "error", err)
-		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		writeServiceError(h.jsonH, w, err)
 		return
 	}
 
@@ -351,9 +440,50 @@ func (h *inventoryHandler) DeleteOutgoing(w http.ResponseWriter, r *http.Request
 	err = h.service.DeleteOutgoing(id)
 	if err != nil {
 		slog.Error("Error deleting outgoing", "error", err)
-		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		writeServiceError(h.jsonH, w, err)
 		return
 	}
 
 	h.jsonH.WriteJSON(w, http.StatusOK, nil)
 }
+
+func (h *inventoryHandler) AllocateOutgoings(w http.ResponseWriter, r *http.Request) {
+	slog.Info("AllocateOutgoings Hit")
+	request := new(models.InventoryAllocationRequest)
+	if err := h.jsonH.ReadJSON(w, r, request); err != nil {
+		slog.Error("Error reading allocation request", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	allocation, err := h.service.AllocateOutgoings(request)
+	if err != nil {
+		slog.Error("Error allocating outgoings", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, allocation)
+}
+
+// parseDateParam reads an optional YYYY-MM-DD query parameter, returning nil
+// when it is not set. A parameter with more than one spelling is read from
+// the first of names that is set.
+func parseDateParam(r *http.Request, names ...string) (*time.Time, error) {
+	var name, value string
+	for _, name = range names {
+		if value = r.URL.Query().Get(name); value != "" {
+			break
+		}
+	}
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date in YYYY-MM-DD format", name)
+	}
+
+	return &date, nil
+}
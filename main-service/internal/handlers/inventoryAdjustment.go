@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+func (h *inventoryHandler) GetAdjustmentReasons(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetAdjustmentReasons Hit")
+	reasons, err := h.service.GetAdjustmentReasons()
+	if err != nil {
+		slog.Error("Error getting adjustment reasons", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, reasons)
+}
+
+func (h *inventoryHandler) GetAdjustments(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetAdjustments Hit")
+	adjustments, err := h.service.GetAdjustments(r.URL.Query().Get("status"))
+	if err != nil {
+		slog.Error("Error getting adjustments", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, adjustments)
+}
+
+func (h *inventoryHandler) GetAdjustment(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetAdjustment Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	adjustment, err := h.service.GetAdjustment(id)
+	if err != nil {
+		slog.Error("Error getting adjustment", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, adjustment)
+}
+
+func (h *inventoryHandler) CreateAdjustment(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateAdjustment Hit")
+	adjustment := new(models.InventoryAdjustment)
+	if err := h.jsonH.ReadJSON(w, r, adjustment); err != nil {
+		slog.Error("Error reading adjustment", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	adjustment, err := h.service.CreateAdjustment(adjustment)
+	if err != nil {
+		slog.Error("Error creating adjustment", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, adjustment)
+}
+
+func (h *inventoryHandler) ApproveAdjustment(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ApproveAdjustment Hit")
+	h.decideAdjustment(w, r, h.service.ApproveAdjustment)
+}
+
+func (h *inventoryHandler) RejectAdjustment(w http.ResponseWriter, r *http.Request) {
+	slog.Info("RejectAdjustment Hit")
+	h.decideAdjustment(w, r, h.service.RejectAdjustment)
+}
+
+func (h *inventoryHandler) decideAdjustment(
+	w http.ResponseWriter,
+	r *http.Request,
+	decide func(int, *models.InventoryAdjustmentDecision) (*models.InventoryAdjustment, error),
+) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	decision := new(models.InventoryAdjustmentDecision)
+	if err := h.jsonH.ReadJSON(w, r, decision); err != nil {
+		slog.Error("Error reading adjustment decision", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	adjustment, err := decide(id, decision)
+	if err != nil {
+		slog.Error("Error deciding adjustment", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, adjustment)
+}
+
+func (h *inventoryHandler) GetCycleCounts(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetCycleCounts Hit")
+	counts, err := h.service.GetCycleCounts()
+	if err != nil {
+		slog.Error("Error getting cycle counts", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, counts)
+}
+
+func (h *inventoryHandler) GetCycleCount(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetCycleCount Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.service.GetCycleCount(id)
+	if err != nil {
+		slog.Error("Error getting cycle count", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, count)
+}
+
+func (h *inventoryHandler) CreateCycleCount(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateCycleCount Hit")
+	count := new(models.CycleCount)
+	if err := h.jsonH.ReadJSON(w, r, count); err != nil {
+		slog.Error("Error reading cycle count", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.service.CreateCycleCount(count)
+	if err != nil {
+		slog.Error("Error creating cycle count", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, count)
+}
+
+func (h *inventoryHandler) UpdateCycleCountLines(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateCycleCountLines Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var entries []*models.CycleCountEntry
+	if err := h.jsonH.ReadJSON(w, r, &entries); err != nil {
+		slog.Error("Error reading cycle count entries", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.service.UpdateCycleCountLines(id, entries)
+	if err != nil {
+		slog.Error("Error updating cycle count lines", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, count)
+}
+
+func (h *inventoryHandler) PostCycleCount(w http.ResponseWriter, r *http.Request) {
+	slog.Info("PostCycleCount Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	request := new(models.CycleCount)
+	if err := h.jsonH.ReadJSON(w, r, request); err != nil {
+		slog.Error("Error reading cycle count", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.service.PostCycleCount(id, request.PostedBy)
+	if err != nil {
+		slog.Error("Error posting cycle count", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, count)
+}
+
+func (h *inventoryHandler) CancelCycleCount(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CancelCycleCount Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.CancelCycleCount(id); err != nil {
+		slog.Error("Error cancelling cycle count", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
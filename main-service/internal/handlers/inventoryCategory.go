@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+// parseProductFilter reads the product list filter from the query:
+// categoryId, parentId, tags as a comma separated list, and
+// attr.<code>=<value> for each attribute, a number attribute also taking a
+// range such as attr.thickness=2..5 with either end left open.
+func parseProductFilter(r *http.Request) (*models.InventoryProductFilter, error) {
+	query := r.URL.Query()
+	filter := new(models.InventoryProductFilter)
+
+	if value := query.Get("categoryId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("categoryId must be a number: %w", err)
+		}
+		filter.CategoryID = id
+	}
+
+	if value := query.Get("parentId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("parentId must be a number: %w", err)
+		}
+		filter.ParentID = id
+	}
+
+	for _, value := range query["tags"] {
+		filter.Tags = append(filter.Tags, strings.Split(value, ",")...)
+	}
+
+	for key, values := range query {
+		code, ok := strings.CutPrefix(key, "attr.")
+		if !ok || code == "" {
+			continue
+		}
+
+		for _, value := range values {
+			attribute := &models.ProductAttributeFilter{Code: code}
+			from, to, isRange := strings.Cut(value, "..")
+			if !isRange {
+				attribute.Value = value
+				filter.Attributes = append(filter.Attributes, attribute)
+				continue
+			}
+
+			if from = strings.TrimSpace(from); from != "" {
+				lower, err := strconv.ParseFloat(from, 64)
+				if err != nil {
+					return nil, fmt.Errorf("%s must be a number range: %w", key, err)
+				}
+				attribute.Min = &lower
+			}
+			if to = strings.TrimSpace(to); to != "" {
+				upper, err := strconv.ParseFloat(to, 64)
+				if err != nil {
+					return nil, fmt.Errorf("%s must be a number range: %w", key, err)
+				}
+				attribute.Max = &upper
+			}
+			filter.Attributes = append(filter.Attributes, attribute)
+		}
+	}
+
+	return filter, nil
+}
+
+func (h *inventoryHandler) GetProductTags(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetProductTags Hit")
+	tags, err := h.service.GetProductTags()
+	if err != nil {
+		slog.Error("Error getting product tags", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, tags)
+}
+
+// Category
+func (h *inventoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetCategories Hit")
+	categories, err := h.service.GetCategories()
+	if err != nil {
+		slog.Error("Error getting categories", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, categories)
+}
+
+func (h *inventoryHandler) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetCategoryTree Hit")
+	categories, err := h.service.GetCategoryTree()
+	if err != nil {
+		slog.Error("Error getting category tree", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, categories)
+}
+
+func (h *inventoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetCategory Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.service.GetCategory(id)
+	if err != nil {
+		slog.Error("Error getting category", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, category)
+}
+
+func (h *inventoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateCategory Hit")
+	category := new(models.ProductCategory)
+	if err := h.jsonH.ReadJSON(w, r, category); err != nil {
+		slog.Error("Error reading category", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.service.CreateCategory(category)
+	if err != nil {
+		slog.Error("Error creating category", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, category)
+}
+
+func (h *inventoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateCategory Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	category := new(models.ProductCategory)
+	if err := h.jsonH.ReadJSON(w, r, category); err != nil {
+		slog.Error("Error reading category", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	category, err = h.service.UpdateCategory(id, category)
+	if err != nil {
+		slog.Error("Error updating category", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, category)
+}
+
+func (h *inventoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteCategory Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteCategory(id); err != nil {
+		slog.Error("Error deleting category", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+// Category attribute
+func (h *inventoryHandler) CreateCategoryAttribute(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateCategoryAttribute Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	attribute := new(models.CategoryAttribute)
+	if err := h.jsonH.ReadJSON(w, r, attribute); err != nil {
+		slog.Error("Error reading category attribute", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	attribute, err = h.service.CreateCategoryAttribute(id, attribute)
+	if err != nil {
+		slog.Error("Error creating category attribute", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, attribute)
+}
+
+func (h *inventoryHandler) UpdateCategoryAttribute(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateCategoryAttribute Hit")
+	categoryID, attributeID, ok := h.attributeIDs(w, r)
+	if !ok {
+		return
+	}
+
+	attribute := new(models.CategoryAttribute)
+	if err := h.jsonH.ReadJSON(w, r, attribute); err != nil {
+		slog.Error("Error reading category attribute", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	attribute, err := h.service.UpdateCategoryAttribute(categoryID, attributeID, attribute)
+	if err != nil {
+		slog.Error("Error updating category attribute", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, attribute)
+}
+
+func (h *inventoryHandler) DeleteCategoryAttribute(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteCategoryAttribute Hit")
+	categoryID, attributeID, ok := h.attributeIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCategoryAttribute(categoryID, attributeID); err != nil {
+		slog.Error("Error deleting category attribute", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+// attributeIDs reads the category and attribute ids of a category attribute
+// route.
+func (h *inventoryHandler) attributeIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	categoryID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	attributeID, err := strconv.Atoi(chi.URLParam(r, "attributeId"))
+	if err != nil {
+		slog.Error("Error parsing attribute id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return categoryID, attributeID, true
+}
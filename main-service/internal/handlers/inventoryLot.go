@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// TraceLot answers where a lot went, or a single item when serialNo is given.
+func (h *inventoryHandler) TraceLot(w http.ResponseWriter, r *http.Request) {
+	slog.Info("TraceLot Hit")
+	productID := 0
+	if value := r.URL.Query().Get("productId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Error parsing productId", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		productID = id
+	}
+
+	trace, err := h.service.TraceLot(r.URL.Query().Get("lotNo"), r.URL.Query().Get("serialNo"), productID)
+	if err != nil {
+		slog.Error("Error tracing lot", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, trace)
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+func (h *inventoryHandler) GetProductMedia(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetProductMedia Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	media, err := h.service.GetProductMedia(id)
+	if err != nil {
+		slog.Error("Error getting product media", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, media)
+}
+
+// AddProductMedia takes a multipart form with the file, its type, and
+// optionally title, isPrimary and createdBy.
+func (h *inventoryHandler) AddProductMedia(w http.ResponseWriter, r *http.Request) {
+	slog.Info("AddProductMedia Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// 10 << 20 = 10MB
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		slog.Error("Error parsing multipart form", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		slog.Error("Error retrieving file from form", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		slog.Error("Error reading file", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	media := &models.ProductMedia{
+		Type:      r.FormValue("type"),
+		Title:     r.FormValue("title"),
+		Filename:  header.Filename,
+		IsPrimary: r.FormValue("isPrimary") == "true",
+		CreatedBy: r.FormValue("createdBy"),
+	}
+
+	media, err = h.service.AddProductMedia(id, media, content)
+	if err != nil {
+		slog.Error("Error adding product media", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, media)
+}
+
+func (h *inventoryHandler) ReorderProductMedia(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ReorderProductMedia Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order := new(models.ProductMediaOrder)
+	if err := h.jsonH.ReadJSON(w, r, order); err != nil {
+		slog.Error("Error reading product media order", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	media, err := h.service.ReorderProductMedia(id, order)
+	if err != nil {
+		slog.Error("Error reordering product media", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, media)
+}
+
+func (h *inventoryHandler) SetPrimaryProductMedia(w http.ResponseWriter, r *http.Request) {
+	slog.Info("SetPrimaryProductMedia Hit")
+	productID, mediaID, ok := h.mediaIDs(w, r)
+	if !ok {
+		return
+	}
+
+	media, err := h.service.SetPrimaryProductMedia(productID, mediaID)
+	if err != nil {
+		slog.Error("Error setting primary product media", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, media)
+}
+
+func (h *inventoryHandler) DeleteProductMedia(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteProductMedia Hit")
+	productID, mediaID, ok := h.mediaIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteProductMedia(productID, mediaID); err != nil {
+		slog.Error("Error deleting product media", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+// mediaIDs reads the product and media ids of a product media route.
+func (h *inventoryHandler) mediaIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	mediaID, err := strconv.Atoi(chi.URLParam(r, "mediaId"))
+	if err != nil {
+		slog.Error("Error parsing media id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return productID, mediaID, true
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/utils"
+)
+
+// Reports
+func (h *inventoryHandler) GetValuationReport(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetValuationReport Hit")
+	format, err := utils.ExportFormat(r)
+	if err != nil {
+		slog.Error("Error parsing format", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	asOf, err := parseDateParam(r, "as_of", "asOf")
+	if err != nil {
+		slog.Error("Error parsing as_of", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetValuationReport(r.URL.Query().Get("groupBy"), asOf)
+	if err != nil {
+		slog.Error("Error getting valuation report", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	if format == utils.ExportFormatJSON {
+		h.jsonH.WriteJSON(w, http.StatusOK, report)
+		return
+	}
+
+	if err := utils.WriteTable(w, format, "stock-valuation", valuationTable(report)); err != nil {
+		slog.Error("Error writing valuation report", "error", err)
+	}
+}
+
+// valuationTable flattens a valuation report into rows, following each group
+// with its subtotal and closing with the grand total.
+func valuationTable(report *models.InventoryValuationReport) *utils.Table {
+	table := &utils.Table{
+		Headers: []string{
+			"Product Code",
+			"Product Name",
+			"Store Location",
+			"Store Country",
+			"Standard Quantity",
+			"Standard Unit",
+			"Average Cost",
+			"Total Value",
+		},
+	}
+
+	for _, group := range report.Groups {
+		for _, line := range group.Lines {
+			table.Rows = append(table.Rows, []any{
+				line.ProductCode,
+				line.ProductName,
+				line.StoreLocation,
+				line.StoreCountry,
+				line.StandardQuantity,
+				line.StandardUnit,
+				line.AverageCost,
+				line.TotalValue,
+			})
+		}
+
+		subtotal := []any{"Subtotal " + group.Key, nil, nil, nil, nil, nil, nil, group.TotalValue}
+		if report.GroupBy == models.ValuationGroupByProduct {
+			subtotal[4] = group.StandardQuantity
+		}
+		table.Rows = append(table.Rows, subtotal)
+	}
+
+	table.Rows = append(table.Rows, []any{"Grand Total", nil, nil, nil, nil, nil, nil, report.TotalValue})
+
+	return table
+}
+
+func (h *inventoryHandler) GetProductLedger(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetProductLedger Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseDateParam(r, "from")
+	if err != nil {
+		slog.Error("Error parsing from", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseDateParam(r, "to")
+	if err != nil {
+		slog.Error("Error parsing to", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	ledger, err := h.service.GetProductLedger(id, from, to)
+	if err != nil {
+		slog.Error("Error getting product ledger", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, ledger)
+}
+
+func (h *inventoryHandler) GetAgingReport(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetAgingReport Hit")
+	format, err := utils.ExportFormat(r)
+	if err != nil {
+		slog.Error("Error parsing format", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	filter := &models.InventoryAgingFilter{
+		Brand:    r.URL.Query().Get("brand"),
+		Supplier: r.URL.Query().Get("supplier"),
+		Basis:    r.URL.Query().Get("basis"),
+	}
+	if value := r.URL.Query().Get("supplierId"); value != "" {
+		filter.SupplierID, err = strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Error parsing supplierId", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.service.GetAgingReport(filter)
+	if err != nil {
+		slog.Error("Error getting aging report", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	if format == utils.ExportFormatJSON {
+		h.jsonH.WriteJSON(w, http.StatusOK, report)
+		return
+	}
+
+	if err := utils.WriteTable(w, format, "stock-aging", agingTable(report)); err != nil {
+		slog.Error("Error writing aging report", "error", err)
+	}
+}
+
+// agingTable writes one row per product and location with a quantity and a
+// value column for every age bucket.
+func agingTable(report *models.InventoryAgingReport) *utils.Table {
+	table := &utils.Table{
+		Headers: []string{
+			"Product Code",
+			"Product Name",
+			"Brand",
+			"Supplier",
+			"Store Location",
+			"Store Country",
+			"Standard Unit",
+		},
+	}
+
+	if len(report.Lines) > 0 {
+		for _, bucket := range report.Lines[0].Buckets {
+			table.Headers = append(table.Headers, bucket.Label+" Quantity", bucket.Label+" Value")
+		}
+	}
+	table.Headers = append(table.Headers, "Total Quantity", "Total Value")
+
+	for _, line := range report.Lines {
+		row := []any{
+			line.ProductCode,
+			line.ProductName,
+			line.Brand,
+			line.Supplier,
+			line.StoreLocation,
+			line.StoreCountry,
+			line.StandardUnit,
+		}
+		for _, bucket := range line.Buckets {
+			row = append(row, bucket.StandardQuantity, bucket.Value)
+		}
+		row = append(row, line.StandardQuantity, line.Value)
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table
+}
+
+// GetExpiryReport lists lots expiring within days, or the largest configured
+// horizon when not given, along with the ones already expired.
+func (h *inventoryHandler) GetExpiryReport(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetExpiryReport Hit")
+	format, err := utils.ExportFormat(r)
+	if err != nil {
+		slog.Error("Error parsing format", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	days := 0
+	if value := r.URL.Query().Get("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Error parsing days", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	productID := 0
+	if value := r.URL.Query().Get("productId"); value != "" {
+		productID, err = strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Error parsing productId", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.service.GetExpiryReport(days, productID)
+	if err != nil {
+		slog.Error("Error getting expiry report", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	if format == utils.ExportFormatJSON {
+		h.jsonH.WriteJSON(w, http.StatusOK, report)
+		return
+	}
+
+	if err := utils.WriteTable(w, format, "stock-expiry", expiryTable(report)); err != nil {
+		slog.Error("Error writing expiry report", "error", err)
+	}
+}
+
+// expiryTable writes one row per lot, soonest to expire first, and closes
+// with the total value at risk.
+func expiryTable(report *models.ExpiryReport) *utils.Table {
+	table := &utils.Table{
+		Headers: []string{
+			"Product Code",
+			"Product Name",
+			"Lot No",
+			"Ref No",
+			"Store Location",
+			"Store Country",
+			"Expires At",
+			"Days To Expiry",
+			"Standard Quantity",
+			"Standard Unit",
+			"Unit Cost",
+			"Value",
+		},
+	}
+
+	for _, line := range report.Lines {
+		table.Rows = append(table.Rows, []any{
+			line.ProductCode,
+			line.ProductName,
+			line.LotNo,
+			line.RefNo,
+			line.StoreLocation,
+			line.StoreCountry,
+			line.ExpiresAt,
+			line.DaysToExpiry,
+			line.StandardQuantity,
+			line.StandardUnit,
+			line.UnitCost,
+			line.Value,
+		})
+	}
+
+	table.Rows = append(table.Rows, []any{"Grand Total", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, report.TotalValue})
+
+	return table
+}
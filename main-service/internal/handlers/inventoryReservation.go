@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+func (h *inventoryHandler) GetReservations(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetReservations Hit")
+	productID := 0
+	if value := r.URL.Query().Get("productId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Error parsing productId", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		productID = id
+	}
+
+	jobOrderID := 0
+	if value := r.URL.Query().Get("jobOrderId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Error parsing jobOrderId", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		jobOrderID = id
+	}
+
+	reservations, err := h.service.GetReservations(productID, jobOrderID, r.URL.Query().Get("status"))
+	if err != nil {
+		slog.Error("Error getting reservations", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, reservations)
+}
+
+func (h *inventoryHandler) GetReservation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetReservation Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	reservation, err := h.service.GetReservation(id)
+	if err != nil {
+		slog.Error("Error getting reservation", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, reservation)
+}
+
+func (h *inventoryHandler) CreateReservation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateReservation Hit")
+	reservation := new(models.InventoryReservation)
+	if err := h.jsonH.ReadJSON(w, r, reservation); err != nil {
+		slog.Error("Error reading reservation", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	reservation, err := h.service.CreateReservation(reservation)
+	if err != nil {
+		slog.Error("Error creating reservation", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, reservation)
+}
+
+func (h *inventoryHandler) UpdateReservation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateReservation Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	reservation := new(models.InventoryReservation)
+	if err := h.jsonH.ReadJSON(w, r, reservation); err != nil {
+		slog.Error("Error reading reservation", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	reservation, err = h.service.UpdateReservation(id, reservation)
+	if err != nil {
+		slog.Error("Error updating reservation", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, reservation)
+}
+
+func (h *inventoryHandler) ReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ReleaseReservation Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	action := new(models.InventoryReservationAction)
+	if err := h.jsonH.ReadJSON(w, r, action); err != nil {
+		slog.Error("Error reading reservation action", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	reservation, err := h.service.ReleaseReservation(id, action)
+	if err != nil {
+		slog.Error("Error releasing reservation", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, reservation)
+}
+
+func (h *inventoryHandler) DeleteReservation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteReservation Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteReservation(id); err != nil {
+		slog.Error("Error deleting reservation", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+func (h *inventoryHandler) GetReturnReasons(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetReturnReasons Hit")
+	reasons, err := h.service.GetReturnReasons()
+	if err != nil {
+		slog.Error("Error getting return reasons", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, reasons)
+}
+
+func (h *inventoryHandler) GetReturns(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetReturns Hit")
+	productID := 0
+	if value := r.URL.Query().Get("productId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Error parsing productId", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		productID = id
+	}
+
+	returns, err := h.service.GetReturns(r.URL.Query().Get("type"), productID)
+	if err != nil {
+		slog.Error("Error getting returns", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, returns)
+}
+
+func (h *inventoryHandler) GetReturn(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetReturn Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	ret, err := h.service.GetReturn(id)
+	if err != nil {
+		slog.Error("Error getting return", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, ret)
+}
+
+func (h *inventoryHandler) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateReturn Hit")
+	ret := new(models.InventoryReturn)
+	if err := h.jsonH.ReadJSON(w, r, ret); err != nil {
+		slog.Error("Error reading return", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	ret, err := h.service.CreateReturn(ret)
+	if err != nil {
+		slog.Error("Error creating return", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, ret)
+}
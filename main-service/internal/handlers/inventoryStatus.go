@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+func (h *inventoryHandler) GetIncomingStatusHistory(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetIncomingStatusHistory Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.service.GetIncomingStatusHistory(id)
+	if err != nil {
+		slog.Error("Error getting incoming status history", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, changes)
+}
+
+func (h *inventoryHandler) TransitionIncoming(w http.ResponseWriter, r *http.Request) {
+	slog.Info("TransitionIncoming Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	transition := new(models.IncomingStatusTransition)
+	if err := h.jsonH.ReadJSON(w, r, transition); err != nil {
+		slog.Error("Error reading status transition", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	incoming, err := h.service.TransitionIncoming(id, transition)
+	if err != nil {
+		slog.Error("Error transitioning incoming", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, incoming)
+}
+
+// TransitionShipment moves every incoming of the shipment named by the ref
+// no in the body.
+func (h *inventoryHandler) TransitionShipment(w http.ResponseWriter, r *http.Request) {
+	slog.Info("TransitionShipment Hit")
+	transition := new(models.IncomingStatusTransition)
+	if err := h.jsonH.ReadJSON(w, r, transition); err != nil {
+		slog.Error("Error reading status transition", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	incomings, err := h.service.TransitionShipment(transition)
+	if err != nil {
+		slog.Error("Error transitioning shipment", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, incomings)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+func (h *inventoryHandler) GetTransfers(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetTransfers Hit")
+	transfers, err := h.service.GetTransfers()
+	if err != nil {
+		slog.Error("Error getting transfers", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, transfers)
+}
+
+func (h *inventoryHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetTransfer Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.service.GetTransfer(id)
+	if err != nil {
+		slog.Error("Error getting transfer", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, transfer)
+}
+
+func (h *inventoryHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateTransfer Hit")
+	transfer := new(models.InventoryTransfer)
+	if err := h.jsonH.ReadJSON(w, r, transfer); err != nil {
+		slog.Error("Error reading transfer", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.service.CreateTransfer(transfer)
+	if err != nil {
+		slog.Error("Error creating transfer", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, transfer)
+}
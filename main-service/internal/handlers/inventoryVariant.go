@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+func (h *inventoryHandler) GetProductVariants(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetProductVariants Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	variants, err := h.service.GetProductVariants(id)
+	if err != nil {
+		slog.Error("Error getting product variants", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, variants)
+}
+
+func (h *inventoryHandler) LinkProductVariant(w http.ResponseWriter, r *http.Request) {
+	slog.Info("LinkProductVariant Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	link := new(models.ProductVariantLink)
+	if err := h.jsonH.ReadJSON(w, r, link); err != nil {
+		slog.Error("Error reading product variant link", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.service.LinkProductVariant(id, link)
+	if err != nil {
+		slog.Error("Error linking product variant", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, product)
+}
+
+func (h *inventoryHandler) UnlinkProductVariant(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UnlinkProductVariant Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	variantID, err := strconv.Atoi(chi.URLParam(r, "variantId"))
+	if err != nil {
+		slog.Error("Error parsing variant id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UnlinkProductVariant(id, variantID); err != nil {
+		slog.Error("Error unlinking product variant", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/services"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/utils"
+)
+
+type JobOrderHandler interface {
+	GetCustomers(w http.ResponseWriter, r *http.Request)
+	GetCustomer(w http.ResponseWriter, r *http.Request)
+	CreateCustomer(w http.ResponseWriter, r *http.Request)
+	UpdateCustomer(w http.ResponseWriter, r *http.Request)
+	DeleteCustomer(w http.ResponseWriter, r *http.Request)
+
+	GetJobOrders(w http.ResponseWriter, r *http.Request)
+	GetJobOrder(w http.ResponseWriter, r *http.Request)
+	CreateJobOrder(w http.ResponseWriter, r *http.Request)
+	UpdateJobOrder(w http.ResponseWriter, r *http.Request)
+	DeleteJobOrder(w http.ResponseWriter, r *http.Request)
+
+	GetJobCosting(w http.ResponseWriter, r *http.Request)
+	GetJobCostingReport(w http.ResponseWriter, r *http.Request)
+}
+
+type jobOrderHandler struct {
+	jsonH   utils.JSONHandler
+	service services.JobOrderService
+}
+
+func NewJobOrderHandler() JobOrderHandler {
+	return &jobOrderHandler{
+		jsonH:   utils.NewJSONHandler(),
+		service: services.NewJobOrderService(),
+	}
+}
+
+// Customer
+func (h *jobOrderHandler) GetCustomers(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetCustomers Hit")
+	customers, err := h.service.GetCustomers()
+	if err != nil {
+		slog.Error("Error getting customers", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, customers)
+}
+
+func (h *jobOrderHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetCustomer Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.service.GetCustomer(id)
+	if err != nil {
+		slog.Error("Error getting customer", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, customer)
+}
+
+func (h *jobOrderHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateCustomer Hit")
+	customer := new(models.Customer)
+	if err := h.jsonH.ReadJSON(w, r, customer); err != nil {
+		slog.Error("Error reading customer", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.service.CreateCustomer(customer)
+	if err != nil {
+		slog.Error("Error creating customer", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, customer)
+}
+
+func (h *jobOrderHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateCustomer Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	customer := new(models.Customer)
+	if err := h.jsonH.ReadJSON(w, r, customer); err != nil {
+		slog.Error("Error reading customer", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	customer, err = h.service.UpdateCustomer(id, customer)
+	if err != nil {
+		slog.Error("Error updating customer", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, customer)
+}
+
+func (h *jobOrderHandler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteCustomer Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteCustomer(id); err != nil {
+		slog.Error("Error deleting customer", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+// Job order
+func (h *jobOrderHandler) GetJobOrders(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetJobOrders Hit")
+	customerID := 0
+	if value := r.URL.Query().Get("customerId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Error parsing customerId", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		customerID = id
+	}
+
+	jobs, err := h.service.GetJobOrders(customerID, r.URL.Query().Get("status"))
+	if err != nil {
+		slog.Error("Error getting job orders", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, jobs)
+}
+
+func (h *jobOrderHandler) GetJobOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetJobOrder Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.service.GetJobOrder(id)
+	if err != nil {
+		slog.Error("Error getting job order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, job)
+}
+
+func (h *jobOrderHandler) CreateJobOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateJobOrder Hit")
+	job := new(models.JobOrder)
+	if err := h.jsonH.ReadJSON(w, r, job); err != nil {
+		slog.Error("Error reading job order", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.service.CreateJobOrder(job)
+	if err != nil {
+		slog.Error("Error creating job order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, job)
+}
+
+func (h *jobOrderHandler) UpdateJobOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateJobOrder Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	job := new(models.JobOrder)
+	if err := h.jsonH.ReadJSON(w, r, job); err != nil {
+		slog.Error("Error reading job order", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	job, err = h.service.UpdateJobOrder(id, job)
+	if err != nil {
+		slog.Error("Error updating job order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, job)
+}
+
+func (h *jobOrderHandler) DeleteJobOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteJobOrder Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteJobOrder(id); err != nil {
+		slog.Error("Error deleting job order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+// Job costing
+func (h *jobOrderHandler) GetJobCosting(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetJobCosting Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	costing, err := h.service.GetJobCosting(id)
+	if err != nil {
+		slog.Error("Error getting job costing", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, costing)
+}
+
+func (h *jobOrderHandler) GetJobCostingReport(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetJobCostingReport Hit")
+	format, err := utils.ExportFormat(r)
+	if err != nil {
+		slog.Error("Error parsing format", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseDateParam(r, "from")
+	if err != nil {
+		slog.Error("Error parsing from", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseDateParam(r, "to")
+	if err != nil {
+		slog.Error("Error parsing to", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	filter := &models.JobCostingFilter{
+		Status: r.URL.Query().Get("status"),
+	}
+	if value := r.URL.Query().Get("customerId"); value != "" {
+		filter.CustomerID, err = strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Error parsing customerId", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.service.GetJobCostingReport(filter, from, to)
+	if err != nil {
+		slog.Error("Error getting job costing report", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	if format == utils.ExportFormatJSON {
+		h.jsonH.WriteJSON(w, http.StatusOK, report)
+		return
+	}
+
+	if err := utils.WriteTable(w, format, "job-costing", jobCostingTable(report)); err != nil {
+		slog.Error("Error writing job costing report", "error", err)
+	}
+}
+
+// jobCostingTable writes one row per job and product, following each job with
+// its subtotal and closing with the grand total.
+func jobCostingTable(report *models.JobCostingReport) *utils.Table {
+	table := &utils.Table{
+		Headers: []string{
+			"Job No",
+			"Customer",
+			"Site",
+			"Product Code",
+			"Product Name",
+			"Standard Quantity",
+			"Standard Unit",
+			"Cost",
+		},
+	}
+
+	for _, job := range report.Jobs {
+		for _, line := range job.Lines {
+			table.Rows = append(table.Rows, []any{
+				job.JobNo,
+				job.CustomerName,
+				job.SiteName,
+				line.ProductCode,
+				line.ProductName,
+				line.StandardQuantity,
+				line.StandardUnit,
+				line.Cost,
+			})
+		}
+
+		table.Rows = append(table.Rows, []any{"Subtotal " + job.JobNo, nil, nil, nil, nil, nil, nil, job.TotalCost})
+	}
+
+	table.Rows = append(table.Rows, []any{"Grand Total", nil, nil, nil, nil, nil, nil, report.TotalCost})
+
+	return table
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/services"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/utils"
+)
+
+type PurchaseOrderHandler interface {
+	GetPurchaseOrders(w http.ResponseWriter, r *http.Request)
+	GetPurchaseOrder(w http.ResponseWriter, r *http.Request)
+	CreatePurchaseOrder(w http.ResponseWriter, r *http.Request)
+	UpdatePurchaseOrder(w http.ResponseWriter, r *http.Request)
+	DeletePurchaseOrder(w http.ResponseWriter, r *http.Request)
+
+	ApprovePurchaseOrder(w http.ResponseWriter, r *http.Request)
+	ReceivePurchaseOrder(w http.ResponseWriter, r *http.Request)
+	ClosePurchaseOrder(w http.ResponseWriter, r *http.Request)
+}
+
+type purchaseOrderHandler struct {
+	jsonH   utils.JSONHandler
+	service services.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler() PurchaseOrderHandler {
+	return &purchaseOrderHandler{
+		jsonH:   utils.NewJSONHandler(),
+		service: services.NewPurchaseOrderService(),
+	}
+}
+
+func (h *purchaseOrderHandler) GetPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetPurchaseOrders Hit")
+	orders, err := h.service.GetPurchaseOrders(r.URL.Query().Get("status"))
+	if err != nil {
+		slog.Error("Error getting purchase orders", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, orders)
+}
+
+func (h *purchaseOrderHandler) GetPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetPurchaseOrder Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.GetPurchaseOrder(id)
+	if err != nil {
+		slog.Error("Error getting purchase order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, order)
+}
+
+func (h *purchaseOrderHandler) CreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreatePurchaseOrder Hit")
+	order := new(models.PurchaseOrder)
+	if err := h.jsonH.ReadJSON(w, r, order); err != nil {
+		slog.Error("Error reading purchase order", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.CreatePurchaseOrder(order)
+	if err != nil {
+		slog.Error("Error creating purchase order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, order)
+}
+
+func (h *purchaseOrderHandler) UpdatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdatePurchaseOrder Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order := new(models.PurchaseOrder)
+	if err := h.jsonH.ReadJSON(w, r, order); err != nil {
+		slog.Error("Error reading purchase order", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	order, err = h.service.UpdatePurchaseOrder(id, order)
+	if err != nil {
+		slog.Error("Error updating purchase order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, order)
+}
+
+func (h *purchaseOrderHandler) DeletePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeletePurchaseOrder Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeletePurchaseOrder(id); err != nil {
+		slog.Error("Error deleting purchase order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *purchaseOrderHandler) ApprovePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ApprovePurchaseOrder Hit")
+	id, action, ok := h.readAction(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := h.service.ApprovePurchaseOrder(id, action.By)
+	if err != nil {
+		slog.Error("Error approving purchase order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, order)
+}
+
+func (h *purchaseOrderHandler) ReceivePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ReceivePurchaseOrder Hit")
+	id, action, ok := h.readAction(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := h.service.ReceivePurchaseOrder(id, action)
+	if err != nil {
+		slog.Error("Error receiving purchase order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, order)
+}
+
+func (h *purchaseOrderHandler) ClosePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ClosePurchaseOrder Hit")
+	id, action, ok := h.readAction(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := h.service.ClosePurchaseOrder(id, action.By)
+	if err != nil {
+		slog.Error("Error closing purchase order", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, order)
+}
+
+// readAction parses the order id and action body shared by the status
+// changing endpoints, writing the error response itself when either is bad.
+func (h *purchaseOrderHandler) readAction(w http.ResponseWriter, r *http.Request) (int, *models.PurchaseOrderAction, bool) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return 0, nil, false
+	}
+
+	action := new(models.PurchaseOrderAction)
+	if err := h.jsonH.ReadJSON(w, r, action); err != nil {
+		slog.Error("Error reading purchase order action", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return 0, nil, false
+	}
+
+	return id, action, true
+}
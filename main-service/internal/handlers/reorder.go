@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/services"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/utils"
+)
+
+type ReorderHandler interface {
+	GetReorderRules(w http.ResponseWriter, r *http.Request)
+	GetReorderRule(w http.ResponseWriter, r *http.Request)
+	CreateReorderRule(w http.ResponseWriter, r *http.Request)
+	UpdateReorderRule(w http.ResponseWriter, r *http.Request)
+	DeleteReorderRule(w http.ResponseWriter, r *http.Request)
+
+	GetAlerts(w http.ResponseWriter, r *http.Request)
+	AcknowledgeAlert(w http.ResponseWriter, r *http.Request)
+	EvaluateAlerts(w http.ResponseWriter, r *http.Request)
+
+	GetSuggestedPurchases(w http.ResponseWriter, r *http.Request)
+}
+
+type reorderHandler struct {
+	jsonH   utils.JSONHandler
+	service services.ReorderService
+}
+
+func NewReorderHandler() ReorderHandler {
+	return &reorderHandler{
+		jsonH:   utils.NewJSONHandler(),
+		service: services.NewReorderService(),
+	}
+}
+
+// Reorder rule
+func (h *reorderHandler) GetReorderRules(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetReorderRules Hit")
+	productID := 0
+	if value := r.URL.Query().Get("productId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Error parsing productId", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		productID = id
+	}
+
+	rules, err := h.service.GetReorderRules(productID)
+	if err != nil {
+		slog.Error("Error getting reorder rules", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, rules)
+}
+
+func (h *reorderHandler) GetReorderRule(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetReorderRule Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.GetReorderRule(id)
+	if err != nil {
+		slog.Error("Error getting reorder rule", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, rule)
+}
+
+func (h *reorderHandler) CreateReorderRule(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateReorderRule Hit")
+	rule := new(models.ReorderRule)
+	if err := h.jsonH.ReadJSON(w, r, rule); err != nil {
+		slog.Error("Error reading reorder rule", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.CreateReorderRule(rule)
+	if err != nil {
+		slog.Error("Error creating reorder rule", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, rule)
+}
+
+func (h *reorderHandler) UpdateReorderRule(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateReorderRule Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	rule := new(models.ReorderRule)
+	if err := h.jsonH.ReadJSON(w, r, rule); err != nil {
+		slog.Error("Error reading reorder rule", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	rule, err = h.service.UpdateReorderRule(id, rule)
+	if err != nil {
+		slog.Error("Error updating reorder rule", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, rule)
+}
+
+func (h *reorderHandler) DeleteReorderRule(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteReorderRule Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteReorderRule(id); err != nil {
+		slog.Error("Error deleting reorder rule", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+// Alert
+func (h *reorderHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetAlerts Hit")
+	alerts, err := h.service.GetAlerts(r.URL.Query().Get("status"), r.URL.Query().Get("type"))
+	if err != nil {
+		slog.Error("Error getting alerts", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, alerts)
+}
+
+func (h *reorderHandler) AcknowledgeAlert(w http.ResponseWriter, r *http.Request) {
+	slog.Info("AcknowledgeAlert Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	action := new(models.InventoryAlertAction)
+	if err := h.jsonH.ReadJSON(w, r, action); err != nil {
+		slog.Error("Error reading alert action", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	alert, err := h.service.AcknowledgeAlert(id, action)
+	if err != nil {
+		slog.Error("Error acknowledging alert", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, alert)
+}
+
+// EvaluateAlerts runs the evaluation straight away instead of waiting for the
+// worker, and answers with the alerts still open afterwards.
+func (h *reorderHandler) EvaluateAlerts(w http.ResponseWriter, r *http.Request) {
+	slog.Info("EvaluateAlerts Hit")
+	if err := h.service.EvaluateReorderRules(0); err != nil {
+		slog.Error("Error evaluating reorder rules", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.EvaluateExpiryAlerts(0); err != nil {
+		slog.Error("Error evaluating expiry alerts", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	alerts, err := h.service.GetAlerts(models.AlertStatusOpen, "")
+	if err != nil {
+		slog.Error("Error getting alerts", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, alerts)
+}
+
+// Suggested purchase
+func (h *reorderHandler) GetSuggestedPurchases(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetSuggestedPurchases Hit")
+	format, err := utils.ExportFormat(r)
+	if err != nil {
+		slog.Error("Error parsing format", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	days := 0
+	if value := r.URL.Query().Get("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Error parsing days", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.service.GetSuggestedPurchases(days)
+	if err != nil {
+		slog.Error("Error getting suggested purchases", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	if format == utils.ExportFormatJSON {
+		h.jsonH.WriteJSON(w, http.StatusOK, report)
+		return
+	}
+
+	if err := utils.WriteTable(w, format, "suggested-purchases", suggestedPurchaseTable(report)); err != nil {
+		slog.Error("Error writing suggested purchases", "error", err)
+	}
+}
+
+// suggestedPurchaseTable writes one row per product, following each supplier
+// with its estimated subtotal.
+func suggestedPurchaseTable(report *models.SuggestedPurchaseReport) *utils.Table {
+	table := &utils.Table{
+		Headers: []string{
+			"Supplier Code",
+			"Supplier Name",
+			"Product Code",
+			"Product Name",
+			"Supplier SKU",
+			"Balance",
+			"On Order",
+			"Daily Consumption",
+			"Lead Time Days",
+			"Reorder Point",
+			"Suggested Quantity",
+			"Standard Unit",
+			"Unit Cost",
+			"Currency",
+			"Estimated Cost",
+		},
+	}
+
+	for _, group := range report.Groups {
+		for _, line := range group.Lines {
+			table.Rows = append(table.Rows, []any{
+				group.SupplierCode,
+				group.SupplierName,
+				line.ProductCode,
+				line.ProductName,
+				line.SupplierSKU,
+				line.Balance,
+				line.OnOrder,
+				line.DailyConsumption,
+				line.LeadTimeDays,
+				line.ReorderPoint,
+				line.SuggestedQuantity,
+				line.StandardUnit,
+				line.UnitCost,
+				line.Currency,
+				line.EstimatedCost,
+			})
+		}
+
+		table.Rows = append(table.Rows, []any{"Subtotal " + group.SupplierCode, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, group.EstimatedCost})
+	}
+
+	return table
+}
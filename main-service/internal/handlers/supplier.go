@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/services"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/utils"
+)
+
+type SupplierHandler interface {
+	GetSuppliers(w http.ResponseWriter, r *http.Request)
+	GetSupplier(w http.ResponseWriter, r *http.Request)
+	CreateSupplier(w http.ResponseWriter, r *http.Request)
+	UpdateSupplier(w http.ResponseWriter, r *http.Request)
+	DeleteSupplier(w http.ResponseWriter, r *http.Request)
+
+	GetSupplierProducts(w http.ResponseWriter, r *http.Request)
+	GetProductSuppliers(w http.ResponseWriter, r *http.Request)
+	SaveProductSupplier(w http.ResponseWriter, r *http.Request)
+	DeleteProductSupplier(w http.ResponseWriter, r *http.Request)
+
+	CreateSupplierPrice(w http.ResponseWriter, r *http.Request)
+	DeleteSupplierPrice(w http.ResponseWriter, r *http.Request)
+}
+
+type supplierHandler struct {
+	jsonH   utils.JSONHandler
+	service services.SupplierService
+}
+
+func NewSupplierHandler() SupplierHandler {
+	return &supplierHandler{
+		jsonH:   utils.NewJSONHandler(),
+		service: services.NewSupplierService(),
+	}
+}
+
+func (h *supplierHandler) GetSuppliers(w http.ResponseWriter, r *http.Request) {
+	suppliers, err := h.service.GetSuppliers()
+	if err != nil {
+		slog.Error("Error getting suppliers", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, suppliers)
+}
+
+func (h *supplierHandler) GetSupplier(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("Error converting id to int", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	supplier, err := h.service.GetSupplier(id)
+	if err != nil {
+		slog.Error("Error getting supplier", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, supplier)
+}
+
+func (h *supplierHandler) CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	supplier := new(models.Supplier)
+	if err := h.jsonH.ReadJSON(w, r, supplier); err != nil {
+		slog.Error("Error decoding supplier", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	supplier, err := h.service.CreateSupplier(supplier)
+	if err != nil {
+		slog.Error("Error creating supplier", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, supplier)
+}
+
+func (h *supplierHandler) UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("Error converting id to int", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	supplier := new(models.Supplier)
+	if err := h.jsonH.ReadJSON(w, r, supplier); err != nil {
+		slog.Error("Error decoding supplier", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	supplier, err = h.service.UpdateSupplier(id, supplier)
+	if err != nil {
+		slog.Error("Error updating supplier", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, supplier)
+}
+
+func (h *supplierHandler) DeleteSupplier(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("Error converting id to int", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteSupplier(id); err != nil {
+		slog.Error("Error deleting supplier", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *supplierHandler) GetSupplierProducts(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("Error converting id to int", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	products, err := h.service.GetSupplierProducts(id)
+	if err != nil {
+		slog.Error("Error getting supplier products", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, products)
+}
+
+func (h *supplierHandler) GetProductSuppliers(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("Error converting id to int", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	suppliers, err := h.service.GetProductSuppliers(id)
+	if err != nil {
+		slog.Error("Error getting product suppliers", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, suppliers)
+}
+
+func (h *supplierHandler) SaveProductSupplier(w http.ResponseWriter, r *http.Request) {
+	supplierID, productID, ok := h.linkIDs(w, r)
+	if !ok {
+		return
+	}
+
+	link := new(models.ProductSupplier)
+	if err := h.jsonH.ReadJSON(w, r, link); err != nil {
+		slog.Error("Error decoding product supplier", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	link.SupplierID = supplierID
+	link.ProductID = productID
+
+	link, err := h.service.SaveProductSupplier(link)
+	if err != nil {
+		slog.Error("Error saving product supplier", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, link)
+}
+
+func (h *supplierHandler) DeleteProductSupplier(w http.ResponseWriter, r *http.Request) {
+	supplierID, productID, ok := h.linkIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteProductSupplier(supplierID, productID); err != nil {
+		slog.Error("Error deleting product supplier", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *supplierHandler) CreateSupplierPrice(w http.ResponseWriter, r *http.Request) {
+	supplierID, productID, ok := h.linkIDs(w, r)
+	if !ok {
+		return
+	}
+
+	price := new(models.SupplierPrice)
+	if err := h.jsonH.ReadJSON(w, r, price); err != nil {
+		slog.Error("Error decoding supplier price", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	price, err := h.service.CreateSupplierPrice(supplierID, productID, price)
+	if err != nil {
+		slog.Error("Error creating supplier price", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, price)
+}
+
+func (h *supplierHandler) DeleteSupplierPrice(w http.ResponseWriter, r *http.Request) {
+	supplierID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("Error converting id to int", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	priceID, err := strconv.Atoi(chi.URLParam(r, "priceId"))
+	if err != nil {
+		slog.Error("Error converting price id to int", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteSupplierPrice(supplierID, priceID); err != nil {
+		slog.Error("Error deleting supplier price", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+// linkIDs reads the supplier and product ids of a product supplier route.
+func (h *supplierHandler) linkIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	supplierID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("Error converting id to int", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "productId"))
+	if err != nil {
+		slog.Error("Error converting product id to int", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return supplierID, productID, true
+}
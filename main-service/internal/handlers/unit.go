@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/services"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/utils"
+)
+
+type UnitHandler interface {
+	GetUnits(w http.ResponseWriter, r *http.Request)
+	CreateUnit(w http.ResponseWriter, r *http.Request)
+	UpdateUnit(w http.ResponseWriter, r *http.Request)
+	DeleteUnit(w http.ResponseWriter, r *http.Request)
+
+	GetProductUnits(w http.ResponseWriter, r *http.Request)
+	SaveProductUnit(w http.ResponseWriter, r *http.Request)
+	DeleteProductUnit(w http.ResponseWriter, r *http.Request)
+}
+
+type unitHandler struct {
+	jsonH   utils.JSONHandler
+	service services.UnitService
+}
+
+func NewUnitHandler() UnitHandler {
+	return &unitHandler{
+		jsonH:   utils.NewJSONHandler(),
+		service: services.NewUnitService(),
+	}
+}
+
+func (h *unitHandler) GetUnits(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetUnits Hit")
+	units, err := h.service.GetUnits()
+	if err != nil {
+		slog.Error("Error getting units", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, units)
+}
+
+func (h *unitHandler) CreateUnit(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateUnit Hit")
+	unit := new(models.Unit)
+	if err := h.jsonH.ReadJSON(w, r, unit); err != nil {
+		slog.Error("Error reading unit", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	unit, err := h.service.CreateUnit(unit)
+	if err != nil {
+		slog.Error("Error creating unit", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusCreated, unit)
+}
+
+func (h *unitHandler) UpdateUnit(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateUnit Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	unit := new(models.Unit)
+	if err := h.jsonH.ReadJSON(w, r, unit); err != nil {
+		slog.Error("Error reading unit", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	unit, err = h.service.UpdateUnit(id, unit)
+	if err != nil {
+		slog.Error("Error updating unit", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, unit)
+}
+
+func (h *unitHandler) DeleteUnit(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteUnit Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteUnit(id); err != nil {
+		slog.Error("Error deleting unit", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *unitHandler) GetProductUnits(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetProductUnits Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	conversions, err := h.service.GetProductUnits(id)
+	if err != nil {
+		slog.Error("Error getting product units", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, conversions)
+}
+
+func (h *unitHandler) SaveProductUnit(w http.ResponseWriter, r *http.Request) {
+	slog.Info("SaveProductUnit Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	conversion := new(models.ProductUnitConversion)
+	if err := h.jsonH.ReadJSON(w, r, conversion); err != nil {
+		slog.Error("Error reading product unit", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	conversion.ProductID = id
+	conversion.Unit = chi.URLParam(r, "unit")
+
+	conversion, err = h.service.SaveProductUnit(conversion)
+	if err != nil {
+		slog.Error("Error saving product unit", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, conversion)
+}
+
+func (h *unitHandler) DeleteProductUnit(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteProductUnit Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteProductUnit(id, chi.URLParam(r, "unit")); err != nil {
+		slog.Error("Error deleting product unit", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
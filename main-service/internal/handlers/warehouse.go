@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/services"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/utils"
+)
+
+type WarehouseHandler interface {
+	GetWarehouses(w http.ResponseWriter, r *http.Request)
+	GetWarehouse(w http.ResponseWriter, r *http.Request)
+	CreateWarehouse(w http.ResponseWriter, r *http.Request)
+	UpdateWarehouse(w http.ResponseWriter, r *http.Request)
+	DeleteWarehouse(w http.ResponseWriter, r *http.Request)
+	GetWarehouseStock(w http.ResponseWriter, r *http.Request)
+
+	GetLocations(w http.ResponseWriter, r *http.Request)
+	GetLocation(w http.ResponseWriter, r *http.Request)
+	CreateLocation(w http.ResponseWriter, r *http.Request)
+	UpdateLocation(w http.ResponseWriter, r *http.Request)
+	DeleteLocation(w http.ResponseWriter, r *http.Request)
+	GetLocationStock(w http.ResponseWriter, r *http.Request)
+}
+
+type warehouseHandler struct {
+	jsonH   utils.JSONHandler
+	service services.WarehouseService
+}
+
+func NewWarehouseHandler() WarehouseHandler {
+	return &warehouseHandler{
+		jsonH:   utils.NewJSONHandler(),
+		service: services.NewWarehouseService(),
+	}
+}
+
+// Warehouse
+func (h *warehouseHandler) GetWarehouses(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetWarehouses Hit")
+	warehouses, err := h.service.GetWarehouses()
+	if err != nil {
+		slog.Error("Error getting warehouses", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, warehouses)
+}
+
+func (h *warehouseHandler) GetWarehouse(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetWarehouse Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	warehouse, err := h.service.GetWarehouse(id)
+	if err != nil {
+		slog.Error("Error getting warehouse", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, warehouse)
+}
+
+func (h *warehouseHandler) CreateWarehouse(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateWarehouse Hit")
+	warehouse := new(models.Warehouse)
+	if err := h.jsonH.ReadJSON(w, r, warehouse); err != nil {
+		slog.Error("Error reading warehouse", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	warehouse, err := h.service.CreateWarehouse(warehouse)
+	if err != nil {
+		slog.Error("Error creating warehouse", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, warehouse)
+}
+
+func (h *warehouseHandler) UpdateWarehouse(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateWarehouse Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	warehouse := new(models.Warehouse)
+	if err := h.jsonH.ReadJSON(w, r, warehouse); err != nil {
+		slog.Error("Error reading warehouse", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	warehouse, err = h.service.UpdateWarehouse(id, warehouse)
+	if err != nil {
+		slog.Error("Error updating warehouse", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, warehouse)
+}
+
+func (h *warehouseHandler) DeleteWarehouse(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteWarehouse Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteWarehouse(id); err != nil {
+		slog.Error("Error deleting warehouse", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *warehouseHandler) GetWarehouseStock(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetWarehouseStock Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	stock, err := h.service.GetWarehouseStock(id)
+	if err != nil {
+		slog.Error("Error getting warehouse stock", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, stock)
+}
+
+// Location
+func (h *warehouseHandler) GetLocations(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetLocations Hit")
+	warehouseID := 0
+	if warehouseIDStr := r.URL.Query().Get("warehouseId"); warehouseIDStr != "" {
+		var err error
+		warehouseID, err = strconv.Atoi(warehouseIDStr)
+		if err != nil {
+			slog.Error("Error parsing warehouseId", "error", err)
+			h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	locations, err := h.service.GetLocations(warehouseID)
+	if err != nil {
+		slog.Error("Error getting locations", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, locations)
+}
+
+func (h *warehouseHandler) GetLocation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetLocation Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	location, err := h.service.GetLocation(id)
+	if err != nil {
+		slog.Error("Error getting location", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, location)
+}
+
+func (h *warehouseHandler) CreateLocation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateLocation Hit")
+	location := new(models.Location)
+	if err := h.jsonH.ReadJSON(w, r, location); err != nil {
+		slog.Error("Error reading location", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	location, err := h.service.CreateLocation(location)
+	if err != nil {
+		slog.Error("Error creating location", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, location)
+}
+
+func (h *warehouseHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateLocation Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	location := new(models.Location)
+	if err := h.jsonH.ReadJSON(w, r, location); err != nil {
+		slog.Error("Error reading location", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	location, err = h.service.UpdateLocation(id, location)
+	if err != nil {
+		slog.Error("Error updating location", "error", err)
+		writeServiceError(h.jsonH, w, err)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, location)
+}
+
+func (h *warehouseHandler) DeleteLocation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteLocation Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteLocation(id); err != nil {
+		slog.Error("Error deleting location", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (h *warehouseHandler) GetLocationStock(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetLocationStock Hit")
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		slog.Error("Error parsing id", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	stock, err := h.service.GetLocationStock(id)
+	if err != nil {
+		slog.Error("Error getting location stock", "error", err)
+		h.jsonH.ErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonH.WriteJSON(w, http.StatusOK, stock)
+}
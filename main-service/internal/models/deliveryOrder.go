@@ -0,0 +1,36 @@
+package models
+
+// DeliveryOrder groups the outgoings sent out together. Document is the path
+// of its rendered PDF, which is also set as the ref doc of every outgoing.
+type DeliveryOrder struct {
+	ID              int    `json:"id" db:"id"`
+	DONo            string `json:"doNo" db:"do_no"`
+	JobOrderID      int    `json:"jobOrderId" db:"job_order_id"`
+	CustomerID      int    `json:"customerId" db:"customer_id"`
+	DeliverTo       string `json:"deliverTo" db:"deliver_to"`
+	DeliveryAddress string `json:"deliveryAddress" db:"delivery_address"`
+	DeliveredAt     string `json:"deliveredAt" db:"delivered_at"`
+	Document        string `json:"document" db:"document"`
+	Remarks         string `json:"remarks" db:"remarks"`
+	CreatedBy       string `json:"createdBy" db:"created_by"`
+	CreatedAt       string `json:"createdAt" db:"created_at"`
+	UpdatedBy       string `json:"updatedBy" db:"updated_by"`
+	UpdatedAt       string `json:"updatedAt" db:"updated_at"`
+
+	JobNo        string `json:"jobNo" db:"job_no"`
+	CustomerName string `json:"customerName" db:"customer_name"`
+
+	OutgoingIDs []int                `json:"outgoingIds"`
+	Lines       []*DeliveryOrderLine `json:"lines"`
+}
+
+type DeliveryOrderLine struct {
+	OutgoingID       int     `json:"outgoingId" db:"outgoing_id"`
+	ProductID        int     `json:"productId" db:"product_id"`
+	ProductCode      string  `json:"productCode" db:"product_code"`
+	ProductName      string  `json:"productName" db:"product_name"`
+	StandardQuantity float64 `json:"standardQuantity" db:"standard_quantity"`
+	StandardUnit     string  `json:"standardUnit" db:"standard_unit"`
+	RefNo            string  `json:"refNo" db:"ref_no"`
+	Remarks          string  `json:"remarks" db:"remarks"`
+}
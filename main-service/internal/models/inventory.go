@@ -1,60 +1,138 @@
 package models
 
+// InventoryProduct is an item kept in stock. Tags are free-form labels kept
+// in lower case, Attributes the typed values its category asks for.
+//
+// A variant has ParentID set and shares the brand, supplier, category and
+// standard unit of its parent. The parent's VariantAxes are the attribute
+// codes its variants differ by, every variant having its own combination.
 type InventoryProduct struct {
-	ID           int    `json:"id" db:"id"`
-	Code         string `json:"code" db:"code"`
-	Name         string `json:"name" db:"name"`
-	Brand        string `json:"brand" db:"brand"`
-	StandardUnit string `json:"standardUnit" db:"standard_unit"`
-	Thumbnail    string `json:"thumbnail" db:"thumbnail"`
-	Supplier     string `json:"supplier" db:"supplier"`
-	Remarks      string `json:"remarks" db:"remarks"`
-	IsExist      bool   `json:"isExist" db:"is_exist"`
-	CreatedBy    string `json:"createdBy" db:"created_by"`
-	CreatedAt    string `json:"createdAt" db:"created_at"`
-	UpdatedBy    string `json:"updatedBy" db:"updated_by"`
-	UpdatedAt    string `json:"updatedAt" db:"updated_at"`
+	ID            int    `json:"id" db:"id"`
+	Code          string `json:"code" db:"code"`
+	Name          string `json:"name" db:"name"`
+	Brand         string `json:"brand" db:"brand"`
+	StandardUnit  string `json:"standardUnit" db:"standard_unit"`
+	Thumbnail     string `json:"thumbnail" db:"thumbnail"` // path of the primary image
+	Supplier      string `json:"supplier" db:"supplier"`
+	Remarks       string `json:"remarks" db:"remarks"`
+	CostingMethod string `json:"costingMethod" db:"costing_method"`
+	CategoryID    int    `json:"categoryId" db:"category_id"`
+	ParentID      int    `json:"parentId" db:"parent_id"`
+	IsExist       bool   `json:"isExist" db:"is_exist"`
+	CreatedBy     string `json:"createdBy" db:"created_by"`
+	CreatedAt     string `json:"createdAt" db:"created_at"`
+	UpdatedBy     string `json:"updatedBy" db:"updated_by"`
+	UpdatedAt     string `json:"updatedAt" db:"updated_at"`
+
+	CategoryPath string                   `json:"categoryPath" db:"category_path"`
+	Tags         []string                 `json:"tags" db:"tags"`
+	VariantAxes  []string                 `json:"variantAxes" db:"variant_axes"`
+	Attributes   []*ProductAttributeValue `json:"attributes,omitempty"`
+	Media        []*ProductMedia          `json:"media,omitempty"`
 }
+
+// InventoryIncoming is a receipt of stock. ReservedStdQty is what
+// reservations made for this incoming hold, reservations on the product as
+// a whole are left out as they could be met from any incoming.
+//
+// An incoming with SerialNos is serialised, it holds one item per serial and
+// every outgoing from it issues whole items by serial.
 type InventoryIncoming struct {
-	ID               int     `json:"id" db:"id"`
-	ProductID        int     `json:"productId" db:"product_id"`
-	Status           string  `json:"status" db:"status"`
-	Quantity         float64 `json:"quantity" db:"quantity"`
-	Length           float64 `json:"length" db:"length"`
-	Width            float64 `json:"width" db:"width"`
-	Height           float64 `json:"height" db:"height"`
-	Unit             string  `json:"unit" db:"unit"`
-	StandardQuantity float64 `json:"standardQuantity" db:"standard_quantity"`
-	RefNo            string  `json:"refNo" db:"ref_no"`
-	RefDoc           string  `json:"refDoc" db:"ref_doc"`
-	Cost             float64 `json:"cost" db:"cost"`
-	StoreLocation    string  `json:"storeLocation" db:"store_location"`
-	StoreCountry     string  `json:"storeCountry" db:"store_country"`
-	Remarks          string  `json:"remarks" db:"remarks"`
-	CreatedBy        string  `json:"createdBy" db:"created_by"`
-	CreatedAt        string  `json:"createdAt" db:"created_at"`
-	UpdatedBy        string  `json:"updatedBy" db:"updated_by"`
-	UpdatedAt        string  `json:"updatedAt" db:"updated_at"`
+	ID                  int     `json:"id" db:"id"`
+	ProductID           int     `json:"productId" db:"product_id"`
+	Status              string  `json:"status" db:"status"`
+	Quantity            float64 `json:"quantity" db:"quantity"`
+	Length              float64 `json:"length" db:"length"`
+	Width               float64 `json:"width" db:"width"`
+	Height              float64 `json:"height" db:"height"`
+	Unit                string  `json:"unit" db:"unit"`
+	QuantityUnit        string  `json:"quantityUnit" db:"quantity_unit"`
+	StandardQuantity    float64 `json:"standardQuantity" db:"standard_quantity"`
+	RefNo               string  `json:"refNo" db:"ref_no"`
+	RefDoc              string  `json:"refDoc" db:"ref_doc"`
+	Cost                float64 `json:"cost" db:"cost"`
+	LocationID          int     `json:"locationId" db:"location_id"`
+	SourceIncomingID    int     `json:"sourceIncomingId" db:"source_incoming_id"`
+	PurchaseOrderLineID int     `json:"purchaseOrderLineId" db:"purchase_order_line_id"`
+	Supplier            string  `json:"supplier" db:"supplier"`
+	LotNo               string  `json:"lotNo" db:"lot_no"`
+	ManufacturedAt      string  `json:"manufacturedAt" db:"manufactured_at"`
+	ExpiresAt           string  `json:"expiresAt" db:"expires_at"`
+	StoreLocation       string  `json:"storeLocation" db:"store_location"`
+	StoreCountry        string  `json:"storeCountry" db:"store_country"`
+	Remarks             string  `json:"remarks" db:"remarks"`
+	ReceivedAt          string  `json:"receivedAt" db:"received_at"`
+	CreatedBy           string  `json:"createdBy" db:"created_by"`
+	CreatedAt           string  `json:"createdAt" db:"created_at"`
+	UpdatedBy           string  `json:"updatedBy" db:"updated_by"`
+	UpdatedAt           string  `json:"updatedAt" db:"updated_at"`
 
 	ProductCode  string `json:"productCode" db:"product_code"`
 	ProductName  string `json:"productName" db:"product_name"`
 	StandardUnit string `json:"standardUnit" db:"standard_unit"`
 
-	BalanceStdQty float64 `json:"balanceStdQty" db:"balance_std_qty"`
-	BalanceQty    float64 `json:"balanceQty" db:"balance_qty"`
+	BalanceStdQty   float64 `json:"balanceStdQty" db:"balance_std_qty"`
+	BalanceQty      float64 `json:"balanceQty" db:"balance_qty"`
+	ReservedStdQty  float64 `json:"reservedStdQty" db:"reserved_std_qty"`
+	AvailableStdQty float64 `json:"availableStdQty" db:"available_std_qty"`
+
+	SerialNos []string `json:"serialNos,omitempty"`
+}
+
+// Incoming statuses. Stock moves from in-transit to received, through
+// qc-hold when it has to be inspected, to available and ends depleted or
+// closed. Only available stock can be issued.
+const (
+	IncomingStatusInTransit = "in-transit"
+	IncomingStatusReceived  = "received"
+	IncomingStatusQCHold    = "qc-hold"
+	IncomingStatusAvailable = "available"
+	IncomingStatusDepleted  = "depleted"
+	IncomingStatusClosed    = "closed"
+)
+
+// IncomingStatusTransition is the body of a status change, of one incoming or
+// of every incoming of the shipment with RefNo.
+type IncomingStatusTransition struct {
+	Status  string `json:"status"`
+	RefNo   string `json:"refNo"`
+	By      string `json:"by"`
+	Remarks string `json:"remarks"`
 }
 
+// IncomingStatusChange is a row of the status history of an incoming,
+// FromStatus is empty for the status it was created with.
+type IncomingStatusChange struct {
+	ID         int    `json:"id" db:"id"`
+	IncomingID int    `json:"incomingId" db:"incoming_id"`
+	FromStatus string `json:"fromStatus" db:"from_status"`
+	ToStatus   string `json:"toStatus" db:"to_status"`
+	Remarks    string `json:"remarks" db:"remarks"`
+	ChangedBy  string `json:"changedBy" db:"changed_by"`
+	ChangedAt  string `json:"changedAt" db:"changed_at"`
+}
+
+// InventoryOutgoing issues stock from an incoming. An expired lot is only
+// issued when ExpiryOverrideBy names who allowed it. SerialNos are the items
+// issued from a serialised incoming, the first ones in stock are taken when
+// none are given.
 type InventoryOutgoing struct {
 	ID               int     `json:"id" db:"id"`
 	IncomingID       int     `json:"incomingId" db:"incoming_id"`
 	ProductID        int     `json:"productId" db:"product_id"`
 	Status           string  `json:"status" db:"status"`
 	Quantity         float64 `json:"quantity" db:"quantity"`
+	QuantityUnit     string  `json:"quantityUnit" db:"quantity_unit"`
 	StandardQuantity float64 `json:"standardQuantity" db:"standard_quantity"`
+	UnitCost         float64 `json:"unitCost" db:"unit_cost"`
 	Cost             float64 `json:"cost" db:"cost"`
 	RefNo            string  `json:"refNo" db:"ref_no"`
 	RefDoc           string  `json:"refDoc" db:"ref_doc"`
 	Remarks          string  `json:"remarks" db:"remarks"`
+	JobOrderID       int     `json:"jobOrderId" db:"job_order_id"`
+	ReservationID    int     `json:"reservationId" db:"reservation_id"`
+	DeliveryOrderID  int     `json:"deliveryOrderId" db:"delivery_order_id"`
+	ExpiryOverrideBy string  `json:"expiryOverrideBy" db:"expiry_override_by"`
 	CreatedBy        string  `json:"createdBy" db:"created_by"`
 	CreatedAt        string  `json:"createdAt" db:"created_at"`
 	UpdatedBy        string  `json:"updatedBy" db:"updated_by"`
@@ -63,11 +141,210 @@ type InventoryOutgoing struct {
 	ProductCode  string `json:"productCode" db:"product_code"`
 	ProductName  string `json:"productName" db:"product_name"`
 	StandardUnit string `json:"standardUnit" db:"standard_unit"`
+
+	SerialNos []string `json:"serialNos,omitempty"`
 }
 
+// InventoryProductSummary totals the movements of a product. TotalReserved is
+// what active reservations hold and TotalAvailable the balance left to
+// promise. Reservations and open orders are only known as they stand today,
+// so these and OnOrder are null in a summary as of an earlier date.
 type InventoryProductSummary struct {
 	InventoryProduct
-	TotalIncoming float64 `json:"totalIncoming" db:"total_incoming"`
-	TotalOutgoing float64 `json:"totalOutgoing" db:"total_outgoing"`
-	TotalBalance  float64 `json:"totalBalance" db:"total_balance"`
+	TotalIncoming  float64  `json:"totalIncoming" db:"total_incoming"`
+	TotalOutgoing  float64  `json:"totalOutgoing" db:"total_outgoing"`
+	TotalBalance   float64  `json:"totalBalance" db:"total_balance"`
+	TotalReserved  *float64 `json:"totalReserved" db:"total_reserved"`
+	TotalAvailable *float64 `json:"totalAvailable" db:"total_available"`
+	OnOrder        *float64 `json:"onOrder" db:"on_order"`
+
+	// Variants are only filled in by the roll up, whose totals include them.
+	Variants []*InventoryProductSummary `json:"variants,omitempty"`
+}
+
+// Costing methods decide how the cost of an outgoing is worked out from the
+// cost of the incomings of its product.
+const (
+	CostingMethodFIFO            = "fifo"
+	CostingMethodWeightedAverage = "weighted_average"
+	CostingMethodSpecific        = "specific"
+)
+
+// Allocation rules decide the order in which open incomings are drawn from
+// when stock is issued by product instead of by incoming. FEFO takes the lot
+// expiring first, incomings without an expiry date last.
+const (
+	AllocationRuleFIFO = "fifo"
+	AllocationRuleLIFO = "lifo"
+	AllocationRuleFEFO = "fefo"
+)
+
+type InventoryAllocationRequest struct {
+	ProductID        int     `json:"productId"`
+	StandardQuantity float64 `json:"standardQuantity"`
+	Rule             string  `json:"rule"`
+	DryRun           bool    `json:"dryRun"`
+	Status           string  `json:"status"`
+	RefNo            string  `json:"refNo"`
+	RefDoc           string  `json:"refDoc"`
+	Remarks          string  `json:"remarks"`
+	JobOrderID       int     `json:"jobOrderId"`
+	ReservationID    int     `json:"reservationId"`
+	ExpiryOverrideBy string  `json:"expiryOverrideBy"`
+	CreatedBy        string  `json:"createdBy"`
+}
+
+type InventoryAllocationLine struct {
+	IncomingID       int     `json:"incomingId"`
+	IncomingRefNo    string  `json:"incomingRefNo"`
+	StoreLocation    string  `json:"storeLocation"`
+	StoreCountry     string  `json:"storeCountry"`
+	ReceivedAt       string  `json:"receivedAt"`
+	LotNo            string  `json:"lotNo"`
+	ExpiresAt        string  `json:"expiresAt"`
+	BalanceStdQty    float64 `json:"balanceStdQty"`
+	Quantity         float64 `json:"quantity"`
+	QuantityUnit     string  `json:"quantityUnit"`
+	StandardQuantity float64 `json:"standardQuantity"`
+	UnitCost         float64 `json:"unitCost"`
+	Cost             float64 `json:"cost"`
+	OutgoingID       int     `json:"outgoingId,omitempty"`
+}
+
+type InventoryAllocation struct {
+	ProductID        int                        `json:"productId"`
+	Rule             string                     `json:"rule"`
+	DryRun           bool                       `json:"dryRun"`
+	StandardQuantity float64                    `json:"standardQuantity"`
+	Lines            []*InventoryAllocationLine `json:"lines"`
+}
+
+// Valuation reports group their lines by product, by location or by the
+// category of the product, products without one grouped as uncategorised.
+const (
+	ValuationGroupByProduct  = "product"
+	ValuationGroupByLocation = "location"
+	ValuationGroupByCategory = "category"
+)
+
+type InventoryValuationLine struct {
+	ProductID        int     `json:"productId"`
+	ProductCode      string  `json:"productCode"`
+	ProductName      string  `json:"productName"`
+	CategoryPath     string  `json:"categoryPath"`
+	StandardUnit     string  `json:"standardUnit"`
+	StoreLocation    string  `json:"storeLocation"`
+	StoreCountry     string  `json:"storeCountry"`
+	StandardQuantity float64 `json:"standardQuantity"`
+	AverageCost      float64 `json:"averageCost"`
+	TotalValue       float64 `json:"totalValue"`
+}
+
+type InventoryValuationGroup struct {
+	Key              string                    `json:"key"`
+	Lines            []*InventoryValuationLine `json:"lines"`
+	StandardQuantity float64                   `json:"standardQuantity"`
+	TotalValue       float64                   `json:"totalValue"`
+}
+
+type InventoryValuationReport struct {
+	GroupBy    string                     `json:"groupBy"`
+	AsOf       string                     `json:"asOf"`
+	Groups     []*InventoryValuationGroup `json:"groups"`
+	TotalValue float64                    `json:"totalValue"`
+}
+
+// MovementTypeOpening marks the brought forward line at the top of a ledger.
+const MovementTypeOpening = "opening"
+
+type InventoryLedgerLine struct {
+	MovedAt      string  `json:"movedAt"`
+	MovementType string  `json:"movementType"`
+	DocumentID   int     `json:"documentId"`
+	IncomingID   int     `json:"incomingId"`
+	RefNo        string  `json:"refNo"`
+	Remarks      string  `json:"remarks"`
+	QuantityIn   float64 `json:"quantityIn"`
+	QuantityOut  float64 `json:"quantityOut"`
+	Balance      float64 `json:"balance"`
+	UnitCost     float64 `json:"unitCost"`
+	Value        float64 `json:"value"`
+	RunningValue float64 `json:"runningValue"`
+}
+
+type InventoryLedger struct {
+	ProductID    int                    `json:"productId"`
+	ProductCode  string                 `json:"productCode"`
+	ProductName  string                 `json:"productName"`
+	StandardUnit string                 `json:"standardUnit"`
+	From         string                 `json:"from"`
+	To           string                 `json:"to"`
+	Lines        []*InventoryLedgerLine `json:"lines"`
+}
+
+// Aging reports measure the age of stock from the received date by default,
+// or from when the incoming was keyed in.
+const (
+	AgingBasisReceived = "received"
+	AgingBasisCreated  = "created"
+)
+
+// InventoryAgingFilter narrows the aging report. Supplier is the name or code
+// of a supplier record, as the supplier text of products was migrated to.
+type InventoryAgingFilter struct {
+	Brand      string `json:"brand"`
+	Supplier   string `json:"supplier"`
+	SupplierID int    `json:"supplierId"`
+	Basis      string `json:"basis"`
+}
+
+type InventoryAgingBucket struct {
+	Label            string  `json:"label"`
+	MinDays          int     `json:"minDays"`
+	MaxDays          int     `json:"maxDays"`
+	StandardQuantity float64 `json:"standardQuantity"`
+	Value            float64 `json:"value"`
+}
+
+type InventoryAgingLine struct {
+	ProductID        int                     `json:"productId"`
+	ProductCode      string                  `json:"productCode"`
+	ProductName      string                  `json:"productName"`
+	Brand            string                  `json:"brand"`
+	Supplier         string                  `json:"supplier"`
+	StandardUnit     string                  `json:"standardUnit"`
+	StoreLocation    string                  `json:"storeLocation"`
+	StoreCountry     string                  `json:"storeCountry"`
+	Buckets          []*InventoryAgingBucket `json:"buckets"`
+	StandardQuantity float64                 `json:"standardQuantity"`
+	Value            float64                 `json:"value"`
+}
+
+type InventoryAgingReport struct {
+	Basis      string                `json:"basis"`
+	Lines      []*InventoryAgingLine `json:"lines"`
+	TotalValue float64               `json:"totalValue"`
+}
+
+type InventoryTransfer struct {
+	ID               int     `json:"id" db:"id"`
+	ProductID        int     `json:"productId" db:"product_id"`
+	FromIncomingID   int     `json:"fromIncomingId" db:"from_incoming_id"`
+	ToIncomingID     int     `json:"toIncomingId" db:"to_incoming_id"`
+	FromLocationID   int     `json:"fromLocationId" db:"from_location_id"`
+	ToLocationID     int     `json:"toLocationId" db:"to_location_id"`
+	Quantity         float64 `json:"quantity" db:"quantity"`
+	StandardQuantity float64 `json:"standardQuantity" db:"standard_quantity"`
+	UnitCost         float64 `json:"unitCost" db:"unit_cost"`
+	Reason           string  `json:"reason" db:"reason"`
+	Remarks          string  `json:"remarks" db:"remarks"`
+	TransferredBy    string  `json:"transferredBy" db:"transferred_by"`
+	TransferredAt    string  `json:"transferredAt" db:"transferred_at"`
+	CreatedAt        string  `json:"createdAt" db:"created_at"`
+
+	ProductCode       string `json:"productCode" db:"product_code"`
+	ProductName       string `json:"productName" db:"product_name"`
+	StandardUnit      string `json:"standardUnit" db:"standard_unit"`
+	FromStoreLocation string `json:"fromStoreLocation" db:"from_store_location"`
+	ToStoreLocation   string `json:"toStoreLocation" db:"to_store_location"`
 }
@@ -0,0 +1,94 @@
+package models
+
+const (
+	AdjustmentStatusPending  = "pending"
+	AdjustmentStatusApproved = "approved"
+	AdjustmentStatusRejected = "rejected"
+
+	AdjustmentReasonCountCorrection = "count_correction"
+
+	CycleCountStatusOpen      = "open"
+	CycleCountStatusPosted    = "posted"
+	CycleCountStatusCancelled = "cancelled"
+)
+
+type InventoryAdjustmentReason struct {
+	Code    string `json:"code" db:"code"`
+	Name    string `json:"name" db:"name"`
+	IsExist bool   `json:"isExist" db:"is_exist"`
+}
+
+// InventoryAdjustment corrects the balance of an incoming. StandardQuantity is
+// signed, negative for shrinkage or damage and positive for found stock.
+type InventoryAdjustment struct {
+	ID               int     `json:"id" db:"id"`
+	ProductID        int     `json:"productId" db:"product_id"`
+	IncomingID       int     `json:"incomingId" db:"incoming_id"`
+	ReasonCode       string  `json:"reasonCode" db:"reason_code"`
+	CycleCountID     int     `json:"cycleCountId" db:"cycle_count_id"`
+	Quantity         float64 `json:"quantity" db:"quantity"`
+	StandardQuantity float64 `json:"standardQuantity" db:"standard_quantity"`
+	UnitCost         float64 `json:"unitCost" db:"unit_cost"`
+	Value            float64 `json:"value"`
+	Status           string  `json:"status" db:"status"`
+	Remarks          string  `json:"remarks" db:"remarks"`
+	CreatedBy        string  `json:"createdBy" db:"created_by"`
+	CreatedAt        string  `json:"createdAt" db:"created_at"`
+	ApprovedBy       string  `json:"approvedBy" db:"approved_by"`
+	ApprovedAt       string  `json:"approvedAt" db:"approved_at"`
+
+	ProductCode   string `json:"productCode" db:"product_code"`
+	ProductName   string `json:"productName" db:"product_name"`
+	StandardUnit  string `json:"standardUnit" db:"standard_unit"`
+	RefNo         string `json:"refNo" db:"ref_no"`
+	StoreLocation string `json:"storeLocation" db:"store_location"`
+}
+
+// InventoryAdjustmentDecision is the body of an approve or reject request.
+type InventoryAdjustmentDecision struct {
+	By      string `json:"by"`
+	Remarks string `json:"remarks"`
+}
+
+type CycleCount struct {
+	ID         int    `json:"id" db:"id"`
+	LocationID int    `json:"locationId" db:"location_id"`
+	Status     string `json:"status" db:"status"`
+	Remarks    string `json:"remarks" db:"remarks"`
+	CreatedBy  string `json:"createdBy" db:"created_by"`
+	CreatedAt  string `json:"createdAt" db:"created_at"`
+	PostedBy   string `json:"postedBy" db:"posted_by"`
+	PostedAt   string `json:"postedAt" db:"posted_at"`
+
+	LocationCode  string `json:"locationCode" db:"location_code"`
+	LocationName  string `json:"locationName" db:"location_name"`
+	WarehouseName string `json:"warehouseName" db:"warehouse_name"`
+
+	Lines []*CycleCountLine `json:"lines,omitempty"`
+}
+
+// CycleCountLine compares the counted quantity of an incoming with its
+// current system balance. CountedQuantity is nil until the incoming has been
+// counted, and uncounted lines carry no variance.
+type CycleCountLine struct {
+	ID              int      `json:"id" db:"id"`
+	CycleCountID    int      `json:"cycleCountId" db:"cycle_count_id"`
+	IncomingID      int      `json:"incomingId" db:"incoming_id"`
+	CountedQuantity *float64 `json:"countedQuantity" db:"counted_quantity"`
+	SystemQuantity  float64  `json:"systemQuantity"`
+	Variance        float64  `json:"variance"`
+	UnitCost        float64  `json:"unitCost"`
+	VarianceValue   float64  `json:"varianceValue"`
+
+	ProductID    int    `json:"productId" db:"product_id"`
+	ProductCode  string `json:"productCode" db:"product_code"`
+	ProductName  string `json:"productName" db:"product_name"`
+	StandardUnit string `json:"standardUnit" db:"standard_unit"`
+	RefNo        string `json:"refNo" db:"ref_no"`
+}
+
+// CycleCountEntry records the counted standard quantity of one incoming.
+type CycleCountEntry struct {
+	IncomingID      int     `json:"incomingId"`
+	CountedQuantity float64 `json:"countedQuantity"`
+}
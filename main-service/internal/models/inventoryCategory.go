@@ -0,0 +1,74 @@
+package models
+
+// Attribute types decide how the value of a product attribute is checked and
+// compared. An option attribute takes one of its Options.
+const (
+	AttributeTypeText    = "text"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	AttributeTypeOption  = "option"
+)
+
+// ProductCategory is a node of the category tree. Path is its name from the
+// root down, Attributes those it defines and those it inherits from the
+// categories above it.
+type ProductCategory struct {
+	ID        int    `json:"id" db:"id"`
+	ParentID  int    `json:"parentId" db:"parent_id"`
+	Code      string `json:"code" db:"code"`
+	Name      string `json:"name" db:"name"`
+	Path      string `json:"path" db:"path"`
+	IsExist   bool   `json:"isExist" db:"is_exist"`
+	CreatedBy string `json:"createdBy" db:"created_by"`
+	CreatedAt string `json:"createdAt" db:"created_at"`
+	UpdatedBy string `json:"updatedBy" db:"updated_by"`
+	UpdatedAt string `json:"updatedAt" db:"updated_at"`
+
+	Attributes []*CategoryAttribute `json:"attributes,omitempty"`
+	Children   []*ProductCategory   `json:"children,omitempty"`
+}
+
+// CategoryAttribute is a typed attribute the products of a category, and of
+// the categories below it, carry. Unit is only shown next to the value.
+type CategoryAttribute struct {
+	ID         int      `json:"id" db:"id"`
+	CategoryID int      `json:"categoryId" db:"category_id"`
+	Code       string   `json:"code" db:"code"`
+	Name       string   `json:"name" db:"name"`
+	Type       string   `json:"type" db:"type"`
+	Unit       string   `json:"unit" db:"unit"`
+	Options    []string `json:"options" db:"options"`
+	IsRequired bool     `json:"isRequired" db:"is_required"`
+	SortOrder  int      `json:"sortOrder" db:"sort_order"`
+}
+
+// ProductAttributeValue is the value a product has for an attribute, named
+// by AttributeID or Code when written.
+type ProductAttributeValue struct {
+	AttributeID int    `json:"attributeId" db:"attribute_id"`
+	Code        string `json:"code" db:"code"`
+	Name        string `json:"name" db:"name"`
+	Type        string `json:"type" db:"type"`
+	Unit        string `json:"unit" db:"unit"`
+	Value       string `json:"value" db:"value"`
+}
+
+// InventoryProductFilter narrows a product list. CategoryID takes in the
+// categories below it, every one of Tags must be on the product and every
+// attribute filter must match. ParentID keeps the variants of a product.
+type InventoryProductFilter struct {
+	CategoryID int                       `json:"categoryId"`
+	ParentID   int                       `json:"parentId"`
+	Tags       []string                  `json:"tags"`
+	Attributes []*ProductAttributeFilter `json:"attributes"`
+}
+
+// ProductAttributeFilter matches the attribute with Code by Value, or for a
+// number attribute by the range Min to Max when Value is empty, either end
+// left open when nil.
+type ProductAttributeFilter struct {
+	Code  string   `json:"code"`
+	Value string   `json:"value"`
+	Min   *float64 `json:"min"`
+	Max   *float64 `json:"max"`
+}
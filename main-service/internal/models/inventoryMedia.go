@@ -0,0 +1,31 @@
+package models
+
+// Media types of the product gallery. Only an image can be the primary one,
+// which is also the thumbnail of the product.
+const (
+	MediaTypeImage             = "image"
+	MediaTypeDatasheet         = "datasheet"
+	MediaTypeInstallationGuide = "installation_guide"
+)
+
+// ProductMedia is an image or document of a product. Path is where the file
+// system service stored the upload, Filename the name it was uploaded with.
+type ProductMedia struct {
+	ID          int    `json:"id" db:"id"`
+	ProductID   int    `json:"productId" db:"product_id"`
+	Type        string `json:"type" db:"type"`
+	Title       string `json:"title" db:"title"`
+	Path        string `json:"path" db:"path"`
+	Filename    string `json:"filename" db:"filename"`
+	ContentType string `json:"contentType" db:"content_type"`
+	Size        int64  `json:"size" db:"size"`
+	IsPrimary   bool   `json:"isPrimary" db:"is_primary"`
+	SortOrder   int    `json:"sortOrder" db:"sort_order"`
+	CreatedBy   string `json:"createdBy" db:"created_by"`
+	CreatedAt   string `json:"createdAt" db:"created_at"`
+}
+
+// ProductMediaOrder is the new order of the whole gallery of a product.
+type ProductMediaOrder struct {
+	MediaIDs []int `json:"mediaIds"`
+}
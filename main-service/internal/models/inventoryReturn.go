@@ -0,0 +1,56 @@
+package models
+
+const (
+	ReturnTypeCustomer = "customer"
+	ReturnTypeSupplier = "supplier"
+
+	ReturnDispositionRestock    = "restock"
+	ReturnDispositionQuarantine = "quarantine"
+)
+
+type InventoryReturnReason struct {
+	Code    string `json:"code" db:"code"`
+	Name    string `json:"name" db:"name"`
+	IsExist bool   `json:"isExist" db:"is_exist"`
+}
+
+// InventoryReturn is a customer return of stock issued on OutgoingID, put
+// back into IncomingID, or a return to the supplier of stock taken out of
+// IncomingID, received against PurchaseOrderID when it came in on a purchase
+// order. StandardQuantity is always positive, Type gives the direction.
+//
+// A customer return is restocked into the incoming it was issued from unless
+// another available IncomingID at the same cost is given. A quarantined one is split off into a new
+// incoming at LocationID, held for QC so it cannot be issued.
+type InventoryReturn struct {
+	ID               int     `json:"id" db:"id"`
+	ReturnNo         string  `json:"returnNo" db:"return_no"`
+	Type             string  `json:"type" db:"type"`
+	ProductID        int     `json:"productId" db:"product_id"`
+	IncomingID       int     `json:"incomingId" db:"incoming_id"`
+	OutgoingID       int     `json:"outgoingId" db:"outgoing_id"`
+	PurchaseOrderID  int     `json:"purchaseOrderId" db:"purchase_order_id"`
+	ReasonCode       string  `json:"reasonCode" db:"reason_code"`
+	Disposition      string  `json:"disposition" db:"disposition"`
+	LocationID       int     `json:"locationId" db:"location_id"`
+	Quantity         float64 `json:"quantity" db:"quantity"`
+	StandardQuantity float64 `json:"standardQuantity" db:"standard_quantity"`
+	UnitCost         float64 `json:"unitCost" db:"unit_cost"`
+	Value            float64 `json:"value"`
+	RefDoc           string  `json:"refDoc" db:"ref_doc"`
+	Remarks          string  `json:"remarks" db:"remarks"`
+	ReturnedBy       string  `json:"returnedBy" db:"returned_by"`
+	ReturnedAt       string  `json:"returnedAt" db:"returned_at"`
+
+	// SerialNos names the serialised items a customer brings back, the first
+	// ones issued on the outgoing when left out.
+	SerialNos []string `json:"serialNos,omitempty"`
+
+	ProductCode   string `json:"productCode" db:"product_code"`
+	ProductName   string `json:"productName" db:"product_name"`
+	StandardUnit  string `json:"standardUnit" db:"standard_unit"`
+	RefNo         string `json:"refNo" db:"ref_no"`
+	StoreLocation string `json:"storeLocation" db:"store_location"`
+	OutgoingRefNo string `json:"outgoingRefNo" db:"outgoing_ref_no"`
+	PONo          string `json:"poNo" db:"po_no"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// ProductVariantLink links an existing product under a parent as one of its
+// variants. Attributes given are set along with the link, typically the
+// values of the parent's variant axes, and replace the product's own values
+// for the same attributes.
+type ProductVariantLink struct {
+	ProductID  int                      `json:"productId"`
+	Attributes []*ProductAttributeValue `json:"attributes"`
+	UpdatedBy  string                   `json:"updatedBy"`
+}
@@ -0,0 +1,78 @@
+package models
+
+type Customer struct {
+	ID          int    `json:"id" db:"id"`
+	Code        string `json:"code" db:"code"`
+	Name        string `json:"name" db:"name"`
+	ContactName string `json:"contactName" db:"contact_name"`
+	Email       string `json:"email" db:"email"`
+	Phone       string `json:"phone" db:"phone"`
+	Address     string `json:"address" db:"address"`
+	Remarks     string `json:"remarks" db:"remarks"`
+	IsExist     bool   `json:"isExist" db:"is_exist"`
+	CreatedBy   string `json:"createdBy" db:"created_by"`
+	CreatedAt   string `json:"createdAt" db:"created_at"`
+	UpdatedBy   string `json:"updatedBy" db:"updated_by"`
+	UpdatedAt   string `json:"updatedAt" db:"updated_at"`
+}
+
+// A job order stays open while material is being issued to its site and is
+// completed or cancelled once the job is done.
+const (
+	JobOrderStatusOpen      = "open"
+	JobOrderStatusCompleted = "completed"
+	JobOrderStatusCancelled = "cancelled"
+)
+
+type JobOrder struct {
+	ID          int    `json:"id" db:"id"`
+	JobNo       string `json:"jobNo" db:"job_no"`
+	CustomerID  int    `json:"customerId" db:"customer_id"`
+	SiteName    string `json:"siteName" db:"site_name"`
+	SiteAddress string `json:"siteAddress" db:"site_address"`
+	Status      string `json:"status" db:"status"`
+	StartedAt   string `json:"startedAt" db:"started_at"`
+	CompletedAt string `json:"completedAt" db:"completed_at"`
+	Remarks     string `json:"remarks" db:"remarks"`
+	CreatedBy   string `json:"createdBy" db:"created_by"`
+	CreatedAt   string `json:"createdAt" db:"created_at"`
+	UpdatedBy   string `json:"updatedBy" db:"updated_by"`
+	UpdatedAt   string `json:"updatedAt" db:"updated_at"`
+
+	CustomerCode string `json:"customerCode" db:"customer_code"`
+	CustomerName string `json:"customerName" db:"customer_name"`
+}
+
+type JobCostingFilter struct {
+	CustomerID int    `json:"customerId"`
+	Status     string `json:"status"`
+}
+
+// JobCostingLine is the material one product contributed to a job, summed
+// over the outgoings linked to it.
+type JobCostingLine struct {
+	ProductID        int     `json:"productId"`
+	ProductCode      string  `json:"productCode"`
+	ProductName      string  `json:"productName"`
+	StandardUnit     string  `json:"standardUnit"`
+	StandardQuantity float64 `json:"standardQuantity"`
+	Cost             float64 `json:"cost"`
+	Outgoings        int     `json:"outgoings"`
+}
+
+type JobCosting struct {
+	JobOrderID   int               `json:"jobOrderId"`
+	JobNo        string            `json:"jobNo"`
+	CustomerName string            `json:"customerName"`
+	SiteName     string            `json:"siteName"`
+	Status       string            `json:"status"`
+	Lines        []*JobCostingLine `json:"lines"`
+	TotalCost    float64           `json:"totalCost"`
+}
+
+type JobCostingReport struct {
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Jobs      []*JobCosting `json:"jobs"`
+	TotalCost float64       `json:"totalCost"`
+}
@@ -0,0 +1,73 @@
+package models
+
+// LotTraceLine is an outgoing that issued stock of the lot or serial being
+// traced, with the job, customer and delivery order it went out on.
+type LotTraceLine struct {
+	OutgoingID       int      `json:"outgoingId"`
+	IncomingID       int      `json:"incomingId"`
+	ProductID        int      `json:"productId"`
+	ProductCode      string   `json:"productCode"`
+	ProductName      string   `json:"productName"`
+	LotNo            string   `json:"lotNo"`
+	ExpiresAt        string   `json:"expiresAt"`
+	SerialNos        []string `json:"serialNos"`
+	StandardQuantity float64  `json:"standardQuantity"`
+	StandardUnit     string   `json:"standardUnit"`
+	RefNo            string   `json:"refNo"`
+	JobOrderID       int      `json:"jobOrderId"`
+	JobNo            string   `json:"jobNo"`
+	CustomerID       int      `json:"customerId"`
+	CustomerName     string   `json:"customerName"`
+	DeliveryOrderID  int      `json:"deliveryOrderId"`
+	DONo             string   `json:"doNo"`
+	ExpiryOverrideBy string   `json:"expiryOverrideBy"`
+	IssuedAt         string   `json:"issuedAt"`
+}
+
+// LotTrace answers where a lot, or a single serialised item, went. Incomings
+// are every receipt of the lot, including the ones split off it by
+// transfers, and Lines every outgoing that issued from them.
+type LotTrace struct {
+	LotNo     string               `json:"lotNo"`
+	SerialNo  string               `json:"serialNo"`
+	ProductID int                  `json:"productId"`
+	Incomings []*InventoryIncoming `json:"incomings"`
+	Lines     []*LotTraceLine      `json:"lines"`
+}
+
+// ExpiryReportLine is an incoming with stock left that expires within the
+// report's days, or has already expired. Horizon is the smallest horizon the
+// lot falls within, zero once expired.
+type ExpiryReportLine struct {
+	IncomingID       int     `json:"incomingId"`
+	ProductID        int     `json:"productId"`
+	ProductCode      string  `json:"productCode"`
+	ProductName      string  `json:"productName"`
+	LotNo            string  `json:"lotNo"`
+	RefNo            string  `json:"refNo"`
+	StoreLocation    string  `json:"storeLocation"`
+	StoreCountry     string  `json:"storeCountry"`
+	ExpiresAt        string  `json:"expiresAt"`
+	DaysToExpiry     int     `json:"daysToExpiry"`
+	Horizon          int     `json:"horizon"`
+	StandardQuantity float64 `json:"standardQuantity"`
+	StandardUnit     string  `json:"standardUnit"`
+	UnitCost         float64 `json:"unitCost"`
+	Value            float64 `json:"value"`
+}
+
+// ExpiryReportBucket totals the lines of one horizon.
+type ExpiryReportBucket struct {
+	Label   string  `json:"label"`
+	Horizon int     `json:"horizon"`
+	Lots    int     `json:"lots"`
+	Value   float64 `json:"value"`
+}
+
+type ExpiryReport struct {
+	AsOf       string                `json:"asOf"`
+	Days       int                   `json:"days"`
+	Buckets    []*ExpiryReportBucket `json:"buckets"`
+	Lines      []*ExpiryReportLine   `json:"lines"`
+	TotalValue float64               `json:"totalValue"`
+}
@@ -0,0 +1,57 @@
+package models
+
+// A purchase order moves from draft to approved when it is placed, through
+// partially received to received as its lines are delivered, and is closed
+// once nothing more is expected on it.
+const (
+	PurchaseOrderStatusDraft             = "draft"
+	PurchaseOrderStatusApproved          = "approved"
+	PurchaseOrderStatusPartiallyReceived = "partially_received"
+	PurchaseOrderStatusReceived          = "received"
+	PurchaseOrderStatusClosed            = "closed"
+)
+
+type PurchaseOrder struct {
+	ID         int    `json:"id" db:"id"`
+	PONo       string `json:"poNo" db:"po_no"`
+	SupplierID int    `json:"supplierId" db:"supplier_id"`
+	Supplier   string `json:"supplier" db:"supplier"`
+	Status     string `json:"status" db:"status"`
+	OrderDate  string `json:"orderDate" db:"order_date"`
+	ExpectedAt string `json:"expectedAt" db:"expected_at"`
+	Remarks    string `json:"remarks" db:"remarks"`
+	ApprovedBy string `json:"approvedBy" db:"approved_by"`
+	ApprovedAt string `json:"approvedAt" db:"approved_at"`
+	CreatedBy  string `json:"createdBy" db:"created_by"`
+	CreatedAt  string `json:"createdAt" db:"created_at"`
+	UpdatedBy  string `json:"updatedBy" db:"updated_by"`
+	UpdatedAt  string `json:"updatedAt" db:"updated_at"`
+
+	TotalCost float64              `json:"totalCost"`
+	Lines     []*PurchaseOrderLine `json:"lines"`
+}
+
+// PurchaseOrderLine orders a standard quantity of one product. Received
+// quantity is the standard quantity of the incomings booked against it.
+type PurchaseOrderLine struct {
+	ID               int     `json:"id" db:"id"`
+	PurchaseOrderID  int     `json:"purchaseOrderId" db:"purchase_order_id"`
+	ProductID        int     `json:"productId" db:"product_id"`
+	StandardQuantity float64 `json:"standardQuantity" db:"standard_quantity"`
+	UnitCost         float64 `json:"unitCost" db:"unit_cost"`
+	Remarks          string  `json:"remarks" db:"remarks"`
+	ReceivedQuantity float64 `json:"receivedQuantity"`
+	OpenQuantity     float64 `json:"openQuantity"`
+
+	ProductCode  string `json:"productCode" db:"product_code"`
+	ProductName  string `json:"productName" db:"product_name"`
+	StandardUnit string `json:"standardUnit" db:"standard_unit"`
+}
+
+// PurchaseOrderAction is the body of approve, receive and close requests.
+// Receipts are only read when receiving, each one becomes an incoming on the
+// purchase order line it names.
+type PurchaseOrderAction struct {
+	By       string               `json:"by"`
+	Receipts []*InventoryIncoming `json:"receipts"`
+}
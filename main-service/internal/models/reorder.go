@@ -0,0 +1,110 @@
+package models
+
+// ReorderRule holds the stock levels of a product, for one location when
+// LocationID is set or for all of its stock otherwise. A MaxQuantity of zero
+// leaves the order size to ReorderQuantity.
+type ReorderRule struct {
+	ID              int     `json:"id" db:"id"`
+	ProductID       int     `json:"productId" db:"product_id"`
+	LocationID      int     `json:"locationId" db:"location_id"`
+	MinQuantity     float64 `json:"minQuantity" db:"min_quantity"`
+	MaxQuantity     float64 `json:"maxQuantity" db:"max_quantity"`
+	ReorderQuantity float64 `json:"reorderQuantity" db:"reorder_quantity"`
+	IsExist         bool    `json:"isExist" db:"is_exist"`
+	CreatedBy       string  `json:"createdBy" db:"created_by"`
+	CreatedAt       string  `json:"createdAt" db:"created_at"`
+	UpdatedBy       string  `json:"updatedBy" db:"updated_by"`
+	UpdatedAt       string  `json:"updatedAt" db:"updated_at"`
+
+	ProductCode  string  `json:"productCode" db:"product_code"`
+	ProductName  string  `json:"productName" db:"product_name"`
+	StandardUnit string  `json:"standardUnit" db:"standard_unit"`
+	LocationCode string  `json:"locationCode" db:"location_code"`
+	LocationName string  `json:"locationName" db:"location_name"`
+	Balance      float64 `json:"balance" db:"balance"`
+}
+
+const (
+	AlertTypeLowStock = "low_stock"
+	AlertTypeExpiry   = "expiry"
+)
+
+// Alert statuses. An acknowledged alert is still unresolved, it only tells
+// others someone is on it.
+const (
+	AlertStatusOpen         = "open"
+	AlertStatusAcknowledged = "acknowledged"
+	AlertStatusResolved     = "resolved"
+)
+
+// InventoryAlert is raised by the alert worker. Quantity is the figure that
+// crossed Threshold, the balance for a low stock alert. An expiry alert is
+// raised for IncomingID with what is left of it, Threshold being the horizon
+// in days the lot fell within, zero once it has expired.
+type InventoryAlert struct {
+	ID             int     `json:"id" db:"id"`
+	Type           string  `json:"type" db:"type"`
+	ReorderRuleID  int     `json:"reorderRuleId" db:"reorder_rule_id"`
+	IncomingID     int     `json:"incomingId" db:"incoming_id"`
+	ProductID      int     `json:"productId" db:"product_id"`
+	LocationID     int     `json:"locationId" db:"location_id"`
+	Quantity       float64 `json:"quantity" db:"quantity"`
+	Threshold      float64 `json:"threshold" db:"threshold"`
+	Message        string  `json:"message" db:"message"`
+	Status         string  `json:"status" db:"status"`
+	RaisedAt       string  `json:"raisedAt" db:"raised_at"`
+	UpdatedAt      string  `json:"updatedAt" db:"updated_at"`
+	AcknowledgedBy string  `json:"acknowledgedBy" db:"acknowledged_by"`
+	AcknowledgedAt string  `json:"acknowledgedAt" db:"acknowledged_at"`
+	ResolvedAt     string  `json:"resolvedAt" db:"resolved_at"`
+
+	ProductCode  string `json:"productCode" db:"product_code"`
+	ProductName  string `json:"productName" db:"product_name"`
+	StandardUnit string `json:"standardUnit" db:"standard_unit"`
+	LocationCode string `json:"locationCode" db:"location_code"`
+	LocationName string `json:"locationName" db:"location_name"`
+}
+
+type InventoryAlertAction struct {
+	By string `json:"by"`
+}
+
+// SuggestedPurchaseLine is a product whose stock position, balance plus on
+// order, will fall below its reorder point before a new delivery can arrive.
+// The reorder point is the minimum plus the expected consumption over the
+// lead time.
+type SuggestedPurchaseLine struct {
+	ProductID         int     `json:"productId" db:"product_id"`
+	ProductCode       string  `json:"productCode" db:"product_code"`
+	ProductName       string  `json:"productName" db:"product_name"`
+	StandardUnit      string  `json:"standardUnit" db:"standard_unit"`
+	SupplierSKU       string  `json:"supplierSku" db:"supplier_sku"`
+	Balance           float64 `json:"balance" db:"balance"`
+	OnOrder           float64 `json:"onOrder" db:"on_order"`
+	MinQuantity       float64 `json:"minQuantity" db:"min_quantity"`
+	MaxQuantity       float64 `json:"maxQuantity" db:"max_quantity"`
+	ReorderQuantity   float64 `json:"reorderQuantity" db:"reorder_quantity"`
+	DailyConsumption  float64 `json:"dailyConsumption" db:"daily_consumption"`
+	LeadTimeDays      int     `json:"leadTimeDays" db:"lead_time_days"`
+	ReorderPoint      float64 `json:"reorderPoint"`
+	SuggestedQuantity float64 `json:"suggestedQuantity"`
+	UnitCost          float64 `json:"unitCost" db:"unit_cost"`
+	Currency          string  `json:"currency" db:"currency"`
+	EstimatedCost     float64 `json:"estimatedCost"`
+}
+
+// SuggestedPurchaseGroup collects the lines to order from one supplier,
+// products without a supplier are grouped under SupplierID zero.
+type SuggestedPurchaseGroup struct {
+	SupplierID    int                      `json:"supplierId"`
+	SupplierCode  string                   `json:"supplierCode"`
+	SupplierName  string                   `json:"supplierName"`
+	EstimatedCost float64                  `json:"estimatedCost"`
+	Lines         []*SuggestedPurchaseLine `json:"lines"`
+}
+
+// SuggestedPurchaseReport averages consumption over the last Days days.
+type SuggestedPurchaseReport struct {
+	Days   int                       `json:"days"`
+	Groups []*SuggestedPurchaseGroup `json:"groups"`
+}
@@ -0,0 +1,45 @@
+package models
+
+// Reservation statuses. Only active and released are stored, a reservation
+// is fulfilled once outgoings consumed all of it and expired once its expiry
+// date has passed.
+const (
+	ReservationStatusActive    = "active"
+	ReservationStatusReleased  = "released"
+	ReservationStatusFulfilled = "fulfilled"
+	ReservationStatusExpired   = "expired"
+)
+
+// InventoryReservation promises StandardQuantity of a product to a job, from
+// one incoming when IncomingID is set or from any of them otherwise.
+// ReservedQuantity is what it still holds back from other outgoings.
+type InventoryReservation struct {
+	ID               int     `json:"id" db:"id"`
+	ProductID        int     `json:"productId" db:"product_id"`
+	IncomingID       int     `json:"incomingId" db:"incoming_id"`
+	JobOrderID       int     `json:"jobOrderId" db:"job_order_id"`
+	StandardQuantity float64 `json:"standardQuantity" db:"standard_quantity"`
+	ConsumedQuantity float64 `json:"consumedQuantity" db:"consumed_quantity"`
+	ReservedQuantity float64 `json:"reservedQuantity" db:"reserved_quantity"`
+	ExpiresAt        string  `json:"expiresAt" db:"expires_at"`
+	Status           string  `json:"status" db:"status"`
+	RefNo            string  `json:"refNo" db:"ref_no"`
+	Remarks          string  `json:"remarks" db:"remarks"`
+	ReleasedBy       string  `json:"releasedBy" db:"released_by"`
+	ReleasedAt       string  `json:"releasedAt" db:"released_at"`
+	CreatedBy        string  `json:"createdBy" db:"created_by"`
+	CreatedAt        string  `json:"createdAt" db:"created_at"`
+	UpdatedBy        string  `json:"updatedBy" db:"updated_by"`
+	UpdatedAt        string  `json:"updatedAt" db:"updated_at"`
+
+	ProductCode   string `json:"productCode" db:"product_code"`
+	ProductName   string `json:"productName" db:"product_name"`
+	StandardUnit  string `json:"standardUnit" db:"standard_unit"`
+	IncomingRefNo string `json:"incomingRefNo" db:"incoming_ref_no"`
+	JobNo         string `json:"jobNo" db:"job_no"`
+}
+
+type InventoryReservationAction struct {
+	By      string `json:"by"`
+	Remarks string `json:"remarks"`
+}
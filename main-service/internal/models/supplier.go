@@ -0,0 +1,66 @@
+package models
+
+type Supplier struct {
+	ID           int    `json:"id" db:"id"`
+	Code         string `json:"code" db:"code"`
+	Name         string `json:"name" db:"name"`
+	Country      string `json:"country" db:"country"`
+	Address      string `json:"address" db:"address"`
+	Currency     string `json:"currency" db:"currency"`
+	PaymentTerms string `json:"paymentTerms" db:"payment_terms"`
+	LeadTimeDays int    `json:"leadTimeDays" db:"lead_time_days"`
+	Remarks      string `json:"remarks" db:"remarks"`
+	IsExist      bool   `json:"isExist" db:"is_exist"`
+	CreatedBy    string `json:"createdBy" db:"created_by"`
+	CreatedAt    string `json:"createdAt" db:"created_at"`
+	UpdatedBy    string `json:"updatedBy" db:"updated_by"`
+	UpdatedAt    string `json:"updatedAt" db:"updated_at"`
+
+	Contacts []*SupplierContact `json:"contacts"`
+}
+
+type SupplierContact struct {
+	ID         int    `json:"id" db:"id"`
+	SupplierID int    `json:"supplierId" db:"supplier_id"`
+	Name       string `json:"name" db:"name"`
+	Position   string `json:"position" db:"position"`
+	Email      string `json:"email" db:"email"`
+	Phone      string `json:"phone" db:"phone"`
+	IsPrimary  bool   `json:"isPrimary" db:"is_primary"`
+}
+
+// ProductSupplier links a product to a supplier that sells it. LeadTimeDays
+// overrides the supplier's default when it is not zero, and
+// EffectiveLeadTimeDays is the one that applies.
+type ProductSupplier struct {
+	ID                    int    `json:"id" db:"id"`
+	ProductID             int    `json:"productId" db:"product_id"`
+	SupplierID            int    `json:"supplierId" db:"supplier_id"`
+	SupplierSKU           string `json:"supplierSku" db:"supplier_sku"`
+	LeadTimeDays          int    `json:"leadTimeDays" db:"lead_time_days"`
+	EffectiveLeadTimeDays int    `json:"effectiveLeadTimeDays"`
+	IsPreferred           bool   `json:"isPreferred" db:"is_preferred"`
+
+	ProductCode  string `json:"productCode" db:"product_code"`
+	ProductName  string `json:"productName" db:"product_name"`
+	SupplierCode string `json:"supplierCode" db:"supplier_code"`
+	SupplierName string `json:"supplierName" db:"supplier_name"`
+
+	CurrentPrice *SupplierPrice   `json:"currentPrice"`
+	Prices       []*SupplierPrice `json:"prices"`
+}
+
+// SupplierPrice is a price list entry, valid from ValidFrom up to and
+// including ValidTo, or open ended when ValidTo is empty.
+type SupplierPrice struct {
+	ID                int     `json:"id" db:"id"`
+	ProductSupplierID int     `json:"productSupplierId" db:"product_supplier_id"`
+	UnitCost          float64 `json:"unitCost" db:"unit_cost"`
+	Currency          string  `json:"currency" db:"currency"`
+	MinQuantity       float64 `json:"minQuantity" db:"min_quantity"`
+	ValidFrom         string  `json:"validFrom" db:"valid_from"`
+	ValidTo           string  `json:"validTo" db:"valid_to"`
+	IsCurrent         bool    `json:"isCurrent"`
+	CreatedBy         string  `json:"createdBy" db:"created_by"`
+	CreatedAt         string  `json:"createdAt" db:"created_at"`
+}
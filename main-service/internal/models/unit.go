@@ -0,0 +1,42 @@
+package models
+
+// Units of the same dimension convert through their base factor, such as ml
+// to litre. Package units like box or tube have no base factor and only
+// convert through a product's own conversions.
+const (
+	UnitDimensionArea    = "area"
+	UnitDimensionVolume  = "volume"
+	UnitDimensionLength  = "length"
+	UnitDimensionMass    = "mass"
+	UnitDimensionCount   = "count"
+	UnitDimensionPackage = "package"
+	UnitDimensionOther   = "other"
+)
+
+type Unit struct {
+	ID         int     `json:"id" db:"id"`
+	Code       string  `json:"code" db:"code"`
+	Name       string  `json:"name" db:"name"`
+	Dimension  string  `json:"dimension" db:"dimension"`
+	BaseFactor float64 `json:"baseFactor" db:"base_factor"`
+	CreatedBy  string  `json:"createdBy" db:"created_by"`
+	CreatedAt  string  `json:"createdAt" db:"created_at"`
+	UpdatedBy  string  `json:"updatedBy" db:"updated_by"`
+	UpdatedAt  string  `json:"updatedAt" db:"updated_at"`
+}
+
+// ProductUnitConversion says one Unit of a product is Factor ToUnit, such as
+// a box of 10 pcs. StandardFactor is how much of the product's standard unit
+// one Unit works out to once the chain is followed through.
+type ProductUnitConversion struct {
+	ID             int     `json:"id" db:"id"`
+	ProductID      int     `json:"productId" db:"product_id"`
+	Unit           string  `json:"unit" db:"unit"`
+	Factor         float64 `json:"factor" db:"factor"`
+	ToUnit         string  `json:"toUnit" db:"to_unit"`
+	StandardFactor float64 `json:"standardFactor"`
+	CreatedBy      string  `json:"createdBy" db:"created_by"`
+	CreatedAt      string  `json:"createdAt" db:"created_at"`
+	UpdatedBy      string  `json:"updatedBy" db:"updated_by"`
+	UpdatedAt      string  `json:"updatedAt" db:"updated_at"`
+}
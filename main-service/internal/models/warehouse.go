@@ -0,0 +1,53 @@
+package models
+
+// Location types, from the widest area of a warehouse down to where a pallet
+// actually sits. Zones hang off the warehouse, pallets and bins off a zone or
+// straight off the warehouse.
+const (
+	LocationTypeZone   = "zone"
+	LocationTypePallet = "pallet"
+	LocationTypeBin    = "bin"
+)
+
+type Warehouse struct {
+	ID        int    `json:"id" db:"id"`
+	Code      string `json:"code" db:"code"`
+	Name      string `json:"name" db:"name"`
+	Country   string `json:"country" db:"country"`
+	Address   string `json:"address" db:"address"`
+	IsExist   bool   `json:"isExist" db:"is_exist"`
+	CreatedBy string `json:"createdBy" db:"created_by"`
+	CreatedAt string `json:"createdAt" db:"created_at"`
+	UpdatedBy string `json:"updatedBy" db:"updated_by"`
+	UpdatedAt string `json:"updatedAt" db:"updated_at"`
+}
+
+type Location struct {
+	ID          int    `json:"id" db:"id"`
+	WarehouseID int    `json:"warehouseId" db:"warehouse_id"`
+	ParentID    int    `json:"parentId" db:"parent_id"`
+	Type        string `json:"type" db:"type"`
+	Code        string `json:"code" db:"code"`
+	Name        string `json:"name" db:"name"`
+	IsExist     bool   `json:"isExist" db:"is_exist"`
+	CreatedBy   string `json:"createdBy" db:"created_by"`
+	CreatedAt   string `json:"createdAt" db:"created_at"`
+	UpdatedBy   string `json:"updatedBy" db:"updated_by"`
+	UpdatedAt   string `json:"updatedAt" db:"updated_at"`
+
+	WarehouseCode string `json:"warehouseCode" db:"warehouse_code"`
+	WarehouseName string `json:"warehouseName" db:"warehouse_name"`
+	Country       string `json:"country" db:"country"`
+}
+
+type LocationStock struct {
+	LocationID       int     `json:"locationId" db:"location_id"`
+	LocationCode     string  `json:"locationCode" db:"location_code"`
+	LocationName     string  `json:"locationName" db:"location_name"`
+	ProductID        int     `json:"productId" db:"product_id"`
+	ProductCode      string  `json:"productCode" db:"product_code"`
+	ProductName      string  `json:"productName" db:"product_name"`
+	StandardUnit     string  `json:"standardUnit" db:"standard_unit"`
+	StandardQuantity float64 `json:"standardQuantity" db:"standard_quantity"`
+	Value            float64 `json:"value" db:"value"`
+}
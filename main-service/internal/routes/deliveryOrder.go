@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/handlers"
+)
+
+func NewDeliveryOrderRouter(r chi.Router) {
+	h := handlers.NewDeliveryOrderHandler()
+
+	r.Route("/delivery-orders", func(r chi.Router) {
+		r.Get("/", h.GetDeliveryOrders)
+		r.Get("/{id}", h.GetDeliveryOrder)
+		r.Post("/", h.CreateDeliveryOrder)
+		r.Post("/{id}/render", h.RenderDeliveryOrder)
+		r.Delete("/{id}", h.DeleteDeliveryOrder)
+	})
+}
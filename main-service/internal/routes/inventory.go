@@ -19,6 +19,31 @@ func NewInventoryRouter() chi.Router {
 	r.Delete("/products/{id}", h.DeleteProduct)
 
 	r.Get("/products/summary", h.GetProductSummary)
+	r.Get("/products/tags", h.GetProductTags)
+	r.Get("/products/{id}/ledger", h.GetProductLedger)
+
+	// Variant
+	r.Get("/products/{id}/variants", h.GetProductVariants)
+	r.Post("/products/{id}/variants", h.LinkProductVariant)
+	r.Delete("/products/{id}/variants/{variantId}", h.UnlinkProductVariant)
+
+	// Media
+	r.Get("/products/{id}/media", h.GetProductMedia)
+	r.Post("/products/{id}/media", h.AddProductMedia)
+	r.Put("/products/{id}/media/order", h.ReorderProductMedia)
+	r.Post("/products/{id}/media/{mediaId}/primary", h.SetPrimaryProductMedia)
+	r.Delete("/products/{id}/media/{mediaId}", h.DeleteProductMedia)
+
+	// Category
+	r.Get("/categories", h.GetCategories)
+	r.Get("/categories/tree", h.GetCategoryTree)
+	r.Get("/categories/{id}", h.GetCategory)
+	r.Post("/categories", h.CreateCategory)
+	r.Put("/categories/{id}", h.UpdateCategory)
+	r.Delete("/categories/{id}", h.DeleteCategory)
+	r.Post("/categories/{id}/attributes", h.CreateCategoryAttribute)
+	r.Put("/categories/{id}/attributes/{attributeId}", h.UpdateCategoryAttribute)
+	r.Delete("/categories/{id}/attributes/{attributeId}", h.DeleteCategoryAttribute)
 
 	// Incoming
 	r.Get("/incomings", h.GetIncomings)
@@ -26,13 +51,78 @@ func NewInventoryRouter() chi.Router {
 	r.Post("/incomings", h.CreateIncoming)
 	r.Put("/incomings/{id}", h.UpdateIncoming)
 	r.Delete("/incomings/{id}", h.DeleteIncoming)
+	r.Post("/incomings/status", h.TransitionShipment)
+	r.Get("/incomings/{id}/status-history", h.GetIncomingStatusHistory)
+	r.Post("/incomings/{id}/status", h.TransitionIncoming)
 
 	// Outgoing
 	r.Get("/outgoings", h.GetOutgoings)
 	r.Get("/outgoings/{id}", h.GetOutgoing)
 	r.Post("/outgoings", h.CreateOutgoing)
+	r.Post("/outgoings/allocate", h.AllocateOutgoings)
 	r.Put("/outgoings/{id}", h.UpdateOutgoing)
 	r.Delete("/outgoings/{id}", h.DeleteOutgoing)
 
+	// Reservation
+	r.Get("/reservations", h.GetReservations)
+	r.Get("/reservations/{id}", h.GetReservation)
+	r.Post("/reservations", h.CreateReservation)
+	r.Put("/reservations/{id}", h.UpdateReservation)
+	r.Post("/reservations/{id}/release", h.ReleaseReservation)
+	r.Delete("/reservations/{id}", h.DeleteReservation)
+
+	// Transfer
+	r.Get("/transfers", h.GetTransfers)
+	r.Get("/transfers/{id}", h.GetTransfer)
+	r.Post("/transfers", h.CreateTransfer)
+
+	// Return
+	r.Get("/returns", h.GetReturns)
+	r.Get("/returns/reasons", h.GetReturnReasons)
+	r.Get("/returns/{id}", h.GetReturn)
+	r.Post("/returns", h.CreateReturn)
+
+	// Lot
+	r.Get("/lots/trace", h.TraceLot)
+
+	// Adjustment
+	r.Get("/adjustments", h.GetAdjustments)
+	r.Get("/adjustments/reasons", h.GetAdjustmentReasons)
+	r.Get("/adjustments/{id}", h.GetAdjustment)
+	r.Post("/adjustments", h.CreateAdjustment)
+	r.Post("/adjustments/{id}/approve", h.ApproveAdjustment)
+	r.Post("/adjustments/{id}/reject", h.RejectAdjustment)
+
+	// Cycle count
+	r.Get("/cycle-counts", h.GetCycleCounts)
+	r.Get("/cycle-counts/{id}", h.GetCycleCount)
+	r.Post("/cycle-counts", h.CreateCycleCount)
+	r.Put("/cycle-counts/{id}/lines", h.UpdateCycleCountLines)
+	r.Post("/cycle-counts/{id}/post", h.PostCycleCount)
+	r.Post("/cycle-counts/{id}/cancel", h.CancelCycleCount)
+
+	// Unit of measure
+	NewUnitRouter(r)
+
+	// Warehouse and location
+	NewWarehouseRouter(r)
+
+	// Supplier
+	NewSupplierRouter(r)
+
+	// Customer and job order
+	NewJobOrderRouter(r)
+
+	// Delivery order
+	NewDeliveryOrderRouter(r)
+
+	// Reorder rule and alert
+	NewReorderRouter(r)
+
+	// Reports
+	r.Get("/reports/valuation", h.GetValuationReport)
+	r.Get("/reports/aging", h.GetAgingReport)
+	r.Get("/reports/expiry", h.GetExpiryReport)
+
 	return r
 }
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/handlers"
+)
+
+func NewJobOrderRouter(r chi.Router) {
+	h := handlers.NewJobOrderHandler()
+
+	r.Route("/customers", func(r chi.Router) {
+		r.Get("/", h.GetCustomers)
+		r.Get("/{id}", h.GetCustomer)
+		r.Post("/", h.CreateCustomer)
+		r.Put("/{id}", h.UpdateCustomer)
+		r.Delete("/{id}", h.DeleteCustomer)
+	})
+
+	r.Route("/job-orders", func(r chi.Router) {
+		r.Get("/", h.GetJobOrders)
+		r.Get("/{id}", h.GetJobOrder)
+		r.Post("/", h.CreateJobOrder)
+		r.Put("/{id}", h.UpdateJobOrder)
+		r.Delete("/{id}", h.DeleteJobOrder)
+
+		r.Get("/{id}/costing", h.GetJobCosting)
+	})
+
+	r.Get("/reports/job-costing", h.GetJobCostingReport)
+}
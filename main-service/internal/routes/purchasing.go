@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/handlers"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/middlewares"
+)
+
+func NewPurchasingRouter() chi.Router {
+	h := handlers.NewPurchaseOrderHandler()
+	r := chi.NewRouter()
+	r.Use(middlewares.NewAuthMiddleware().AuthRoute)
+
+	// Purchase order
+	r.Get("/purchase-orders", h.GetPurchaseOrders)
+	r.Get("/purchase-orders/{id}", h.GetPurchaseOrder)
+	r.Post("/purchase-orders", h.CreatePurchaseOrder)
+	r.Put("/purchase-orders/{id}", h.UpdatePurchaseOrder)
+	r.Delete("/purchase-orders/{id}", h.DeletePurchaseOrder)
+
+	r.Post("/purchase-orders/{id}/approve", h.ApprovePurchaseOrder)
+	r.Post("/purchase-orders/{id}/receive", h.ReceivePurchaseOrder)
+	r.Post("/purchase-orders/{id}/close", h.ClosePurchaseOrder)
+
+	return r
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/handlers"
+)
+
+func NewReorderRouter(r chi.Router) {
+	h := handlers.NewReorderHandler()
+
+	r.Route("/reorder-rules", func(r chi.Router) {
+		r.Get("/", h.GetReorderRules)
+		r.Get("/{id}", h.GetReorderRule)
+		r.Post("/", h.CreateReorderRule)
+		r.Put("/{id}", h.UpdateReorderRule)
+		r.Delete("/{id}", h.DeleteReorderRule)
+	})
+
+	r.Route("/alerts", func(r chi.Router) {
+		r.Get("/", h.GetAlerts)
+		r.Post("/evaluate", h.EvaluateAlerts)
+		r.Post("/{id}/acknowledge", h.AcknowledgeAlert)
+	})
+
+	r.Get("/reports/suggested-purchases", h.GetSuggestedPurchases)
+}
@@ -50,6 +50,7 @@ func Init() chi.Router {
 		// r.Use(middlewares.NewAuthMiddleware().AuthRoute)
 		r.Mount("/filesystem", NewFileSystemRouter())
 		r.Mount("/inventory", NewInventoryRouter())
+		r.Mount("/purchasing", NewPurchasingRouter())
 	})
 
 	return r
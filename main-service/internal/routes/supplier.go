@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/handlers"
+)
+
+func NewSupplierRouter(r chi.Router) {
+	h := handlers.NewSupplierHandler()
+
+	r.Route("/suppliers", func(r chi.Router) {
+		r.Get("/", h.GetSuppliers)
+		r.Get("/{id}", h.GetSupplier)
+		r.Post("/", h.CreateSupplier)
+		r.Put("/{id}", h.UpdateSupplier)
+		r.Delete("/{id}", h.DeleteSupplier)
+
+		r.Get("/{id}/products", h.GetSupplierProducts)
+		r.Put("/{id}/products/{productId}", h.SaveProductSupplier)
+		r.Delete("/{id}/products/{productId}", h.DeleteProductSupplier)
+		r.Post("/{id}/products/{productId}/prices", h.CreateSupplierPrice)
+		r.Delete("/{id}/prices/{priceId}", h.DeleteSupplierPrice)
+	})
+
+	r.Get("/products/{id}/suppliers", h.GetProductSuppliers)
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/handlers"
+)
+
+func NewUnitRouter(r chi.Router) {
+	h := handlers.NewUnitHandler()
+
+	r.Route("/units", func(r chi.Router) {
+		r.Get("/", h.GetUnits)
+		r.Post("/", h.CreateUnit)
+		r.Put("/{id}", h.UpdateUnit)
+		r.Delete("/{id}", h.DeleteUnit)
+	})
+
+	r.Get("/products/{id}/units", h.GetProductUnits)
+	r.Put("/products/{id}/units/{unit}", h.SaveProductUnit)
+	r.Delete("/products/{id}/units/{unit}", h.DeleteProductUnit)
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/handlers"
+)
+
+func NewWarehouseRouter(r chi.Router) {
+	h := handlers.NewWarehouseHandler()
+
+	r.Route("/warehouses", func(r chi.Router) {
+		r.Get("/", h.GetWarehouses)
+		r.Get("/{id}", h.GetWarehouse)
+		r.Get("/{id}/stock", h.GetWarehouseStock)
+		r.Post("/", h.CreateWarehouse)
+		r.Put("/{id}", h.UpdateWarehouse)
+		r.Delete("/{id}", h.DeleteWarehouse)
+	})
+
+	r.Route("/locations", func(r chi.Router) {
+		r.Get("/", h.GetLocations)
+		r.Get("/{id}", h.GetLocation)
+		r.Get("/{id}/stock", h.GetLocationStock)
+		r.Post("/", h.CreateLocation)
+		r.Put("/{id}", h.UpdateLocation)
+		r.Delete("/{id}", h.DeleteLocation)
+	})
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+)
+
+// stockChanges queues the products whose balance moved for the alert worker.
+// Sends never block, a change dropped while the queue is full is picked up by
+// the next scheduled run.
+var stockChanges = make(chan int, 256)
+
+// NotifyStockChanged asks the alert worker to evaluate the products again.
+func NotifyStockChanged(productIDs ...int) {
+	for _, id := range productIDs {
+		if id == 0 {
+			continue
+		}
+
+		select {
+		case stockChanges <- id:
+		default:
+			slog.Warn("Alert queue is full, leaving product to the scheduled run", "product", id)
+		}
+	}
+}
+
+// StartAlertWorker evaluates the alerts of every product once at start up and
+// then every interval, and of a single product whenever its stock changes.
+func StartAlertWorker(interval time.Duration) {
+	reorder := NewReorderService()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		evaluate := func(productID int) {
+			if err := reorder.EvaluateReorderRules(productID); err != nil {
+				slog.Error("Error evaluating reorder rules", "error", err, "product", productID)
+			}
+			if err := reorder.EvaluateExpiryAlerts(productID); err != nil {
+				slog.Error("Error evaluating expiry alerts", "error", err, "product", productID)
+			}
+		}
+
+		evaluate(0)
+		for {
+			select {
+			case productID := <-stockChanges:
+				evaluate(productID)
+			case <-ticker.C:
+				evaluate(0)
+			}
+		}
+	}()
+
+	slog.Info("Alert worker started", "interval", interval)
+}
@@ -0,0 +1,679 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/config"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/db"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/utils"
+	"github.com/lib/pq"
+)
+
+type DeliveryOrderService interface {
+	GetDeliveryOrders() ([]*models.DeliveryOrder, error)
+	GetDeliveryOrder(id int) (*models.DeliveryOrder, error)
+	CreateDeliveryOrder(order *models.DeliveryOrder) (*models.DeliveryOrder, error)
+	RenderDeliveryOrder(id int, by string) (*models.DeliveryOrder, error)
+	DeleteDeliveryOrder(id int) error
+}
+
+type deliveryOrderService struct {
+	db         *sql.DB
+	fileSystem FileSystemService
+}
+
+func NewDeliveryOrderService() DeliveryOrderService {
+	return &deliveryOrderService{
+		db:         db.GetDB(),
+		fileSystem: NewFileSystemService(),
+	}
+}
+
+// deliveryOrderPath is where rendered delivery orders are uploaded to.
+const deliveryOrderPath = "inventory/delivery-orders"
+
+const deliveryOrderQuery = `
+	SELECT
+		d.id,
+		d.do_no,
+		COALESCE(d.job_order_id, 0),
+		COALESCE(d.customer_id, 0),
+		d.deliver_to,
+		d.delivery_address,
+		d.delivered_at,
+		d.document,
+		d.remarks,
+		d.created_by,
+		d.created_at,
+		d.updated_by,
+		d.updated_at,
+		COALESCE(j.job_no, '') AS job_no,
+		COALESCE(c.name, '') AS customer_name
+	FROM
+		delivery_orders d
+	LEFT JOIN
+		job_orders j
+	ON
+		d.job_order_id = j.id
+	LEFT JOIN
+		customers c
+	ON
+		d.customer_id = c.id
+	`
+
+func scanDeliveryOrder(row interface{ Scan(...any) error }, order *models.DeliveryOrder) error {
+	return row.Scan(
+		&order.ID,
+		&order.DONo,
+		&order.JobOrderID,
+		&order.CustomerID,
+		&order.DeliverTo,
+		&order.DeliveryAddress,
+		&order.DeliveredAt,
+		&order.Document,
+		&order.Remarks,
+		&order.CreatedBy,
+		&order.CreatedAt,
+		&order.UpdatedBy,
+		&order.UpdatedAt,
+		&order.JobNo,
+		&order.CustomerName,
+	)
+}
+
+// Delivery order
+func (s *deliveryOrderService) GetDeliveryOrders() ([]*models.DeliveryOrder, error) {
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		deliveryOrderQuery+`
+		ORDER BY
+			d.delivered_at DESC,
+			d.id DESC
+		`,
+	)
+	if err != nil {
+		slog.Error("Error querying delivery orders", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []*models.DeliveryOrder{}
+	for rows.Next() {
+		order := new(models.DeliveryOrder)
+		if err := scanDeliveryOrder(rows, order); err != nil {
+			slog.Error("Error scanning delivery order", "error", err)
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating delivery orders", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried delivery orders", "count", len(orders))
+
+	return orders, nil
+}
+
+func (s *deliveryOrderService) GetDeliveryOrder(id int) (*models.DeliveryOrder, error) {
+	order := new(models.DeliveryOrder)
+	err := scanDeliveryOrder(s.db.QueryRowContext(
+		context.Background(),
+		deliveryOrderQuery+`
+		WHERE
+			d.id = $1
+		`,
+		id,
+	), order)
+	if err != nil {
+		slog.Error("Error querying delivery order", "error", err, "id", id)
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			o.id,
+			o.product_id,
+			p.code,
+			p.name,
+			o.standard_quantity,
+			p.standard_unit,
+			o.ref_no,
+			o.remarks
+		FROM
+			inventory_outgoings o
+		JOIN
+			inventory_products p
+		ON
+			o.product_id = p.id
+		WHERE
+			o.delivery_order_id = $1
+		ORDER BY
+			o.id
+		`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error querying delivery order lines", "error", err, "id", id)
+		return nil, err
+	}
+	defer rows.Close()
+
+	order.OutgoingIDs = []int{}
+	order.Lines = []*models.DeliveryOrderLine{}
+	for rows.Next() {
+		line := new(models.DeliveryOrderLine)
+		err := rows.Scan(
+			&line.OutgoingID,
+			&line.ProductID,
+			&line.ProductCode,
+			&line.ProductName,
+			&line.StandardQuantity,
+			&line.StandardUnit,
+			&line.RefNo,
+			&line.Remarks,
+		)
+		if err != nil {
+			slog.Error("Error scanning delivery order line", "error", err)
+			return nil, err
+		}
+		order.OutgoingIDs = append(order.OutgoingIDs, line.OutgoingID)
+		order.Lines = append(order.Lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating delivery order lines", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried delivery order", "id", id)
+
+	return order, nil
+}
+
+// CreateDeliveryOrder numbers a new delivery order, links its outgoings and
+// renders its document. The job order is taken from the outgoings when they
+// all belong to the same one, and the customer and address default from it.
+func (s *deliveryOrderService) CreateDeliveryOrder(order *models.DeliveryOrder) (*models.DeliveryOrder, error) {
+	order.DeliverTo = strings.TrimSpace(order.DeliverTo)
+	order.DeliveryAddress = strings.TrimSpace(order.DeliveryAddress)
+
+	if len(order.OutgoingIDs) == 0 {
+		return nil, &ValidationError{Message: "at least one outgoing is required"}
+	}
+	seen := make(map[int]bool, len(order.OutgoingIDs))
+	for _, id := range order.OutgoingIDs {
+		if seen[id] {
+			return nil, &ValidationError{Message: "outgoing " + strconv.Itoa(id) + " is listed twice"}
+		}
+		seen[id] = true
+	}
+
+	deliveredAt, err := parseOptionalDate(order.DeliveredAt)
+	if err != nil {
+		return nil, &ValidationError{Message: "delivered at must be YYYY-MM-DD"}
+	}
+	if deliveredAt == nil {
+		today := time.Now()
+		deliveredAt = &today
+	}
+	order.DeliveredAt = deliveredAt.Format("2006-01-02")
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			id,
+			COALESCE(job_order_id, 0),
+			COALESCE(delivery_order_id, 0)
+		FROM
+			inventory_outgoings
+		WHERE
+			id = ANY($1)
+		FOR UPDATE
+		`,
+		pq.Array(order.OutgoingIDs),
+	)
+	if err != nil {
+		slog.Error("Error locking outgoings", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := 0
+	jobOrderIDs := map[int]bool{}
+	for rows.Next() {
+		var outgoingID, jobOrderID, deliveryOrderID int
+		if err := rows.Scan(&outgoingID, &jobOrderID, &deliveryOrderID); err != nil {
+			slog.Error("Error scanning outgoing", "error", err)
+			return nil, err
+		}
+		if deliveryOrderID != 0 {
+			return nil, &ValidationError{Message: "outgoing " + strconv.Itoa(outgoingID) + " is already on a delivery order"}
+		}
+		if jobOrderID != 0 {
+			jobOrderIDs[jobOrderID] = true
+		}
+		found++
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating outgoings", "error", err)
+		return nil, err
+	}
+	if found != len(order.OutgoingIDs) {
+		return nil, &ValidationError{Message: "some outgoings do not exist"}
+	}
+
+	if order.JobOrderID == 0 && len(jobOrderIDs) == 1 {
+		for id := range jobOrderIDs {
+			order.JobOrderID = id
+		}
+	}
+	for id := range jobOrderIDs {
+		if id != order.JobOrderID {
+			return nil, &ValidationError{Message: "outgoings belong to a different job order"}
+		}
+	}
+
+	if order.JobOrderID != 0 {
+		var customerID int
+		var customerName, siteName, siteAddress string
+		err := tx.QueryRowContext(
+			context.Background(),
+			`
+			SELECT
+				COALESCE(j.customer_id, 0),
+				COALESCE(c.name, ''),
+				j.site_name,
+				j.site_address
+			FROM
+				job_orders j
+			LEFT JOIN
+				customers c
+			ON
+				j.customer_id = c.id
+			WHERE
+				j.id = $1
+			`,
+			order.JobOrderID,
+		).Scan(&customerID, &customerName, &siteName, &siteAddress)
+		if err == sql.ErrNoRows {
+			return nil, &ValidationError{Message: "job order does not exist"}
+		}
+		if err != nil {
+			slog.Error("Error querying job order", "error", err, "id", order.JobOrderID)
+			return nil, err
+		}
+
+		if order.CustomerID == 0 {
+			order.CustomerID = customerID
+		}
+		if order.DeliverTo == "" {
+			order.DeliverTo = customerName
+		}
+		if order.DeliverTo == "" {
+			order.DeliverTo = siteName
+		}
+		if order.DeliveryAddress == "" {
+			order.DeliveryAddress = siteAddress
+		}
+	}
+
+	order.DONo, err = nextDocumentNo(tx, "DO", deliveredAt.Year())
+	if err != nil {
+		return nil, err
+	}
+
+	queryStr := `
+		INSERT INTO delivery_orders (
+			do_no,
+			job_order_id,
+			customer_id,
+			deliver_to,
+			delivery_address,
+			delivered_at,
+			remarks,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			$1, NULLIF($2, 0), NULLIF($3, 0), $4, $5, $6, $7, $8, NOW(), $9, NOW()
+		)
+		RETURNING id
+	`
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		queryStr,
+		order.DONo,
+		order.JobOrderID,
+		order.CustomerID,
+		order.DeliverTo,
+		order.DeliveryAddress,
+		order.DeliveredAt,
+		order.Remarks,
+		order.CreatedBy,
+		order.UpdatedBy,
+	).Scan(&order.ID)
+	if err != nil {
+		slog.Error("Error inserting delivery order", "error", err)
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`UPDATE inventory_outgoings SET delivery_order_id = $1 WHERE id = ANY($2)`,
+		order.ID,
+		pq.Array(order.OutgoingIDs),
+	)
+	if err != nil {
+		slog.Error("Error linking outgoings", "error", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing delivery order", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully created delivery order", "id", order.ID, "doNo", order.DONo)
+
+	// the document is only uploaded once the order is there to point at it,
+	// a failed upload leaves the order to be rendered again
+	if err := s.storeDocument(order.ID, order.CreatedBy); err != nil {
+		return nil, fmt.Errorf("delivery order %s was saved without its document, render it again: %w", order.DONo, err)
+	}
+
+	return s.GetDeliveryOrder(order.ID)
+}
+
+// RenderDeliveryOrder renders the document again, after its outgoings or the
+// company details have changed.
+func (s *deliveryOrderService) RenderDeliveryOrder(id int, by string) (*models.DeliveryOrder, error) {
+	if err := s.storeDocument(id, by); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Successfully rendered delivery order", "id", id)
+
+	return s.GetDeliveryOrder(id)
+}
+
+// DeleteDeliveryOrder unlinks the outgoings, clearing their ref doc when it
+// still points at the document, and removes the document. The number is not
+// reused.
+func (s *deliveryOrderService) DeleteDeliveryOrder(id int) error {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var document string
+	err = tx.QueryRowContext(
+		context.Background(),
+		`SELECT document FROM delivery_orders WHERE id = $1 FOR UPDATE`,
+		id,
+	).Scan(&document)
+	if err != nil {
+		slog.Error("Error locking delivery order", "error", err, "id", id)
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_outgoings
+		SET
+			delivery_order_id = NULL,
+			ref_doc = CASE WHEN ref_doc = $2 THEN '' ELSE ref_doc END
+		WHERE
+			delivery_order_id = $1
+		`,
+		id,
+		document,
+	)
+	if err != nil {
+		slog.Error("Error unlinking outgoings", "error", err)
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`DELETE FROM delivery_orders WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error deleting delivery order", "error", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing delivery order", "error", err)
+		return err
+	}
+
+	if document != "" {
+		if err := s.fileSystem.Delete(document); err != nil {
+			slog.Error("Error deleting delivery order document", "error", err, "document", document)
+		}
+	}
+
+	slog.Info("Successfully deleted delivery order", "id", id)
+
+	return nil
+}
+
+// storeDocument renders a committed delivery order, uploads it and then
+// points the delivery order and the ref doc of its outgoings at it. Nothing
+// is uploaded for a delivery order whose transaction may still roll back.
+func (s *deliveryOrderService) storeDocument(id int, by string) error {
+	order, err := s.GetDeliveryOrder(id)
+	if err != nil {
+		return err
+	}
+
+	path, err := s.fileSystem.Upload(order.DONo+".pdf", deliveryOrderPath, renderDeliveryOrder(order))
+	if err != nil {
+		slog.Error("Error uploading delivery order document", "error", err, "id", id)
+		return err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			delivery_orders
+		SET
+			document = $1,
+			updated_by = $2,
+			updated_at = NOW()
+		WHERE
+			id = $3
+		`,
+		path,
+		by,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating delivery order document", "error", err)
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_outgoings
+		SET
+			ref_doc = $1,
+			updated_by = $2,
+			updated_at = NOW()
+		WHERE
+			delivery_order_id = $3
+		`,
+		path,
+		by,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating outgoing ref doc", "error", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing delivery order document", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// nextDocumentNo takes the next number of prefix in year, such as
+// DO-2026-0001. The sequence row stays locked until tx ends, so numbers are
+// handed out without gaps or duplicates.
+func nextDocumentNo(tx *sql.Tx, prefix string, year int) (string, error) {
+	var no int
+	err := tx.QueryRowContext(
+		context.Background(),
+		`
+		INSERT INTO document_sequences (
+			prefix,
+			year,
+			last_no
+		) VALUES (
+			$1, $2, 1
+		)
+		ON CONFLICT (prefix, year) DO UPDATE SET
+			last_no = document_sequences.last_no + 1
+		RETURNING last_no
+		`,
+		prefix,
+		year,
+	).Scan(&no)
+	if err != nil {
+		slog.Error("Error taking document number", "error", err, "prefix", prefix, "year", year)
+		return "", err
+	}
+
+	return fmt.Sprintf("%s-%d-%04d", prefix, year, no), nil
+}
+
+// renderDeliveryOrder lays the delivery order out on A4 pages: the company
+// header, who it is delivered to, one row per outgoing, repeating the table
+// header on every page, and the signature blocks at the end.
+func renderDeliveryOrder(order *models.DeliveryOrder) []byte {
+	const (
+		left      = 40.0
+		right     = utils.PDFPageWidth - 40
+		top       = 60.0
+		bottom    = utils.PDFPageHeight - 60
+		rowHeight = 16.0
+	)
+
+	pdf := utils.NewPDF()
+	pdf.AddPage()
+
+	pdf.Text(left, top, 16, true, config.Cfg.CompanyName)
+	pdf.Text(left, top+14, 9, false, utils.FitText(config.Cfg.CompanyAddress, 9, 300))
+	if config.Cfg.CompanyPhone != "" {
+		pdf.Text(left, top+26, 9, false, "Tel: "+config.Cfg.CompanyPhone)
+	}
+
+	pdf.TextRight(right, top, 16, true, "DELIVERY ORDER")
+	pdf.TextRight(right, top+18, 10, false, "DO No: "+order.DONo)
+	pdf.TextRight(right, top+30, 10, false, "Date: "+dateOnly(order.DeliveredAt))
+	if order.JobNo != "" {
+		pdf.TextRight(right, top+42, 10, false, "Job No: "+order.JobNo)
+	}
+	pdf.Line(left, top+55, right, top+55)
+
+	pdf.Text(left, top+75, 10, true, "Deliver To")
+	pdf.Text(left, top+90, 10, false, utils.FitText(order.DeliverTo, 10, right-left))
+	pdf.Text(left, top+103, 9, false, utils.FitText(order.DeliveryAddress, 9, right-left))
+	if order.Remarks != "" {
+		pdf.Text(left, top+116, 9, false, utils.FitText("Remarks: "+order.Remarks, 9, right-left))
+	}
+
+	columns := []float64{left, left + 30, left + 130, left + 390, left + 400, left + 440}
+	tableHeader := func(y float64) float64 {
+		pdf.Text(columns[0], y, 9, true, "No.")
+		pdf.Text(columns[1], y, 9, true, "Product Code")
+		pdf.Text(columns[2], y, 9, true, "Description")
+		pdf.TextRight(columns[3], y, 9, true, "Quantity")
+		pdf.Text(columns[4], y, 9, true, "Unit")
+		pdf.Text(columns[5], y, 9, true, "Remarks")
+		pdf.Line(left, y+5, right, y+5)
+		return y + 5 + rowHeight
+	}
+
+	y := tableHeader(top + 145)
+	for i, line := range order.Lines {
+		if y > bottom {
+			pdf.AddPage()
+			y = tableHeader(top)
+		}
+
+		pdf.Text(columns[0], y, 9, false, strconv.Itoa(i+1))
+		pdf.Text(columns[1], y, 9, false, utils.FitText(line.ProductCode, 9, columns[2]-columns[1]-5))
+		pdf.Text(columns[2], y, 9, false, utils.FitText(line.ProductName, 9, columns[3]-columns[2]-50))
+		pdf.TextRight(columns[3], y, 9, false, strconv.FormatFloat(line.StandardQuantity, 'f', -1, 64))
+		pdf.Text(columns[4], y, 9, false, line.StandardUnit)
+		pdf.Text(columns[5], y, 9, false, utils.FitText(line.Remarks, 9, right-columns[5]))
+		y += rowHeight
+	}
+	pdf.Line(left, y-rowHeight+5, right, y-rowHeight+5)
+
+	// signature blocks need about 100pt, start them on a new page otherwise
+	if y+100 > utils.PDFPageHeight-40 {
+		pdf.AddPage()
+		y = top
+	}
+	y += 60
+
+	width := (right - left - 40) / 3
+	for i, label := range []string{"Issued By", "Delivered By", "Received By"} {
+		x := left + float64(i)*(width+20)
+		pdf.Line(x, y, x+width, y)
+		pdf.Text(x, y+12, 9, true, label)
+		pdf.Text(x, y+24, 8, false, "Name, signature and date")
+	}
+
+	return pdf.Bytes()
+}
+
+// dateOnly trims the time off a date scanned into a string.
+func dateOnly(value string) string {
+	if len(value) > 10 {
+		return value[:10]
+	}
+	return value
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"fmt"
+)
+
+// InsufficientStockError is returned when an outgoing asks for more standard
+// quantity than is left on the incoming it draws from, or when an allocation
+// asks for more than all open incomings of a product hold together.
+type InsufficientStockError struct {
+	IncomingID int     `json:"incomingId,omitempty"`
+	ProductID  int     `json:"productId,omitempty"`
+	Requested  float64 `json:"requested"`
+	Available  float64 `json:"available"`
+}
+
+func (e *InsufficientStockError) Error() string {
+	if e.IncomingID == 0 {
+		return fmt.Sprintf(
+			"insufficient stock on product %d: requested %v, available %v",
+			e.ProductID,
+			e.Requested,
+			e.Available,
+		)
+	}
+
+	return fmt.Sprintf(
+		"insufficient stock on incoming %d: requested %v, available %v",
+		e.IncomingID,
+		e.Requested,
+		e.Available,
+	)
+}
+
+// ValidationError is returned when a request is rejected because of its own
+// content rather than the state of the stock.
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
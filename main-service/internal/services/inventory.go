@@ -4,65 +4,154 @@ import (
 	"context"
 	"database/sql"
 	"log/slog"
+	"math"
+	"strings"
+	"time"
 
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/config"
 	"github.com/kokweikhong/calvary-admin-system/main-service/internal/db"
 	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/lib/pq"
 )
 
 type InventoryService interface {
-	GetProducts() ([]*models.InventoryProduct, error)
+	GetProducts(filter *models.InventoryProductFilter) ([]*models.InventoryProduct, error)
 	GetProduct(id int) (*models.InventoryProduct, error)
 	CreateProduct(product *models.InventoryProduct) (*models.InventoryProduct, error)
 	UpdateProduct(id int, product *models.InventoryProduct) (*models.InventoryProduct, error)
 	DeleteProduct(id int) error
-	GetProductSummary() ([]*models.InventoryProductSummary, error)
+	GetProductTags() ([]string, error)
+	GetProductVariants(id int) ([]*models.InventoryProduct, error)
+	LinkProductVariant(parentID int, link *models.ProductVariantLink) (*models.InventoryProduct, error)
+	UnlinkProductVariant(parentID, variantID int) error
+	GetProductMedia(productID int) ([]*models.ProductMedia, error)
+	AddProductMedia(productID int, media *models.ProductMedia, content []byte) (*models.ProductMedia, error)
+	ReorderProductMedia(productID int, order *models.ProductMediaOrder) ([]*models.ProductMedia, error)
+	SetPrimaryProductMedia(productID, mediaID int) ([]*models.ProductMedia, error)
+	DeleteProductMedia(productID, mediaID int) error
+
+	GetCategories() ([]*models.ProductCategory, error)
+	GetCategoryTree() ([]*models.ProductCategory, error)
+	GetCategory(id int) (*models.ProductCategory, error)
+	CreateCategory(category *models.ProductCategory) (*models.ProductCategory, error)
+	UpdateCategory(id int, category *models.ProductCategory) (*models.ProductCategory, error)
+	DeleteCategory(id int) error
+	CreateCategoryAttribute(categoryID int, attribute *models.CategoryAttribute) (*models.CategoryAttribute, error)
+	UpdateCategoryAttribute(categoryID, id int, attribute *models.CategoryAttribute) (*models.CategoryAttribute, error)
+	DeleteCategoryAttribute(categoryID, id int) error
+	GetProductSummary(asOf *time.Time, filter *models.InventoryProductFilter) ([]*models.InventoryProductSummary, error)
+	GetProductRollup(asOf *time.Time, filter *models.InventoryProductFilter) ([]*models.InventoryProductSummary, error)
 
 	GetIncomings() ([]*models.InventoryIncoming, error)
 	GetIncoming(id int) (*models.InventoryIncoming, error)
 	CreateIncoming(incoming *models.InventoryIncoming) (*models.InventoryIncoming, error)
 	UpdateIncoming(id int, incoming *models.InventoryIncoming) (*models.InventoryIncoming, error)
 	DeleteIncoming(id int) error
+	GetIncomingStatusHistory(id int) ([]*models.IncomingStatusChange, error)
+	TransitionIncoming(id int, transition *models.IncomingStatusTransition) (*models.InventoryIncoming, error)
+	TransitionShipment(transition *models.IncomingStatusTransition) ([]*models.InventoryIncoming, error)
 
 	GetOutgoings() ([]*models.InventoryOutgoing, error)
 	GetOutgoing(id int) (*models.InventoryOutgoing, error)
 	CreateOutgoing(outgoing *models.InventoryOutgoing) (*models.InventoryOutgoing, error)
 	UpdateOutgoing(id int, outgoing *models.InventoryOutgoing) (*models.InventoryOutgoing, error)
 	DeleteOutgoing(id int) error
+	AllocateOutgoings(request *models.InventoryAllocationRequest) (*models.InventoryAllocation, error)
+
+	GetReservations(productID, jobOrderID int, status string) ([]*models.InventoryReservation, error)
+	GetReservation(id int) (*models.InventoryReservation, error)
+	CreateReservation(reservation *models.InventoryReservation) (*models.InventoryReservation, error)
+	UpdateReservation(id int, reservation *models.InventoryReservation) (*models.InventoryReservation, error)
+	ReleaseReservation(id int, action *models.InventoryReservationAction) (*models.InventoryReservation, error)
+	DeleteReservation(id int) error
+
+	GetValuationReport(groupBy string, asOf *time.Time) (*models.InventoryValuationReport, error)
+	GetProductLedger(productID int, from, to *time.Time) (*models.InventoryLedger, error)
+	GetAgingReport(filter *models.InventoryAgingFilter) (*models.InventoryAgingReport, error)
+	GetExpiryReport(days, productID int) (*models.ExpiryReport, error)
+
+	GetTransfers() ([]*models.InventoryTransfer, error)
+	GetTransfer(id int) (*models.InventoryTransfer, error)
+	CreateTransfer(transfer *models.InventoryTransfer) (*models.InventoryTransfer, error)
+
+	GetReturnReasons() ([]*models.InventoryReturnReason, error)
+	GetReturns(returnType string, productID int) ([]*models.InventoryReturn, error)
+	GetReturn(id int) (*models.InventoryReturn, error)
+	CreateReturn(ret *models.InventoryReturn) (*models.InventoryReturn, error)
+
+	TraceLot(lotNo, serialNo string, productID int) (*models.LotTrace, error)
+
+	GetAdjustmentReasons() ([]*models.InventoryAdjustmentReason, error)
+	GetAdjustments(status string) ([]*models.InventoryAdjustment, error)
+	GetAdjustment(id int) (*models.InventoryAdjustment, error)
+	CreateAdjustment(adjustment *models.InventoryAdjustment) (*models.InventoryAdjustment, error)
+	ApproveAdjustment(id int, decision *models.InventoryAdjustmentDecision) (*models.InventoryAdjustment, error)
+	RejectAdjustment(id int, decision *models.InventoryAdjustmentDecision) (*models.InventoryAdjustment, error)
+
+	GetCycleCounts() ([]*models.CycleCount, error)
+	GetCycleCount(id int) (*models.CycleCount, error)
+	CreateCycleCount(count *models.CycleCount) (*models.CycleCount, error)
+	UpdateCycleCountLines(id int, entries []*models.CycleCountEntry) (*models.CycleCount, error)
+	PostCycleCount(id int, postedBy string) (*models.CycleCount, error)
+	CancelCycleCount(id int) error
 }
 
 type inventoryService struct {
-	db *sql.DB
+	db         *sql.DB
+	fileSystem FileSystemService
 }
 
+// stockTolerance absorbs float rounding when comparing NUMERIC balances.
+const stockTolerance = 1e-6
+
 func NewInventoryService() InventoryService {
+	return newInventoryService()
+}
+
+// newInventoryService is for services of this package that write stock
+// inside their own transaction.
+func newInventoryService() *inventoryService {
 	return &inventoryService{
-		db: db.GetDB(),
+		db:         db.GetDB(),
+		fileSystem: NewFileSystemService(),
 	}
 }
 
 // Product
-func (s *inventoryService) GetProducts() ([]*models.InventoryProduct, error) {
+func (s *inventoryService) GetProducts(filter *models.InventoryProductFilter) ([]*models.InventoryProduct, error) {
+	where, args := productFilterClause(filter, nil)
 	queryStr := `
 		SELECT
-			id,
-			code,
-			name,
-			brand,
-			standard_unit,
-			thumbnail,
-			supplier,
-			remarks,
-			is_exist,
-			created_by,
-			created_at,
-			updated_by,
-			updated_at
+			p.id,
+			p.code,
+			p.name,
+			p.brand,
+			p.standard_unit,
+			p.thumbnail,
+			p.supplier,
+			p.remarks,
+			p.costing_method,
+			COALESCE(p.category_id, 0),
+			COALESCE(p.parent_id, 0),
+			p.is_exist,
+			p.created_by,
+			p.created_at,
+			p.updated_by,
+			p.updated_at,
+			COALESCE(cp.path, '') AS category_path,
+			p.tags,
+			p.variant_axes
 		FROM
-			inventory_products
+			inventory_products p
+		LEFT JOIN
+			product_category_paths cp
+		ON
+			p.category_id = cp.id
+		` + where + `
 	`
 
 	// execute query with context, transaction, and arguments
-	rows, err := s.db.QueryContext(context.Background(), queryStr)
+	rows, err := s.db.QueryContext(context.Background(), queryStr, args...)
 	if err != nil {
 		slog.Error("Error querying products", "error", err)
 		return nil, err
@@ -84,11 +173,17 @@ func (s *inventoryService) GetProducts() ([]*models.InventoryProduct, error) {
 			&product.Thumbnail,
 			&product.Supplier,
 			&product.Remarks,
+			&product.CostingMethod,
+			&product.CategoryID,
+			&product.ParentID,
 			&product.IsExist,
 			&product.CreatedBy,
 			&product.CreatedAt,
 			&product.UpdatedBy,
 			&product.UpdatedAt,
+			&product.CategoryPath,
+			pq.Array(&product.Tags),
+			pq.Array(&product.VariantAxes),
 		)
 		if err != nil {
 			slog.Error("Error scanning product", "error", err)
@@ -112,23 +207,33 @@ func (s *inventoryService) GetProducts() ([]*models.InventoryProduct, error) {
 func (s *inventoryService) GetProduct(id int) (*models.InventoryProduct, error) {
 	queryStr := `
 		SELECT
-			id,
-			code,
-			name,
-			brand,
-			standard_unit,
-			thumbnail,
-			supplier,
-			remarks,
-			is_exist,
-			created_by,
-			created_at,
-			updated_by,
-			updated_at
+			p.id,
+			p.code,
+			p.name,
+			p.brand,
+			p.standard_unit,
+			p.thumbnail,
+			p.supplier,
+			p.remarks,
+			p.costing_method,
+			COALESCE(p.category_id, 0),
+			COALESCE(p.parent_id, 0),
+			p.is_exist,
+			p.created_by,
+			p.created_at,
+			p.updated_by,
+			p.updated_at,
+			COALESCE(cp.path, '') AS category_path,
+			p.tags,
+			p.variant_axes
 		FROM
-			inventory_products
+			inventory_products p
+		LEFT JOIN
+			product_category_paths cp
+		ON
+			p.category_id = cp.id
 		WHERE
-			id = $1
+			p.id = $1
 	`
 
 	// database execute with commit, transaction, context and commit
@@ -144,17 +249,33 @@ func (s *inventoryService) GetProduct(id int) (*models.InventoryProduct, error)
 		&product.Thumbnail,
 		&product.Supplier,
 		&product.Remarks,
+		&product.CostingMethod,
+		&product.CategoryID,
+		&product.ParentID,
 		&product.IsExist,
 		&product.CreatedBy,
 		&product.CreatedAt,
 		&product.UpdatedBy,
 		&product.UpdatedAt,
+		&product.CategoryPath,
+		pq.Array(&product.Tags),
+		pq.Array(&product.VariantAxes),
 	)
 	if err != nil {
 		slog.Error("Error scanning product", "error", err)
 		return nil, err
 	}
 
+	product.Attributes, err = getProductAttributes(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Media, err = getProductMedia(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
 	slog.Info("Successfully queried product", "product", product)
 
 	return product, nil
@@ -170,18 +291,45 @@ func (s *inventoryService) CreateProduct(product *models.InventoryProduct) (*mod
 			thumbnail,
 			supplier,
 			remarks,
+			costing_method,
+			category_id,
+			tags,
+			parent_id,
+			variant_axes,
 			is_exist,
 			created_by,
 			created_at,
 			updated_by,
 			updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), $10, NOW()
+			$1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, 0), COALESCE($10, '{}'), NULLIF($11, 0), COALESCE($12, '{}'),
+			$13, $14, NOW(), $15, NOW()
 		)
+		RETURNING id, tags, variant_axes
 	`
 
+	if err := validateCostingMethod(product); err != nil {
+		return nil, err
+	}
+
+	product.StandardUnit = strings.TrimSpace(product.StandardUnit)
+	if err := checkStandardUnit(s.db, product.StandardUnit); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := applyProductParent(tx, 0, product); err != nil {
+		return nil, err
+	}
+
 	// database execute with commit, transaction, context and commit
-	_, err := s.db.ExecContext(
+	err = tx.QueryRowContext(
 		context.Background(),
 		queryStr,
 		product.Code,
@@ -191,20 +339,46 @@ func (s *inventoryService) CreateProduct(product *models.InventoryProduct) (*mod
 		product.Thumbnail,
 		product.Supplier,
 		product.Remarks,
+		product.CostingMethod,
+		product.CategoryID,
+		pq.Array(normaliseTags(product.Tags)),
+		product.ParentID,
+		pq.Array(normaliseTags(product.VariantAxes)),
 		product.IsExist,
 		product.CreatedBy,
 		product.UpdatedBy,
-	)
+	).Scan(&product.ID, pq.Array(&product.Tags), pq.Array(&product.VariantAxes))
 	if err != nil {
 		slog.Error("Error inserting product", "error", err)
 		return nil, err
 	}
 
+	if err := seedProductMedia(tx, product); err != nil {
+		return nil, err
+	}
+
+	if err := saveProductAttributes(tx, product); err != nil {
+		return nil, err
+	}
+
+	if err := syncProductVariants(tx, product); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing product", "error", err)
+		return nil, err
+	}
+
 	slog.Info("Successfully inserted product", "product", product)
 
 	return product, nil
 }
 
+// UpdateProduct saves a product. Tags, VariantAxes and Attributes left out
+// keep what the product has. The thumbnail follows the primary image of the
+// media gallery, a new one becomes the primary image and an empty one keeps
+// what the product has.
 func (s *inventoryService) UpdateProduct(id int, product *models.InventoryProduct) (*models.InventoryProduct, error) {
 	queryStr := `
 		UPDATE
@@ -214,36 +388,84 @@ func (s *inventoryService) UpdateProduct(id int, product *models.InventoryProduc
 			name = $2,
 			brand = $3,
 			standard_unit = $4,
-			thumbnail = $5,
-			supplier = $6,
-			remarks = $7,
-			is_exist = $8,
-			updated_by = $9,
+			supplier = $5,
+			remarks = $6,
+			costing_method = $7,
+			category_id = NULLIF($8, 0),
+			tags = COALESCE($9, tags),
+			parent_id = NULLIF($10, 0),
+			variant_axes = COALESCE($11, variant_axes),
+			is_exist = $12,
+			updated_by = $13,
 			updated_at = NOW()
 		WHERE
-			id = $10
+			id = $14
+		RETURNING thumbnail, tags, variant_axes
 	`
 
+	if err := validateCostingMethod(product); err != nil {
+		return nil, err
+	}
+
+	product.StandardUnit = strings.TrimSpace(product.StandardUnit)
+	if err := checkStandardUnit(s.db, product.StandardUnit); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := applyProductParent(tx, id, product); err != nil {
+		return nil, err
+	}
+
 	// database execute with commit, transaction, context and commit
-	_, err := s.db.ExecContext(
+	thumbnail := product.Thumbnail
+	err = tx.QueryRowContext(
 		context.Background(),
 		queryStr,
 		product.Code,
 		product.Name,
 		product.Brand,
 		product.StandardUnit,
-		product.Thumbnail,
 		product.Supplier,
 		product.Remarks,
+		product.CostingMethod,
+		product.CategoryID,
+		pq.Array(normaliseTags(product.Tags)),
+		product.ParentID,
+		pq.Array(normaliseTags(product.VariantAxes)),
 		product.IsExist,
 		product.UpdatedBy,
 		id,
-	)
+	).Scan(&product.Thumbnail, pq.Array(&product.Tags), pq.Array(&product.VariantAxes))
 	if err != nil {
 		slog.Error("Error updating product", "error", err)
 		return nil, err
 	}
 
+	product.ID = id
+	if err := changeProductThumbnail(tx, product, thumbnail); err != nil {
+		return nil, err
+	}
+
+	if err := saveProductAttributes(tx, product); err != nil {
+		return nil, err
+	}
+
+	if err := syncProductVariants(tx, product); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing product", "error", err)
+		return nil, err
+	}
+
 	slog.Info("Successfully updated product", "product", product)
 
 	return product, nil
@@ -257,8 +479,28 @@ func (s *inventoryService) DeleteProduct(id int) error {
 			id = $1
 	`
 
+	var hasVariants bool
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM inventory_products WHERE parent_id = $1)`,
+		id,
+	).Scan(&hasVariants)
+	if err != nil {
+		slog.Error("Error checking product variants", "error", err, "product", id)
+		return err
+	}
+	if hasVariants {
+		return &ValidationError{Message: "unlink the variants of the product before deleting it"}
+	}
+
+	// the media rows go with the product, their files are removed after
+	media, err := getProductMedia(s.db, id)
+	if err != nil {
+		return err
+	}
+
 	// database execute with commit, transaction, context and commit
-	_, err := s.db.ExecContext(
+	_, err = s.db.ExecContext(
 		context.Background(),
 		queryStr,
 		id,
@@ -268,12 +510,27 @@ func (s *inventoryService) DeleteProduct(id int) error {
 		return err
 	}
 
+	for _, item := range media {
+		s.deleteMediaFile(item.Path)
+	}
+
 	slog.Info("Successfully deleted product", "product", id)
 
 	return nil
 }
 
-func (s *inventoryService) GetProductSummary() ([]*models.InventoryProductSummary, error) {
+// GetProductSummary returns the stock position of every product. When asOf
+// is set only movements made up to the end of that day are counted. On order
+// is what approved purchase orders still have to deliver today.
+func (s *inventoryService) GetProductSummary(asOf *time.Time, filter *models.InventoryProductFilter) ([]*models.InventoryProductSummary, error) {
+	// count the whole of the as-of day
+	var cutoff *time.Time
+	if asOf != nil {
+		next := asOf.AddDate(0, 0, 1)
+		cutoff = &next
+	}
+
+	where, args := productFilterClause(filter, []any{cutoff})
 	queryStr := `
 		SELECT
 			p.id,
@@ -284,30 +541,92 @@ func (s *inventoryService) GetProductSummary() ([]*models.InventoryProductSummar
 			p.thumbnail,
 			p.supplier,
 			p.remarks,
+			p.costing_method,
+			COALESCE(p.category_id, 0),
+			COALESCE(p.parent_id, 0),
 			p.is_exist,
 			p.created_by,
 			p.created_at,
 			p.updated_by,
 			p.updated_at,
-			COALESCE(SUM(i.standard_quantity), 0) AS total_incoming,
-			COALESCE(SUM(o.standard_quantity), 0) AS total_outgoing,
-			COALESCE(SUM(i.standard_quantity), 0) - COALESCE(SUM(o.standard_quantity), 0) AS total_balance
+			COALESCE(cp.path, '') AS category_path,
+			p.tags,
+			p.variant_axes,
+			COALESCE(m.total_incoming, 0) AS total_incoming,
+			COALESCE(m.total_outgoing, 0) AS total_outgoing,
+			COALESCE(m.total_balance, 0) AS total_balance,
+			COALESCE(rv.total_reserved, 0) AS total_reserved,
+			COALESCE(m.total_balance, 0) - COALESCE(rv.total_reserved, 0) AS total_available,
+			COALESCE(o.on_order, 0) AS on_order
 		FROM
 			inventory_products p
-		LEFT JOIN
-			inventory_incomings i
+		LEFT JOIN (
+			SELECT
+				product_id,
+				SUM(standard_quantity) FILTER (WHERE movement_type = 'incoming') AS total_incoming,
+				-SUM(standard_quantity) FILTER (WHERE movement_type = 'outgoing') AS total_outgoing,
+				SUM(standard_quantity) AS total_balance
+			FROM
+				inventory_movements
+			WHERE
+				$1::TIMESTAMP IS NULL OR moved_at < $1::TIMESTAMP
+			GROUP BY
+				product_id
+			) m
 		ON
-			p.id = i.product_id
-		LEFT JOIN
-			inventory_outgoings o
+			p.id = m.product_id
+		LEFT JOIN (
+			SELECT
+				l.product_id,
+				SUM(GREATEST(l.standard_quantity - COALESCE(r.received, 0), 0)) AS on_order
+			FROM
+				purchase_order_lines l
+			JOIN
+				purchase_orders po
+			ON
+				l.purchase_order_id = po.id
+			LEFT JOIN (
+				SELECT
+					purchase_order_line_id,
+					SUM(standard_quantity) AS received
+				FROM
+					inventory_incomings
+				WHERE
+					purchase_order_line_id IS NOT NULL
+				GROUP BY
+					purchase_order_line_id
+			) r
+			ON
+				r.purchase_order_line_id = l.id
+			WHERE
+				po.status IN ('approved', 'partially_received')
+			GROUP BY
+				l.product_id
+			) o
 		ON
 			p.id = o.product_id
-		GROUP BY
+		LEFT JOIN (
+			SELECT
+				product_id,
+				SUM(reserved_quantity) AS total_reserved
+			FROM
+				inventory_reservation_balances
+			GROUP BY
+				product_id
+			) rv
+		ON
+			p.id = rv.product_id
+		LEFT JOIN
+			product_category_paths cp
+		ON
+			p.category_id = cp.id
+		` + where + `
+		ORDER BY
 			p.id
 	`
 
 	// execute query with context, transaction, and arguments
-	rows, err := s.db.QueryContext(context.Background(), queryStr)
+	rows, err := s.db.QueryContext(context.Background(), queryStr, args...)
 	if err != nil {
 		slog.Error("Error querying products", "error", err)
 		return nil, err
@@ -320,6 +639,7 @@ func (s *inventoryService) GetProductSummary() ([]*models.InventoryProductSummar
 	products := []*models.InventoryProductSummary{}
 	for rows.Next() {
 		product := new(models.InventoryProductSummary)
+		var reserved, available, onOrder float64
 		err := rows.Scan(
 			&product.ID,
 			&product.Code,
@@ -329,19 +649,33 @@ func (s *inventoryService) GetProductSummary() ([]*models.InventoryProductSummar
 			&product.Thumbnail,
 			&product.Supplier,
 			&product.Remarks,
+			&product.CostingMethod,
+			&product.CategoryID,
+			&product.ParentID,
 			&product.IsExist,
 			&product.CreatedBy,
 			&product.CreatedAt,
 			&product.UpdatedBy,
 			&product.UpdatedAt,
+			&product.CategoryPath,
+			pq.Array(&product.Tags),
+			pq.Array(&product.VariantAxes),
 			&product.TotalIncoming,
 			&product.TotalOutgoing,
 			&product.TotalBalance,
+			&reserved,
+			&available,
+			&onOrder,
 		)
 		if err != nil {
 			slog.Error("Error scanning product", "error", err)
 			return nil, err
 		}
+		if asOf == nil {
+			product.TotalReserved = &reserved
+			product.TotalAvailable = &available
+			product.OnOrder = &onOrder
+		}
 
 		products = append(products, product)
 	}
@@ -369,13 +703,22 @@ func (s *inventoryService) GetIncomings() ([]*models.InventoryIncoming, error) {
 			i.width,
 			i.height,
 			i.unit,
+			i.quantity_unit,
 			i.standard_quantity,
 			i.ref_no,
 			i.ref_doc,
 			i.cost,
+			COALESCE(i.location_id, 0),
+			COALESCE(i.source_incoming_id, 0),
+			COALESCE(i.purchase_order_line_id, 0),
+			i.supplier,
+			i.lot_no,
+			i.manufactured_at,
+			i.expires_at,
 			i.store_location,
 			i.store_country,
 			i.remarks,
+			i.received_at,
 			i.created_by,
 			i.created_at,
 			i.updated_by,
@@ -383,29 +726,43 @@ func (s *inventoryService) GetIncomings() ([]*models.InventoryIncoming, error) {
 			p.code AS product_code,
 			p.name AS product_name,
 			p.standard_unit AS standard_unit,
-            
-            COALESCE(i.standard_quantity, 0) - COALESCE(o.sum_standard_quantity, 0) AS balance_std_qty,
-            COALESCE(i.quantity, 0) - COALESCE(o.sum_quantity, 0) AS balance_qty
-        FROM
-            inventory_incomings i
-        LEFT JOIN
-            inventory_products p
-        ON
-            i.product_id = p.id
-        LEFT JOIN (
-            SELECT
-                incoming_id,
-                SUM(COALESCE(standard_quantity, 0)) AS sum_standard_quantity,
-                SUM(COALESCE(quantity, 0)) AS sum_quantity
-            FROM
-                inventory_outgoings
-            GROUP BY
-                incoming_id
-            ) o
-        ON
-            i.id = o.incoming_id
-        ORDER BY
-            i.id DESC
+			COALESCE(b.balance_std_qty, 0) AS balance_std_qty,
+			COALESCE(b.balance_qty, 0) AS balance_qty,
+			COALESCE(rv.reserved_std_qty, 0) AS reserved_std_qty,
+			COALESCE(b.balance_std_qty, 0) - COALESCE(rv.reserved_std_qty, 0) AS available_std_qty
+		FROM
+			inventory_incomings i
+		LEFT JOIN
+			inventory_products p
+		ON
+			i.product_id = p.id
+		LEFT JOIN (
+			SELECT
+				incoming_id,
+				SUM(standard_quantity) AS balance_std_qty,
+				SUM(quantity) AS balance_qty
+			FROM
+				inventory_movements
+			GROUP BY
+				incoming_id
+			) b
+		ON
+			i.id = b.incoming_id
+		LEFT JOIN (
+			SELECT
+				incoming_id,
+				SUM(reserved_quantity) AS reserved_std_qty
+			FROM
+				inventory_reservation_balances
+			WHERE
+				incoming_id IS NOT NULL
+			GROUP BY
+				incoming_id
+			) rv
+		ON
+			i.id = rv.incoming_id
+		ORDER BY
+			i.id DESC
 	`
 
 	// execute query with context, transaction, and arguments
@@ -422,6 +779,7 @@ func (s *inventoryService) GetIncomings() ([]*models.InventoryIncoming, error) {
 	var incomings []*models.InventoryIncoming
 	for rows.Next() {
 		incoming := new(models.InventoryIncoming)
+		var manufacturedAt, expiresAt sql.NullString
 		err := rows.Scan(
 			&incoming.ID,
 			&incoming.ProductID,
@@ -431,13 +789,22 @@ func (s *inventoryService) GetIncomings() ([]*models.InventoryIncoming, error) {
 			&incoming.Width,
 			&incoming.Height,
 			&incoming.Unit,
+			&incoming.QuantityUnit,
 			&incoming.StandardQuantity,
 			&incoming.RefNo,
 			&incoming.RefDoc,
 			&incoming.Cost,
+			&incoming.LocationID,
+			&incoming.SourceIncomingID,
+			&incoming.PurchaseOrderLineID,
+			&incoming.Supplier,
+			&incoming.LotNo,
+			&manufacturedAt,
+			&expiresAt,
 			&incoming.StoreLocation,
 			&incoming.StoreCountry,
 			&incoming.Remarks,
+			&incoming.ReceivedAt,
 			&incoming.CreatedBy,
 			&incoming.CreatedAt,
 			&incoming.UpdatedBy,
@@ -449,11 +816,15 @@ func (s *inventoryService) GetIncomings() ([]*models.InventoryIncoming, error) {
 
 			&incoming.BalanceStdQty,
 			&incoming.BalanceQty,
+			&incoming.ReservedStdQty,
+			&incoming.AvailableStdQty,
 		)
 		if err != nil {
 			slog.Error("Error scanning incoming", "error", err)
 			return nil, err
 		}
+		incoming.ManufacturedAt = manufacturedAt.String
+		incoming.ExpiresAt = expiresAt.String
 
 		incomings = append(incomings, incoming)
 	}
@@ -480,13 +851,22 @@ func (s *inventoryService) GetIncoming(id int) (*models.InventoryIncoming, error
 			i.width,
 			i.height,
 			i.unit,
+			i.quantity_unit,
 			i.standard_quantity,
 			i.ref_no,
 			i.ref_doc,
 			i.cost,
+			COALESCE(i.location_id, 0),
+			COALESCE(i.source_incoming_id, 0),
+			COALESCE(i.purchase_order_line_id, 0),
+			i.supplier,
+			i.lot_no,
+			i.manufactured_at,
+			i.expires_at,
 			i.store_location,
 			i.store_country,
 			i.remarks,
+			i.received_at,
 			i.created_by,
 			i.created_at,
 			i.updated_by,
@@ -494,27 +874,41 @@ func (s *inventoryService) GetIncoming(id int) (*models.InventoryIncoming, error
 			p.code AS product_code,
 			p.name AS product_name,
 			p.standard_unit AS standard_unit,
-            
-            COALESCE(i.standard_quantity, 0) - COALESCE(o.sum_standard_quantity, 0) AS balance_std_qty,
-            COALESCE(i.quantity, 0) - COALESCE(o.sum_quantity, 0) AS balance_qty
-        FROM
-            inventory_incomings i
-        LEFT JOIN
-            inventory_products p
-        ON
-            i.product_id = p.id
-        LEFT JOIN (
-            SELECT
-                incoming_id,
-                SUM(COALESCE(standard_quantity, 0)) AS sum_standard_quantity,
-                SUM(COALESCE(quantity, 0)) AS sum_quantity
-            FROM
-                inventory_outgoings
-            GROUP BY
-                incoming_id
-            ) o
-        ON
-            i.id = o.incoming_id
+			COALESCE(b.balance_std_qty, 0) AS balance_std_qty,
+			COALESCE(b.balance_qty, 0) AS balance_qty,
+			COALESCE(rv.reserved_std_qty, 0) AS reserved_std_qty,
+			COALESCE(b.balance_std_qty, 0) - COALESCE(rv.reserved_std_qty, 0) AS available_std_qty
+		FROM
+			inventory_incomings i
+		LEFT JOIN
+			inventory_products p
+		ON
+			i.product_id = p.id
+		LEFT JOIN (
+			SELECT
+				incoming_id,
+				SUM(standard_quantity) AS balance_std_qty,
+				SUM(quantity) AS balance_qty
+			FROM
+				inventory_movements
+			GROUP BY
+				incoming_id
+			) b
+		ON
+			i.id = b.incoming_id
+		LEFT JOIN (
+			SELECT
+				incoming_id,
+				SUM(reserved_quantity) AS reserved_std_qty
+			FROM
+				inventory_reservation_balances
+			WHERE
+				incoming_id IS NOT NULL
+			GROUP BY
+				incoming_id
+			) rv
+		ON
+			i.id = rv.incoming_id
 		WHERE
 			i.id = $1
 			
@@ -524,6 +918,7 @@ func (s *inventoryService) GetIncoming(id int) (*models.InventoryIncoming, error
 	row := s.db.QueryRowContext(context.Background(), queryStr, id)
 
 	incoming := new(models.InventoryIncoming)
+	var manufacturedAt, expiresAt sql.NullString
 	err := row.Scan(
 		&incoming.ID,
 		&incoming.ProductID,
@@ -533,13 +928,22 @@ func (s *inventoryService) GetIncoming(id int) (*models.InventoryIncoming, error
 		&incoming.Width,
 		&incoming.Height,
 		&incoming.Unit,
+		&incoming.QuantityUnit,
 		&incoming.StandardQuantity,
 		&incoming.RefNo,
 		&incoming.RefDoc,
 		&incoming.Cost,
+		&incoming.LocationID,
+		&incoming.SourceIncomingID,
+		&incoming.PurchaseOrderLineID,
+		&incoming.Supplier,
+		&incoming.LotNo,
+		&manufacturedAt,
+		&expiresAt,
 		&incoming.StoreLocation,
 		&incoming.StoreCountry,
 		&incoming.Remarks,
+		&incoming.ReceivedAt,
 		&incoming.CreatedBy,
 		&incoming.CreatedAt,
 		&incoming.UpdatedBy,
@@ -551,18 +955,60 @@ func (s *inventoryService) GetIncoming(id int) (*models.InventoryIncoming, error
 		// get balance quantity from outgoing
 		&incoming.BalanceStdQty,
 		&incoming.BalanceQty,
+		&incoming.ReservedStdQty,
+		&incoming.AvailableStdQty,
 	)
 	if err != nil {
 		slog.Error("Error scanning incoming", "error", err)
 		return nil, err
 	}
+	incoming.ManufacturedAt = manufacturedAt.String
+	incoming.ExpiresAt = expiresAt.String
+
+	incoming.SerialNos, err = s.getIncomingSerials(id)
+	if err != nil {
+		return nil, err
+	}
 
 	slog.Info("Successfully queried incoming", "incoming", incoming)
 
 	return incoming, nil
 }
 
+// CreateIncoming records stock received outside a purchase order. Receipts
+// against a purchase order line go through the purchase order, which checks
+// the line and writes them with insertIncoming in its own transaction.
 func (s *inventoryService) CreateIncoming(incoming *models.InventoryIncoming) (*models.InventoryIncoming, error) {
+	if incoming.PurchaseOrderLineID != 0 {
+		return nil, &ValidationError{Message: "receive stock against a purchase order line through the purchase order"}
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.insertIncoming(tx, incoming); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing incoming", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted incoming", "incoming", incoming)
+
+	NotifyStockChanged(incoming.ProductID)
+
+	return incoming, nil
+}
+
+// insertIncoming writes incoming inside tx and sets its generated id. The
+// caller commits and notifies the stock change.
+func (s *inventoryService) insertIncoming(tx *sql.Tx, incoming *models.InventoryIncoming) error {
 	queryStr := `
 		INSERT INTO inventory_incomings (
 			product_id,
@@ -579,18 +1025,54 @@ func (s *inventoryService) CreateIncoming(incoming *models.InventoryIncoming) (*
 			store_location,
 			store_country,
 			remarks,
+			received_at,
+			location_id,
+			purchase_order_line_id,
+			supplier,
+			quantity_unit,
+			lot_no,
+			manufactured_at,
+			expires_at,
 			created_by,
 			created_at,
 			updated_by,
 			updated_at
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
-			$11, $12, $13, $14, $15, NOW(), $16, NOW()
+			$11, $12, $13, $14, COALESCE(NULLIF($15, '')::DATE, CURRENT_DATE),
+			NULLIF($16, 0), NULLIF($17, 0), $18, $19, $20,
+			NULLIF($21, '')::DATE, NULLIF($22, '')::DATE, $23, NOW(), $24, NOW()
 		)
+		RETURNING id
 	`
 
+	// an incoming starts out anywhere before it is used up, available when no
+	// status is given
+	status, ok := normaliseIncomingStatus(incoming.Status)
+	if incoming.Status == "" {
+		status, ok = models.IncomingStatusAvailable, true
+	}
+	if !ok || status == models.IncomingStatusDepleted || status == models.IncomingStatusClosed {
+		return &ValidationError{Message: "an incoming cannot be created with status " + incoming.Status}
+	}
+	incoming.Status = status
+
+	if err := applyIncomingLot(incoming); err != nil {
+		return err
+	}
+
+	if err := s.resolveLocation(incoming); err != nil {
+		return err
+	}
+
+	if err := applyIncomingQuantity(tx, incoming); err != nil {
+		return err
+	}
+
+	incoming.Supplier = strings.TrimSpace(incoming.Supplier)
+
 	// database execute with commit, transaction, context and commit
-	_, err := s.db.ExecContext(
+	err := tx.QueryRowContext(
 		context.Background(),
 		queryStr,
 		incoming.ProductID,
@@ -607,17 +1089,27 @@ func (s *inventoryService) CreateIncoming(incoming *models.InventoryIncoming) (*
 		incoming.StoreLocation,
 		incoming.StoreCountry,
 		incoming.Remarks,
+		incoming.ReceivedAt,
+		incoming.LocationID,
+		incoming.PurchaseOrderLineID,
+		incoming.Supplier,
+		incoming.QuantityUnit,
+		incoming.LotNo,
+		incoming.ManufacturedAt,
+		incoming.ExpiresAt,
 		incoming.CreatedBy,
 		incoming.UpdatedBy,
-	)
+	).Scan(&incoming.ID)
 	if err != nil {
 		slog.Error("Error inserting incoming", "error", err)
-		return nil, err
+		return err
 	}
 
-	slog.Info("Successfully inserted incoming", "incoming", incoming)
+	if err := saveIncomingSerials(tx, incoming); err != nil {
+		return err
+	}
 
-	return incoming, nil
+	return recordIncomingStatus(tx, incoming.ID, "", incoming.Status, incoming.CreatedBy, "")
 }
 
 func (s *inventoryService) UpdateIncoming(id int, incoming *models.InventoryIncoming) (*models.InventoryIncoming, error) {
@@ -639,14 +1131,91 @@ func (s *inventoryService) UpdateIncoming(id int, incoming *models.InventoryInco
 			store_location = $12,
 			store_country = $13,
 			remarks = $14,
-			updated_by = $15,
+			received_at = COALESCE(NULLIF($15, '')::DATE, received_at),
+			location_id = NULLIF($16, 0),
+			supplier = $17,
+			quantity_unit = $18,
+			lot_no = $19,
+			manufactured_at = NULLIF($20, '')::DATE,
+			expires_at = NULLIF($21, '')::DATE,
+			updated_by = $22,
 			updated_at = NOW()
 		WHERE
-			id = $16
+			id = $23
 	`
 
+	if err := applyIncomingLot(incoming); err != nil {
+		return nil, err
+	}
+
+	if err := s.resolveLocation(incoming); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// a status change is a transition like any other, no status keeps the
+	// current one
+	if incoming.Status == "" {
+		err = tx.QueryRowContext(
+			context.Background(),
+			`SELECT status FROM inventory_incomings WHERE id = $1`,
+			id,
+		).Scan(&incoming.Status)
+		if err != nil {
+			slog.Error("Error querying incoming status", "error", err, "id", id)
+			return nil, err
+		}
+	}
+	status, ok := normaliseIncomingStatus(incoming.Status)
+	if !ok {
+		return nil, &ValidationError{Message: "unknown incoming status: " + incoming.Status}
+	}
+	incoming.Status = status
+	if err := s.transitionIncoming(tx, id, incoming.Status, incoming.UpdatedBy, ""); err != nil {
+		return nil, err
+	}
+
+	if err := applyIncomingQuantity(tx, incoming); err != nil {
+		return nil, err
+	}
+
+	// what was received against a purchase order line counts towards the
+	// order's status, so it is corrected with an adjustment or return instead
+	var (
+		lineID           int
+		productID        int
+		standardQuantity float64
+	)
+	err = tx.QueryRowContext(
+		context.Background(),
+		`SELECT COALESCE(purchase_order_line_id, 0), product_id, standard_quantity FROM inventory_incomings WHERE id = $1`,
+		id,
+	).Scan(&lineID, &productID, &standardQuantity)
+	if err != nil {
+		slog.Error("Error querying incoming", "error", err, "id", id)
+		return nil, err
+	}
+	if lineID != 0 && (productID != incoming.ProductID || math.Abs(standardQuantity-incoming.StandardQuantity) > stockTolerance) {
+		return nil, &ValidationError{Message: "incoming was received against a purchase order, correct its product or quantity with an adjustment or a supplier return"}
+	}
+
+	// lowering the quantity cannot take back what has already been issued
+	if reduction := standardQuantity - incoming.StandardQuantity; reduction > stockTolerance {
+		if err := s.checkIncomingBalance(tx, id, 0, reduction); err != nil {
+			return nil, err
+		}
+	}
+
+	incoming.Supplier = strings.TrimSpace(incoming.Supplier)
+
 	// database execute with commit, transaction, context and commit
-	_, err := s.db.ExecContext(
+	_, err = tx.ExecContext(
 		context.Background(),
 		queryStr,
 		incoming.ProductID,
@@ -663,6 +1232,13 @@ func (s *inventoryService) UpdateIncoming(id int, incoming *models.InventoryInco
 		incoming.StoreLocation,
 		incoming.StoreCountry,
 		incoming.Remarks,
+		incoming.ReceivedAt,
+		incoming.LocationID,
+		incoming.Supplier,
+		incoming.QuantityUnit,
+		incoming.LotNo,
+		incoming.ManufacturedAt,
+		incoming.ExpiresAt,
 		incoming.UpdatedBy,
 		id,
 	)
@@ -671,32 +1247,111 @@ func (s *inventoryService) UpdateIncoming(id int, incoming *models.InventoryInco
 		return nil, err
 	}
 
-	slog.Info("Successfully updated incoming", "incoming", incoming)
+	// serials left out are kept but must still match the quantity
+	incoming.ID = id
+	if err := saveIncomingSerials(tx, incoming); err != nil {
+		return nil, err
+	}
 
-	return incoming, nil
-}
+	if err := s.restoreDepletedIncoming(tx, id, incoming.UpdatedBy, "incoming updated"); err != nil {
+		return nil, err
+	}
+	err = tx.QueryRowContext(
+		context.Background(),
+		`SELECT status FROM inventory_incomings WHERE id = $1`,
+		id,
+	).Scan(&incoming.Status)
+	if err != nil {
+		slog.Error("Error querying incoming status", "error", err, "id", id)
+		return nil, err
+	}
 
-func (s *inventoryService) DeleteIncoming(id int) error {
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing incoming", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully updated incoming", "incoming", incoming)
+
+	NotifyStockChanged(incoming.ProductID)
+
+	return incoming, nil
+}
+
+// DeleteIncoming removes an incoming nothing was moved against yet. The
+// incoming is locked first, so no movement can be added while it is checked.
+func (s *inventoryService) DeleteIncoming(id int) error {
 	queryStr := `
 		DELETE FROM
 			inventory_incomings
 		WHERE
 			id = $1
+		RETURNING
+			product_id
 	`
 
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var dependants string
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			CASE
+				WHEN EXISTS (SELECT 1 FROM inventory_outgoings WHERE incoming_id = i.id) THEN 'outgoings'
+				WHEN EXISTS (SELECT 1 FROM inventory_transfers WHERE i.id IN (from_incoming_id, to_incoming_id))
+					OR EXISTS (SELECT 1 FROM inventory_incomings WHERE source_incoming_id = i.id) THEN 'transfers'
+				WHEN EXISTS (SELECT 1 FROM inventory_adjustments WHERE incoming_id = i.id) THEN 'adjustments'
+				WHEN EXISTS (SELECT 1 FROM cycle_count_lines WHERE incoming_id = i.id) THEN 'cycle counts'
+				WHEN EXISTS (SELECT 1 FROM inventory_returns WHERE incoming_id = i.id) THEN 'returns'
+				WHEN EXISTS (SELECT 1 FROM inventory_reservations WHERE incoming_id = i.id) THEN 'reservations'
+				ELSE ''
+			END
+		FROM
+			inventory_incomings i
+		WHERE
+			i.id = $1
+		FOR UPDATE
+		`,
+		id,
+	).Scan(&dependants)
+	if err == sql.ErrNoRows {
+		return err
+	}
+	if err != nil {
+		slog.Error("Error checking incoming movements", "error", err, "incoming", id)
+		return err
+	}
+	if dependants != "" {
+		return &ValidationError{Message: "incoming has " + dependants + " against it"}
+	}
+
 	// database execute with commit, transaction, context and commit
-	_, err := s.db.ExecContext(
+	var productID int
+	err = tx.QueryRowContext(
 		context.Background(),
 		queryStr,
 		id,
-	)
+	).Scan(&productID)
 	if err != nil {
 		slog.Error("Error deleting incoming", "error", err)
 		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing incoming", "error", err)
+		return err
+	}
+
 	slog.Info("Successfully deleted incoming", "incoming", id)
 
+	NotifyStockChanged(productID)
+
 	return nil
 }
 
@@ -709,27 +1364,33 @@ func (s *inventoryService) GetOutgoings() ([]*models.InventoryOutgoing, error) {
 			o.product_id,
 			o.status,
 			o.quantity,
+			o.quantity_unit,
 			o.standard_quantity,
+			o.unit_cost,
 			o.cost,
 			o.ref_no,
 			o.ref_doc,
 			o.remarks,
+			COALESCE(o.job_order_id, 0),
+			COALESCE(o.reservation_id, 0),
+			COALESCE(o.delivery_order_id, 0),
+			o.expiry_override_by,
 			o.created_by,
 			o.created_at,
 			o.updated_by,
 			o.updated_at,
 
-            p.code AS product_code,
-            p.name AS product_name,
-            p.standard_unit AS standard_unit
+			p.code AS product_code,
+			p.name AS product_name,
+			p.standard_unit AS standard_unit
 		FROM
 			inventory_outgoings o
-        LEFT JOIN
-            inventory_products p
-        ON
-            o.product_id = p.id
-        ORDER BY
-            o.id DESC
+		LEFT JOIN
+			inventory_products p
+		ON
+			o.product_id = p.id
+		ORDER BY
+			o.id DESC
 	`
 
 	// execute query with context, transaction, and arguments
@@ -752,11 +1413,17 @@ func (s *inventoryService) GetOutgoings() ([]*models.InventoryOutgoing, error) {
 			&outgoing.ProductID,
 			&outgoing.Status,
 			&outgoing.Quantity,
+			&outgoing.QuantityUnit,
 			&outgoing.StandardQuantity,
+			&outgoing.UnitCost,
 			&outgoing.Cost,
 			&outgoing.RefNo,
 			&outgoing.RefDoc,
 			&outgoing.Remarks,
+			&outgoing.JobOrderID,
+			&outgoing.ReservationID,
+			&outgoing.DeliveryOrderID,
+			&outgoing.ExpiryOverrideBy,
 			&outgoing.CreatedBy,
 			&outgoing.CreatedAt,
 			&outgoing.UpdatedBy,
@@ -793,11 +1460,17 @@ func (s *inventoryService) GetOutgoing(id int) (*models.InventoryOutgoing, error
 			product_id,
 			status,
 			quantity,
+			quantity_unit,
 			standard_quantity,
+			unit_cost,
 			cost,
 			ref_no,
 			ref_doc,
 			remarks,
+			COALESCE(job_order_id, 0),
+			COALESCE(reservation_id, 0),
+			COALESCE(delivery_order_id, 0),
+			expiry_override_by,
 			created_by,
 			created_at,
 			updated_by,
@@ -818,11 +1491,17 @@ func (s *inventoryService) GetOutgoing(id int) (*models.InventoryOutgoing, error
 		&outgoing.ProductID,
 		&outgoing.Status,
 		&outgoing.Quantity,
+		&outgoing.QuantityUnit,
 		&outgoing.StandardQuantity,
+		&outgoing.UnitCost,
 		&outgoing.Cost,
 		&outgoing.RefNo,
 		&outgoing.RefDoc,
 		&outgoing.Remarks,
+		&outgoing.JobOrderID,
+		&outgoing.ReservationID,
+		&outgoing.DeliveryOrderID,
+		&outgoing.ExpiryOverrideBy,
 		&outgoing.CreatedBy,
 		&outgoing.CreatedAt,
 		&outgoing.UpdatedBy,
@@ -833,12 +1512,82 @@ func (s *inventoryService) GetOutgoing(id int) (*models.InventoryOutgoing, error
 		return nil, err
 	}
 
+	outgoing.SerialNos, err = s.getOutgoingSerials(id)
+	if err != nil {
+		return nil, err
+	}
+
 	slog.Info("Successfully queried outgoing", "outgoing", outgoing)
 
 	return outgoing, nil
 }
 
 func (s *inventoryService) CreateOutgoing(outgoing *models.InventoryOutgoing) (*models.InventoryOutgoing, error) {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	outgoing.ProductID, err = incomingProduct(tx, outgoing.IncomingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyQuantityUnit(tx, outgoing.ProductID, &outgoing.QuantityUnit, &outgoing.Quantity, &outgoing.StandardQuantity); err != nil {
+		return nil, err
+	}
+
+	if _, err := checkReservation(tx, outgoing, 0, true); err != nil {
+		return nil, err
+	}
+
+	// lock the incoming and make sure it still has enough balance
+	if err := s.checkIncomingBalance(tx, outgoing.IncomingID, 0, outgoing.StandardQuantity); err != nil {
+		return nil, err
+	}
+
+	if err := checkIncomingIssuable(tx, outgoing.IncomingID); err != nil {
+		return nil, err
+	}
+
+	if err := checkIncomingExpiry(tx, outgoing); err != nil {
+		return nil, err
+	}
+
+	// what other reservations hold is not there to take
+	if err := checkAvailableToPromise(tx, outgoing.ProductID, outgoing.IncomingID, outgoing.ReservationID, 0, outgoing.StandardQuantity); err != nil {
+		return nil, err
+	}
+
+	outgoing.UnitCost, outgoing.Cost, err = s.outgoingCost(tx, outgoing.IncomingID, 0, outgoing.StandardQuantity, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.insertOutgoing(tx, outgoing); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing outgoing", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted outgoing", "outgoing", outgoing)
+
+	NotifyStockChanged(outgoing.ProductID)
+
+	return outgoing, nil
+}
+
+// insertOutgoing writes outgoing inside tx and sets its generated id.
+func (s *inventoryService) insertOutgoing(tx *sql.Tx, outgoing *models.InventoryOutgoing) error {
+	if err := checkJobOrderOpen(tx, outgoing.JobOrderID); err != nil {
+		return err
+	}
+
 	queryStr := `
 		INSERT INTO inventory_outgoings (
 			incoming_id,
@@ -846,22 +1595,27 @@ func (s *inventoryService) CreateOutgoing(outgoing *models.InventoryOutgoing) (*
 			status,
 			quantity,
 			standard_quantity,
+			unit_cost,
 			cost,
 			ref_no,
 			ref_doc,
 			remarks,
+			job_order_id,
+			quantity_unit,
+			reservation_id,
+			expiry_override_by,
 			created_by,
 			created_at,
 			updated_by,
 			updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9,
-			$10, NOW(), $11, NOW()
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+			NULLIF($11, 0), $12, NULLIF($13, 0), $14, $15, NOW(), $16, NOW()
 		)
+		RETURNING id
 	`
 
-	// database execute with commit, transaction, context and commit
-	_, err := s.db.ExecContext(
+	err := tx.QueryRowContext(
 		context.Background(),
 		queryStr,
 		outgoing.IncomingID,
@@ -869,21 +1623,24 @@ func (s *inventoryService) CreateOutgoing(outgoing *models.InventoryOutgoing) (*
 		outgoing.Status,
 		outgoing.Quantity,
 		outgoing.StandardQuantity,
+		outgoing.UnitCost,
 		outgoing.Cost,
 		outgoing.RefNo,
 		outgoing.RefDoc,
 		outgoing.Remarks,
+		outgoing.JobOrderID,
+		outgoing.QuantityUnit,
+		outgoing.ReservationID,
+		outgoing.ExpiryOverrideBy,
 		outgoing.CreatedBy,
 		outgoing.UpdatedBy,
-	)
+	).Scan(&outgoing.ID)
 	if err != nil {
 		slog.Error("Error inserting outgoing", "error", err)
-		return nil, err
+		return err
 	}
 
-	slog.Info("Successfully inserted outgoing", "outgoing", outgoing)
-
-	return outgoing, nil
+	return issueSerials(tx, outgoing)
 }
 
 func (s *inventoryService) UpdateOutgoing(id int, outgoing *models.InventoryOutgoing) (*models.InventoryOutgoing, error) {
@@ -896,18 +1653,85 @@ func (s *inventoryService) UpdateOutgoing(id int, outgoing *models.InventoryOutg
 			status = $3,
 			quantity = $4,
 			standard_quantity = $5,
-			cost = $6,
-			ref_no = $7,
-			ref_doc = $8,
-			remarks = $9,
-			updated_by = $10,
+			unit_cost = $6,
+			cost = $7,
+			ref_no = $8,
+			ref_doc = $9,
+			remarks = $10,
+			job_order_id = NULLIF($11, 0),
+			quantity_unit = $12,
+			reservation_id = NULLIF($13, 0),
+			expiry_override_by = $14,
+			updated_by = $15,
 			updated_at = NOW()
 		WHERE
-			id = $11
+			id = $16
 	`
 
-	// database execute with commit, transaction, context and commit
-	_, err := s.db.ExecContext(
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	outgoing.ProductID, err = incomingProduct(tx, outgoing.IncomingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyQuantityUnit(tx, outgoing.ProductID, &outgoing.QuantityUnit, &outgoing.Quantity, &outgoing.StandardQuantity); err != nil {
+		return nil, err
+	}
+
+	var previousReservationID, previousIncomingID, previousProductID int
+	var previousQuantity float64
+	err = tx.QueryRowContext(
+		context.Background(),
+		`SELECT COALESCE(reservation_id, 0), incoming_id, product_id, standard_quantity FROM inventory_outgoings WHERE id = $1`,
+		id,
+	).Scan(&previousReservationID, &previousIncomingID, &previousProductID, &previousQuantity)
+	if err != nil {
+		slog.Error("Error querying outgoing reservation", "error", err, "id", id)
+		return nil, err
+	}
+
+	if _, err := checkReservation(tx, outgoing, previousReservationID, true); err != nil {
+		return nil, err
+	}
+
+	// the outgoing being edited must not count against its own balance
+	if err := s.checkIncomingBalance(tx, outgoing.IncomingID, id, outgoing.StandardQuantity); err != nil {
+		return nil, err
+	}
+
+	// what was already issued may still be corrected once the incoming is
+	// depleted, issuing more needs available stock
+	if outgoing.IncomingID != previousIncomingID || outgoing.StandardQuantity-previousQuantity > stockTolerance {
+		if err := checkIncomingIssuable(tx, outgoing.IncomingID); err != nil {
+			return nil, err
+		}
+	}
+
+	// the override is sent back with every edit of an outgoing on an expired lot
+	if err := checkIncomingExpiry(tx, outgoing); err != nil {
+		return nil, err
+	}
+
+	if err := checkAvailableToPromise(tx, outgoing.ProductID, outgoing.IncomingID, outgoing.ReservationID, id, outgoing.StandardQuantity); err != nil {
+		return nil, err
+	}
+
+	if err := checkJobOrderOpen(tx, outgoing.JobOrderID); err != nil {
+		return nil, err
+	}
+
+	outgoing.UnitCost, outgoing.Cost, err = s.outgoingCost(tx, outgoing.IncomingID, id, outgoing.StandardQuantity, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
 		context.Background(),
 		queryStr,
 		outgoing.IncomingID,
@@ -915,10 +1739,15 @@ func (s *inventoryService) UpdateOutgoing(id int, outgoing *models.InventoryOutg
 		outgoing.Status,
 		outgoing.Quantity,
 		outgoing.StandardQuantity,
+		outgoing.UnitCost,
 		outgoing.Cost,
 		outgoing.RefNo,
 		outgoing.RefDoc,
 		outgoing.Remarks,
+		outgoing.JobOrderID,
+		outgoing.QuantityUnit,
+		outgoing.ReservationID,
+		outgoing.ExpiryOverrideBy,
 		outgoing.UpdatedBy,
 		id,
 	)
@@ -927,31 +1756,718 @@ func (s *inventoryService) UpdateOutgoing(id int, outgoing *models.InventoryOutg
 		return nil, err
 	}
 
+	outgoing.ID = id
+	if err := issueSerials(tx, outgoing); err != nil {
+		return nil, err
+	}
+
+	// what the edit gave back may bring a depleted incoming back into stock
+	if err := s.restoreDepletedIncoming(tx, previousIncomingID, outgoing.UpdatedBy, "outgoing updated"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing outgoing", "error", err)
+		return nil, err
+	}
+
 	slog.Info("Successfully updated outgoing", "outgoing", outgoing)
 
+	NotifyStockChanged(outgoing.ProductID)
+	if previousProductID != outgoing.ProductID {
+		NotifyStockChanged(previousProductID)
+	}
+
 	return outgoing, nil
 }
 
+// DeleteOutgoing removes an outgoing nothing was returned against. The
+// outgoing is locked first, so no return can be added while it is checked.
 func (s *inventoryService) DeleteOutgoing(id int) error {
 	queryStr := `
 		DELETE FROM
 			inventory_outgoings
 		WHERE
 			id = $1
+		RETURNING
+			product_id,
+			incoming_id
 	`
 
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var returns int
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			(SELECT COUNT(*) FROM inventory_returns WHERE outgoing_id = o.id)
+		FROM
+			inventory_outgoings o
+		WHERE
+			o.id = $1
+		FOR UPDATE
+		`,
+		id,
+	).Scan(&returns)
+	if err == sql.ErrNoRows {
+		return err
+	}
+	if err != nil {
+		slog.Error("Error counting returns", "error", err, "outgoing", id)
+		return err
+	}
+	if returns > 0 {
+		return &ValidationError{Message: "outgoing has returns against it"}
+	}
+
 	// database execute with commit, transaction, context and commit
-	_, err := s.db.ExecContext(
+	var productID, incomingID int
+	err = tx.QueryRowContext(
 		context.Background(),
 		queryStr,
 		id,
-	)
+	).Scan(&productID, &incomingID)
 	if err != nil {
 		slog.Error("Error deleting outgoing", "error", err)
 		return err
 	}
 
+	// the stock goes back to the incoming, which may have been depleted
+	if err := s.restoreDepletedIncoming(tx, incomingID, "", "outgoing deleted"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing outgoing", "error", err)
+		return err
+	}
+
 	slog.Info("Successfully deleted outgoing", "outgoing", id)
 
+	NotifyStockChanged(productID)
+
+	return nil
+}
+
+// Allocation
+func (s *inventoryService) AllocateOutgoings(request *models.InventoryAllocationRequest) (*models.InventoryAllocation, error) {
+	rule := request.Rule
+	if rule == "" {
+		rule = config.Cfg.AllocationRule
+	}
+	if rule == "" {
+		rule = models.AllocationRuleFIFO
+	}
+
+	var orderBy string
+	switch rule {
+	case models.AllocationRuleFIFO:
+		orderBy = "i.received_at ASC, i.created_at ASC, i.id ASC"
+	case models.AllocationRuleLIFO:
+		orderBy = "i.received_at DESC, i.created_at DESC, i.id DESC"
+	case models.AllocationRuleFEFO:
+		orderBy = "i.expires_at ASC NULLS LAST, i.received_at ASC, i.created_at ASC, i.id ASC"
+	default:
+		return nil, &ValidationError{Message: "unknown allocation rule: " + rule}
+	}
+
+	if request.StandardQuantity <= 0 {
+		return nil, &ValidationError{Message: "standard quantity must be greater than zero"}
+	}
+
+	request.ExpiryOverrideBy = strings.TrimSpace(request.ExpiryOverrideBy)
+
+	// lock every available incoming of the product so two allocations cannot
+	// split the same balance between them, expired lots are only drawn on
+	// when someone overrides. A dry run only reads, a preview must not hold
+	// up real issues
+	lock := "FOR UPDATE OF i"
+	if request.DryRun {
+		lock = ""
+	}
+	queryStr := `
+		SELECT
+			i.id,
+			i.ref_no,
+			i.store_location,
+			i.store_country,
+			i.received_at,
+			i.lot_no,
+			i.expires_at,
+			COALESCE(i.expires_at < CURRENT_DATE, false) AS expired,
+			i.quantity,
+			i.quantity_unit,
+			i.standard_quantity,
+			COALESCE(b.balance_std_qty, 0) AS balance_std_qty,
+			COALESCE(rv.reserved_std_qty, 0) AS reserved_std_qty
+		FROM
+			inventory_incomings i
+		LEFT JOIN (
+			SELECT
+				incoming_id,
+				SUM(standard_quantity) AS balance_std_qty
+			FROM
+				inventory_movements
+			GROUP BY
+				incoming_id
+			) b
+		ON
+			i.id = b.incoming_id
+		LEFT JOIN (
+			SELECT
+				incoming_id,
+				SUM(reserved_quantity) AS reserved_std_qty
+			FROM
+				inventory_reservation_balances
+			WHERE
+				incoming_id IS NOT NULL
+			AND
+				reservation_id <> $2
+			GROUP BY
+				incoming_id
+			) rv
+		ON
+			i.id = rv.incoming_id
+		WHERE
+			i.product_id = $1
+		AND
+			($3 = 0 OR i.id = $3)
+		AND
+			i.status = $4
+		AND
+			($5 OR i.expires_at IS NULL OR i.expires_at >= CURRENT_DATE)
+		ORDER BY
+			` + orderBy + `
+		` + lock
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// an allocation against a reservation made for one incoming draws from
+	// that incoming only
+	reservedIncomingID := 0
+	probe := &models.InventoryOutgoing{
+		ProductID:     request.ProductID,
+		ReservationID: request.ReservationID,
+		JobOrderID:    request.JobOrderID,
+	}
+	reservation, err := checkReservation(tx, probe, 0, !request.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	if reservation != nil {
+		reservedIncomingID = reservation.IncomingID
+	}
+	request.JobOrderID = probe.JobOrderID
+
+	rows, err := tx.QueryContext(context.Background(), queryStr, request.ProductID, request.ReservationID, reservedIncomingID, models.IncomingStatusAvailable, request.ExpiryOverrideBy != "")
+	if err != nil {
+		slog.Error("Error querying incomings for allocation", "error", err)
+		return nil, err
+	}
+
+	allocation := &models.InventoryAllocation{
+		ProductID:        request.ProductID,
+		Rule:             rule,
+		DryRun:           request.DryRun,
+		StandardQuantity: request.StandardQuantity,
+		Lines:            []*models.InventoryAllocationLine{},
+	}
+
+	remaining := request.StandardQuantity
+	available := 0.0
+	expired := make(map[int]bool)
+	for rows.Next() {
+		line := new(models.InventoryAllocationLine)
+		var quantity, standardQuantity, reserved float64
+		var expiresAt sql.NullString
+		var isExpired bool
+		err := rows.Scan(
+			&line.IncomingID,
+			&line.IncomingRefNo,
+			&line.StoreLocation,
+			&line.StoreCountry,
+			&line.ReceivedAt,
+			&line.LotNo,
+			&expiresAt,
+			&isExpired,
+			&quantity,
+			&line.QuantityUnit,
+			&standardQuantity,
+			&line.BalanceStdQty,
+			&reserved,
+		)
+		if err != nil {
+			rows.Close()
+			slog.Error("Error scanning incoming for allocation", "error", err)
+			return nil, err
+		}
+		line.ExpiresAt = expiresAt.String
+		expired[line.IncomingID] = isExpired
+
+		// stock other reservations hold on the incoming is not there to take
+		free := line.BalanceStdQty - reserved
+		if free <= stockTolerance {
+			continue
+		}
+		available += free
+
+		if remaining <= stockTolerance {
+			continue
+		}
+
+		line.StandardQuantity = free
+		if remaining < line.StandardQuantity {
+			line.StandardQuantity = remaining
+		}
+		remaining -= line.StandardQuantity
+
+		// carry the incoming's own quantity to standard quantity ratio
+		line.Quantity = line.StandardQuantity
+		if standardQuantity != 0 {
+			line.Quantity = line.StandardQuantity * quantity / standardQuantity
+		}
+
+		allocation.Lines = append(allocation.Lines, line)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating over incomings for allocation", "error", err)
+		return nil, err
+	}
+
+	// earlier lines of the plan are not written yet, so pass them on as
+	// pending for FIFO layers to skip
+	pending := 0.0
+	for _, line := range allocation.Lines {
+		line.UnitCost, line.Cost, err = s.outgoingCost(tx, line.IncomingID, 0, line.StandardQuantity, pending)
+		if err != nil {
+			return nil, err
+		}
+		pending += line.StandardQuantity
+	}
+
+	// reservations on the product as a whole are tied to no incoming, they
+	// only show in the figure for the product
+	toPromise := availableToPromise
+	if request.DryRun {
+		toPromise = promisable
+	}
+	productPromisable, _, err := toPromise(tx, request.ProductID, 0, request.ReservationID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if productPromisable < available {
+		available = productPromisable
+	}
+
+	if request.StandardQuantity-available > stockTolerance {
+		return nil, &InsufficientStockError{
+			ProductID: request.ProductID,
+			Requested: request.StandardQuantity,
+			Available: available,
+		}
+	}
+
+	if request.DryRun {
+		slog.Info("Previewed allocation", "product", request.ProductID, "lines", len(allocation.Lines))
+		return allocation, nil
+	}
+
+	for _, line := range allocation.Lines {
+		outgoing := &models.InventoryOutgoing{
+			IncomingID:       line.IncomingID,
+			ProductID:        request.ProductID,
+			Status:           request.Status,
+			Quantity:         line.Quantity,
+			QuantityUnit:     line.QuantityUnit,
+			StandardQuantity: line.StandardQuantity,
+			UnitCost:         line.UnitCost,
+			Cost:             line.Cost,
+			RefNo:            request.RefNo,
+			RefDoc:           request.RefDoc,
+			Remarks:          request.Remarks,
+			JobOrderID:       request.JobOrderID,
+			ReservationID:    request.ReservationID,
+			CreatedBy:        request.CreatedBy,
+			UpdatedBy:        request.CreatedBy,
+		}
+		// only outgoings that really draw on an expired lot carry the override
+		if expired[line.IncomingID] {
+			outgoing.ExpiryOverrideBy = request.ExpiryOverrideBy
+		}
+		if err := s.insertOutgoing(tx, outgoing); err != nil {
+			return nil, err
+		}
+		line.OutgoingID = outgoing.ID
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing allocation", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully allocated outgoings", "product", request.ProductID, "lines", len(allocation.Lines))
+
+	NotifyStockChanged(request.ProductID)
+
+	return allocation, nil
+}
+
+// incomingProduct returns the product of an incoming, which is the product of
+// whatever is issued from it whatever the client says.
+func incomingProduct(tx *sql.Tx, incomingID int) (int, error) {
+	var productID int
+	err := tx.QueryRowContext(
+		context.Background(),
+		`SELECT product_id FROM inventory_incomings WHERE id = $1`,
+		incomingID,
+	).Scan(&productID)
+	if err == sql.ErrNoRows {
+		return 0, &ValidationError{Message: "incoming does not exist"}
+	}
+	if err != nil {
+		slog.Error("Error querying incoming product", "error", err, "incoming", incomingID)
+		return 0, err
+	}
+
+	return productID, nil
+}
+
+// checkIncomingBalance locks the incoming row for the rest of the transaction
+// and returns an InsufficientStockError when requested is more than its
+// remaining standard quantity. excludeOutgoingID is left out of the balance
+// so an outgoing can be edited without counting itself twice.
+func (s *inventoryService) checkIncomingBalance(tx *sql.Tx, incomingID, excludeOutgoingID int, requested float64) error {
+	_, err := tx.ExecContext(
+		context.Background(),
+		`SELECT id FROM inventory_incomings WHERE id = $1 FOR UPDATE`,
+		incomingID,
+	)
+	if err != nil {
+		slog.Error("Error locking incoming", "error", err, "incoming", incomingID)
+		return err
+	}
+
+	var available float64
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			COALESCE(SUM(standard_quantity), 0)
+		FROM
+			inventory_movements
+		WHERE
+			incoming_id = $1
+		AND NOT
+			(movement_type = 'outgoing' AND document_id = $2)
+		`,
+		incomingID,
+		excludeOutgoingID,
+	).Scan(&available)
+	if err != nil {
+		slog.Error("Error summing incoming balance", "error", err, "incoming", incomingID)
+		return err
+	}
+
+	if requested-available > stockTolerance {
+		slog.Error("Insufficient stock", "incoming", incomingID, "requested", requested, "available", available)
+		return &InsufficientStockError{
+			IncomingID: incomingID,
+			Requested:  requested,
+			Available:  available,
+		}
+	}
+
+	return nil
+}
+
+// resolveLocation fills the store location and country text of an incoming
+// from its location, so reports grouping on the text stay consistent.
+func (s *inventoryService) resolveLocation(incoming *models.InventoryIncoming) error {
+	incoming.StoreLocation = strings.TrimSpace(incoming.StoreLocation)
+	incoming.StoreCountry = strings.TrimSpace(incoming.StoreCountry)
+
+	if incoming.LocationID == 0 {
+		return nil
+	}
+
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			l.name,
+			w.country
+		FROM
+			locations l
+		JOIN
+			warehouses w
+		ON
+			l.warehouse_id = w.id
+		WHERE
+			l.id = $1
+		`,
+		incoming.LocationID,
+	).Scan(&incoming.StoreLocation, &incoming.StoreCountry)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Message: "location does not exist"}
+	}
+	if err != nil {
+		slog.Error("Error querying location", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// outgoingCost works out the unit and extended cost of issuing
+// standardQuantity from an incoming, using the costing method of the
+// incoming's product. excludeOutgoingID is left out of the product's issue
+// history, and pending is standard quantity already planned in the same
+// transaction but not yet written.
+func (s *inventoryService) outgoingCost(tx *sql.Tx, incomingID, excludeOutgoingID int, standardQuantity, pending float64) (float64, float64, error) {
+	var productID int
+	var method string
+	var incomingCost float64
+	err := tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			i.product_id,
+			p.costing_method,
+			i.cost
+		FROM
+			inventory_incomings i
+		JOIN
+			inventory_products p
+		ON
+			i.product_id = p.id
+		WHERE
+			i.id = $1
+		`,
+		incomingID,
+	).Scan(&productID, &method, &incomingCost)
+	if err != nil {
+		slog.Error("Error querying costing method", "error", err, "incoming", incomingID)
+		return 0, 0, err
+	}
+
+	unitCost := incomingCost
+	switch method {
+	case models.CostingMethodWeightedAverage:
+		unitCost, err = s.weightedAverageCost(tx, productID, excludeOutgoingID)
+		if err != nil {
+			return 0, 0, err
+		}
+		if unitCost == 0 {
+			unitCost = incomingCost
+		}
+	case models.CostingMethodFIFO:
+		cost, err := s.fifoCost(tx, productID, excludeOutgoingID, standardQuantity, pending)
+		if err != nil {
+			return 0, 0, err
+		}
+		if standardQuantity != 0 {
+			unitCost = cost / standardQuantity
+		}
+		return unitCost, cost, nil
+	}
+
+	return unitCost, unitCost * standardQuantity, nil
+}
+
+// weightedAverageCost returns the moving average cost of the stock of a
+// product still on hand: everything received at cost less everything issued
+// at the cost it was issued at.
+func (s *inventoryService) weightedAverageCost(tx *sql.Tx, productID, excludeOutgoingID int) (float64, error) {
+	queryStr := `
+		SELECT
+			COALESCE(SUM(value), 0),
+			COALESCE(SUM(standard_quantity), 0)
+		FROM
+			inventory_movements
+		WHERE
+			product_id = $1
+		AND NOT
+			(movement_type = 'outgoing' AND document_id = $2)
+	`
+
+	var value, quantity float64
+	err := tx.QueryRowContext(context.Background(), queryStr, productID, excludeOutgoingID).Scan(&value, &quantity)
+	if err != nil {
+		slog.Error("Error querying weighted average cost", "error", err, "product", productID)
+		return 0, err
+	}
+
+	if quantity <= stockTolerance {
+		return 0, nil
+	}
+
+	return value / quantity, nil
+}
+
+// fifoCost returns the extended cost of taking standardQuantity from the
+// oldest cost layers of a product that earlier issues have not used up.
+// Receipts are layers at their cost, and so is stock put back by a customer
+// return or found by an adjustment, at the cost it came back at. Issues are
+// outgoings, supplier returns and adjustments that take stock away. An
+// outgoing being edited is priced at its place in the sequence, after the
+// issues made before it only.
+func (s *inventoryService) fifoCost(tx *sql.Tx, productID, excludeOutgoingID int, standardQuantity, pending float64) (float64, error) {
+	var issuedAt sql.NullTime
+	if excludeOutgoingID != 0 {
+		err := tx.QueryRowContext(
+			context.Background(),
+			`SELECT created_at FROM inventory_outgoings WHERE id = $1`,
+			excludeOutgoingID,
+		).Scan(&issuedAt)
+		if err != nil && err != sql.ErrNoRows {
+			slog.Error("Error querying outgoing for FIFO", "error", err, "outgoing", excludeOutgoingID)
+			return 0, err
+		}
+	}
+
+	var consumed float64
+	err := tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			COALESCE(-SUM(standard_quantity), 0)
+		FROM
+			inventory_movements
+		WHERE
+			product_id = $1
+		AND (
+			movement_type IN ('outgoing', 'supplier_return')
+			OR (movement_type = 'adjustment' AND standard_quantity < 0)
+		)
+		AND NOT
+			(movement_type = 'outgoing' AND document_id = $2)
+		AND (
+			$3::TIMESTAMP IS NULL
+			OR moved_at < $3
+			OR (movement_type = 'outgoing' AND moved_at = $3 AND document_id < $2)
+		)
+		`,
+		productID,
+		excludeOutgoingID,
+		issuedAt,
+	).Scan(&consumed)
+	if err != nil {
+		slog.Error("Error summing issues for FIFO", "error", err, "product", productID)
+		return 0, err
+	}
+	consumed += pending
+
+	rows, err := tx.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			standard_quantity,
+			unit_cost
+		FROM (
+			SELECT
+				i.standard_quantity,
+				i.cost AS unit_cost,
+				i.received_at AS layer_date,
+				i.created_at AS layer_at,
+				i.id
+			FROM
+				inventory_incomings i
+			WHERE
+				i.product_id = $1
+			AND
+				i.source_incoming_id IS NULL
+			UNION ALL
+			SELECT
+				m.standard_quantity,
+				m.unit_cost,
+				m.moved_at::DATE,
+				m.moved_at,
+				m.document_id
+			FROM
+				inventory_movements m
+			WHERE
+				m.product_id = $1
+			AND
+				m.movement_type IN ('adjustment', 'customer_return')
+			AND
+				m.standard_quantity > 0
+		) layers
+		ORDER BY
+			layer_date ASC,
+			layer_at ASC,
+			id ASC
+		`,
+		productID,
+	)
+	if err != nil {
+		slog.Error("Error querying FIFO layers", "error", err, "product", productID)
+		return 0, err
+	}
+	defer rows.Close()
+
+	remaining := standardQuantity
+	cost := 0.0
+	lastCost := 0.0
+	for rows.Next() && remaining > stockTolerance {
+		var layerQuantity, layerCost float64
+		if err := rows.Scan(&layerQuantity, &layerCost); err != nil {
+			slog.Error("Error scanning FIFO layer", "error", err)
+			return 0, err
+		}
+		lastCost = layerCost
+
+		// skip what earlier issues already took from this layer
+		if consumed >= layerQuantity {
+			consumed -= layerQuantity
+			continue
+		}
+		layerQuantity -= consumed
+		consumed = 0
+
+		take := layerQuantity
+		if remaining < take {
+			take = remaining
+		}
+		cost += take * layerCost
+		remaining -= take
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating over FIFO layers", "error", err)
+		return 0, err
+	}
+
+	// layers ran out, price the rest at the latest known cost
+	if remaining > stockTolerance {
+		cost += remaining * lastCost
+	}
+
+	return cost, nil
+}
+
+// validateCostingMethod defaults an empty costing method to specific
+// identification and rejects anything it does not know.
+func validateCostingMethod(product *models.InventoryProduct) error {
+	switch product.CostingMethod {
+	case "":
+		product.CostingMethod = models.CostingMethodSpecific
+	case models.CostingMethodFIFO, models.CostingMethodWeightedAverage, models.CostingMethodSpecific:
+	default:
+		return &ValidationError{Message: "unknown costing method: " + product.CostingMethod}
+	}
+
 	return nil
 }
@@ -0,0 +1,992 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/config"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+const adjustmentQuery = `
+	SELECT
+		a.id,
+		a.product_id,
+		a.incoming_id,
+		a.reason_code,
+		COALESCE(a.cycle_count_id, 0),
+		a.quantity,
+		a.standard_quantity,
+		a.unit_cost,
+		a.status,
+		a.remarks,
+		a.created_by,
+		a.created_at,
+		a.approved_by,
+		a.approved_at,
+		p.code AS product_code,
+		p.name AS product_name,
+		p.standard_unit,
+		i.ref_no,
+		i.store_location
+	FROM
+		inventory_adjustments a
+	JOIN
+		inventory_products p
+	ON
+		a.product_id = p.id
+	JOIN
+		inventory_incomings i
+	ON
+		a.incoming_id = i.id
+	`
+
+func scanAdjustment(row interface{ Scan(...any) error }, adjustment *models.InventoryAdjustment) error {
+	var approvedAt sql.NullString
+	err := row.Scan(
+		&adjustment.ID,
+		&adjustment.ProductID,
+		&adjustment.IncomingID,
+		&adjustment.ReasonCode,
+		&adjustment.CycleCountID,
+		&adjustment.Quantity,
+		&adjustment.StandardQuantity,
+		&adjustment.UnitCost,
+		&adjustment.Status,
+		&adjustment.Remarks,
+		&adjustment.CreatedBy,
+		&adjustment.CreatedAt,
+		&adjustment.ApprovedBy,
+		&approvedAt,
+		&adjustment.ProductCode,
+		&adjustment.ProductName,
+		&adjustment.StandardUnit,
+		&adjustment.RefNo,
+		&adjustment.StoreLocation,
+	)
+	if err != nil {
+		return err
+	}
+
+	adjustment.ApprovedAt = approvedAt.String
+	adjustment.Value = adjustment.StandardQuantity * adjustment.UnitCost
+
+	return nil
+}
+
+// Adjustment
+func (s *inventoryService) GetAdjustmentReasons() ([]*models.InventoryAdjustmentReason, error) {
+	// execute query with context
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			code,
+			name,
+			is_exist
+		FROM
+			inventory_adjustment_reasons
+		WHERE
+			is_exist = true
+		ORDER BY
+			name
+		`,
+	)
+	if err != nil {
+		slog.Error("Error querying adjustment reasons", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reasons []*models.InventoryAdjustmentReason
+	for rows.Next() {
+		reason := new(models.InventoryAdjustmentReason)
+		if err := rows.Scan(&reason.Code, &reason.Name, &reason.IsExist); err != nil {
+			slog.Error("Error scanning adjustment reason", "error", err)
+			return nil, err
+		}
+		reasons = append(reasons, reason)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating adjustment reasons", "error", err)
+		return nil, err
+	}
+
+	return reasons, nil
+}
+
+// GetAdjustments lists adjustments, newest first, optionally only those in
+// the given status.
+func (s *inventoryService) GetAdjustments(status string) ([]*models.InventoryAdjustment, error) {
+	// execute query with context
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		adjustmentQuery+`
+		WHERE
+			$1 = '' OR a.status = $1
+		ORDER BY
+			a.created_at DESC,
+			a.id DESC
+		`,
+		status,
+	)
+	if err != nil {
+		slog.Error("Error querying adjustments", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var adjustments []*models.InventoryAdjustment
+	for rows.Next() {
+		adjustment := new(models.InventoryAdjustment)
+		if err := scanAdjustment(rows, adjustment); err != nil {
+			slog.Error("Error scanning adjustment", "error", err)
+			return nil, err
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating adjustments", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried adjustments", "count", len(adjustments))
+
+	return adjustments, nil
+}
+
+func (s *inventoryService) GetAdjustment(id int) (*models.InventoryAdjustment, error) {
+	adjustment := new(models.InventoryAdjustment)
+
+	// execute query with context
+	err := scanAdjustment(s.db.QueryRowContext(
+		context.Background(),
+		adjustmentQuery+`
+		WHERE
+			a.id = $1
+		`,
+		id,
+	), adjustment)
+	if err != nil {
+		slog.Error("Error querying adjustment", "error", err, "id", id)
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+func (s *inventoryService) CreateAdjustment(adjustment *models.InventoryAdjustment) (*models.InventoryAdjustment, error) {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.insertAdjustment(tx, adjustment); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing adjustment", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully created adjustment", "id", adjustment.ID, "status", adjustment.Status)
+
+	if adjustment.Status == models.AdjustmentStatusApproved {
+		NotifyStockChanged(adjustment.ProductID)
+	}
+
+	return s.GetAdjustment(adjustment.ID)
+}
+
+// insertAdjustment writes an adjustment valued at the cost of its incoming.
+// Adjustments worth more than the configured approval threshold are left
+// pending, the rest are approved straight away and must not take the
+// incoming below zero.
+func (s *inventoryService) insertAdjustment(tx *sql.Tx, adjustment *models.InventoryAdjustment) error {
+	adjustment.ReasonCode = strings.TrimSpace(adjustment.ReasonCode)
+	adjustment.Remarks = strings.TrimSpace(adjustment.Remarks)
+
+	if adjustment.IncomingID == 0 {
+		return &ValidationError{Message: "incoming is required"}
+	}
+	if math.Abs(adjustment.StandardQuantity) <= stockTolerance {
+		return &ValidationError{Message: "standard quantity must not be zero"}
+	}
+
+	var exists bool
+	err := tx.QueryRowContext(
+		context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM inventory_adjustment_reasons WHERE code = $1 AND is_exist = true)`,
+		adjustment.ReasonCode,
+	).Scan(&exists)
+	if err != nil {
+		slog.Error("Error querying adjustment reason", "error", err)
+		return err
+	}
+	if !exists {
+		return &ValidationError{Message: "unknown adjustment reason: " + adjustment.ReasonCode}
+	}
+
+	var quantity, standardQuantity float64
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			product_id,
+			quantity,
+			standard_quantity,
+			cost
+		FROM
+			inventory_incomings
+		WHERE
+			id = $1
+		`,
+		adjustment.IncomingID,
+	).Scan(&adjustment.ProductID, &quantity, &standardQuantity, &adjustment.UnitCost)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Message: "incoming does not exist"}
+	}
+	if err != nil {
+		slog.Error("Error querying incoming", "error", err, "incoming", adjustment.IncomingID)
+		return err
+	}
+
+	// quantity is in the unit of the incoming, derive it from the standard
+	// quantity like a transfer does
+	adjustment.Quantity = adjustment.StandardQuantity
+	if standardQuantity != 0 {
+		adjustment.Quantity = adjustment.StandardQuantity * quantity / standardQuantity
+	}
+
+	adjustment.Value = adjustment.StandardQuantity * adjustment.UnitCost
+	adjustment.Status = models.AdjustmentStatusApproved
+	if math.Abs(adjustment.Value) > config.Cfg.AdjustmentApprovalThreshold {
+		adjustment.Status = models.AdjustmentStatusPending
+	}
+
+	approved := adjustment.Status == models.AdjustmentStatusApproved
+	adjustment.ApprovedBy = ""
+	if approved {
+		adjustment.ApprovedBy = adjustment.CreatedBy
+	}
+
+	// a write-down cannot take stock promised to reservations, like an issue
+	if approved && adjustment.StandardQuantity < 0 {
+		if err := s.checkIncomingBalance(tx, adjustment.IncomingID, 0, -adjustment.StandardQuantity); err != nil {
+			return err
+		}
+		if err := checkAvailableToPromise(tx, adjustment.ProductID, adjustment.IncomingID, 0, 0, -adjustment.StandardQuantity); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		INSERT INTO inventory_adjustments (
+			product_id,
+			incoming_id,
+			reason_code,
+			cycle_count_id,
+			quantity,
+			standard_quantity,
+			unit_cost,
+			status,
+			remarks,
+			created_by,
+			approved_by,
+			approved_at
+		) VALUES (
+			$1, $2, $3, NULLIF($4, 0), $5, $6, $7, $8, $9, $10, $11,
+			CASE WHEN $12::BOOLEAN THEN CURRENT_TIMESTAMP END
+		)
+		RETURNING id
+		`,
+		adjustment.ProductID,
+		adjustment.IncomingID,
+		adjustment.ReasonCode,
+		adjustment.CycleCountID,
+		adjustment.Quantity,
+		adjustment.StandardQuantity,
+		adjustment.UnitCost,
+		adjustment.Status,
+		adjustment.Remarks,
+		adjustment.CreatedBy,
+		adjustment.ApprovedBy,
+		approved,
+	).Scan(&adjustment.ID)
+	if err != nil {
+		slog.Error("Error inserting adjustment", "error", err)
+		return err
+	}
+
+	if approved && adjustment.StandardQuantity > 0 {
+		return s.restoreDepletedIncoming(tx, adjustment.IncomingID, adjustment.CreatedBy, "stock adjustment")
+	}
+
+	return nil
+}
+
+func (s *inventoryService) ApproveAdjustment(id int, decision *models.InventoryAdjustmentDecision) (*models.InventoryAdjustment, error) {
+	return s.decideAdjustment(id, models.AdjustmentStatusApproved, decision)
+}
+
+func (s *inventoryService) RejectAdjustment(id int, decision *models.InventoryAdjustmentDecision) (*models.InventoryAdjustment, error) {
+	return s.decideAdjustment(id, models.AdjustmentStatusRejected, decision)
+}
+
+// decideAdjustment moves a pending adjustment to approved or rejected. An
+// approval re-checks the balance because stock may have moved since the
+// adjustment was raised.
+func (s *inventoryService) decideAdjustment(id int, status string, decision *models.InventoryAdjustmentDecision) (*models.InventoryAdjustment, error) {
+	decision.By = strings.TrimSpace(decision.By)
+	if decision.By == "" {
+		return nil, &ValidationError{Message: "approver is required"}
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	var incomingID, productID int
+	var standardQuantity float64
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			status,
+			incoming_id,
+			product_id,
+			standard_quantity
+		FROM
+			inventory_adjustments
+		WHERE
+			id = $1
+		FOR UPDATE
+		`,
+		id,
+	).Scan(&current, &incomingID, &productID, &standardQuantity)
+	if err != nil {
+		slog.Error("Error locking adjustment", "error", err, "id", id)
+		return nil, err
+	}
+
+	if current != models.AdjustmentStatusPending {
+		return nil, &ValidationError{Message: fmt.Sprintf("adjustment %d is already %s", id, current)}
+	}
+
+	if status == models.AdjustmentStatusApproved && standardQuantity < 0 {
+		if err := s.checkIncomingBalance(tx, incomingID, 0, -standardQuantity); err != nil {
+			return nil, err
+		}
+		if err := checkAvailableToPromise(tx, productID, incomingID, 0, 0, -standardQuantity); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_adjustments
+		SET
+			status = $1,
+			approved_by = $2,
+			approved_at = CURRENT_TIMESTAMP,
+			remarks = COALESCE(NULLIF($3, ''), remarks)
+		WHERE
+			id = $4
+		`,
+		status,
+		decision.By,
+		strings.TrimSpace(decision.Remarks),
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating adjustment", "error", err, "id", id)
+		return nil, err
+	}
+
+	if status == models.AdjustmentStatusApproved && standardQuantity > 0 {
+		if err := s.restoreDepletedIncoming(tx, incomingID, decision.By, "stock adjustment"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing adjustment", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully decided adjustment", "id", id, "status", status, "by", decision.By)
+
+	if status == models.AdjustmentStatusApproved {
+		NotifyStockChanged(productID)
+	}
+
+	return s.GetAdjustment(id)
+}
+
+// Cycle count
+const cycleCountQuery = `
+	SELECT
+		c.id,
+		c.location_id,
+		c.status,
+		c.remarks,
+		c.created_by,
+		c.created_at,
+		c.posted_by,
+		c.posted_at,
+		l.code AS location_code,
+		l.name AS location_name,
+		w.name AS warehouse_name
+	FROM
+		cycle_counts c
+	JOIN
+		locations l
+	ON
+		c.location_id = l.id
+	JOIN
+		warehouses w
+	ON
+		l.warehouse_id = w.id
+	`
+
+func scanCycleCount(row interface{ Scan(...any) error }, count *models.CycleCount) error {
+	var postedAt sql.NullString
+	err := row.Scan(
+		&count.ID,
+		&count.LocationID,
+		&count.Status,
+		&count.Remarks,
+		&count.CreatedBy,
+		&count.CreatedAt,
+		&count.PostedBy,
+		&postedAt,
+		&count.LocationCode,
+		&count.LocationName,
+		&count.WarehouseName,
+	)
+	if err != nil {
+		return err
+	}
+
+	count.PostedAt = postedAt.String
+
+	return nil
+}
+
+func (s *inventoryService) GetCycleCounts() ([]*models.CycleCount, error) {
+	// execute query with context
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		cycleCountQuery+`
+		ORDER BY
+			c.created_at DESC,
+			c.id DESC
+		`,
+	)
+	if err != nil {
+		slog.Error("Error querying cycle counts", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []*models.CycleCount
+	for rows.Next() {
+		count := new(models.CycleCount)
+		if err := scanCycleCount(rows, count); err != nil {
+			slog.Error("Error scanning cycle count", "error", err)
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating cycle counts", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried cycle counts", "count", len(counts))
+
+	return counts, nil
+}
+
+// GetCycleCount returns a count with its lines. Open counts compare against
+// the live balance of each incoming, posted ones against the balance frozen
+// when they were posted.
+func (s *inventoryService) GetCycleCount(id int) (*models.CycleCount, error) {
+	count := new(models.CycleCount)
+
+	// execute query with context
+	err := scanCycleCount(s.db.QueryRowContext(
+		context.Background(),
+		cycleCountQuery+`
+		WHERE
+			c.id = $1
+		`,
+		id,
+	), count)
+	if err != nil {
+		slog.Error("Error querying cycle count", "error", err, "id", id)
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			l.id,
+			l.cycle_count_id,
+			l.incoming_id,
+			l.counted_quantity,
+			COALESCE(l.system_quantity, b.balance, 0),
+			i.cost,
+			i.product_id,
+			p.code AS product_code,
+			p.name AS product_name,
+			p.standard_unit,
+			i.ref_no
+		FROM
+			cycle_count_lines l
+		JOIN
+			inventory_incomings i
+		ON
+			l.incoming_id = i.id
+		JOIN
+			inventory_products p
+		ON
+			i.product_id = p.id
+		LEFT JOIN (
+			SELECT
+				incoming_id,
+				SUM(standard_quantity) AS balance
+			FROM
+				inventory_movements
+			GROUP BY
+				incoming_id
+		) b
+		ON
+			b.incoming_id = i.id
+		WHERE
+			l.cycle_count_id = $1
+		ORDER BY
+			p.code,
+			i.id
+		`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error querying cycle count lines", "error", err, "id", id)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		line := new(models.CycleCountLine)
+		err := rows.Scan(
+			&line.ID,
+			&line.CycleCountID,
+			&line.IncomingID,
+			&line.CountedQuantity,
+			&line.SystemQuantity,
+			&line.UnitCost,
+			&line.ProductID,
+			&line.ProductCode,
+			&line.ProductName,
+			&line.StandardUnit,
+			&line.RefNo,
+		)
+		if err != nil {
+			slog.Error("Error scanning cycle count line", "error", err)
+			return nil, err
+		}
+
+		if line.CountedQuantity != nil {
+			line.Variance = *line.CountedQuantity - line.SystemQuantity
+			line.VarianceValue = line.Variance * line.UnitCost
+		}
+
+		count.Lines = append(count.Lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating cycle count lines", "error", err)
+		return nil, err
+	}
+
+	return count, nil
+}
+
+// CreateCycleCount opens a count on a location with a line for every
+// incoming there that still holds stock.
+func (s *inventoryService) CreateCycleCount(count *models.CycleCount) (*models.CycleCount, error) {
+	count.Remarks = strings.TrimSpace(count.Remarks)
+
+	if count.LocationID == 0 {
+		return nil, &ValidationError{Message: "location is required"}
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var open bool
+	err = tx.QueryRowContext(
+		context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM cycle_counts WHERE location_id = $1 AND status = $2)`,
+		count.LocationID,
+		models.CycleCountStatusOpen,
+	).Scan(&open)
+	if err != nil {
+		slog.Error("Error querying open cycle counts", "error", err)
+		return nil, err
+	}
+	if open {
+		return nil, &ValidationError{Message: "location already has an open cycle count"}
+	}
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		INSERT INTO cycle_counts (
+			location_id,
+			status,
+			remarks,
+			created_by
+		) VALUES (
+			$1, $2, $3, $4
+		)
+		RETURNING id
+		`,
+		count.LocationID,
+		models.CycleCountStatusOpen,
+		count.Remarks,
+		count.CreatedBy,
+	).Scan(&count.ID)
+	if err != nil {
+		slog.Error("Error inserting cycle count", "error", err)
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		INSERT INTO cycle_count_lines (
+			cycle_count_id,
+			incoming_id
+		)
+		SELECT
+			$1,
+			i.id
+		FROM
+			inventory_incomings i
+		JOIN (
+			SELECT
+				incoming_id,
+				SUM(standard_quantity) AS balance
+			FROM
+				inventory_movements
+			GROUP BY
+				incoming_id
+		) b
+		ON
+			b.incoming_id = i.id
+		WHERE
+			i.location_id = $2
+		AND
+			b.balance > $3
+		`,
+		count.ID,
+		count.LocationID,
+		stockTolerance,
+	)
+	if err != nil {
+		slog.Error("Error inserting cycle count lines", "error", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing cycle count", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully created cycle count", "id", count.ID, "location", count.LocationID)
+
+	return s.GetCycleCount(count.ID)
+}
+
+// UpdateCycleCountLines records counted quantities on an open count. An
+// incoming at the location that was empty when the count was opened gets a
+// new line, so found stock can be counted too.
+func (s *inventoryService) UpdateCycleCountLines(id int, entries []*models.CycleCountEntry) (*models.CycleCount, error) {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	locationID, err := s.lockOpenCycleCount(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.CountedQuantity < 0 {
+			return nil, &ValidationError{Message: "counted quantity must not be negative"}
+		}
+
+		var incomingLocationID int
+		err := tx.QueryRowContext(
+			context.Background(),
+			`SELECT COALESCE(location_id, 0) FROM inventory_incomings WHERE id = $1`,
+			entry.IncomingID,
+		).Scan(&incomingLocationID)
+		if err == sql.ErrNoRows {
+			return nil, &ValidationError{Message: fmt.Sprintf("incoming %d does not exist", entry.IncomingID)}
+		}
+		if err != nil {
+			slog.Error("Error querying incoming", "error", err, "incoming", entry.IncomingID)
+			return nil, err
+		}
+		if incomingLocationID != locationID {
+			return nil, &ValidationError{Message: fmt.Sprintf("incoming %d is not stored at the counted location", entry.IncomingID)}
+		}
+
+		_, err = tx.ExecContext(
+			context.Background(),
+			`
+			INSERT INTO cycle_count_lines (
+				cycle_count_id,
+				incoming_id,
+				counted_quantity
+			) VALUES (
+				$1, $2, $3
+			)
+			ON CONFLICT (cycle_count_id, incoming_id) DO UPDATE
+			SET
+				counted_quantity = EXCLUDED.counted_quantity
+			`,
+			id,
+			entry.IncomingID,
+			entry.CountedQuantity,
+		)
+		if err != nil {
+			slog.Error("Error saving cycle count line", "error", err, "incoming", entry.IncomingID)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing cycle count lines", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully updated cycle count lines", "id", id, "count", len(entries))
+
+	return s.GetCycleCount(id)
+}
+
+// PostCycleCount freezes the system balance of every counted line and raises
+// a count correction adjustment for each variance. Adjustments above the
+// approval threshold stay pending like any other.
+func (s *inventoryService) PostCycleCount(id int, postedBy string) (*models.CycleCount, error) {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.lockOpenCycleCount(tx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			l.id,
+			l.incoming_id,
+			l.counted_quantity,
+			COALESCE(SUM(m.standard_quantity), 0)
+		FROM
+			cycle_count_lines l
+		LEFT JOIN
+			inventory_movements m
+		ON
+			m.incoming_id = l.incoming_id
+		WHERE
+			l.cycle_count_id = $1
+		AND
+			l.counted_quantity IS NOT NULL
+		GROUP BY
+			l.id,
+			l.incoming_id,
+			l.counted_quantity
+		`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error querying cycle count lines", "error", err, "id", id)
+		return nil, err
+	}
+
+	var lines []*models.CycleCountLine
+	for rows.Next() {
+		line := new(models.CycleCountLine)
+		if err := rows.Scan(&line.ID, &line.IncomingID, &line.CountedQuantity, &line.SystemQuantity); err != nil {
+			rows.Close()
+			slog.Error("Error scanning cycle count line", "error", err)
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating cycle count lines", "error", err)
+		return nil, err
+	}
+
+	// products whose stock the count changed, for the alerts once committed
+	changed := map[int]bool{}
+	for _, line := range lines {
+		_, err := tx.ExecContext(
+			context.Background(),
+			`UPDATE cycle_count_lines SET system_quantity = $1 WHERE id = $2`,
+			line.SystemQuantity,
+			line.ID,
+		)
+		if err != nil {
+			slog.Error("Error freezing cycle count line", "error", err, "line", line.ID)
+			return nil, err
+		}
+
+		variance := *line.CountedQuantity - line.SystemQuantity
+		if math.Abs(variance) <= stockTolerance {
+			continue
+		}
+
+		adjustment := &models.InventoryAdjustment{
+			IncomingID:       line.IncomingID,
+			ReasonCode:       models.AdjustmentReasonCountCorrection,
+			CycleCountID:     id,
+			StandardQuantity: variance,
+			Remarks:          fmt.Sprintf("Cycle count %d", id),
+			CreatedBy:        postedBy,
+		}
+		if err := s.insertAdjustment(tx, adjustment); err != nil {
+			return nil, err
+		}
+		if adjustment.Status == models.AdjustmentStatusApproved {
+			changed[adjustment.ProductID] = true
+		}
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			cycle_counts
+		SET
+			status = $1,
+			posted_by = $2,
+			posted_at = CURRENT_TIMESTAMP
+		WHERE
+			id = $3
+		`,
+		models.CycleCountStatusPosted,
+		postedBy,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error posting cycle count", "error", err, "id", id)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing cycle count", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully posted cycle count", "id", id, "lines", len(lines))
+
+	for productID := range changed {
+		NotifyStockChanged(productID)
+	}
+
+	return s.GetCycleCount(id)
+}
+
+func (s *inventoryService) CancelCycleCount(id int) error {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.lockOpenCycleCount(tx, id); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`UPDATE cycle_counts SET status = $1 WHERE id = $2`,
+		models.CycleCountStatusCancelled,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error cancelling cycle count", "error", err, "id", id)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing cycle count", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully cancelled cycle count", "id", id)
+
+	return nil
+}
+
+// lockOpenCycleCount locks a count for the rest of the transaction and
+// returns its location, refusing counts that are no longer open.
+func (s *inventoryService) lockOpenCycleCount(tx *sql.Tx, id int) (int, error) {
+	var locationID int
+	var status string
+	err := tx.QueryRowContext(
+		context.Background(),
+		`SELECT location_id, status FROM cycle_counts WHERE id = $1 FOR UPDATE`,
+		id,
+	).Scan(&locationID, &status)
+	if err != nil {
+		slog.Error("Error locking cycle count", "error", err, "id", id)
+		return 0, err
+	}
+
+	if status != models.CycleCountStatusOpen {
+		return 0, &ValidationError{Message: fmt.Sprintf("cycle count %d is %s", id, status)}
+	}
+
+	return locationID, nil
+}
@@ -0,0 +1,963 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/lib/pq"
+)
+
+const categoryQuery = `
+	SELECT
+		c.id,
+		COALESCE(c.parent_id, 0),
+		c.code,
+		c.name,
+		COALESCE(cp.path, c.name) AS path,
+		c.is_exist,
+		c.created_by,
+		c.created_at,
+		c.updated_by,
+		c.updated_at
+	FROM
+		product_categories c
+	LEFT JOIN
+		product_category_paths cp
+	ON
+		c.id = cp.id
+	`
+
+func scanCategory(row interface{ Scan(...any) error }, category *models.ProductCategory) error {
+	return row.Scan(
+		&category.ID,
+		&category.ParentID,
+		&category.Code,
+		&category.Name,
+		&category.Path,
+		&category.IsExist,
+		&category.CreatedBy,
+		&category.CreatedAt,
+		&category.UpdatedBy,
+		&category.UpdatedAt,
+	)
+}
+
+// Category
+func (s *inventoryService) GetCategories() ([]*models.ProductCategory, error) {
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		categoryQuery+`
+		ORDER BY
+			path
+		`,
+	)
+	if err != nil {
+		slog.Error("Error querying categories", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*models.ProductCategory{}
+	for rows.Next() {
+		category := new(models.ProductCategory)
+		if err := scanCategory(rows, category); err != nil {
+			slog.Error("Error scanning category", "error", err)
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating categories", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried categories", "categories", len(categories))
+
+	return categories, nil
+}
+
+// GetCategoryTree returns the root categories with the ones below them
+// nested as Children.
+func (s *inventoryService) GetCategoryTree() ([]*models.ProductCategory, error) {
+	categories, err := s.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*models.ProductCategory, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	// categories come sorted by path, so children keep that order
+	roots := []*models.ProductCategory{}
+	for _, category := range categories {
+		parent, ok := byID[category.ParentID]
+		if !ok {
+			roots = append(roots, category)
+			continue
+		}
+		parent.Children = append(parent.Children, category)
+	}
+
+	return roots, nil
+}
+
+func (s *inventoryService) GetCategory(id int) (*models.ProductCategory, error) {
+	category := new(models.ProductCategory)
+	err := scanCategory(s.db.QueryRowContext(
+		context.Background(),
+		categoryQuery+`
+		WHERE
+			c.id = $1
+		`,
+		id,
+	), category)
+	if err != nil {
+		slog.Error("Error querying category", "error", err, "id", id)
+		return nil, err
+	}
+
+	category.Attributes, err = categoryAttributes(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (s *inventoryService) CreateCategory(category *models.ProductCategory) (*models.ProductCategory, error) {
+	if err := s.validateCategory(0, category); err != nil {
+		return nil, err
+	}
+
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`
+		INSERT INTO product_categories (
+			parent_id,
+			code,
+			name,
+			is_exist,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			NULLIF($1, 0), $2, $3, $4, $5, NOW(), $6, NOW()
+		)
+		RETURNING id
+		`,
+		category.ParentID,
+		category.Code,
+		category.Name,
+		category.IsExist,
+		category.CreatedBy,
+		category.UpdatedBy,
+	).Scan(&category.ID)
+	if err != nil {
+		slog.Error("Error inserting category", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted category", "category", category.ID)
+
+	return s.GetCategory(category.ID)
+}
+
+func (s *inventoryService) UpdateCategory(id int, category *models.ProductCategory) (*models.ProductCategory, error) {
+	if err := s.validateCategory(id, category); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			product_categories
+		SET
+			parent_id = NULLIF($1, 0),
+			code = $2,
+			name = $3,
+			is_exist = $4,
+			updated_by = $5,
+			updated_at = NOW()
+		WHERE
+			id = $6
+		`,
+		category.ParentID,
+		category.Code,
+		category.Name,
+		category.IsExist,
+		category.UpdatedBy,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating category", "error", err)
+		return nil, err
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	slog.Info("Successfully updated category", "category", id)
+
+	return s.GetCategory(id)
+}
+
+// DeleteCategory removes a category with its attributes, as long as no
+// category sits below it and no product is filed under it.
+func (s *inventoryService) DeleteCategory(id int) error {
+	var children, products int
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			(SELECT COUNT(*) FROM product_categories WHERE parent_id = $1),
+			(SELECT COUNT(*) FROM inventory_products WHERE category_id = $1)
+		`,
+		id,
+	).Scan(&children, &products)
+	if err != nil {
+		slog.Error("Error counting category usage", "error", err, "id", id)
+		return err
+	}
+	if children > 0 {
+		return &ValidationError{Message: "category has categories below it"}
+	}
+	if products > 0 {
+		return &ValidationError{Message: "category has products filed under it"}
+	}
+
+	_, err = s.db.ExecContext(context.Background(), `DELETE FROM product_categories WHERE id = $1`, id)
+	if err != nil {
+		slog.Error("Error deleting category", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted category", "category", id)
+
+	return nil
+}
+
+// validateCategory checks the code and name of a category and that its
+// parent exists and is not the category itself or one below it.
+func (s *inventoryService) validateCategory(id int, category *models.ProductCategory) error {
+	category.Code = strings.ToUpper(strings.TrimSpace(category.Code))
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Code == "" {
+		return &ValidationError{Message: "category code is required"}
+	}
+	if category.Name == "" {
+		return &ValidationError{Message: "category name is required"}
+	}
+
+	var taken bool
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM product_categories WHERE code = $1 AND id <> $2)`,
+		category.Code,
+		id,
+	).Scan(&taken)
+	if err != nil {
+		slog.Error("Error checking category code", "error", err)
+		return err
+	}
+	if taken {
+		return &ValidationError{Message: "category code " + category.Code + " is already used"}
+	}
+
+	if category.ParentID == 0 {
+		return nil
+	}
+
+	if category.ParentID == id {
+		return &ValidationError{Message: "a category cannot be its own parent"}
+	}
+
+	var exists, below bool
+	err = s.db.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			EXISTS(SELECT 1 FROM product_categories WHERE id = $1),
+			EXISTS(SELECT 1 FROM product_category_tree WHERE ancestor_id = $2 AND category_id = $1)
+		`,
+		category.ParentID,
+		id,
+	).Scan(&exists, &below)
+	if err != nil {
+		slog.Error("Error checking parent category", "error", err)
+		return err
+	}
+	if !exists {
+		return &ValidationError{Message: "parent category does not exist"}
+	}
+	if below {
+		return &ValidationError{Message: "a category cannot be moved below itself"}
+	}
+
+	return nil
+}
+
+// Category attribute
+
+// categoryAttributes returns the attributes that apply to products of the
+// category, those of the categories above it first.
+func categoryAttributes(q queryer, categoryID int) ([]*models.CategoryAttribute, error) {
+	rows, err := q.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			a.id,
+			a.category_id,
+			a.code,
+			a.name,
+			a.type,
+			a.unit,
+			a.options,
+			a.is_required,
+			a.sort_order
+		FROM
+			product_category_attributes a
+		JOIN
+			product_category_tree t
+		ON
+			a.category_id = t.ancestor_id
+		WHERE
+			t.category_id = $1
+		ORDER BY
+			t.depth DESC,
+			a.sort_order,
+			a.id
+		`,
+		categoryID,
+	)
+	if err != nil {
+		slog.Error("Error querying category attributes", "error", err, "category", categoryID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	attributes := []*models.CategoryAttribute{}
+	for rows.Next() {
+		attribute := new(models.CategoryAttribute)
+		err := rows.Scan(
+			&attribute.ID,
+			&attribute.CategoryID,
+			&attribute.Code,
+			&attribute.Name,
+			&attribute.Type,
+			&attribute.Unit,
+			pq.Array(&attribute.Options),
+			&attribute.IsRequired,
+			&attribute.SortOrder,
+		)
+		if err != nil {
+			slog.Error("Error scanning category attribute", "error", err)
+			return nil, err
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating category attributes", "error", err)
+		return nil, err
+	}
+
+	return attributes, nil
+}
+
+func (s *inventoryService) CreateCategoryAttribute(categoryID int, attribute *models.CategoryAttribute) (*models.CategoryAttribute, error) {
+	attribute.CategoryID = categoryID
+	if err := s.validateCategoryAttribute(0, attribute); err != nil {
+		return nil, err
+	}
+
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`
+		INSERT INTO product_category_attributes (
+			category_id,
+			code,
+			name,
+			type,
+			unit,
+			options,
+			is_required,
+			sort_order
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+		RETURNING id
+		`,
+		attribute.CategoryID,
+		attribute.Code,
+		attribute.Name,
+		attribute.Type,
+		attribute.Unit,
+		pq.Array(attribute.Options),
+		attribute.IsRequired,
+		attribute.SortOrder,
+	).Scan(&attribute.ID)
+	if err != nil {
+		slog.Error("Error inserting category attribute", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted category attribute", "category", categoryID, "attribute", attribute.ID)
+
+	return attribute, nil
+}
+
+// UpdateCategoryAttribute changes an attribute of the category. Its type can
+// only change while no product has a value for it, and an option still in
+// use cannot be dropped.
+func (s *inventoryService) UpdateCategoryAttribute(categoryID, id int, attribute *models.CategoryAttribute) (*models.CategoryAttribute, error) {
+	attribute.ID = id
+	attribute.CategoryID = categoryID
+	if err := s.validateCategoryAttribute(id, attribute); err != nil {
+		return nil, err
+	}
+
+	var currentType string
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`SELECT type FROM product_category_attributes WHERE id = $1 AND category_id = $2`,
+		id,
+		categoryID,
+	).Scan(&currentType)
+	if err != nil {
+		slog.Error("Error querying category attribute", "error", err, "id", id)
+		return nil, err
+	}
+
+	var inUse sql.NullString
+	err = s.db.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			MIN(value)
+		FROM
+			inventory_product_attributes
+		WHERE
+			attribute_id = $1
+		AND
+			($2 OR NOT (value = ANY($3)))
+		`,
+		id,
+		currentType != attribute.Type,
+		pq.Array(attribute.Options),
+	).Scan(&inUse)
+	if err != nil {
+		slog.Error("Error querying attribute values", "error", err, "id", id)
+		return nil, err
+	}
+	if inUse.Valid && currentType != attribute.Type {
+		return nil, &ValidationError{Message: "attribute " + attribute.Code + " has values, its type cannot change"}
+	}
+	if inUse.Valid && attribute.Type == models.AttributeTypeOption {
+		return nil, &ValidationError{Message: "option " + inUse.String + " of attribute " + attribute.Code + " is still in use"}
+	}
+
+	_, err = s.db.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			product_category_attributes
+		SET
+			code = $1,
+			name = $2,
+			type = $3,
+			unit = $4,
+			options = $5,
+			is_required = $6,
+			sort_order = $7
+		WHERE
+			id = $8
+		AND
+			category_id = $9
+		`,
+		attribute.Code,
+		attribute.Name,
+		attribute.Type,
+		attribute.Unit,
+		pq.Array(attribute.Options),
+		attribute.IsRequired,
+		attribute.SortOrder,
+		id,
+		categoryID,
+	)
+	if err != nil {
+		slog.Error("Error updating category attribute", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully updated category attribute", "category", categoryID, "attribute", id)
+
+	return attribute, nil
+}
+
+// DeleteCategoryAttribute removes an attribute along with the values
+// products have for it.
+func (s *inventoryService) DeleteCategoryAttribute(categoryID, id int) error {
+	_, err := s.db.ExecContext(
+		context.Background(),
+		`DELETE FROM product_category_attributes WHERE id = $1 AND category_id = $2`,
+		id,
+		categoryID,
+	)
+	if err != nil {
+		slog.Error("Error deleting category attribute", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted category attribute", "category", categoryID, "attribute", id)
+
+	return nil
+}
+
+// validateCategoryAttribute checks the type and options of an attribute and
+// that no category above or below its own already uses its code, so a
+// product never sees the same code twice.
+func (s *inventoryService) validateCategoryAttribute(id int, attribute *models.CategoryAttribute) error {
+	attribute.Code = strings.ToLower(strings.TrimSpace(attribute.Code))
+	attribute.Name = strings.TrimSpace(attribute.Name)
+	attribute.Unit = strings.TrimSpace(attribute.Unit)
+	if attribute.Code == "" {
+		return &ValidationError{Message: "attribute code is required"}
+	}
+	if attribute.Name == "" {
+		return &ValidationError{Message: "attribute name is required"}
+	}
+	if attribute.Type == "" {
+		attribute.Type = models.AttributeTypeText
+	}
+
+	switch attribute.Type {
+	case models.AttributeTypeOption:
+		options := make([]string, 0, len(attribute.Options))
+		seen := make(map[string]bool, len(attribute.Options))
+		for _, option := range attribute.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || seen[strings.ToLower(option)] {
+				continue
+			}
+			seen[strings.ToLower(option)] = true
+			options = append(options, option)
+		}
+		if len(options) == 0 {
+			return &ValidationError{Message: "an option attribute needs options"}
+		}
+		attribute.Options = options
+	case models.AttributeTypeText, models.AttributeTypeNumber, models.AttributeTypeBoolean:
+		attribute.Options = []string{}
+	default:
+		return &ValidationError{Message: "unknown attribute type: " + attribute.Type}
+	}
+
+	var exists, taken bool
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			EXISTS(SELECT 1 FROM product_categories WHERE id = $1),
+			EXISTS(
+				SELECT
+					1
+				FROM
+					product_category_attributes a
+				WHERE
+					a.code = $2
+				AND
+					a.id <> $3
+				AND
+					a.category_id IN (
+						SELECT ancestor_id FROM product_category_tree WHERE category_id = $1
+						UNION
+						SELECT category_id FROM product_category_tree WHERE ancestor_id = $1
+					)
+			)
+		`,
+		attribute.CategoryID,
+		attribute.Code,
+		id,
+	).Scan(&exists, &taken)
+	if err != nil {
+		slog.Error("Error checking category attribute", "error", err)
+		return err
+	}
+	if !exists {
+		return &ValidationError{Message: "category does not exist"}
+	}
+	if taken {
+		return &ValidationError{Message: "attribute " + attribute.Code + " is already used in this branch of the category tree"}
+	}
+
+	return nil
+}
+
+// attributeValue checks value against the type of the attribute and returns
+// it as stored, with the number it holds for a number attribute.
+func attributeValue(attribute *models.CategoryAttribute, value string) (string, *float64, error) {
+	value = strings.TrimSpace(value)
+
+	switch attribute.Type {
+	case models.AttributeTypeNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", nil, &ValidationError{Message: attribute.Name + " must be a number"}
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), &number, nil
+	case models.AttributeTypeBoolean:
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", nil, &ValidationError{Message: attribute.Name + " must be true or false"}
+		}
+		return strconv.FormatBool(flag), nil, nil
+	case models.AttributeTypeOption:
+		for _, option := range attribute.Options {
+			if strings.EqualFold(option, value) {
+				return option, nil, nil
+			}
+		}
+		return "", nil, &ValidationError{Message: fmt.Sprintf("%s must be one of %s", attribute.Name, strings.Join(attribute.Options, ", "))}
+	}
+
+	return value, nil, nil
+}
+
+// Product attribute
+func getProductAttributes(q queryer, productID int) ([]*models.ProductAttributeValue, error) {
+	rows, err := q.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			pa.attribute_id,
+			a.code,
+			a.name,
+			a.type,
+			a.unit,
+			pa.value
+		FROM
+			inventory_product_attributes pa
+		JOIN
+			product_category_attributes a
+		ON
+			pa.attribute_id = a.id
+		WHERE
+			pa.product_id = $1
+		ORDER BY
+			a.sort_order,
+			a.id
+		`,
+		productID,
+	)
+	if err != nil {
+		slog.Error("Error querying product attributes", "error", err, "product", productID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []*models.ProductAttributeValue{}
+	for rows.Next() {
+		value := new(models.ProductAttributeValue)
+		err := rows.Scan(
+			&value.AttributeID,
+			&value.Code,
+			&value.Name,
+			&value.Type,
+			&value.Unit,
+			&value.Value,
+		)
+		if err != nil {
+			slog.Error("Error scanning product attribute", "error", err)
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating product attributes", "error", err)
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// saveProductAttributes writes the attribute values of a product after it
+// is saved. Attributes left nil keep the values the product has, dropping
+// those its category no longer asks for. Every required attribute of the
+// category must end up with a value.
+func saveProductAttributes(tx *sql.Tx, product *models.InventoryProduct) error {
+	allowed := []*models.CategoryAttribute{}
+	if product.CategoryID != 0 {
+		var exists bool
+		err := tx.QueryRowContext(
+			context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM product_categories WHERE id = $1)`,
+			product.CategoryID,
+		).Scan(&exists)
+		if err != nil {
+			slog.Error("Error checking product category", "error", err)
+			return err
+		}
+		if !exists {
+			return &ValidationError{Message: "category does not exist"}
+		}
+
+		allowed, err = categoryAttributes(tx, product.CategoryID)
+		if err != nil {
+			return err
+		}
+	}
+
+	values := product.Attributes
+	keep := values == nil
+	if keep {
+		var err error
+		values, err = getProductAttributes(tx, product.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	byID := make(map[int]*models.CategoryAttribute, len(allowed))
+	byCode := make(map[string]*models.CategoryAttribute, len(allowed))
+	for _, attribute := range allowed {
+		byID[attribute.ID] = attribute
+		byCode[attribute.Code] = attribute
+	}
+
+	type storedValue struct {
+		value  string
+		number *float64
+	}
+	stored := make(map[int]storedValue, len(values))
+	for _, value := range values {
+		attribute, ok := byID[value.AttributeID]
+		if !ok {
+			attribute, ok = byCode[strings.ToLower(strings.TrimSpace(value.Code))]
+		}
+		if !ok {
+			if keep {
+				continue
+			}
+			name := value.Code
+			if name == "" {
+				name = strconv.Itoa(value.AttributeID)
+			}
+			return &ValidationError{Message: "attribute " + name + " does not apply to the category of the product"}
+		}
+		if strings.TrimSpace(value.Value) == "" {
+			continue
+		}
+		if _, ok := stored[attribute.ID]; ok {
+			return &ValidationError{Message: "attribute " + attribute.Code + " is given twice"}
+		}
+
+		text, number, err := attributeValue(attribute, value.Value)
+		if err != nil {
+			return err
+		}
+		stored[attribute.ID] = storedValue{value: text, number: number}
+	}
+
+	for _, attribute := range allowed {
+		if _, ok := stored[attribute.ID]; attribute.IsRequired && !ok {
+			return &ValidationError{Message: attribute.Name + " is required"}
+		}
+	}
+
+	_, err := tx.ExecContext(
+		context.Background(),
+		`DELETE FROM inventory_product_attributes WHERE product_id = $1`,
+		product.ID,
+	)
+	if err != nil {
+		slog.Error("Error deleting product attributes", "error", err, "product", product.ID)
+		return err
+	}
+
+	product.Attributes = []*models.ProductAttributeValue{}
+	for _, attribute := range allowed {
+		value, ok := stored[attribute.ID]
+		if !ok {
+			continue
+		}
+
+		_, err := tx.ExecContext(
+			context.Background(),
+			`
+			INSERT INTO inventory_product_attributes (
+				product_id,
+				attribute_id,
+				value,
+				number_value
+			) VALUES (
+				$1, $2, $3, $4
+			)
+			`,
+			product.ID,
+			attribute.ID,
+			value.value,
+			value.number,
+		)
+		if err != nil {
+			slog.Error("Error inserting product attribute", "error", err, "product", product.ID)
+			return err
+		}
+
+		product.Attributes = append(product.Attributes, &models.ProductAttributeValue{
+			AttributeID: attribute.ID,
+			Code:        attribute.Code,
+			Name:        attribute.Name,
+			Type:        attribute.Type,
+			Unit:        attribute.Unit,
+			Value:       value.value,
+		})
+	}
+
+	return nil
+}
+
+// Tag
+
+// normaliseTags trims and lower cases tags, dropping blanks and repeats. Nil
+// stays nil so an update without tags keeps the ones the product has.
+func normaliseTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	normalised := []string{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalised = append(normalised, tag)
+	}
+	sort.Strings(normalised)
+
+	return normalised
+}
+
+// GetProductTags lists every tag in use, for pickers to offer.
+func (s *inventoryService) GetProductTags() ([]string, error) {
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		`
+		SELECT DISTINCT
+			UNNEST(tags) AS tag
+		FROM
+			inventory_products
+		ORDER BY
+			tag
+		`,
+	)
+	if err != nil {
+		slog.Error("Error querying product tags", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			slog.Error("Error scanning product tag", "error", err)
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating product tags", "error", err)
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// productFilterClause returns the WHERE clause on products aliased p that
+// keeps those matching filter, numbering its arguments after args.
+func productFilterClause(filter *models.InventoryProductFilter, args []any) (string, []any) {
+	if filter == nil {
+		return "", args
+	}
+
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var conditions []string
+	if filter.CategoryID != 0 {
+		conditions = append(conditions, "p.category_id IN (SELECT category_id FROM product_category_tree WHERE ancestor_id = "+arg(filter.CategoryID)+")")
+	}
+	if filter.ParentID != 0 {
+		conditions = append(conditions, "p.parent_id = "+arg(filter.ParentID))
+	}
+	if tags := normaliseTags(filter.Tags); len(tags) > 0 {
+		conditions = append(conditions, "p.tags @> "+arg(pq.Array(tags))+"::TEXT[]")
+	}
+
+	for _, attribute := range filter.Attributes {
+		var match []string
+		value := strings.TrimSpace(attribute.Value)
+		switch {
+		case value != "":
+			equal := "LOWER(pa.value) = LOWER(" + arg(value) + ")"
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				equal = "(" + equal + " OR pa.number_value = " + arg(number) + ")"
+			}
+			match = append(match, equal)
+		default:
+			if attribute.Min != nil {
+				match = append(match, "pa.number_value >= "+arg(*attribute.Min))
+			}
+			if attribute.Max != nil {
+				match = append(match, "pa.number_value <= "+arg(*attribute.Max))
+			}
+		}
+
+		// an attribute named without a value only has to be set
+		condition := `
+			EXISTS (
+				SELECT
+					1
+				FROM
+					inventory_product_attributes pa
+				JOIN
+					product_category_attributes a
+				ON
+					pa.attribute_id = a.id
+				WHERE
+					pa.product_id = p.id
+				AND
+					a.code = ` + arg(strings.ToLower(strings.TrimSpace(attribute.Code)))
+		for _, m := range match {
+			condition += `
+				AND
+					` + m
+		}
+		conditions = append(conditions, condition+`
+			)`)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return "WHERE\n\t\t\t" + strings.Join(conditions, "\n\t\tAND\n\t\t\t"), args
+}
@@ -0,0 +1,876 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/config"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/lib/pq"
+)
+
+// defaultExpiryDays is how far ahead the expiry report looks when neither the
+// caller nor the configuration says.
+const defaultExpiryDays = 90
+
+// applyIncomingLot tidies the lot of an incoming and checks its dates, which
+// are kept as YYYY-MM-DD.
+func applyIncomingLot(incoming *models.InventoryIncoming) error {
+	incoming.LotNo = strings.TrimSpace(incoming.LotNo)
+
+	manufacturedAt, err := parseOptionalDate(dateOnly(strings.TrimSpace(incoming.ManufacturedAt)))
+	if err != nil {
+		return &ValidationError{Message: "manufactured at must be YYYY-MM-DD"}
+	}
+	expiresAt, err := parseOptionalDate(dateOnly(strings.TrimSpace(incoming.ExpiresAt)))
+	if err != nil {
+		return &ValidationError{Message: "expires at must be YYYY-MM-DD"}
+	}
+	if manufacturedAt != nil && expiresAt != nil && expiresAt.Before(*manufacturedAt) {
+		return &ValidationError{Message: "expiry date is before the manufacture date"}
+	}
+
+	incoming.ManufacturedAt = ""
+	if manufacturedAt != nil {
+		incoming.ManufacturedAt = manufacturedAt.Format("2006-01-02")
+	}
+	incoming.ExpiresAt = ""
+	if expiresAt != nil {
+		incoming.ExpiresAt = expiresAt.Format("2006-01-02")
+	}
+
+	return nil
+}
+
+// cleanSerialNos trims the serials given and refuses blanks and repeats.
+func cleanSerialNos(serialNos []string) ([]string, error) {
+	serials := make([]string, 0, len(serialNos))
+	seen := make(map[string]bool, len(serialNos))
+	for _, serialNo := range serialNos {
+		serialNo = strings.TrimSpace(serialNo)
+		if serialNo == "" {
+			return nil, &ValidationError{Message: "serial no must not be empty"}
+		}
+		if seen[serialNo] {
+			return nil, &ValidationError{Message: "serial " + serialNo + " is listed twice"}
+		}
+		seen[serialNo] = true
+		serials = append(serials, serialNo)
+	}
+
+	return serials, nil
+}
+
+func querySerials(q queryer, queryStr string, args ...any) ([]string, error) {
+	rows, err := q.QueryContext(context.Background(), queryStr, args...)
+	if err != nil {
+		slog.Error("Error querying serials", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var serials []string
+	for rows.Next() {
+		var serialNo string
+		if err := rows.Scan(&serialNo); err != nil {
+			slog.Error("Error scanning serial", "error", err)
+			return nil, err
+		}
+		serials = append(serials, serialNo)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating serials", "error", err)
+		return nil, err
+	}
+
+	return serials, nil
+}
+
+func (s *inventoryService) getIncomingSerials(incomingID int) ([]string, error) {
+	return querySerials(s.db, `SELECT serial_no FROM inventory_serials WHERE incoming_id = $1 ORDER BY serial_no`, incomingID)
+}
+
+func (s *inventoryService) getOutgoingSerials(outgoingID int) ([]string, error) {
+	return querySerials(s.db, `SELECT serial_no FROM inventory_serials WHERE outgoing_id = $1 ORDER BY serial_no`, outgoingID)
+}
+
+// saveIncomingSerials brings the serials of an incoming in line with
+// SerialNos, nil keeping the ones it has. Serials given must number one per
+// unit of its standard quantity, and serials already issued cannot be taken
+// off it.
+func saveIncomingSerials(tx *sql.Tx, incoming *models.InventoryIncoming) error {
+	current, err := querySerials(tx, `SELECT serial_no FROM inventory_serials WHERE incoming_id = $1 ORDER BY serial_no`, incoming.ID)
+	if err != nil {
+		return err
+	}
+
+	if incoming.SerialNos == nil {
+		incoming.SerialNos = current
+		return nil
+	}
+
+	serials, err := cleanSerialNos(incoming.SerialNos)
+	if err != nil {
+		return err
+	}
+	incoming.SerialNos = serials
+
+	if len(serials) > 0 && math.Abs(float64(len(serials))-incoming.StandardQuantity) > stockTolerance {
+		return &ValidationError{Message: fmt.Sprintf("%d serials given for a standard quantity of %v", len(serials), incoming.StandardQuantity)}
+	}
+
+	kept := make(map[string]bool, len(serials))
+	for _, serialNo := range serials {
+		kept[serialNo] = true
+	}
+	var removed, added []string
+	existing := make(map[string]bool, len(current))
+	for _, serialNo := range current {
+		existing[serialNo] = true
+		if !kept[serialNo] {
+			removed = append(removed, serialNo)
+		}
+	}
+	for _, serialNo := range serials {
+		if !existing[serialNo] {
+			added = append(added, serialNo)
+		}
+	}
+
+	if len(removed) > 0 {
+		var issued sql.NullString
+		err := tx.QueryRowContext(
+			context.Background(),
+			`
+			SELECT
+				MIN(serial_no)
+			FROM
+				inventory_serials
+			WHERE
+				incoming_id = $1
+			AND
+				serial_no = ANY($2)
+			AND
+				outgoing_id IS NOT NULL
+			`,
+			incoming.ID,
+			pq.Array(removed),
+		).Scan(&issued)
+		if err != nil {
+			slog.Error("Error querying issued serials", "error", err, "incoming", incoming.ID)
+			return err
+		}
+		if issued.Valid {
+			return &ValidationError{Message: "serial " + issued.String + " is already issued"}
+		}
+
+		_, err = tx.ExecContext(
+			context.Background(),
+			`DELETE FROM inventory_serials WHERE incoming_id = $1 AND serial_no = ANY($2)`,
+			incoming.ID,
+			pq.Array(removed),
+		)
+		if err != nil {
+			slog.Error("Error deleting serials", "error", err, "incoming", incoming.ID)
+			return err
+		}
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	var duplicate sql.NullString
+	err = tx.QueryRowContext(
+		context.Background(),
+		`SELECT MIN(serial_no) FROM inventory_serials WHERE product_id = $1 AND serial_no = ANY($2)`,
+		incoming.ProductID,
+		pq.Array(added),
+	).Scan(&duplicate)
+	if err != nil {
+		slog.Error("Error querying serials", "error", err, "product", incoming.ProductID)
+		return err
+	}
+	if duplicate.Valid {
+		return &ValidationError{Message: "serial " + duplicate.String + " was already received"}
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		INSERT INTO inventory_serials (
+			product_id,
+			incoming_id,
+			serial_no
+		)
+		SELECT
+			$1,
+			$2,
+			UNNEST($3::VARCHAR[])
+		`,
+		incoming.ProductID,
+		incoming.ID,
+		pq.Array(added),
+	)
+	if err != nil {
+		slog.Error("Error inserting serials", "error", err, "incoming", incoming.ID)
+		return err
+	}
+
+	return nil
+}
+
+// issueSerials ties items of a serialised incoming to an outgoing, the ones
+// named by SerialNos or else the first ones still in stock. What the outgoing
+// held before is released first so an edit can pick again.
+func issueSerials(tx *sql.Tx, outgoing *models.InventoryOutgoing) error {
+	_, err := tx.ExecContext(
+		context.Background(),
+		`UPDATE inventory_serials SET outgoing_id = NULL WHERE outgoing_id = $1`,
+		outgoing.ID,
+	)
+	if err != nil {
+		slog.Error("Error releasing serials", "error", err, "outgoing", outgoing.ID)
+		return err
+	}
+
+	var total int
+	err = tx.QueryRowContext(
+		context.Background(),
+		`SELECT COUNT(*) FROM inventory_serials WHERE incoming_id = $1`,
+		outgoing.IncomingID,
+	).Scan(&total)
+	if err != nil {
+		slog.Error("Error counting serials", "error", err, "incoming", outgoing.IncomingID)
+		return err
+	}
+
+	serials, err := cleanSerialNos(outgoing.SerialNos)
+	if err != nil {
+		return err
+	}
+	if total == 0 {
+		if len(serials) > 0 {
+			return &ValidationError{Message: fmt.Sprintf("incoming %d is not serialised", outgoing.IncomingID)}
+		}
+		outgoing.SerialNos = nil
+		return nil
+	}
+
+	count := math.Round(outgoing.StandardQuantity)
+	if math.Abs(outgoing.StandardQuantity-count) > stockTolerance {
+		return &ValidationError{Message: fmt.Sprintf("incoming %d is serialised, only whole items can be issued", outgoing.IncomingID)}
+	}
+
+	if len(serials) == 0 {
+		serials, err = querySerials(
+			tx,
+			`
+			SELECT
+				serial_no
+			FROM
+				inventory_serials
+			WHERE
+				incoming_id = $1
+			AND
+				outgoing_id IS NULL
+			ORDER BY
+				serial_no
+			LIMIT $2
+			`,
+			outgoing.IncomingID,
+			int(count),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if len(serials) != int(count) {
+		return &ValidationError{Message: fmt.Sprintf("%d serials for a standard quantity of %v", len(serials), outgoing.StandardQuantity)}
+	}
+
+	result, err := tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_serials
+		SET
+			outgoing_id = $1
+		WHERE
+			incoming_id = $2
+		AND
+			outgoing_id IS NULL
+		AND
+			serial_no = ANY($3)
+		`,
+		outgoing.ID,
+		outgoing.IncomingID,
+		pq.Array(serials),
+	)
+	if err != nil {
+		slog.Error("Error issuing serials", "error", err, "outgoing", outgoing.ID)
+		return err
+	}
+	issued, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(issued) != len(serials) {
+		return &ValidationError{Message: fmt.Sprintf("some serials are not in stock on incoming %d", outgoing.IncomingID)}
+	}
+
+	outgoing.SerialNos = serials
+
+	return nil
+}
+
+// returnSerials puts the serialised items of a customer return back in
+// stock, in the incoming the return went to. Named serials must have been
+// issued on the outgoing, otherwise the first ones issued come back.
+func returnSerials(tx *sql.Tx, ret *models.InventoryReturn) error {
+	issued, err := querySerials(tx, `SELECT serial_no FROM inventory_serials WHERE outgoing_id = $1 ORDER BY serial_no`, ret.OutgoingID)
+	if err != nil {
+		return err
+	}
+	if len(issued) == 0 {
+		if len(ret.SerialNos) > 0 {
+			return &ValidationError{Message: fmt.Sprintf("outgoing %d has no serials issued", ret.OutgoingID)}
+		}
+		return nil
+	}
+
+	count := math.Round(ret.StandardQuantity)
+	if math.Abs(ret.StandardQuantity-count) > stockTolerance {
+		return &ValidationError{Message: fmt.Sprintf("outgoing %d is serialised, only whole items can be returned", ret.OutgoingID)}
+	}
+
+	serials, err := cleanSerialNos(ret.SerialNos)
+	if err != nil {
+		return err
+	}
+	if len(serials) == 0 {
+		if int(count) > len(issued) {
+			return &ValidationError{Message: fmt.Sprintf("outgoing %d has only %d serials issued", ret.OutgoingID, len(issued))}
+		}
+		serials = issued[:int(count)]
+	}
+	if len(serials) != int(count) {
+		return &ValidationError{Message: fmt.Sprintf("%d serials are returned but the quantity is %v", len(serials), ret.StandardQuantity)}
+	}
+
+	onOutgoing := make(map[string]bool, len(issued))
+	for _, serialNo := range issued {
+		onOutgoing[serialNo] = true
+	}
+	for _, serialNo := range serials {
+		if !onOutgoing[serialNo] {
+			return &ValidationError{Message: fmt.Sprintf("serial %s was not issued on outgoing %d", serialNo, ret.OutgoingID)}
+		}
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_serials
+		SET
+			outgoing_id = NULL,
+			incoming_id = $3
+		WHERE
+			outgoing_id = $1
+		AND
+			serial_no = ANY($2)
+		`,
+		ret.OutgoingID,
+		pq.Array(serials),
+		ret.IncomingID,
+	)
+	if err != nil {
+		slog.Error("Error returning serials", "error", err, "outgoing", ret.OutgoingID)
+		return err
+	}
+	ret.SerialNos = serials
+
+	return nil
+}
+
+// moveSerials hands the first items still in stock on a serialised incoming
+// to the incoming split off it, one per unit of standard quantity moved.
+func moveSerials(tx *sql.Tx, fromIncomingID, toIncomingID int, standardQuantity float64) error {
+	var total int
+	err := tx.QueryRowContext(
+		context.Background(),
+		`SELECT COUNT(*) FROM inventory_serials WHERE incoming_id = $1`,
+		fromIncomingID,
+	).Scan(&total)
+	if err != nil {
+		slog.Error("Error counting serials", "error", err, "incoming", fromIncomingID)
+		return err
+	}
+	if total == 0 {
+		return nil
+	}
+
+	count := math.Round(standardQuantity)
+	if math.Abs(standardQuantity-count) > stockTolerance {
+		return &ValidationError{Message: fmt.Sprintf("incoming %d is serialised, only whole items can be moved", fromIncomingID)}
+	}
+
+	result, err := tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_serials
+		SET
+			incoming_id = $2
+		WHERE
+			id IN (
+				SELECT
+					id
+				FROM
+					inventory_serials
+				WHERE
+					incoming_id = $1
+				AND
+					outgoing_id IS NULL
+				ORDER BY
+					serial_no
+				LIMIT $3
+			)
+		`,
+		fromIncomingID,
+		toIncomingID,
+		int(count),
+	)
+	if err != nil {
+		slog.Error("Error moving serials", "error", err, "from", fromIncomingID, "to", toIncomingID)
+		return err
+	}
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if moved != int64(count) {
+		return &ValidationError{Message: fmt.Sprintf("incoming %d has only %d serials in stock", fromIncomingID, moved)}
+	}
+
+	return nil
+}
+
+// checkIncomingExpiry refuses to issue an expired lot unless the outgoing
+// names who overrode it. The override is dropped when the lot has not
+// expired so it only ever marks outgoings that needed one.
+func checkIncomingExpiry(tx *sql.Tx, outgoing *models.InventoryOutgoing) error {
+	outgoing.ExpiryOverrideBy = strings.TrimSpace(outgoing.ExpiryOverrideBy)
+
+	var lotNo string
+	var expiresAt sql.NullString
+	var expired bool
+	err := tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			lot_no,
+			expires_at,
+			COALESCE(expires_at < CURRENT_DATE, false)
+		FROM
+			inventory_incomings
+		WHERE
+			id = $1
+		`,
+		outgoing.IncomingID,
+	).Scan(&lotNo, &expiresAt, &expired)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Message: "incoming does not exist"}
+	}
+	if err != nil {
+		slog.Error("Error querying incoming expiry", "error", err, "incoming", outgoing.IncomingID)
+		return err
+	}
+
+	if !expired {
+		outgoing.ExpiryOverrideBy = ""
+		return nil
+	}
+	if outgoing.ExpiryOverrideBy == "" {
+		return &ValidationError{Message: fmt.Sprintf(
+			"lot %s of incoming %d expired on %s, issuing it needs an override",
+			lotNo,
+			outgoing.IncomingID,
+			dateOnly(expiresAt.String),
+		)}
+	}
+
+	slog.Warn("Issuing expired lot", "incoming", outgoing.IncomingID, "lot", lotNo, "by", outgoing.ExpiryOverrideBy)
+
+	return nil
+}
+
+// Lot
+// TraceLot finds every incoming of a lot, or the incoming a serial was
+// received on, and every outgoing that issued from them with the job and
+// customer it went to.
+func (s *inventoryService) TraceLot(lotNo, serialNo string, productID int) (*models.LotTrace, error) {
+	lotNo = strings.TrimSpace(lotNo)
+	serialNo = strings.TrimSpace(serialNo)
+	if lotNo == "" && serialNo == "" {
+		return nil, &ValidationError{Message: "lot no or serial no is required"}
+	}
+
+	trace := &models.LotTrace{
+		LotNo:     lotNo,
+		SerialNo:  serialNo,
+		ProductID: productID,
+		Incomings: []*models.InventoryIncoming{},
+		Lines:     []*models.LotTraceLine{},
+	}
+
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			i.id
+		FROM
+			inventory_incomings i
+		WHERE
+			($1 = '' OR i.lot_no = $1)
+		AND
+			($2 = '' OR i.id IN (SELECT incoming_id FROM inventory_serials WHERE serial_no = $2))
+		AND
+			($3 = 0 OR i.product_id = $3)
+		ORDER BY
+			i.received_at,
+			i.id
+		`,
+		lotNo,
+		serialNo,
+		productID,
+	)
+	if err != nil {
+		slog.Error("Error querying lot incomings", "error", err, "lot", lotNo)
+		return nil, err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			slog.Error("Error scanning lot incoming", "error", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating lot incomings", "error", err)
+		return nil, err
+	}
+
+	for _, id := range ids {
+		incoming, err := s.GetIncoming(id)
+		if err != nil {
+			return nil, err
+		}
+		trace.Incomings = append(trace.Incomings, incoming)
+	}
+
+	if len(ids) == 0 {
+		return trace, nil
+	}
+
+	rows, err = s.db.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			o.id,
+			o.incoming_id,
+			o.product_id,
+			p.code,
+			p.name,
+			i.lot_no,
+			i.expires_at,
+			ARRAY(SELECT serial_no FROM inventory_serials WHERE outgoing_id = o.id ORDER BY serial_no),
+			o.standard_quantity,
+			p.standard_unit,
+			o.ref_no,
+			COALESCE(o.job_order_id, 0),
+			COALESCE(j.job_no, ''),
+			COALESCE(j.customer_id, 0),
+			COALESCE(c.name, ''),
+			COALESCE(o.delivery_order_id, 0),
+			COALESCE(d.do_no, ''),
+			o.expiry_override_by,
+			o.created_at
+		FROM
+			inventory_outgoings o
+		JOIN
+			inventory_incomings i
+		ON
+			o.incoming_id = i.id
+		JOIN
+			inventory_products p
+		ON
+			o.product_id = p.id
+		LEFT JOIN
+			job_orders j
+		ON
+			o.job_order_id = j.id
+		LEFT JOIN
+			customers c
+		ON
+			j.customer_id = c.id
+		LEFT JOIN
+			delivery_orders d
+		ON
+			o.delivery_order_id = d.id
+		WHERE
+			o.incoming_id = ANY($1)
+		AND
+			($2 = '' OR o.id IN (SELECT outgoing_id FROM inventory_serials WHERE serial_no = $2))
+		ORDER BY
+			o.created_at,
+			o.id
+		`,
+		pq.Array(ids),
+		serialNo,
+	)
+	if err != nil {
+		slog.Error("Error querying lot outgoings", "error", err, "lot", lotNo)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		line := new(models.LotTraceLine)
+		var expiresAt sql.NullString
+		err := rows.Scan(
+			&line.OutgoingID,
+			&line.IncomingID,
+			&line.ProductID,
+			&line.ProductCode,
+			&line.ProductName,
+			&line.LotNo,
+			&expiresAt,
+			pq.Array(&line.SerialNos),
+			&line.StandardQuantity,
+			&line.StandardUnit,
+			&line.RefNo,
+			&line.JobOrderID,
+			&line.JobNo,
+			&line.CustomerID,
+			&line.CustomerName,
+			&line.DeliveryOrderID,
+			&line.DONo,
+			&line.ExpiryOverrideBy,
+			&line.IssuedAt,
+		)
+		if err != nil {
+			slog.Error("Error scanning lot outgoing", "error", err)
+			return nil, err
+		}
+		line.ExpiresAt = expiresAt.String
+
+		trace.Lines = append(trace.Lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating lot outgoings", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully traced lot", "lot", lotNo, "serial", serialNo, "incomings", len(ids), "outgoings", len(trace.Lines))
+
+	return trace, nil
+}
+
+// Expiry
+
+// expiryHorizons returns the configured horizons up to days, closing with
+// days itself.
+func expiryHorizons(days int) []int {
+	var horizons []int
+	for _, horizon := range config.Cfg.ExpiryAlertHorizons {
+		if horizon < days {
+			horizons = append(horizons, horizon)
+		}
+	}
+	return append(horizons, days)
+}
+
+// expiryHorizon returns the smallest horizon a lot expiring in daysToExpiry
+// days falls within, zero once it has expired.
+func expiryHorizon(daysToExpiry int, horizons []int) int {
+	if daysToExpiry < 0 {
+		return 0
+	}
+	for _, horizon := range horizons {
+		if daysToExpiry <= horizon {
+			return horizon
+		}
+	}
+	return horizons[len(horizons)-1]
+}
+
+// queryNearExpiry reads the incomings of the product, or of every product
+// when productID is zero, with stock left that expire within days or have
+// already expired, soonest first.
+func queryNearExpiry(q queryer, days, productID int) ([]*models.ExpiryReportLine, error) {
+	rows, err := q.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			i.id,
+			p.id,
+			p.code,
+			p.name,
+			i.lot_no,
+			i.ref_no,
+			TRIM(i.store_location) AS store_location,
+			TRIM(i.store_country) AS store_country,
+			TO_CHAR(i.expires_at, 'YYYY-MM-DD') AS expires_at,
+			i.expires_at - CURRENT_DATE AS days_to_expiry,
+			b.balance_std_qty,
+			p.standard_unit,
+			i.cost,
+			b.balance_std_qty * i.cost AS value
+		FROM
+			inventory_incomings i
+		JOIN
+			inventory_products p
+		ON
+			i.product_id = p.id
+		JOIN (
+			SELECT
+				incoming_id,
+				SUM(standard_quantity) AS balance_std_qty
+			FROM
+				inventory_movements
+			GROUP BY
+				incoming_id
+			) b
+		ON
+			i.id = b.incoming_id
+		WHERE
+			i.expires_at IS NOT NULL
+		AND
+			i.expires_at <= CURRENT_DATE + $1::INTEGER
+		AND
+			b.balance_std_qty > $2
+		AND
+			($3 = 0 OR i.product_id = $3)
+		ORDER BY
+			i.expires_at,
+			p.code,
+			i.id
+		`,
+		days,
+		stockTolerance,
+		productID,
+	)
+	if err != nil {
+		slog.Error("Error querying near expiry incomings", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []*models.ExpiryReportLine{}
+	for rows.Next() {
+		line := new(models.ExpiryReportLine)
+		err := rows.Scan(
+			&line.IncomingID,
+			&line.ProductID,
+			&line.ProductCode,
+			&line.ProductName,
+			&line.LotNo,
+			&line.RefNo,
+			&line.StoreLocation,
+			&line.StoreCountry,
+			&line.ExpiresAt,
+			&line.DaysToExpiry,
+			&line.StandardQuantity,
+			&line.StandardUnit,
+			&line.UnitCost,
+			&line.Value,
+		)
+		if err != nil {
+			slog.Error("Error scanning near expiry incoming", "error", err)
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating near expiry incomings", "error", err)
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// GetExpiryReport lists the lots with stock left that expire within days,
+// the largest configured horizon by default, or have already expired, and
+// totals them per horizon.
+func (s *inventoryService) GetExpiryReport(days, productID int) (*models.ExpiryReport, error) {
+	if days < 0 {
+		return nil, &ValidationError{Message: "days must not be negative"}
+	}
+	if days == 0 {
+		days = defaultExpiryDays
+		if horizons := config.Cfg.ExpiryAlertHorizons; len(horizons) > 0 {
+			days = horizons[len(horizons)-1]
+		}
+	}
+
+	lines, err := queryNearExpiry(s.db, days, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the lines count days from the date of the database, not of this server
+	var asOf string
+	err = s.db.QueryRowContext(context.Background(), `SELECT CURRENT_DATE::TEXT`).Scan(&asOf)
+	if err != nil {
+		slog.Error("Error querying current date", "error", err)
+		return nil, err
+	}
+
+	report := &models.ExpiryReport{
+		AsOf:    asOf,
+		Days:    days,
+		Buckets: []*models.ExpiryReportBucket{{Label: "Expired"}},
+		Lines:   lines,
+	}
+
+	horizons := expiryHorizons(days)
+	from := 0
+	for _, horizon := range horizons {
+		report.Buckets = append(report.Buckets, &models.ExpiryReportBucket{
+			Label:   fmt.Sprintf("%d-%d days", from, horizon),
+			Horizon: horizon,
+		})
+		from = horizon + 1
+	}
+
+	for _, line := range lines {
+		line.Horizon = expiryHorizon(line.DaysToExpiry, horizons)
+		for _, bucket := range report.Buckets {
+			if bucket.Horizon == line.Horizon {
+				bucket.Lots++
+				bucket.Value += line.Value
+				break
+			}
+		}
+		report.TotalValue += line.Value
+	}
+
+	slog.Info("Successfully queried expiry report", "days", days, "lots", len(lines))
+
+	return report, nil
+}
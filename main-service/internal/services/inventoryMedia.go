@@ -0,0 +1,612 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+// productMediaPath is where product media is uploaded to, under a directory
+// per product.
+const productMediaPath = "inventory/products"
+
+const productMediaQuery = `
+	SELECT
+		m.id,
+		m.product_id,
+		m.type,
+		m.title,
+		m.path,
+		m.filename,
+		m.content_type,
+		m.size,
+		m.is_primary,
+		m.sort_order,
+		m.created_by,
+		m.created_at
+	FROM
+		inventory_product_media m
+`
+
+func scanProductMedia(row interface{ Scan(...any) error }, media *models.ProductMedia) error {
+	return row.Scan(
+		&media.ID,
+		&media.ProductID,
+		&media.Type,
+		&media.Title,
+		&media.Path,
+		&media.Filename,
+		&media.ContentType,
+		&media.Size,
+		&media.IsPrimary,
+		&media.SortOrder,
+		&media.CreatedBy,
+		&media.CreatedAt,
+	)
+}
+
+func getProductMedia(q queryer, productID int) ([]*models.ProductMedia, error) {
+	rows, err := q.QueryContext(
+		context.Background(),
+		productMediaQuery+`
+		WHERE
+			m.product_id = $1
+		ORDER BY
+			m.sort_order,
+			m.id
+		`,
+		productID,
+	)
+	if err != nil {
+		slog.Error("Error querying product media", "error", err, "product", productID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := []*models.ProductMedia{}
+	for rows.Next() {
+		item := new(models.ProductMedia)
+		if err := scanProductMedia(rows, item); err != nil {
+			slog.Error("Error scanning product media", "error", err)
+			return nil, err
+		}
+		media = append(media, item)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error("Error querying product media", "error", err)
+		return nil, err
+	}
+
+	return media, nil
+}
+
+// seedProductMedia puts the thumbnail a new product is created with into its
+// gallery as the primary image.
+func seedProductMedia(tx *sql.Tx, product *models.InventoryProduct) error {
+	product.Thumbnail = strings.TrimSpace(product.Thumbnail)
+	if product.Thumbnail == "" {
+		return nil
+	}
+
+	_, err := tx.ExecContext(
+		context.Background(),
+		`
+		INSERT INTO inventory_product_media (
+			product_id,
+			type,
+			path,
+			filename,
+			is_primary,
+			sort_order,
+			created_by
+		) VALUES (
+			$1, $2, $3, $4, TRUE, 0, $5
+		)
+		`,
+		product.ID,
+		models.MediaTypeImage,
+		product.Thumbnail,
+		filepath.Base(product.Thumbnail),
+		product.CreatedBy,
+	)
+	if err != nil {
+		slog.Error("Error inserting product thumbnail media", "error", err, "product", product.ID)
+		return err
+	}
+
+	return nil
+}
+
+// changeProductThumbnail makes thumbnail the primary image of a product when
+// it differs from the one the product has, taking it from the gallery when it
+// is there and adding it otherwise. An empty thumbnail changes nothing.
+func changeProductThumbnail(tx *sql.Tx, product *models.InventoryProduct, thumbnail string) error {
+	thumbnail = strings.TrimSpace(thumbnail)
+	if thumbnail == "" || thumbnail == product.Thumbnail {
+		return nil
+	}
+
+	// cleared first, the primary index allows one per product at any time
+	_, err := tx.ExecContext(
+		context.Background(),
+		`UPDATE inventory_product_media SET is_primary = FALSE WHERE product_id = $1 AND is_primary`,
+		product.ID,
+	)
+	if err != nil {
+		slog.Error("Error clearing primary product media", "error", err, "product", product.ID)
+		return err
+	}
+
+	result, err := tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_product_media
+		SET
+			is_primary = TRUE
+		WHERE
+			id = (
+				SELECT
+					id
+				FROM
+					inventory_product_media
+				WHERE
+					product_id = $1
+					AND type = $2
+					AND path = $3
+				ORDER BY
+					id
+				LIMIT 1
+			)
+		`,
+		product.ID,
+		models.MediaTypeImage,
+		thumbnail,
+	)
+	if err != nil {
+		slog.Error("Error setting primary product media", "error", err, "product", product.ID)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		slog.Error("Error setting primary product media", "error", err)
+		return err
+	}
+	if affected == 0 {
+		seed := &models.InventoryProduct{ID: product.ID, Thumbnail: thumbnail, CreatedBy: product.UpdatedBy}
+		if err := seedProductMedia(tx, seed); err != nil {
+			return err
+		}
+	}
+
+	if err := syncPrimaryMedia(tx, product.ID); err != nil {
+		return err
+	}
+	product.Thumbnail = thumbnail
+
+	return nil
+}
+
+// syncPrimaryMedia makes the first image primary when the product has images
+// but none is, and points the thumbnail of the product at the primary image.
+func syncPrimaryMedia(tx *sql.Tx, productID int) error {
+	_, err := tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_product_media
+		SET
+			is_primary = TRUE
+		WHERE
+			id = (
+				SELECT
+					id
+				FROM
+					inventory_product_media
+				WHERE
+					product_id = $1
+					AND type = $2
+				ORDER BY
+					sort_order,
+					id
+				LIMIT 1
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM inventory_product_media WHERE product_id = $1 AND is_primary
+			)
+		`,
+		productID,
+		models.MediaTypeImage,
+	)
+	if err != nil {
+		slog.Error("Error setting primary product media", "error", err, "product", productID)
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_products
+		SET
+			thumbnail = COALESCE((
+				SELECT path FROM inventory_product_media WHERE product_id = $1 AND is_primary
+			), '')
+		WHERE
+			id = $1
+		`,
+		productID,
+	)
+	if err != nil {
+		slog.Error("Error updating product thumbnail", "error", err, "product", productID)
+		return err
+	}
+
+	return nil
+}
+
+// lockProduct holds the product row until tx ends so changes to its gallery
+// are made one at a time.
+func lockProduct(tx *sql.Tx, productID int) error {
+	var id int
+	err := tx.QueryRowContext(
+		context.Background(),
+		`SELECT id FROM inventory_products WHERE id = $1 FOR UPDATE`,
+		productID,
+	).Scan(&id)
+	if err != nil {
+		slog.Error("Error locking product", "error", err, "product", productID)
+		return err
+	}
+
+	return nil
+}
+
+// checkMediaFile checks the upload is of a kind its media type takes: images
+// for the gallery, PDFs for documents.
+func checkMediaFile(media *models.ProductMedia, content []byte) error {
+	if len(content) == 0 {
+		return &ValidationError{Message: "file is empty"}
+	}
+
+	media.ContentType = http.DetectContentType(content)
+	switch media.Type {
+	case models.MediaTypeImage:
+		if !strings.HasPrefix(media.ContentType, "image/") {
+			return &ValidationError{Message: "an image must be an image file, not " + media.ContentType}
+		}
+	case models.MediaTypeDatasheet, models.MediaTypeInstallationGuide:
+		if media.ContentType != "application/pdf" {
+			return &ValidationError{Message: "a " + strings.ReplaceAll(media.Type, "_", " ") + " must be a PDF"}
+		}
+		if media.IsPrimary {
+			return &ValidationError{Message: "only an image can be the primary media"}
+		}
+	default:
+		return &ValidationError{Message: "media type must be image, datasheet or installation_guide"}
+	}
+
+	return nil
+}
+
+// deleteMediaFile removes a media file nothing else points at. A failure is
+// only logged, the row is already gone.
+func (s *inventoryService) deleteMediaFile(path string) {
+	var inUse bool
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			EXISTS(SELECT 1 FROM inventory_product_media WHERE path = $1)
+			OR EXISTS(SELECT 1 FROM inventory_products WHERE thumbnail = $1)
+		`,
+		path,
+	).Scan(&inUse)
+	if err != nil {
+		slog.Error("Error checking product media file", "error", err, "path", path)
+		return
+	}
+	if inUse {
+		return
+	}
+
+	if err := s.fileSystem.Delete(path); err != nil {
+		slog.Error("Error deleting product media file", "error", err, "path", path)
+	}
+}
+
+func (s *inventoryService) GetProductMedia(productID int) ([]*models.ProductMedia, error) {
+	return getProductMedia(s.db, productID)
+}
+
+// AddProductMedia uploads a file to the gallery of a product, after the media
+// it already has. The first image of a product becomes its primary image.
+func (s *inventoryService) AddProductMedia(productID int, media *models.ProductMedia, content []byte) (*models.ProductMedia, error) {
+	media.Type = strings.ToLower(strings.TrimSpace(media.Type))
+	media.Title = strings.TrimSpace(media.Title)
+	media.Filename = filepath.Base(strings.TrimSpace(media.Filename))
+	if err := checkMediaFile(media, content); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(tx, productID); err != nil {
+		return nil, err
+	}
+
+	path, err := s.fileSystem.Upload(
+		uuid.New().String()+strings.ToLower(filepath.Ext(media.Filename)),
+		filepath.Join(productMediaPath, strconv.Itoa(productID)),
+		content,
+	)
+	if err != nil {
+		slog.Error("Error uploading product media", "error", err, "product", productID)
+		return nil, err
+	}
+
+	stored := false
+	defer func() {
+		if !stored {
+			s.deleteMediaFile(path)
+		}
+	}()
+
+	if media.IsPrimary {
+		_, err := tx.ExecContext(
+			context.Background(),
+			`UPDATE inventory_product_media SET is_primary = FALSE WHERE product_id = $1 AND is_primary`,
+			productID,
+		)
+		if err != nil {
+			slog.Error("Error clearing primary product media", "error", err, "product", productID)
+			return nil, err
+		}
+	}
+
+	var id int
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		INSERT INTO inventory_product_media (
+			product_id,
+			type,
+			title,
+			path,
+			filename,
+			content_type,
+			size,
+			is_primary,
+			sort_order,
+			created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8,
+			(SELECT COALESCE(MAX(sort_order), -1) + 1 FROM inventory_product_media WHERE product_id = $1),
+			$9
+		)
+		RETURNING id
+		`,
+		productID,
+		media.Type,
+		media.Title,
+		path,
+		media.Filename,
+		media.ContentType,
+		len(content),
+		media.IsPrimary,
+		media.CreatedBy,
+	).Scan(&id)
+	if err != nil {
+		slog.Error("Error inserting product media", "error", err, "product", productID)
+		return nil, err
+	}
+
+	if err := syncPrimaryMedia(tx, productID); err != nil {
+		return nil, err
+	}
+
+	media = new(models.ProductMedia)
+	err = scanProductMedia(tx.QueryRowContext(context.Background(), productMediaQuery+` WHERE m.id = $1`, id), media)
+	if err != nil {
+		slog.Error("Error querying product media", "error", err, "id", id)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing product media", "error", err)
+		return nil, err
+	}
+	stored = true
+
+	slog.Info("Successfully added product media", "media", media)
+
+	return media, nil
+}
+
+// ReorderProductMedia puts the gallery of a product in the order given, which
+// must list all of its media.
+func (s *inventoryService) ReorderProductMedia(productID int, order *models.ProductMediaOrder) ([]*models.ProductMedia, error) {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(tx, productID); err != nil {
+		return nil, err
+	}
+
+	current, err := getProductMedia(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make(map[int]bool, len(current))
+	for _, item := range current {
+		remaining[item.ID] = true
+	}
+	for _, id := range order.MediaIDs {
+		if !remaining[id] {
+			return nil, &ValidationError{Message: "media " + strconv.Itoa(id) + " is not in the gallery of the product or is listed twice"}
+		}
+		delete(remaining, id)
+	}
+	if len(remaining) > 0 {
+		return nil, &ValidationError{Message: "the order must list every media of the product"}
+	}
+
+	for i, id := range order.MediaIDs {
+		_, err := tx.ExecContext(
+			context.Background(),
+			`UPDATE inventory_product_media SET sort_order = $1 WHERE id = $2`,
+			i,
+			id,
+		)
+		if err != nil {
+			slog.Error("Error reordering product media", "error", err, "id", id)
+			return nil, err
+		}
+	}
+
+	media, err := getProductMedia(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing product media order", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully reordered product media", "product", productID)
+
+	return media, nil
+}
+
+// SetPrimaryProductMedia makes an image the primary image, and so the
+// thumbnail, of its product.
+func (s *inventoryService) SetPrimaryProductMedia(productID, mediaID int) ([]*models.ProductMedia, error) {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(tx, productID); err != nil {
+		return nil, err
+	}
+
+	var mediaType string
+	err = tx.QueryRowContext(
+		context.Background(),
+		`SELECT type FROM inventory_product_media WHERE id = $1 AND product_id = $2`,
+		mediaID,
+		productID,
+	).Scan(&mediaType)
+	if err != nil {
+		slog.Error("Error querying product media", "error", err, "id", mediaID)
+		return nil, err
+	}
+	if mediaType != models.MediaTypeImage {
+		return nil, &ValidationError{Message: "only an image can be the primary media"}
+	}
+
+	// cleared first, the primary index allows one per product at any time
+	_, err = tx.ExecContext(
+		context.Background(),
+		`UPDATE inventory_product_media SET is_primary = FALSE WHERE product_id = $1 AND is_primary`,
+		productID,
+	)
+	if err != nil {
+		slog.Error("Error clearing primary product media", "error", err, "product", productID)
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`UPDATE inventory_product_media SET is_primary = TRUE WHERE id = $1`,
+		mediaID,
+	)
+	if err != nil {
+		slog.Error("Error setting primary product media", "error", err, "id", mediaID)
+		return nil, err
+	}
+
+	if err := syncPrimaryMedia(tx, productID); err != nil {
+		return nil, err
+	}
+
+	media, err := getProductMedia(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing primary product media", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully set primary product media", "product", productID, "media", mediaID)
+
+	return media, nil
+}
+
+// DeleteProductMedia removes media from the gallery of a product and deletes
+// its file. Removing the primary image makes the next image primary.
+func (s *inventoryService) DeleteProductMedia(productID, mediaID int) error {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(tx, productID); err != nil {
+		return err
+	}
+
+	var path string
+	err = tx.QueryRowContext(
+		context.Background(),
+		`DELETE FROM inventory_product_media WHERE id = $1 AND product_id = $2 RETURNING path`,
+		mediaID,
+		productID,
+	).Scan(&path)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Error("Error deleting product media", "error", err, "id", mediaID)
+		}
+		return err
+	}
+
+	if err := syncPrimaryMedia(tx, productID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing product media", "error", err)
+		return err
+	}
+
+	s.deleteMediaFile(path)
+
+	slog.Info("Successfully deleted product media", "product", productID, "media", mediaID)
+
+	return nil
+}
@@ -0,0 +1,457 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+// Valuation
+func (s *inventoryService) GetValuationReport(groupBy string, asOf *time.Time) (*models.InventoryValuationReport, error) {
+	if groupBy == "" {
+		groupBy = models.ValuationGroupByProduct
+	}
+
+	var orderBy string
+	switch groupBy {
+	case models.ValuationGroupByProduct:
+		orderBy = "p.code, store_country, store_location"
+	case models.ValuationGroupByLocation:
+		orderBy = "store_country, store_location, p.code"
+	case models.ValuationGroupByCategory:
+		orderBy = "cp.path NULLS LAST, p.code, store_country, store_location"
+	default:
+		return nil, &ValidationError{Message: "unknown valuation grouping: " + groupBy}
+	}
+
+	queryStr := `
+		SELECT
+			p.id,
+			p.code,
+			p.name,
+			p.standard_unit,
+			COALESCE(cp.path, '') AS category_path,
+			TRIM(i.store_location) AS store_location,
+			TRIM(i.store_country) AS store_country,
+			SUM(m.standard_quantity) AS standard_quantity,
+			SUM(m.value) AS total_value
+		FROM
+			inventory_movements m
+		JOIN
+			inventory_incomings i
+		ON
+			m.incoming_id = i.id
+		JOIN
+			inventory_products p
+		ON
+			m.product_id = p.id
+		LEFT JOIN
+			product_category_paths cp
+		ON
+			p.category_id = cp.id
+		WHERE
+			$1::TIMESTAMP IS NULL OR m.moved_at < $1::TIMESTAMP
+		GROUP BY
+			p.id,
+			p.code,
+			p.name,
+			p.standard_unit,
+			cp.path,
+			TRIM(i.store_location),
+			TRIM(i.store_country)
+		HAVING
+			SUM(m.standard_quantity) <> 0
+		ORDER BY
+			` + orderBy + `
+	`
+
+	report := &models.InventoryValuationReport{
+		GroupBy: groupBy,
+		Groups:  []*models.InventoryValuationGroup{},
+	}
+
+	// count the whole of the as-of day
+	var cutoff *time.Time
+	if asOf != nil {
+		next := asOf.AddDate(0, 0, 1)
+		cutoff = &next
+		report.AsOf = asOf.Format("2006-01-02")
+	}
+
+	rows, err := s.db.QueryContext(context.Background(), queryStr, cutoff)
+	if err != nil {
+		slog.Error("Error querying valuation", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var group *models.InventoryValuationGroup
+	for rows.Next() {
+		line := new(models.InventoryValuationLine)
+		err := rows.Scan(
+			&line.ProductID,
+			&line.ProductCode,
+			&line.ProductName,
+			&line.StandardUnit,
+			&line.CategoryPath,
+			&line.StoreLocation,
+			&line.StoreCountry,
+			&line.StandardQuantity,
+			&line.TotalValue,
+		)
+		if err != nil {
+			slog.Error("Error scanning valuation line", "error", err)
+			return nil, err
+		}
+
+		if line.StandardQuantity != 0 {
+			line.AverageCost = line.TotalValue / line.StandardQuantity
+		}
+
+		key := line.ProductCode
+		switch groupBy {
+		case models.ValuationGroupByLocation:
+			key = line.StoreLocation + ", " + line.StoreCountry
+		case models.ValuationGroupByCategory:
+			key = line.CategoryPath
+			if key == "" {
+				key = "Uncategorised"
+			}
+		}
+
+		if group == nil || group.Key != key {
+			group = &models.InventoryValuationGroup{
+				Key:   key,
+				Lines: []*models.InventoryValuationLine{},
+			}
+			report.Groups = append(report.Groups, group)
+		}
+
+		// quantities of different products do not add up across a location
+		if groupBy == models.ValuationGroupByProduct {
+			group.StandardQuantity += line.StandardQuantity
+		}
+		group.Lines = append(group.Lines, line)
+		group.TotalValue += line.TotalValue
+		report.TotalValue += line.TotalValue
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating over valuation", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried valuation", "groups", len(report.Groups))
+
+	return report, nil
+}
+
+// Ledger
+
+// GetProductLedger returns the stock card of a product: an opening balance
+// brought forward from before from, then every movement up to the end of the
+// to day in date order with running balance and value.
+func (s *inventoryService) GetProductLedger(productID int, from, to *time.Time) (*models.InventoryLedger, error) {
+	product, err := s.GetProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger := &models.InventoryLedger{
+		ProductID:    product.ID,
+		ProductCode:  product.Code,
+		ProductName:  product.Name,
+		StandardUnit: product.StandardUnit,
+		Lines:        []*models.InventoryLedgerLine{},
+	}
+
+	var until *time.Time
+	if from != nil {
+		ledger.From = from.Format("2006-01-02")
+	}
+	if to != nil {
+		next := to.AddDate(0, 0, 1)
+		until = &next
+		ledger.To = to.Format("2006-01-02")
+	}
+
+	opening := &models.InventoryLedgerLine{
+		MovedAt:      ledger.From,
+		MovementType: models.MovementTypeOpening,
+	}
+
+	if from != nil {
+		err := s.db.QueryRowContext(
+			context.Background(),
+			`
+			SELECT
+				COALESCE(SUM(standard_quantity), 0),
+				COALESCE(SUM(value), 0)
+			FROM
+				inventory_movements
+			WHERE
+				product_id = $1
+			AND
+				moved_at < $2
+			`,
+			productID,
+			from,
+		).Scan(&opening.Balance, &opening.RunningValue)
+		if err != nil {
+			slog.Error("Error querying opening balance", "error", err)
+			return nil, err
+		}
+	}
+	opening.Value = opening.RunningValue
+	if opening.Balance != 0 {
+		opening.UnitCost = opening.RunningValue / opening.Balance
+	}
+	ledger.Lines = append(ledger.Lines, opening)
+
+	queryStr := `
+		SELECT
+			moved_at,
+			movement_type,
+			document_id,
+			incoming_id,
+			ref_no,
+			remarks,
+			standard_quantity,
+			unit_cost,
+			value
+		FROM
+			inventory_movements
+		WHERE
+			product_id = $1
+		AND
+			($2::TIMESTAMP IS NULL OR moved_at >= $2::TIMESTAMP)
+		AND
+			($3::TIMESTAMP IS NULL OR moved_at < $3::TIMESTAMP)
+		ORDER BY
+			moved_at ASC,
+			document_id ASC
+	`
+
+	rows, err := s.db.QueryContext(context.Background(), queryStr, productID, from, until)
+	if err != nil {
+		slog.Error("Error querying ledger", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	balance := opening.Balance
+	runningValue := opening.RunningValue
+	for rows.Next() {
+		line := new(models.InventoryLedgerLine)
+		var standardQuantity float64
+		err := rows.Scan(
+			&line.MovedAt,
+			&line.MovementType,
+			&line.DocumentID,
+			&line.IncomingID,
+			&line.RefNo,
+			&line.Remarks,
+			&standardQuantity,
+			&line.UnitCost,
+			&line.Value,
+		)
+		if err != nil {
+			slog.Error("Error scanning ledger line", "error", err)
+			return nil, err
+		}
+
+		if standardQuantity >= 0 {
+			line.QuantityIn = standardQuantity
+		} else {
+			line.QuantityOut = -standardQuantity
+		}
+
+		balance += standardQuantity
+		runningValue += line.Value
+		line.Balance = balance
+		line.RunningValue = runningValue
+
+		ledger.Lines = append(ledger.Lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating over ledger", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried ledger", "product", productID, "lines", len(ledger.Lines))
+
+	return ledger, nil
+}
+
+// Aging
+
+// agingBuckets are the age bands of the aging report, MaxDays 0 is open ended.
+var agingBuckets = []models.InventoryAgingBucket{
+	{Label: "0-30 days", MinDays: 0, MaxDays: 30},
+	{Label: "31-90 days", MinDays: 31, MaxDays: 90},
+	{Label: "91-180 days", MinDays: 91, MaxDays: 180},
+	{Label: "180+ days", MinDays: 181, MaxDays: 0},
+}
+
+// GetAgingReport buckets the remaining balance of every incoming by age and
+// sums it per product and location, valued at the incoming's cost.
+func (s *inventoryService) GetAgingReport(filter *models.InventoryAgingFilter) (*models.InventoryAgingReport, error) {
+	basis := filter.Basis
+	if basis == "" {
+		basis = models.AgingBasisReceived
+	}
+
+	var ageFrom string
+	switch basis {
+	case models.AgingBasisReceived:
+		ageFrom = "i.received_at"
+	case models.AgingBasisCreated:
+		ageFrom = "i.created_at::DATE"
+	default:
+		return nil, &ValidationError{Message: "unknown aging basis: " + basis}
+	}
+
+	queryStr := `
+		SELECT
+			p.id,
+			p.code,
+			p.name,
+			p.brand,
+			p.supplier,
+			p.standard_unit,
+			TRIM(i.store_location) AS store_location,
+			TRIM(i.store_country) AS store_country,
+			CURRENT_DATE - ` + ageFrom + ` AS age_days,
+			b.balance_std_qty,
+			b.balance_std_qty * i.cost AS value
+		FROM
+			inventory_incomings i
+		JOIN
+			inventory_products p
+		ON
+			i.product_id = p.id
+		JOIN (
+			SELECT
+				incoming_id,
+				SUM(standard_quantity) AS balance_std_qty
+			FROM
+				inventory_movements
+			GROUP BY
+				incoming_id
+			) b
+		ON
+			i.id = b.incoming_id
+		WHERE
+			b.balance_std_qty > 0
+		AND
+			($1 = '' OR p.brand = $1)
+		AND
+			($2 = 0 OR EXISTS (
+				SELECT
+					1
+				FROM
+					product_suppliers ps
+				WHERE
+					ps.product_id = p.id
+				AND
+					ps.supplier_id = $2
+			))
+		AND
+			($3 = '' OR EXISTS (
+				SELECT
+					1
+				FROM
+					product_suppliers ps
+				JOIN
+					suppliers s
+				ON
+					ps.supplier_id = s.id
+				WHERE
+					ps.product_id = p.id
+				AND
+					(LOWER(s.name) = LOWER($3) OR LOWER(s.code) = LOWER($3))
+			))
+		ORDER BY
+			p.code,
+			store_country,
+			store_location
+	`
+
+	rows, err := s.db.QueryContext(context.Background(), queryStr, filter.Brand, filter.SupplierID, strings.TrimSpace(filter.Supplier))
+	if err != nil {
+		slog.Error("Error querying aging", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &models.InventoryAgingReport{
+		Basis: basis,
+		Lines: []*models.InventoryAgingLine{},
+	}
+
+	var line *models.InventoryAgingLine
+	for rows.Next() {
+		row := new(models.InventoryAgingLine)
+		var ageDays int
+		var quantity, value float64
+		err := rows.Scan(
+			&row.ProductID,
+			&row.ProductCode,
+			&row.ProductName,
+			&row.Brand,
+			&row.Supplier,
+			&row.StandardUnit,
+			&row.StoreLocation,
+			&row.StoreCountry,
+			&ageDays,
+			&quantity,
+			&value,
+		)
+		if err != nil {
+			slog.Error("Error scanning aging line", "error", err)
+			return nil, err
+		}
+
+		// rows come sorted, so a new product or location starts a new line
+		if line == nil ||
+			line.ProductID != row.ProductID ||
+			line.StoreLocation != row.StoreLocation ||
+			line.StoreCountry != row.StoreCountry {
+			line = row
+			for _, bucket := range agingBuckets {
+				bucket := bucket
+				line.Buckets = append(line.Buckets, &bucket)
+			}
+			report.Lines = append(report.Lines, line)
+		}
+
+		// stock received with a future date counts as new
+		if ageDays < 0 {
+			ageDays = 0
+		}
+
+		for _, bucket := range line.Buckets {
+			if ageDays >= bucket.MinDays && (bucket.MaxDays == 0 || ageDays <= bucket.MaxDays) {
+				bucket.StandardQuantity += quantity
+				bucket.Value += value
+				break
+			}
+		}
+		line.StandardQuantity += quantity
+		line.Value += value
+		report.TotalValue += value
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating over aging", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried aging", "lines", len(report.Lines))
+
+	return report, nil
+}
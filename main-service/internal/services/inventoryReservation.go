@@ -0,0 +1,661 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+const reservationQuery = `
+	SELECT
+		r.id,
+		r.product_id,
+		COALESCE(r.incoming_id, 0),
+		COALESCE(r.job_order_id, 0),
+		r.standard_quantity,
+		b.consumed_quantity,
+		b.reserved_quantity,
+		r.expires_at,
+		CASE
+			WHEN r.status = 'released' THEN 'released'
+			WHEN r.standard_quantity - b.consumed_quantity <= 0.000001 THEN 'fulfilled'
+			WHEN r.expires_at < CURRENT_DATE THEN 'expired'
+			ELSE r.status
+		END AS status,
+		r.ref_no,
+		r.remarks,
+		r.released_by,
+		r.released_at,
+		r.created_by,
+		r.created_at,
+		r.updated_by,
+		r.updated_at,
+		p.code AS product_code,
+		p.name AS product_name,
+		p.standard_unit,
+		COALESCE(i.ref_no, '') AS incoming_ref_no,
+		COALESCE(j.job_no, '') AS job_no
+	FROM
+		inventory_reservations r
+	JOIN
+		inventory_reservation_balances b
+	ON
+		b.reservation_id = r.id
+	JOIN
+		inventory_products p
+	ON
+		r.product_id = p.id
+	LEFT JOIN
+		inventory_incomings i
+	ON
+		r.incoming_id = i.id
+	LEFT JOIN
+		job_orders j
+	ON
+		r.job_order_id = j.id
+	`
+
+func scanReservation(row interface{ Scan(...any) error }, reservation *models.InventoryReservation) error {
+	var releasedAt sql.NullString
+	err := row.Scan(
+		&reservation.ID,
+		&reservation.ProductID,
+		&reservation.IncomingID,
+		&reservation.JobOrderID,
+		&reservation.StandardQuantity,
+		&reservation.ConsumedQuantity,
+		&reservation.ReservedQuantity,
+		&reservation.ExpiresAt,
+		&reservation.Status,
+		&reservation.RefNo,
+		&reservation.Remarks,
+		&reservation.ReleasedBy,
+		&releasedAt,
+		&reservation.CreatedBy,
+		&reservation.CreatedAt,
+		&reservation.UpdatedBy,
+		&reservation.UpdatedAt,
+		&reservation.ProductCode,
+		&reservation.ProductName,
+		&reservation.StandardUnit,
+		&reservation.IncomingRefNo,
+		&reservation.JobNo,
+	)
+	if err != nil {
+		return err
+	}
+
+	reservation.ReleasedAt = releasedAt.String
+
+	return nil
+}
+
+// GetReservations lists the reservations of a product, of a job order, or all
+// of them when both are zero. Status matches the derived status, so expired
+// and fulfilled can be asked for too.
+func (s *inventoryService) GetReservations(productID, jobOrderID int, status string) ([]*models.InventoryReservation, error) {
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		reservationQuery+`
+		WHERE
+			($1 = 0 OR r.product_id = $1)
+		AND
+			($2 = 0 OR r.job_order_id = $2)
+		ORDER BY
+			r.expires_at,
+			r.id
+		`,
+		productID,
+		jobOrderID,
+	)
+	if err != nil {
+		slog.Error("Error querying reservations", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := []*models.InventoryReservation{}
+	for rows.Next() {
+		reservation := new(models.InventoryReservation)
+		if err := scanReservation(rows, reservation); err != nil {
+			slog.Error("Error scanning reservation", "error", err)
+			return nil, err
+		}
+		if status != "" && reservation.Status != status {
+			continue
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating reservations", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried reservations", "count", len(reservations))
+
+	return reservations, nil
+}
+
+func (s *inventoryService) GetReservation(id int) (*models.InventoryReservation, error) {
+	reservation := new(models.InventoryReservation)
+	err := scanReservation(s.db.QueryRowContext(
+		context.Background(),
+		reservationQuery+`
+		WHERE
+			r.id = $1
+		`,
+		id,
+	), reservation)
+	if err != nil {
+		slog.Error("Error querying reservation", "error", err, "id", id)
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// validateReservation checks reservation against its product, incoming and
+// job order, and that what it has left to hold is still free to promise.
+// consumed is what outgoings already took from it.
+func validateReservation(tx *sql.Tx, id int, reservation *models.InventoryReservation, consumed float64) error {
+	reservation.RefNo = strings.TrimSpace(reservation.RefNo)
+	reservation.Remarks = strings.TrimSpace(reservation.Remarks)
+
+	if reservation.ProductID == 0 {
+		return &ValidationError{Message: "product is required"}
+	}
+	if reservation.StandardQuantity <= 0 {
+		return &ValidationError{Message: "standard quantity must be greater than zero"}
+	}
+	if reservation.StandardQuantity-consumed < -stockTolerance {
+		return &ValidationError{Message: fmt.Sprintf("reservation has already consumed %v", consumed)}
+	}
+
+	expiresAt, err := parseOptionalDate(reservation.ExpiresAt)
+	if err != nil {
+		return &ValidationError{Message: "expires at must be a date in YYYY-MM-DD format"}
+	}
+	if expiresAt == nil {
+		return &ValidationError{Message: "expiry date is required"}
+	}
+
+	// today is the date of the database, the one expiry is checked against
+	var past bool
+	err = tx.QueryRowContext(
+		context.Background(),
+		`SELECT $1::DATE < CURRENT_DATE`,
+		expiresAt.Format("2006-01-02"),
+	).Scan(&past)
+	if err != nil {
+		slog.Error("Error checking reservation expiry", "error", err)
+		return err
+	}
+	if past {
+		return &ValidationError{Message: "expiry date cannot be in the past"}
+	}
+
+	if reservation.IncomingID != 0 {
+		var productID int
+		err := tx.QueryRowContext(
+			context.Background(),
+			`SELECT product_id FROM inventory_incomings WHERE id = $1`,
+			reservation.IncomingID,
+		).Scan(&productID)
+		if err == sql.ErrNoRows {
+			return &ValidationError{Message: "incoming does not exist"}
+		}
+		if err != nil {
+			slog.Error("Error querying incoming", "error", err, "id", reservation.IncomingID)
+			return err
+		}
+		if productID != reservation.ProductID {
+			return &ValidationError{Message: fmt.Sprintf("incoming %d is not of product %d", reservation.IncomingID, reservation.ProductID)}
+		}
+	}
+
+	if err := checkJobOrderOpen(tx, reservation.JobOrderID); err != nil {
+		return err
+	}
+
+	return checkAvailableToPromise(tx, reservation.ProductID, reservation.IncomingID, id, 0, reservation.StandardQuantity-consumed)
+}
+
+func (s *inventoryService) CreateReservation(reservation *models.InventoryReservation) (*models.InventoryReservation, error) {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := validateReservation(tx, 0, reservation, 0); err != nil {
+		return nil, err
+	}
+
+	queryStr := `
+		INSERT INTO inventory_reservations (
+			product_id,
+			incoming_id,
+			job_order_id,
+			standard_quantity,
+			expires_at,
+			status,
+			ref_no,
+			remarks,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			$1, NULLIF($2, 0), NULLIF($3, 0), $4, $5::DATE, $6, $7, $8, $9, NOW(), $10, NOW()
+		)
+		RETURNING id
+	`
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		queryStr,
+		reservation.ProductID,
+		reservation.IncomingID,
+		reservation.JobOrderID,
+		reservation.StandardQuantity,
+		reservation.ExpiresAt,
+		models.ReservationStatusActive,
+		reservation.RefNo,
+		reservation.Remarks,
+		reservation.CreatedBy,
+		reservation.UpdatedBy,
+	).Scan(&reservation.ID)
+	if err != nil {
+		slog.Error("Error inserting reservation", "error", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing reservation", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully created reservation", "id", reservation.ID, "product", reservation.ProductID)
+
+	return s.GetReservation(reservation.ID)
+}
+
+// UpdateReservation changes a reservation that was not released, an expired
+// one comes back to life with a later expiry date. It cannot move to another
+// product once outgoings consumed it, nor drop below what they consumed.
+func (s *inventoryService) UpdateReservation(id int, reservation *models.InventoryReservation) (*models.InventoryReservation, error) {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, consumed, err := lockReservation(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status == models.ReservationStatusReleased {
+		return nil, &ValidationError{Message: fmt.Sprintf("reservation %d is released", id)}
+	}
+	if consumed > 0 && (reservation.ProductID != current.ProductID || reservation.IncomingID != current.IncomingID) {
+		return nil, &ValidationError{Message: "cannot move a reservation that outgoings already consumed"}
+	}
+
+	if err := validateReservation(tx, id, reservation, consumed); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_reservations
+		SET
+			product_id = $1,
+			incoming_id = NULLIF($2, 0),
+			job_order_id = NULLIF($3, 0),
+			standard_quantity = $4,
+			expires_at = $5::DATE,
+			ref_no = $6,
+			remarks = $7,
+			updated_by = $8,
+			updated_at = NOW()
+		WHERE
+			id = $9
+		`,
+		reservation.ProductID,
+		reservation.IncomingID,
+		reservation.JobOrderID,
+		reservation.StandardQuantity,
+		reservation.ExpiresAt,
+		reservation.RefNo,
+		reservation.Remarks,
+		reservation.UpdatedBy,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating reservation", "error", err, "id", id)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing reservation", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully updated reservation", "id", id)
+
+	return s.GetReservation(id)
+}
+
+// ReleaseReservation gives what is left of a reservation back to be promised
+// elsewhere. Outgoings that already consumed it are kept.
+func (s *inventoryService) ReleaseReservation(id int, action *models.InventoryReservationAction) (*models.InventoryReservation, error) {
+	action.By = strings.TrimSpace(action.By)
+	if action.By == "" {
+		return nil, &ValidationError{Message: "released by is required"}
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, _, err := lockReservation(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status != models.ReservationStatusActive {
+		return nil, &ValidationError{Message: fmt.Sprintf("reservation %d is already %s", id, current.Status)}
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_reservations
+		SET
+			status = $1,
+			released_by = $2,
+			released_at = NOW(),
+			remarks = COALESCE(NULLIF($3, ''), remarks),
+			updated_by = $2,
+			updated_at = NOW()
+		WHERE
+			id = $4
+		`,
+		models.ReservationStatusReleased,
+		action.By,
+		strings.TrimSpace(action.Remarks),
+		id,
+	)
+	if err != nil {
+		slog.Error("Error releasing reservation", "error", err, "id", id)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing reservation", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully released reservation", "id", id, "by", action.By)
+
+	return s.GetReservation(id)
+}
+
+// DeleteReservation removes a reservation no outgoing consumed, release it
+// otherwise.
+func (s *inventoryService) DeleteReservation(id int) error {
+	var outgoings int
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`SELECT COUNT(*) FROM inventory_outgoings WHERE reservation_id = $1`,
+		id,
+	).Scan(&outgoings)
+	if err != nil {
+		slog.Error("Error counting reservation outgoings", "error", err)
+		return err
+	}
+	if outgoings > 0 {
+		return &ValidationError{Message: "reservation has outgoings, release it instead"}
+	}
+
+	_, err = s.db.ExecContext(
+		context.Background(),
+		`DELETE FROM inventory_reservations WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error deleting reservation", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted reservation", "id", id)
+
+	return nil
+}
+
+// lockReservation locks the reservation for the rest of the transaction and
+// returns it with its stored status and what outgoings consumed of it.
+func lockReservation(tx *sql.Tx, id int) (*models.InventoryReservation, float64, error) {
+	return readReservation(tx, id, true)
+}
+
+// readReservation is lockReservation that leaves the row unlocked when lock
+// is false, for previews that must not hold up writers.
+func readReservation(tx *sql.Tx, id int, lock bool) (*models.InventoryReservation, float64, error) {
+	forUpdate := ""
+	if lock {
+		forUpdate = "FOR UPDATE"
+	}
+
+	reservation := new(models.InventoryReservation)
+	var expired bool
+	err := tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			product_id,
+			COALESCE(incoming_id, 0),
+			COALESCE(job_order_id, 0),
+			status,
+			expires_at < CURRENT_DATE
+		FROM
+			inventory_reservations
+		WHERE
+			id = $1
+		`+forUpdate,
+		id,
+	).Scan(
+		&reservation.ProductID,
+		&reservation.IncomingID,
+		&reservation.JobOrderID,
+		&reservation.Status,
+		&expired,
+	)
+	if err != nil {
+		slog.Error("Error locking reservation", "error", err, "id", id)
+		return nil, 0, err
+	}
+	reservation.ID = id
+	if expired && reservation.Status == models.ReservationStatusActive {
+		reservation.Status = models.ReservationStatusExpired
+	}
+
+	var consumed float64
+	err = tx.QueryRowContext(
+		context.Background(),
+		`SELECT COALESCE(SUM(standard_quantity), 0) FROM inventory_outgoings WHERE reservation_id = $1`,
+		id,
+	).Scan(&consumed)
+	if err != nil {
+		slog.Error("Error summing reservation outgoings", "error", err, "id", id)
+		return nil, 0, err
+	}
+
+	return reservation, consumed, nil
+}
+
+// checkReservation locks the reservation an outgoing consumes and makes sure
+// it is of the same product, and of the same incoming when it was made for
+// one and the outgoing has one yet. A new reservation must still be active,
+// while the one an outgoing consumed before an edit, previousID, stays usable
+// after it expired or was released. The outgoing takes the job order of the
+// reservation when it has none. A preview passes lock false to check without
+// locking.
+//
+// Reservations are locked before incomings and incomings before products, so
+// callers must check the reservation first.
+func checkReservation(tx *sql.Tx, outgoing *models.InventoryOutgoing, previousID int, lock bool) (*models.InventoryReservation, error) {
+	if outgoing.ReservationID == 0 {
+		return nil, nil
+	}
+
+	reservation, _, err := readReservation(tx, outgoing.ReservationID, lock)
+	if err == sql.ErrNoRows {
+		return nil, &ValidationError{Message: "reservation does not exist"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if reservation.ProductID != outgoing.ProductID {
+		return nil, &ValidationError{Message: fmt.Sprintf("reservation %d is not for product %d", reservation.ID, outgoing.ProductID)}
+	}
+	if reservation.IncomingID != 0 && outgoing.IncomingID != 0 && reservation.IncomingID != outgoing.IncomingID {
+		return nil, &ValidationError{Message: fmt.Sprintf("reservation %d is for incoming %d", reservation.ID, reservation.IncomingID)}
+	}
+	if reservation.ID != previousID && reservation.Status != models.ReservationStatusActive {
+		return nil, &ValidationError{Message: fmt.Sprintf("reservation %d is %s", reservation.ID, reservation.Status)}
+	}
+
+	if outgoing.JobOrderID == 0 {
+		outgoing.JobOrderID = reservation.JobOrderID
+	}
+
+	return reservation, nil
+}
+
+// availableToPromise locks the product for the rest of the transaction and
+// returns how much of it, and of incomingID when that is set, is free to
+// promise: the balance less what active reservations other than
+// reservationID still hold. excludeOutgoingID is left out of the balance and
+// of what reservations consumed, so an outgoing can be edited.
+func availableToPromise(tx *sql.Tx, productID, incomingID, reservationID, excludeOutgoingID int) (float64, float64, error) {
+	_, err := tx.ExecContext(
+		context.Background(),
+		`SELECT id FROM inventory_products WHERE id = $1 FOR UPDATE`,
+		productID,
+	)
+	if err != nil {
+		slog.Error("Error locking product", "error", err, "product", productID)
+		return 0, 0, err
+	}
+
+	return promisable(tx, productID, incomingID, reservationID, excludeOutgoingID)
+}
+
+// promisable is availableToPromise without the lock on the product, for
+// previews that must not hold up writers.
+func promisable(tx *sql.Tx, productID, incomingID, reservationID, excludeOutgoingID int) (float64, float64, error) {
+	var product, incoming float64
+	err := tx.QueryRowContext(
+		context.Background(),
+		`
+		WITH reserved AS (
+			SELECT
+				r.incoming_id,
+				GREATEST(r.standard_quantity - COALESCE(SUM(o.standard_quantity), 0), 0) AS quantity
+			FROM
+				inventory_reservations r
+			LEFT JOIN
+				inventory_outgoings o
+			ON
+				o.reservation_id = r.id
+			AND
+				o.id <> $4
+			WHERE
+				r.product_id = $1
+			AND
+				r.id <> $3
+			AND
+				r.status = 'active'
+			AND
+				r.expires_at >= CURRENT_DATE
+			GROUP BY
+				r.id
+		)
+		SELECT
+			COALESCE((
+				SELECT
+					SUM(standard_quantity)
+				FROM
+					inventory_movements
+				WHERE
+					product_id = $1
+				AND NOT
+					(movement_type = 'outgoing' AND document_id = $4)
+			), 0) - COALESCE((SELECT SUM(quantity) FROM reserved), 0),
+			COALESCE((
+				SELECT
+					SUM(standard_quantity)
+				FROM
+					inventory_movements
+				WHERE
+					incoming_id = $2
+				AND NOT
+					(movement_type = 'outgoing' AND document_id = $4)
+			), 0) - COALESCE((SELECT SUM(quantity) FROM reserved WHERE incoming_id = $2), 0)
+		`,
+		productID,
+		incomingID,
+		reservationID,
+		excludeOutgoingID,
+	).Scan(&product, &incoming)
+	if err != nil {
+		slog.Error("Error working out available to promise", "error", err, "product", productID)
+		return 0, 0, err
+	}
+
+	return product, incoming, nil
+}
+
+// checkAvailableToPromise returns an InsufficientStockError when requested
+// would take stock promised to other reservations, on the incoming or on the
+// product as a whole.
+func checkAvailableToPromise(tx *sql.Tx, productID, incomingID, reservationID, excludeOutgoingID int, requested float64) error {
+	product, incoming, err := availableToPromise(tx, productID, incomingID, reservationID, excludeOutgoingID)
+	if err != nil {
+		return err
+	}
+
+	if incomingID != 0 && requested-incoming > stockTolerance {
+		slog.Error("Stock is reserved", "incoming", incomingID, "requested", requested, "available", incoming)
+		return &InsufficientStockError{
+			IncomingID: incomingID,
+			Requested:  requested,
+			Available:  incoming,
+		}
+	}
+
+	if requested-product > stockTolerance {
+		slog.Error("Stock is reserved", "product", productID, "requested", requested, "available", product)
+		return &InsufficientStockError{
+			ProductID: productID,
+			Requested: requested,
+			Available: product,
+		}
+	}
+
+	return nil
+}
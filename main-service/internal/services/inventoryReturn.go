@@ -0,0 +1,623 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+const returnQuery = `
+	SELECT
+		r.id,
+		r.return_no,
+		r.type,
+		r.product_id,
+		r.incoming_id,
+		COALESCE(r.outgoing_id, 0),
+		COALESCE(r.purchase_order_id, 0),
+		r.reason_code,
+		r.disposition,
+		COALESCE(i.location_id, 0),
+		r.quantity,
+		r.standard_quantity,
+		r.unit_cost,
+		r.ref_doc,
+		r.remarks,
+		r.returned_by,
+		r.returned_at,
+		p.code AS product_code,
+		p.name AS product_name,
+		p.standard_unit,
+		i.ref_no,
+		i.store_location,
+		COALESCE(o.ref_no, '') AS outgoing_ref_no,
+		COALESCE(po.po_no, '') AS po_no
+	FROM
+		inventory_returns r
+	JOIN
+		inventory_products p
+	ON
+		r.product_id = p.id
+	JOIN
+		inventory_incomings i
+	ON
+		r.incoming_id = i.id
+	LEFT JOIN
+		inventory_outgoings o
+	ON
+		r.outgoing_id = o.id
+	LEFT JOIN
+		purchase_orders po
+	ON
+		r.purchase_order_id = po.id
+	`
+
+func scanReturn(row interface{ Scan(...any) error }, ret *models.InventoryReturn) error {
+	err := row.Scan(
+		&ret.ID,
+		&ret.ReturnNo,
+		&ret.Type,
+		&ret.ProductID,
+		&ret.IncomingID,
+		&ret.OutgoingID,
+		&ret.PurchaseOrderID,
+		&ret.ReasonCode,
+		&ret.Disposition,
+		&ret.LocationID,
+		&ret.Quantity,
+		&ret.StandardQuantity,
+		&ret.UnitCost,
+		&ret.RefDoc,
+		&ret.Remarks,
+		&ret.ReturnedBy,
+		&ret.ReturnedAt,
+		&ret.ProductCode,
+		&ret.ProductName,
+		&ret.StandardUnit,
+		&ret.RefNo,
+		&ret.StoreLocation,
+		&ret.OutgoingRefNo,
+		&ret.PONo,
+	)
+	if err != nil {
+		return err
+	}
+
+	ret.Value = ret.StandardQuantity * ret.UnitCost
+
+	return nil
+}
+
+// Return
+func (s *inventoryService) GetReturnReasons() ([]*models.InventoryReturnReason, error) {
+	// execute query with context
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			code,
+			name,
+			is_exist
+		FROM
+			inventory_return_reasons
+		WHERE
+			is_exist = true
+		ORDER BY
+			name
+		`,
+	)
+	if err != nil {
+		slog.Error("Error querying return reasons", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reasons []*models.InventoryReturnReason
+	for rows.Next() {
+		reason := new(models.InventoryReturnReason)
+		if err := rows.Scan(&reason.Code, &reason.Name, &reason.IsExist); err != nil {
+			slog.Error("Error scanning return reason", "error", err)
+			return nil, err
+		}
+		reasons = append(reasons, reason)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating return reasons", "error", err)
+		return nil, err
+	}
+
+	return reasons, nil
+}
+
+// GetReturns lists returns, newest first, optionally only those of one type
+// or product.
+func (s *inventoryService) GetReturns(returnType string, productID int) ([]*models.InventoryReturn, error) {
+	// execute query with context
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		returnQuery+`
+		WHERE
+			($1 = '' OR r.type = $1)
+		AND
+			($2 = 0 OR r.product_id = $2)
+		ORDER BY
+			r.returned_at DESC,
+			r.id DESC
+		`,
+		returnType,
+		productID,
+	)
+	if err != nil {
+		slog.Error("Error querying returns", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var returns []*models.InventoryReturn
+	for rows.Next() {
+		ret := new(models.InventoryReturn)
+		if err := scanReturn(rows, ret); err != nil {
+			slog.Error("Error scanning return", "error", err)
+			return nil, err
+		}
+		returns = append(returns, ret)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating returns", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried returns", "count", len(returns))
+
+	return returns, nil
+}
+
+func (s *inventoryService) GetReturn(id int) (*models.InventoryReturn, error) {
+	ret := new(models.InventoryReturn)
+
+	// execute query with context
+	err := scanReturn(s.db.QueryRowContext(
+		context.Background(),
+		returnQuery+`
+		WHERE
+			r.id = $1
+		`,
+		id,
+	), ret)
+	if err != nil {
+		slog.Error("Error querying return", "error", err, "id", id)
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// CreateReturn numbers and books a customer or supplier return. Customer
+// returns are numbered CR and supplier returns SR.
+func (s *inventoryService) CreateReturn(ret *models.InventoryReturn) (*models.InventoryReturn, error) {
+	ret.ReasonCode = strings.TrimSpace(ret.ReasonCode)
+	ret.Disposition = strings.TrimSpace(ret.Disposition)
+	ret.RefDoc = strings.TrimSpace(ret.RefDoc)
+	ret.Remarks = strings.TrimSpace(ret.Remarks)
+
+	if ret.StandardQuantity <= 0 {
+		return nil, &ValidationError{Message: "standard quantity must be greater than zero"}
+	}
+
+	var prefix string
+	switch ret.Type {
+	case models.ReturnTypeCustomer:
+		prefix = "CR"
+	case models.ReturnTypeSupplier:
+		prefix = "SR"
+	default:
+		return nil, &ValidationError{Message: "unknown return type: " + ret.Type}
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(
+		context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM inventory_return_reasons WHERE code = $1 AND is_exist = true)`,
+		ret.ReasonCode,
+	).Scan(&exists)
+	if err != nil {
+		slog.Error("Error querying return reason", "error", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, &ValidationError{Message: "unknown return reason: " + ret.ReasonCode}
+	}
+
+	if ret.Type == models.ReturnTypeCustomer {
+		err = s.prepareCustomerReturn(tx, ret)
+		if err == nil {
+			err = returnSerials(tx, ret)
+		}
+	} else {
+		err = s.prepareSupplierReturn(tx, ret)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret.ReturnNo, err = nextDocumentNo(tx, prefix, time.Now().Year())
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		INSERT INTO inventory_returns (
+			return_no,
+			type,
+			product_id,
+			incoming_id,
+			outgoing_id,
+			purchase_order_id,
+			reason_code,
+			disposition,
+			quantity,
+			standard_quantity,
+			unit_cost,
+			ref_doc,
+			remarks,
+			returned_by
+		) VALUES (
+			$1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0), $7, $8, $9, $10, $11, $12, $13, $14
+		)
+		RETURNING id
+		`,
+		ret.ReturnNo,
+		ret.Type,
+		ret.ProductID,
+		ret.IncomingID,
+		ret.OutgoingID,
+		ret.PurchaseOrderID,
+		ret.ReasonCode,
+		ret.Disposition,
+		ret.Quantity,
+		ret.StandardQuantity,
+		ret.UnitCost,
+		ret.RefDoc,
+		ret.Remarks,
+		ret.ReturnedBy,
+	).Scan(&ret.ID)
+	if err != nil {
+		slog.Error("Error inserting return", "error", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing return", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully created return", "id", ret.ID, "returnNo", ret.ReturnNo, "type", ret.Type)
+
+	NotifyStockChanged(ret.ProductID)
+
+	return s.GetReturn(ret.ID)
+}
+
+// prepareCustomerReturn checks a customer return against the outgoing it was
+// issued on, which must not get back more than it issued, and works out the
+// incoming the stock goes back into. It is valued at the cost it was issued
+// at so the return reverses the outgoing.
+func (s *inventoryService) prepareCustomerReturn(tx *sql.Tx, ret *models.InventoryReturn) error {
+	if ret.OutgoingID == 0 {
+		return &ValidationError{Message: "outgoing is required for a customer return"}
+	}
+	if ret.Disposition == "" {
+		ret.Disposition = models.ReturnDispositionRestock
+	}
+	ret.PurchaseOrderID = 0
+
+	var issued, returned float64
+	var sourceIncomingID int
+	err := tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			product_id,
+			incoming_id,
+			standard_quantity,
+			unit_cost
+		FROM
+			inventory_outgoings
+		WHERE
+			id = $1
+		FOR UPDATE
+		`,
+		ret.OutgoingID,
+	).Scan(&ret.ProductID, &sourceIncomingID, &issued, &ret.UnitCost)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Message: "outgoing does not exist"}
+	}
+	if err != nil {
+		slog.Error("Error locking outgoing", "error", err, "outgoing", ret.OutgoingID)
+		return err
+	}
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			COALESCE(SUM(standard_quantity), 0)
+		FROM
+			inventory_returns
+		WHERE
+			outgoing_id = $1
+		AND
+			type = $2
+		`,
+		ret.OutgoingID,
+		models.ReturnTypeCustomer,
+	).Scan(&returned)
+	if err != nil {
+		slog.Error("Error summing returns", "error", err, "outgoing", ret.OutgoingID)
+		return err
+	}
+	if ret.StandardQuantity-(issued-returned) > stockTolerance {
+		return &InsufficientStockError{
+			ProductID: ret.ProductID,
+			Requested: ret.StandardQuantity,
+			Available: issued - returned,
+		}
+	}
+
+	switch ret.Disposition {
+	case models.ReturnDispositionRestock:
+		if ret.IncomingID == 0 {
+			ret.IncomingID = sourceIncomingID
+		}
+	case models.ReturnDispositionQuarantine:
+		if ret.LocationID == 0 {
+			return &ValidationError{Message: "location is required to quarantine a return"}
+		}
+		ret.IncomingID = 0
+	default:
+		return &ValidationError{Message: "unknown return disposition: " + ret.Disposition}
+	}
+
+	if ret.IncomingID != 0 {
+		var productID int
+		var status string
+		var quantity, standardQuantity, cost float64
+		err = tx.QueryRowContext(
+			context.Background(),
+			`
+			SELECT
+				product_id,
+				status,
+				quantity,
+				standard_quantity,
+				cost
+			FROM
+				inventory_incomings
+			WHERE
+				id = $1
+			FOR UPDATE
+			`,
+			ret.IncomingID,
+		).Scan(&productID, &status, &quantity, &standardQuantity, &cost)
+		if err == sql.ErrNoRows {
+			return &ValidationError{Message: "incoming does not exist"}
+		}
+		if err != nil {
+			slog.Error("Error locking incoming", "error", err, "incoming", ret.IncomingID)
+			return err
+		}
+		if productID != ret.ProductID {
+			return &ValidationError{Message: "incoming holds a different product"}
+		}
+		// the return is valued at what it was issued at, which another
+		// incoming's cost basis must agree with
+		if ret.IncomingID != sourceIncomingID && math.Abs(cost-ret.UnitCost) > stockTolerance {
+			return &ValidationError{Message: "incoming has a different cost from the stock returned, restock into the incoming it was issued from"}
+		}
+
+		// restocking a used up incoming makes it available again, stock not
+		// yet released for issue is no place to put it back
+		switch status {
+		case models.IncomingStatusAvailable:
+		case models.IncomingStatusDepleted:
+			err := s.transitionIncoming(tx, ret.IncomingID, models.IncomingStatusAvailable, ret.ReturnedBy, "customer return")
+			if err != nil {
+				return err
+			}
+		default:
+			return &ValidationError{Message: "incoming is " + status + ", restock into an available incoming or quarantine the return"}
+		}
+
+		// quantity is in the unit of the incoming, like a transfer's
+		ret.Quantity = ret.StandardQuantity
+		if standardQuantity != 0 {
+			ret.Quantity = ret.StandardQuantity * quantity / standardQuantity
+		}
+
+		return nil
+	}
+
+	// quarantined stock is split off the incoming it was issued from, keeping
+	// its ref no and received date, and starts empty as the return itself
+	// puts the stock in
+	destination := &models.InventoryIncoming{LocationID: ret.LocationID}
+	if err := s.resolveLocation(destination); err != nil {
+		return err
+	}
+
+	var quantity, standardQuantity float64
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			quantity,
+			standard_quantity
+		FROM
+			inventory_incomings
+		WHERE
+			id = $1
+		`,
+		sourceIncomingID,
+	).Scan(&quantity, &standardQuantity)
+	if err != nil {
+		slog.Error("Error querying incoming", "error", err, "incoming", sourceIncomingID)
+		return err
+	}
+	ret.Quantity = ret.StandardQuantity
+	if standardQuantity != 0 {
+		ret.Quantity = ret.StandardQuantity * quantity / standardQuantity
+	}
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		INSERT INTO inventory_incomings (
+			product_id,
+			status,
+			quantity,
+			length,
+			width,
+			height,
+			unit,
+			quantity_unit,
+			standard_quantity,
+			ref_no,
+			ref_doc,
+			cost,
+			store_location,
+			store_country,
+			remarks,
+			received_at,
+			location_id,
+			source_incoming_id,
+			supplier,
+			lot_no,
+			manufactured_at,
+			expires_at,
+			created_by,
+			updated_by
+		)
+		SELECT
+			product_id,
+			$2,
+			$3,
+			length,
+			width,
+			height,
+			unit,
+			quantity_unit,
+			$4,
+			ref_no,
+			ref_doc,
+			$5,
+			$6,
+			$7,
+			remarks,
+			received_at,
+			$8,
+			id,
+			supplier,
+			lot_no,
+			manufactured_at,
+			expires_at,
+			$9,
+			$9
+		FROM
+			inventory_incomings
+		WHERE
+			id = $1
+		RETURNING id
+		`,
+		sourceIncomingID,
+		models.IncomingStatusQCHold,
+		ret.Quantity,
+		ret.StandardQuantity,
+		ret.UnitCost,
+		destination.StoreLocation,
+		destination.StoreCountry,
+		ret.LocationID,
+		ret.ReturnedBy,
+	).Scan(&ret.IncomingID)
+	if err != nil {
+		slog.Error("Error creating quarantine incoming", "error", err)
+		return err
+	}
+
+	return recordIncomingStatus(tx, ret.IncomingID, "", models.IncomingStatusQCHold, ret.ReturnedBy, "customer return")
+}
+
+// prepareSupplierReturn checks a return to the supplier against the balance
+// of the incoming it is taken from, leaving what reservations hold alone. It
+// is valued at the cost of the incoming and linked to the purchase order the
+// incoming was received against.
+func (s *inventoryService) prepareSupplierReturn(tx *sql.Tx, ret *models.InventoryReturn) error {
+	if ret.IncomingID == 0 {
+		return &ValidationError{Message: "incoming is required for a supplier return"}
+	}
+	ret.OutgoingID = 0
+	ret.Disposition = ""
+
+	if err := s.checkIncomingBalance(tx, ret.IncomingID, 0, ret.StandardQuantity); err != nil {
+		return err
+	}
+
+	var quantity, standardQuantity float64
+	var purchaseOrderID int
+	err := tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			i.product_id,
+			i.quantity,
+			i.standard_quantity,
+			i.cost,
+			COALESCE(l.purchase_order_id, 0)
+		FROM
+			inventory_incomings i
+		LEFT JOIN
+			purchase_order_lines l
+		ON
+			i.purchase_order_line_id = l.id
+		WHERE
+			i.id = $1
+		`,
+		ret.IncomingID,
+	).Scan(&ret.ProductID, &quantity, &standardQuantity, &ret.UnitCost, &purchaseOrderID)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Message: "incoming does not exist"}
+	}
+	if err != nil {
+		slog.Error("Error querying incoming", "error", err, "incoming", ret.IncomingID)
+		return err
+	}
+
+	if ret.PurchaseOrderID == 0 {
+		ret.PurchaseOrderID = purchaseOrderID
+	}
+	if ret.PurchaseOrderID != purchaseOrderID {
+		return &ValidationError{Message: "incoming was not received against the purchase order"}
+	}
+
+	ret.Quantity = ret.StandardQuantity
+	if standardQuantity != 0 {
+		ret.Quantity = ret.StandardQuantity * quantity / standardQuantity
+	}
+
+	return checkAvailableToPromise(tx, ret.ProductID, ret.IncomingID, 0, 0, ret.StandardQuantity)
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+// incomingTransitions lists the statuses an incoming may move to from each
+// status. A depleted incoming comes back to available when stock is returned
+// into it.
+var incomingTransitions = map[string][]string{
+	models.IncomingStatusInTransit: {models.IncomingStatusReceived},
+	models.IncomingStatusReceived:  {models.IncomingStatusQCHold, models.IncomingStatusAvailable},
+	models.IncomingStatusQCHold:    {models.IncomingStatusAvailable, models.IncomingStatusClosed},
+	models.IncomingStatusAvailable: {models.IncomingStatusQCHold, models.IncomingStatusDepleted, models.IncomingStatusClosed},
+	models.IncomingStatusDepleted:  {models.IncomingStatusAvailable, models.IncomingStatusClosed},
+	models.IncomingStatusClosed:    {},
+}
+
+// legacyIncomingStatuses maps the free text statuses used before the state
+// machine, which older clients still send.
+var legacyIncomingStatuses = map[string]string{
+	"incoming":  models.IncomingStatusInTransit,
+	"in-stock":  models.IncomingStatusAvailable,
+	"returned":  models.IncomingStatusQCHold,
+	"clearance": models.IncomingStatusAvailable,
+}
+
+// normaliseIncomingStatus returns the state machine status for status and
+// whether it is one.
+func normaliseIncomingStatus(status string) (string, bool) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if legacy, ok := legacyIncomingStatuses[status]; ok {
+		status = legacy
+	}
+	_, ok := incomingTransitions[status]
+	return status, ok
+}
+
+func canTransitionIncoming(from, to string) bool {
+	for _, status := range incomingTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// recordIncomingStatus writes a row of the status history of an incoming.
+func recordIncomingStatus(tx *sql.Tx, incomingID int, from, to, by, remarks string) error {
+	_, err := tx.ExecContext(
+		context.Background(),
+		`
+		INSERT INTO inventory_incoming_status_history (
+			incoming_id,
+			from_status,
+			to_status,
+			remarks,
+			changed_by
+		) VALUES (
+			$1, $2, $3, $4, $5
+		)
+		`,
+		incomingID,
+		from,
+		to,
+		strings.TrimSpace(remarks),
+		by,
+	)
+	if err != nil {
+		slog.Error("Error inserting incoming status history", "error", err, "incoming", incomingID)
+		return err
+	}
+
+	return nil
+}
+
+// transitionIncoming locks the incoming and moves it to status to, recording
+// the change. Moving to its current status changes nothing. An incoming is
+// only depleted or closed once nothing is left in it.
+func (s *inventoryService) transitionIncoming(tx *sql.Tx, id int, to, by, remarks string) error {
+	var from string
+	err := tx.QueryRowContext(
+		context.Background(),
+		`SELECT status FROM inventory_incomings WHERE id = $1 FOR UPDATE`,
+		id,
+	).Scan(&from)
+	if err != nil {
+		slog.Error("Error locking incoming", "error", err, "incoming", id)
+		return err
+	}
+
+	if from == to {
+		return nil
+	}
+	if !canTransitionIncoming(from, to) {
+		return &ValidationError{Message: fmt.Sprintf("incoming %d cannot move from %s to %s", id, from, to)}
+	}
+
+	if to == models.IncomingStatusDepleted || to == models.IncomingStatusClosed {
+		var balance float64
+		err := tx.QueryRowContext(
+			context.Background(),
+			`SELECT COALESCE(SUM(standard_quantity), 0) FROM inventory_movements WHERE incoming_id = $1`,
+			id,
+		).Scan(&balance)
+		if err != nil {
+			slog.Error("Error summing incoming balance", "error", err, "incoming", id)
+			return err
+		}
+		if balance > stockTolerance {
+			return &ValidationError{Message: fmt.Sprintf("incoming %d still holds %v and cannot be %s", id, balance, to)}
+		}
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_incomings
+		SET
+			status = $1,
+			updated_by = $2,
+			updated_at = NOW()
+		WHERE
+			id = $3
+		`,
+		to,
+		by,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating incoming status", "error", err, "incoming", id)
+		return err
+	}
+
+	return recordIncomingStatus(tx, id, from, to, by, remarks)
+}
+
+// restoreDepletedIncoming moves a depleted incoming back to available once it
+// holds stock again, as when an outgoing from it is reduced or deleted or
+// stock is found on it.
+func (s *inventoryService) restoreDepletedIncoming(tx *sql.Tx, incomingID int, by, remarks string) error {
+	var status string
+	var balance float64
+	err := tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			i.status,
+			COALESCE((SELECT SUM(m.standard_quantity) FROM inventory_movements m WHERE m.incoming_id = i.id), 0)
+		FROM
+			inventory_incomings i
+		WHERE
+			i.id = $1
+		`,
+		incomingID,
+	).Scan(&status, &balance)
+	if err != nil {
+		slog.Error("Error querying incoming balance", "error", err, "incoming", incomingID)
+		return err
+	}
+
+	if status != models.IncomingStatusDepleted || balance <= stockTolerance {
+		return nil
+	}
+
+	return s.transitionIncoming(tx, incomingID, models.IncomingStatusAvailable, by, remarks)
+}
+
+// checkIncomingIssuable returns a ValidationError unless the incoming is
+// available, stock still in transit, on QC hold or closed cannot be issued.
+func checkIncomingIssuable(tx *sql.Tx, incomingID int) error {
+	var status string
+	err := tx.QueryRowContext(
+		context.Background(),
+		`SELECT status FROM inventory_incomings WHERE id = $1`,
+		incomingID,
+	).Scan(&status)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Message: "incoming does not exist"}
+	}
+	if err != nil {
+		slog.Error("Error querying incoming status", "error", err, "incoming", incomingID)
+		return err
+	}
+
+	if status != models.IncomingStatusAvailable {
+		return &ValidationError{Message: fmt.Sprintf("incoming %d is %s, only available stock can be issued", incomingID, status)}
+	}
+
+	return nil
+}
+
+// Incoming status
+func (s *inventoryService) GetIncomingStatusHistory(id int) ([]*models.IncomingStatusChange, error) {
+	// execute query with context
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			id,
+			incoming_id,
+			from_status,
+			to_status,
+			remarks,
+			changed_by,
+			changed_at
+		FROM
+			inventory_incoming_status_history
+		WHERE
+			incoming_id = $1
+		ORDER BY
+			changed_at ASC,
+			id ASC
+		`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error querying incoming status history", "error", err, "incoming", id)
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*models.IncomingStatusChange{}
+	for rows.Next() {
+		change := new(models.IncomingStatusChange)
+		err := rows.Scan(
+			&change.ID,
+			&change.IncomingID,
+			&change.FromStatus,
+			&change.ToStatus,
+			&change.Remarks,
+			&change.ChangedBy,
+			&change.ChangedAt,
+		)
+		if err != nil {
+			slog.Error("Error scanning incoming status change", "error", err)
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating incoming status history", "error", err)
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (s *inventoryService) TransitionIncoming(id int, transition *models.IncomingStatusTransition) (*models.InventoryIncoming, error) {
+	status, err := validateIncomingTransition(transition)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.transitionIncoming(tx, id, status, transition.By, transition.Remarks); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing incoming status", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully transitioned incoming", "incoming", id, "status", status, "by", transition.By)
+
+	return s.GetIncoming(id)
+}
+
+// TransitionShipment moves every incoming with the ref no of a shipment to
+// the same status, all of them or none. Incomings already at the status are
+// left alone.
+func (s *inventoryService) TransitionShipment(transition *models.IncomingStatusTransition) ([]*models.InventoryIncoming, error) {
+	transition.RefNo = strings.TrimSpace(transition.RefNo)
+	if transition.RefNo == "" {
+		return nil, &ValidationError{Message: "ref no is required"}
+	}
+
+	status, err := validateIncomingTransition(transition)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			id
+		FROM
+			inventory_incomings
+		WHERE
+			ref_no = $1
+		ORDER BY
+			id
+		FOR UPDATE
+		`,
+		transition.RefNo,
+	)
+	if err != nil {
+		slog.Error("Error locking shipment incomings", "error", err, "refNo", transition.RefNo)
+		return nil, err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			slog.Error("Error scanning shipment incoming", "error", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating shipment incomings", "error", err)
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, &ValidationError{Message: "no incomings with ref no " + transition.RefNo}
+	}
+
+	for _, id := range ids {
+		if err := s.transitionIncoming(tx, id, status, transition.By, transition.Remarks); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing shipment status", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully transitioned shipment", "refNo", transition.RefNo, "status", status, "incomings", len(ids))
+
+	incomings := make([]*models.InventoryIncoming, 0, len(ids))
+	for _, id := range ids {
+		incoming, err := s.GetIncoming(id)
+		if err != nil {
+			return nil, err
+		}
+		incomings = append(incomings, incoming)
+	}
+
+	return incomings, nil
+}
+
+func validateIncomingTransition(transition *models.IncomingStatusTransition) (string, error) {
+	transition.By = strings.TrimSpace(transition.By)
+	if transition.By == "" {
+		return "", &ValidationError{Message: "user making the change is required"}
+	}
+
+	status, ok := normaliseIncomingStatus(transition.Status)
+	if !ok {
+		return "", &ValidationError{Message: "unknown incoming status: " + transition.Status}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,404 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"math"
+	"strings"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+const transferQuery = `
+	SELECT
+		t.id,
+		t.product_id,
+		t.from_incoming_id,
+		t.to_incoming_id,
+		COALESCE(t.from_location_id, 0),
+		COALESCE(t.to_location_id, 0),
+		t.quantity,
+		t.standard_quantity,
+		t.unit_cost,
+		t.reason,
+		t.remarks,
+		t.transferred_by,
+		t.transferred_at,
+		t.created_at,
+		p.code AS product_code,
+		p.name AS product_name,
+		p.standard_unit,
+		fi.store_location AS from_store_location,
+		ti.store_location AS to_store_location
+	FROM
+		inventory_transfers t
+	JOIN
+		inventory_products p
+	ON
+		t.product_id = p.id
+	JOIN
+		inventory_incomings fi
+	ON
+		t.from_incoming_id = fi.id
+	JOIN
+		inventory_incomings ti
+	ON
+		t.to_incoming_id = ti.id
+	`
+
+func scanTransfer(row interface{ Scan(...any) error }, transfer *models.InventoryTransfer) error {
+	return row.Scan(
+		&transfer.ID,
+		&transfer.ProductID,
+		&transfer.FromIncomingID,
+		&transfer.ToIncomingID,
+		&transfer.FromLocationID,
+		&transfer.ToLocationID,
+		&transfer.Quantity,
+		&transfer.StandardQuantity,
+		&transfer.UnitCost,
+		&transfer.Reason,
+		&transfer.Remarks,
+		&transfer.TransferredBy,
+		&transfer.TransferredAt,
+		&transfer.CreatedAt,
+		&transfer.ProductCode,
+		&transfer.ProductName,
+		&transfer.StandardUnit,
+		&transfer.FromStoreLocation,
+		&transfer.ToStoreLocation,
+	)
+}
+
+// Transfers
+func (s *inventoryService) GetTransfers() ([]*models.InventoryTransfer, error) {
+	// execute query with context
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		transferQuery+`
+		ORDER BY
+			t.transferred_at DESC,
+			t.id DESC
+		`,
+	)
+	if err != nil {
+		slog.Error("Error querying transfers", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []*models.InventoryTransfer
+	for rows.Next() {
+		transfer := new(models.InventoryTransfer)
+		if err := scanTransfer(rows, transfer); err != nil {
+			slog.Error("Error scanning transfer", "error", err)
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating transfers", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried transfers", "count", len(transfers))
+
+	return transfers, nil
+}
+
+func (s *inventoryService) GetTransfer(id int) (*models.InventoryTransfer, error) {
+	transfer := new(models.InventoryTransfer)
+
+	// execute query with context
+	err := scanTransfer(s.db.QueryRowContext(
+		context.Background(),
+		transferQuery+`
+		WHERE
+			t.id = $1
+		`,
+		id,
+	), transfer)
+	if err != nil {
+		slog.Error("Error querying transfer", "error", err, "id", id)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried transfer", "id", id)
+
+	return transfer, nil
+}
+
+// CreateTransfer moves standard quantity out of one incoming into another
+// location. When no destination incoming is given, a new one is split off the
+// source at the destination location, keeping its ref no, status, cost and
+// received date so aging and costing carry on from the original receipt.
+func (s *inventoryService) CreateTransfer(transfer *models.InventoryTransfer) (*models.InventoryTransfer, error) {
+	transfer.Reason = strings.TrimSpace(transfer.Reason)
+	transfer.Remarks = strings.TrimSpace(transfer.Remarks)
+
+	if transfer.FromIncomingID == 0 {
+		return nil, &ValidationError{Message: "source incoming is required"}
+	}
+	if transfer.FromIncomingID == transfer.ToIncomingID {
+		return nil, &ValidationError{Message: "source and destination incoming must differ"}
+	}
+	if transfer.StandardQuantity <= 0 {
+		return nil, &ValidationError{Message: "standard quantity must be greater than zero"}
+	}
+	if transfer.ToIncomingID == 0 && transfer.ToLocationID == 0 {
+		return nil, &ValidationError{Message: "destination incoming or location is required"}
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.checkIncomingBalance(tx, transfer.FromIncomingID, 0, transfer.StandardQuantity); err != nil {
+		return nil, err
+	}
+
+	var source models.InventoryIncoming
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			product_id,
+			quantity,
+			standard_quantity,
+			cost,
+			COALESCE(location_id, 0),
+			received_at::TEXT,
+			lot_no
+		FROM
+			inventory_incomings
+		WHERE
+			id = $1
+		`,
+		transfer.FromIncomingID,
+	).Scan(
+		&source.ProductID,
+		&source.Quantity,
+		&source.StandardQuantity,
+		&source.Cost,
+		&source.LocationID,
+		&source.ReceivedAt,
+		&source.LotNo,
+	)
+	if err != nil {
+		slog.Error("Error querying source incoming", "error", err, "incoming", transfer.FromIncomingID)
+		return nil, err
+	}
+
+	transfer.ProductID = source.ProductID
+	transfer.FromLocationID = source.LocationID
+	transfer.UnitCost = source.Cost
+
+	// quantity is in the unit of the source incoming, derive it from the
+	// standard quantity when only that was given
+	if transfer.Quantity == 0 && source.StandardQuantity != 0 {
+		transfer.Quantity = transfer.StandardQuantity * source.Quantity / source.StandardQuantity
+	}
+
+	if transfer.ToIncomingID != 0 {
+		var destination models.InventoryIncoming
+		err = tx.QueryRowContext(
+			context.Background(),
+			`
+			SELECT
+				product_id,
+				COALESCE(location_id, 0),
+				cost,
+				received_at::TEXT,
+				lot_no
+			FROM
+				inventory_incomings
+			WHERE
+				id = $1
+			FOR UPDATE
+			`,
+			transfer.ToIncomingID,
+		).Scan(&destination.ProductID, &destination.LocationID, &destination.Cost, &destination.ReceivedAt, &destination.LotNo)
+		if err == sql.ErrNoRows {
+			return nil, &ValidationError{Message: "destination incoming does not exist"}
+		}
+		if err != nil {
+			slog.Error("Error locking destination incoming", "error", err, "incoming", transfer.ToIncomingID)
+			return nil, err
+		}
+		if destination.ProductID != transfer.ProductID {
+			return nil, &ValidationError{Message: "destination incoming holds a different product"}
+		}
+		// merged stock takes on the destination's cost and age, so only stock
+		// that already matches may be merged
+		if math.Abs(destination.Cost-source.Cost) > stockTolerance ||
+			destination.ReceivedAt != source.ReceivedAt ||
+			destination.LotNo != source.LotNo {
+			return nil, &ValidationError{Message: "destination incoming differs in cost, received date or lot, transfer to a location instead"}
+		}
+		transfer.ToLocationID = destination.LocationID
+	} else {
+		if transfer.ToLocationID == transfer.FromLocationID {
+			return nil, &ValidationError{Message: "destination location must differ from the source location"}
+		}
+
+		var status string
+		destination := &models.InventoryIncoming{LocationID: transfer.ToLocationID}
+		if err := s.resolveLocation(destination); err != nil {
+			return nil, err
+		}
+
+		err = tx.QueryRowContext(
+			context.Background(),
+			`
+			INSERT INTO inventory_incomings (
+				product_id,
+				status,
+				quantity,
+				length,
+				width,
+				height,
+				unit,
+				quantity_unit,
+				standard_quantity,
+				ref_no,
+				ref_doc,
+				cost,
+				store_location,
+				store_country,
+				remarks,
+				received_at,
+				location_id,
+				source_incoming_id,
+				supplier,
+				lot_no,
+				manufactured_at,
+				expires_at,
+				created_by,
+				updated_by
+			)
+			SELECT
+				product_id,
+				status,
+				$2,
+				length,
+				width,
+				height,
+				unit,
+				quantity_unit,
+				$3,
+				ref_no,
+				ref_doc,
+				cost,
+				$4,
+				$5,
+				remarks,
+				received_at,
+				$6,
+				id,
+				supplier,
+				lot_no,
+				manufactured_at,
+				expires_at,
+				$7,
+				$7
+			FROM
+				inventory_incomings
+			WHERE
+				id = $1
+			RETURNING id, status
+			`,
+			transfer.FromIncomingID,
+			transfer.Quantity,
+			transfer.StandardQuantity,
+			destination.StoreLocation,
+			destination.StoreCountry,
+			transfer.ToLocationID,
+			transfer.TransferredBy,
+		).Scan(&transfer.ToIncomingID, &status)
+		if err != nil {
+			slog.Error("Error creating destination incoming", "error", err)
+			return nil, err
+		}
+
+		if err := recordIncomingStatus(tx, transfer.ToIncomingID, "", status, transfer.TransferredBy, "transfer"); err != nil {
+			return nil, err
+		}
+	}
+
+	// serialised items travel with the stock
+	if err := moveSerials(tx, transfer.FromIncomingID, transfer.ToIncomingID, transfer.StandardQuantity); err != nil {
+		return nil, err
+	}
+
+	// stock reserved on the source incoming stays where it was promised, the
+	// product is locked after both incomings like every other writer does
+	_, free, err := availableToPromise(tx, transfer.ProductID, transfer.FromIncomingID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.StandardQuantity-free > stockTolerance {
+		return nil, &InsufficientStockError{
+			IncomingID: transfer.FromIncomingID,
+			Requested:  transfer.StandardQuantity,
+			Available:  free,
+		}
+	}
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		INSERT INTO inventory_transfers (
+			product_id,
+			from_incoming_id,
+			to_incoming_id,
+			from_location_id,
+			to_location_id,
+			quantity,
+			standard_quantity,
+			unit_cost,
+			reason,
+			remarks,
+			transferred_by
+		) VALUES (
+			$1, $2, $3, NULLIF($4, 0), NULLIF($5, 0), $6, $7, $8, $9, $10, $11
+		)
+		RETURNING id
+		`,
+		transfer.ProductID,
+		transfer.FromIncomingID,
+		transfer.ToIncomingID,
+		transfer.FromLocationID,
+		transfer.ToLocationID,
+		transfer.Quantity,
+		transfer.StandardQuantity,
+		transfer.UnitCost,
+		transfer.Reason,
+		transfer.Remarks,
+		transfer.TransferredBy,
+	).Scan(&transfer.ID)
+	if err != nil {
+		slog.Error("Error inserting transfer", "error", err)
+		return nil, err
+	}
+
+	// a depleted destination holds stock again
+	if err := s.restoreDepletedIncoming(tx, transfer.ToIncomingID, transfer.TransferredBy, "transfer"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing transfer", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully created transfer", "id", transfer.ID, "from", transfer.FromIncomingID, "to", transfer.ToIncomingID)
+
+	NotifyStockChanged(transfer.ProductID)
+
+	return s.GetTransfer(transfer.ID)
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/lib/pq"
+)
+
+// applyProductParent checks the parent of a variant and gives the variant the
+// brand, supplier and category it shares with its parent. id is the product
+// being saved, 0 for a new one.
+func applyProductParent(tx *sql.Tx, id int, product *models.InventoryProduct) error {
+	if product.ParentID == 0 {
+		return nil
+	}
+	if product.ParentID == id {
+		return &ValidationError{Message: "a product cannot be its own parent"}
+	}
+
+	var (
+		grandparentID int
+		standardUnit  string
+	)
+	err := tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			COALESCE(parent_id, 0),
+			brand,
+			supplier,
+			COALESCE(category_id, 0),
+			standard_unit
+		FROM
+			inventory_products
+		WHERE
+			id = $1
+		FOR UPDATE
+		`,
+		product.ParentID,
+	).Scan(&grandparentID, &product.Brand, &product.Supplier, &product.CategoryID, &standardUnit)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &ValidationError{Message: "parent product does not exist"}
+	}
+	if err != nil {
+		slog.Error("Error querying parent product", "error", err, "parent", product.ParentID)
+		return err
+	}
+	if grandparentID != 0 {
+		return &ValidationError{Message: "a variant cannot be a parent product"}
+	}
+
+	// the roll up adds variant quantities together
+	if product.StandardUnit != standardUnit {
+		return &ValidationError{Message: "a variant must have the standard unit of its parent, " + standardUnit}
+	}
+
+	if id != 0 {
+		var hasVariants bool
+		err := tx.QueryRowContext(
+			context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM inventory_products WHERE parent_id = $1)`,
+			id,
+		).Scan(&hasVariants)
+		if err != nil {
+			slog.Error("Error checking product variants", "error", err, "product", id)
+			return err
+		}
+		if hasVariants {
+			return &ValidationError{Message: "a product with variants cannot be a variant"}
+		}
+	}
+
+	return nil
+}
+
+// syncProductVariants runs once a product is saved. A parent passes its
+// brand, supplier and category down to its variants, whose attributes are
+// then kept to the ones that category has. Either way the variants of the
+// family are checked against the axes.
+func syncProductVariants(tx *sql.Tx, product *models.InventoryProduct) error {
+	if product.ParentID != 0 {
+		if len(product.VariantAxes) > 0 {
+			return &ValidationError{Message: "a variant cannot have variant axes"}
+		}
+		return checkVariantAxes(tx, product.ParentID)
+	}
+
+	if len(product.VariantAxes) > 0 {
+		allowed, err := categoryAttributes(tx, product.CategoryID)
+		if err != nil {
+			return err
+		}
+		codes := make(map[string]bool, len(allowed))
+		for _, attribute := range allowed {
+			codes[attribute.Code] = true
+		}
+		for _, axis := range product.VariantAxes {
+			if !codes[axis] {
+				return &ValidationError{Message: "variant axis " + axis + " is not an attribute of the category of the product"}
+			}
+		}
+	}
+
+	rows, err := tx.QueryContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_products
+		SET
+			brand = $1,
+			supplier = $2,
+			category_id = NULLIF($3, 0),
+			updated_by = $4,
+			updated_at = NOW()
+		WHERE
+			parent_id = $5
+			AND (brand, supplier, COALESCE(category_id, 0)) IS DISTINCT FROM ($1, $2, $3)
+		RETURNING id
+		`,
+		product.Brand,
+		product.Supplier,
+		product.CategoryID,
+		product.UpdatedBy,
+		product.ID,
+	)
+	if err != nil {
+		slog.Error("Error updating product variants", "error", err, "product", product.ID)
+		return err
+	}
+
+	var variantIDs []int
+	for rows.Next() {
+		var variantID int
+		if err := rows.Scan(&variantID); err != nil {
+			rows.Close()
+			slog.Error("Error scanning product variant", "error", err)
+			return err
+		}
+		variantIDs = append(variantIDs, variantID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		slog.Error("Error updating product variants", "error", err)
+		return err
+	}
+
+	for _, variantID := range variantIDs {
+		variant := &models.InventoryProduct{ID: variantID, CategoryID: product.CategoryID}
+		if err := saveProductAttributes(tx, variant); err != nil {
+			return err
+		}
+	}
+
+	return checkVariantAxes(tx, product.ID)
+}
+
+// checkVariantAxes makes sure every variant of a parent has a value for each
+// of its axes and that no two variants have the same values.
+func checkVariantAxes(tx *sql.Tx, parentID int) error {
+	var axes []string
+	err := tx.QueryRowContext(
+		context.Background(),
+		`SELECT variant_axes FROM inventory_products WHERE id = $1`,
+		parentID,
+	).Scan(pq.Array(&axes))
+	if err != nil {
+		slog.Error("Error querying variant axes", "error", err, "parent", parentID)
+		return err
+	}
+	if len(axes) == 0 {
+		return nil
+	}
+
+	var code, axis string
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			v.code,
+			ax.code
+		FROM
+			inventory_products v
+		CROSS JOIN
+			UNNEST($2::TEXT[]) AS ax(code)
+		WHERE
+			v.parent_id = $1
+			AND NOT EXISTS (
+				SELECT
+					1
+				FROM
+					inventory_product_attributes pa
+				JOIN
+					product_category_attributes a
+				ON
+					pa.attribute_id = a.id
+				WHERE
+					pa.product_id = v.id
+					AND a.code = ax.code
+			)
+		ORDER BY
+			v.code,
+			ax.code
+		LIMIT 1
+		`,
+		parentID,
+		pq.Array(axes),
+	).Scan(&code, &axis)
+	switch {
+	case err == nil:
+		return &ValidationError{Message: "variant " + code + " has no value for " + axis}
+	case !errors.Is(err, sql.ErrNoRows):
+		slog.Error("Error checking variant axes", "error", err, "parent", parentID)
+		return err
+	}
+
+	var codes string
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			STRING_AGG(code, ', ' ORDER BY code)
+		FROM (
+			SELECT
+				v.code,
+				(
+					SELECT
+						STRING_AGG(a.code || '=' || LOWER(pa.value), ';' ORDER BY a.code)
+					FROM
+						inventory_product_attributes pa
+					JOIN
+						product_category_attributes a
+					ON
+						pa.attribute_id = a.id
+					WHERE
+						pa.product_id = v.id
+						AND a.code = ANY($2)
+				) AS combination
+			FROM
+				inventory_products v
+			WHERE
+				v.parent_id = $1
+		) c
+		GROUP BY
+			combination
+		HAVING
+			COUNT(*) > 1
+		LIMIT 1
+		`,
+		parentID,
+		pq.Array(axes),
+	).Scan(&codes)
+	switch {
+	case err == nil:
+		return &ValidationError{Message: "variants " + codes + " have the same " + strings.Join(axes, ", ")}
+	case !errors.Is(err, sql.ErrNoRows):
+		slog.Error("Error checking variant combinations", "error", err, "parent", parentID)
+		return err
+	}
+
+	return nil
+}
+
+// GetProductVariants lists the variants of a parent with their attributes, so
+// the values on its axes can be shown.
+func (s *inventoryService) GetProductVariants(id int) ([]*models.InventoryProduct, error) {
+	variants, err := s.GetProducts(&models.InventoryProductFilter{ParentID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, variant := range variants {
+		variant.Attributes, err = getProductAttributes(s.db, variant.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return variants, nil
+}
+
+// LinkProductVariant makes an existing product a variant of a parent. The
+// product keeps its id, so its incomings and outgoings stay as they are; its
+// attributes are kept to the ones the parent's category has, with the ones
+// given on the link set over them.
+func (s *inventoryService) LinkProductVariant(parentID int, link *models.ProductVariantLink) (*models.InventoryProduct, error) {
+	product, err := s.GetProduct(link.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := s.GetProduct(parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &ValidationError{Message: "parent product does not exist"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := categoryAttributes(s.db, parent.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	codes := make(map[string]bool, len(allowed))
+	for _, attribute := range allowed {
+		codes[attribute.Code] = true
+	}
+
+	given := make(map[string]bool, len(link.Attributes))
+	attributes := []*models.ProductAttributeValue{}
+	for _, value := range link.Attributes {
+		value.Code = strings.ToLower(strings.TrimSpace(value.Code))
+		given[value.Code] = true
+		attributes = append(attributes, value)
+	}
+	for _, value := range product.Attributes {
+		if codes[value.Code] && !given[value.Code] {
+			attributes = append(attributes, value)
+		}
+	}
+
+	product.ParentID = parentID
+	product.Attributes = attributes
+	product.UpdatedBy = link.UpdatedBy
+
+	return s.UpdateProduct(product.ID, product)
+}
+
+// UnlinkProductVariant makes a variant a product of its own again. It keeps
+// the brand, supplier, category and attributes it had as a variant.
+func (s *inventoryService) UnlinkProductVariant(parentID, variantID int) error {
+	result, err := s.db.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_products
+		SET
+			parent_id = NULL,
+			updated_at = NOW()
+		WHERE
+			id = $1
+			AND parent_id = $2
+		`,
+		variantID,
+		parentID,
+	)
+	if err != nil {
+		slog.Error("Error unlinking product variant", "error", err, "variant", variantID)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		slog.Error("Error unlinking product variant", "error", err)
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	slog.Info("Successfully unlinked product variant", "parent", parentID, "variant", variantID)
+
+	return nil
+}
+
+// GetProductRollup is the product summary with variants nested under their
+// parent, whose totals take in its own stock and that of its variants. A
+// variant whose parent the filter leaves out stays at the top level.
+func (s *inventoryService) GetProductRollup(asOf *time.Time, filter *models.InventoryProductFilter) ([]*models.InventoryProductSummary, error) {
+	summaries, err := s.GetProductSummary(asOf, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*models.InventoryProductSummary, len(summaries))
+	for _, summary := range summaries {
+		byID[summary.ID] = summary
+	}
+
+	rollup := []*models.InventoryProductSummary{}
+	for _, summary := range summaries {
+		parent, ok := byID[summary.ParentID]
+		if summary.ParentID == 0 || !ok {
+			rollup = append(rollup, summary)
+			continue
+		}
+
+		parent.Variants = append(parent.Variants, summary)
+		parent.TotalIncoming += summary.TotalIncoming
+		parent.TotalOutgoing += summary.TotalOutgoing
+		parent.TotalBalance += summary.TotalBalance
+		addOptional(parent.TotalReserved, summary.TotalReserved)
+		addOptional(parent.TotalAvailable, summary.TotalAvailable)
+		addOptional(parent.OnOrder, summary.OnOrder)
+	}
+
+	return rollup, nil
+}
+
+// addOptional adds value to total when both are known.
+func addOptional(total, value *float64) {
+	if total != nil && value != nil {
+		*total += *value
+	}
+}
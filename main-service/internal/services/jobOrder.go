@@ -0,0 +1,734 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/db"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+type JobOrderService interface {
+	GetCustomers() ([]*models.Customer, error)
+	GetCustomer(id int) (*models.Customer, error)
+	CreateCustomer(customer *models.Customer) (*models.Customer, error)
+	UpdateCustomer(id int, customer *models.Customer) (*models.Customer, error)
+	DeleteCustomer(id int) error
+
+	GetJobOrders(customerID int, status string) ([]*models.JobOrder, error)
+	GetJobOrder(id int) (*models.JobOrder, error)
+	CreateJobOrder(job *models.JobOrder) (*models.JobOrder, error)
+	UpdateJobOrder(id int, job *models.JobOrder) (*models.JobOrder, error)
+	DeleteJobOrder(id int) error
+
+	GetJobCosting(id int) (*models.JobCosting, error)
+	GetJobCostingReport(filter *models.JobCostingFilter, from, to *time.Time) (*models.JobCostingReport, error)
+}
+
+type jobOrderService struct {
+	db *sql.DB
+}
+
+func NewJobOrderService() JobOrderService {
+	return &jobOrderService{
+		db: db.GetDB(),
+	}
+}
+
+const customerQuery = `
+	SELECT
+		id,
+		code,
+		name,
+		contact_name,
+		email,
+		phone,
+		address,
+		remarks,
+		is_exist,
+		created_by,
+		created_at,
+		updated_by,
+		updated_at
+	FROM
+		customers
+	`
+
+func scanCustomer(row interface{ Scan(...any) error }, customer *models.Customer) error {
+	return row.Scan(
+		&customer.ID,
+		&customer.Code,
+		&customer.Name,
+		&customer.ContactName,
+		&customer.Email,
+		&customer.Phone,
+		&customer.Address,
+		&customer.Remarks,
+		&customer.IsExist,
+		&customer.CreatedBy,
+		&customer.CreatedAt,
+		&customer.UpdatedBy,
+		&customer.UpdatedAt,
+	)
+}
+
+// Customer
+func (s *jobOrderService) GetCustomers() ([]*models.Customer, error) {
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		customerQuery+`
+		ORDER BY
+			name
+		`,
+	)
+	if err != nil {
+		slog.Error("Error querying customers", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := []*models.Customer{}
+	for rows.Next() {
+		customer := new(models.Customer)
+		if err := scanCustomer(rows, customer); err != nil {
+			slog.Error("Error scanning customer", "error", err)
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating customers", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried customers", "count", len(customers))
+
+	return customers, nil
+}
+
+func (s *jobOrderService) GetCustomer(id int) (*models.Customer, error) {
+	customer := new(models.Customer)
+	err := scanCustomer(s.db.QueryRowContext(
+		context.Background(),
+		customerQuery+`
+		WHERE
+			id = $1
+		`,
+		id,
+	), customer)
+	if err != nil {
+		slog.Error("Error querying customer", "error", err, "id", id)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried customer", "id", id)
+
+	return customer, nil
+}
+
+func validateCustomer(customer *models.Customer) error {
+	customer.Code = strings.ToUpper(strings.TrimSpace(customer.Code))
+	customer.Name = strings.TrimSpace(customer.Name)
+
+	if customer.Code == "" {
+		return &ValidationError{Message: "customer code is required"}
+	}
+	if customer.Name == "" {
+		return &ValidationError{Message: "customer name is required"}
+	}
+
+	return nil
+}
+
+func (s *jobOrderService) CreateCustomer(customer *models.Customer) (*models.Customer, error) {
+	if err := validateCustomer(customer); err != nil {
+		return nil, err
+	}
+
+	queryStr := `
+		INSERT INTO customers (
+			code,
+			name,
+			contact_name,
+			email,
+			phone,
+			address,
+			remarks,
+			is_exist,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), $10, NOW()
+		)
+		RETURNING id
+	`
+
+	err := s.db.QueryRowContext(
+		context.Background(),
+		queryStr,
+		customer.Code,
+		customer.Name,
+		customer.ContactName,
+		customer.Email,
+		customer.Phone,
+		customer.Address,
+		customer.Remarks,
+		customer.IsExist,
+		customer.CreatedBy,
+		customer.UpdatedBy,
+	).Scan(&customer.ID)
+	if err != nil {
+		slog.Error("Error inserting customer", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted customer", "id", customer.ID)
+
+	return s.GetCustomer(customer.ID)
+}
+
+func (s *jobOrderService) UpdateCustomer(id int, customer *models.Customer) (*models.Customer, error) {
+	if err := validateCustomer(customer); err != nil {
+		return nil, err
+	}
+
+	queryStr := `
+		UPDATE
+			customers
+		SET
+			code = $1,
+			name = $2,
+			contact_name = $3,
+			email = $4,
+			phone = $5,
+			address = $6,
+			remarks = $7,
+			is_exist = $8,
+			updated_by = $9,
+			updated_at = NOW()
+		WHERE
+			id = $10
+	`
+
+	result, err := s.db.ExecContext(
+		context.Background(),
+		queryStr,
+		customer.Code,
+		customer.Name,
+		customer.ContactName,
+		customer.Email,
+		customer.Phone,
+		customer.Address,
+		customer.Remarks,
+		customer.IsExist,
+		customer.UpdatedBy,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating customer", "error", err)
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	slog.Info("Successfully updated customer", "id", id)
+
+	return s.GetCustomer(id)
+}
+
+// DeleteCustomer refuses to remove a customer that still has job orders, mark
+// it as no longer existing instead.
+func (s *jobOrderService) DeleteCustomer(id int) error {
+	var jobs int
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`SELECT COUNT(*) FROM job_orders WHERE customer_id = $1`,
+		id,
+	).Scan(&jobs)
+	if err != nil {
+		slog.Error("Error counting customer job orders", "error", err)
+		return err
+	}
+	if jobs > 0 {
+		return &ValidationError{Message: "customer has job orders"}
+	}
+
+	_, err = s.db.ExecContext(
+		context.Background(),
+		`DELETE FROM customers WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error deleting customer", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted customer", "id", id)
+
+	return nil
+}
+
+const jobOrderQuery = `
+	SELECT
+		j.id,
+		j.job_no,
+		COALESCE(j.customer_id, 0),
+		j.site_name,
+		j.site_address,
+		j.status,
+		j.started_at,
+		j.completed_at,
+		j.remarks,
+		j.created_by,
+		j.created_at,
+		j.updated_by,
+		j.updated_at,
+		COALESCE(c.code, '') AS customer_code,
+		COALESCE(c.name, '') AS customer_name
+	FROM
+		job_orders j
+	LEFT JOIN
+		customers c
+	ON
+		j.customer_id = c.id
+	`
+
+func scanJobOrder(row interface{ Scan(...any) error }, job *models.JobOrder) error {
+	var startedAt, completedAt sql.NullString
+	err := row.Scan(
+		&job.ID,
+		&job.JobNo,
+		&job.CustomerID,
+		&job.SiteName,
+		&job.SiteAddress,
+		&job.Status,
+		&startedAt,
+		&completedAt,
+		&job.Remarks,
+		&job.CreatedBy,
+		&job.CreatedAt,
+		&job.UpdatedBy,
+		&job.UpdatedAt,
+		&job.CustomerCode,
+		&job.CustomerName,
+	)
+	if err != nil {
+		return err
+	}
+
+	job.StartedAt = startedAt.String
+	job.CompletedAt = completedAt.String
+
+	return nil
+}
+
+// Job order
+func (s *jobOrderService) GetJobOrders(customerID int, status string) ([]*models.JobOrder, error) {
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		jobOrderQuery+`
+		WHERE
+			($1 = 0 OR j.customer_id = $1)
+		AND
+			($2 = '' OR j.status = $2)
+		ORDER BY
+			j.job_no DESC
+		`,
+		customerID,
+		status,
+	)
+	if err != nil {
+		slog.Error("Error querying job orders", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*models.JobOrder{}
+	for rows.Next() {
+		job := new(models.JobOrder)
+		if err := scanJobOrder(rows, job); err != nil {
+			slog.Error("Error scanning job order", "error", err)
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating job orders", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried job orders", "count", len(jobs))
+
+	return jobs, nil
+}
+
+func (s *jobOrderService) GetJobOrder(id int) (*models.JobOrder, error) {
+	job := new(models.JobOrder)
+	err := scanJobOrder(s.db.QueryRowContext(
+		context.Background(),
+		jobOrderQuery+`
+		WHERE
+			j.id = $1
+		`,
+		id,
+	), job)
+	if err != nil {
+		slog.Error("Error querying job order", "error", err, "id", id)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried job order", "id", id)
+
+	return job, nil
+}
+
+func validateJobOrder(job *models.JobOrder) error {
+	job.JobNo = strings.ToUpper(strings.TrimSpace(job.JobNo))
+	job.SiteName = strings.TrimSpace(job.SiteName)
+
+	if job.JobNo == "" {
+		return &ValidationError{Message: "job no is required"}
+	}
+	if job.Status == "" {
+		job.Status = models.JobOrderStatusOpen
+	}
+	switch job.Status {
+	case models.JobOrderStatusOpen, models.JobOrderStatusCompleted, models.JobOrderStatusCancelled:
+	default:
+		return &ValidationError{Message: "unknown job order status: " + job.Status}
+	}
+
+	startedAt, err := parseOptionalDate(job.StartedAt)
+	if err != nil {
+		return &ValidationError{Message: "started at must be YYYY-MM-DD"}
+	}
+	completedAt, err := parseOptionalDate(job.CompletedAt)
+	if err != nil {
+		return &ValidationError{Message: "completed at must be YYYY-MM-DD"}
+	}
+	if startedAt != nil && completedAt != nil && completedAt.Before(*startedAt) {
+		return &ValidationError{Message: "completed at must not be before started at"}
+	}
+
+	return nil
+}
+
+func (s *jobOrderService) CreateJobOrder(job *models.JobOrder) (*models.JobOrder, error) {
+	if err := validateJobOrder(job); err != nil {
+		return nil, err
+	}
+
+	queryStr := `
+		INSERT INTO job_orders (
+			job_no,
+			customer_id,
+			site_name,
+			site_address,
+			status,
+			started_at,
+			completed_at,
+			remarks,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			$1, NULLIF($2, 0), $3, $4, $5, NULLIF($6, '')::DATE, NULLIF($7, '')::DATE, $8, $9, NOW(), $10, NOW()
+		)
+		RETURNING id
+	`
+
+	err := s.db.QueryRowContext(
+		context.Background(),
+		queryStr,
+		job.JobNo,
+		job.CustomerID,
+		job.SiteName,
+		job.SiteAddress,
+		job.Status,
+		job.StartedAt,
+		job.CompletedAt,
+		job.Remarks,
+		job.CreatedBy,
+		job.UpdatedBy,
+	).Scan(&job.ID)
+	if err != nil {
+		slog.Error("Error inserting job order", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted job order", "id", job.ID, "jobNo", job.JobNo)
+
+	return s.GetJobOrder(job.ID)
+}
+
+func (s *jobOrderService) UpdateJobOrder(id int, job *models.JobOrder) (*models.JobOrder, error) {
+	if err := validateJobOrder(job); err != nil {
+		return nil, err
+	}
+
+	queryStr := `
+		UPDATE
+			job_orders
+		SET
+			job_no = $1,
+			customer_id = NULLIF($2, 0),
+			site_name = $3,
+			site_address = $4,
+			status = $5,
+			started_at = NULLIF($6, '')::DATE,
+			completed_at = NULLIF($7, '')::DATE,
+			remarks = $8,
+			updated_by = $9,
+			updated_at = NOW()
+		WHERE
+			id = $10
+	`
+
+	result, err := s.db.ExecContext(
+		context.Background(),
+		queryStr,
+		job.JobNo,
+		job.CustomerID,
+		job.SiteName,
+		job.SiteAddress,
+		job.Status,
+		job.StartedAt,
+		job.CompletedAt,
+		job.Remarks,
+		job.UpdatedBy,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating job order", "error", err)
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	slog.Info("Successfully updated job order", "id", id)
+
+	return s.GetJobOrder(id)
+}
+
+// DeleteJobOrder refuses to remove a job order that material was issued to,
+// cancel it instead.
+func (s *jobOrderService) DeleteJobOrder(id int) error {
+	var outgoings int
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`SELECT COUNT(*) FROM inventory_outgoings WHERE job_order_id = $1`,
+		id,
+	).Scan(&outgoings)
+	if err != nil {
+		slog.Error("Error counting job order outgoings", "error", err)
+		return err
+	}
+	if outgoings > 0 {
+		return &ValidationError{Message: "job order has outgoings"}
+	}
+
+	_, err = s.db.ExecContext(
+		context.Background(),
+		`DELETE FROM job_orders WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error deleting job order", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted job order", "id", id)
+
+	return nil
+}
+
+// Job costing
+func (s *jobOrderService) GetJobCosting(id int) (*models.JobCosting, error) {
+	job, err := s.GetJobOrder(id)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.jobCosting(id, &models.JobCostingFilter{}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(report.Jobs) > 0 {
+		return report.Jobs[0], nil
+	}
+
+	return &models.JobCosting{
+		JobOrderID:   job.ID,
+		JobNo:        job.JobNo,
+		CustomerName: job.CustomerName,
+		SiteName:     job.SiteName,
+		Status:       job.Status,
+		Lines:        []*models.JobCostingLine{},
+	}, nil
+}
+
+func (s *jobOrderService) GetJobCostingReport(filter *models.JobCostingFilter, from, to *time.Time) (*models.JobCostingReport, error) {
+	return s.jobCosting(0, filter, from, to)
+}
+
+// jobCosting sums the standard quantity and cost of the outgoings linked to
+// each job order by product. Outgoings are dated by when they were keyed in,
+// to is inclusive.
+func (s *jobOrderService) jobCosting(jobOrderID int, filter *models.JobCostingFilter, from, to *time.Time) (*models.JobCostingReport, error) {
+	report := &models.JobCostingReport{
+		Jobs: []*models.JobCosting{},
+	}
+
+	var until *time.Time
+	if from != nil {
+		report.From = from.Format("2006-01-02")
+	}
+	if to != nil {
+		next := to.AddDate(0, 0, 1)
+		until = &next
+		report.To = to.Format("2006-01-02")
+	}
+
+	queryStr := `
+		SELECT
+			j.id,
+			j.job_no,
+			COALESCE(c.name, '') AS customer_name,
+			j.site_name,
+			j.status,
+			p.id,
+			p.code,
+			p.name,
+			p.standard_unit,
+			SUM(o.standard_quantity),
+			SUM(o.cost),
+			COUNT(o.id)
+		FROM
+			inventory_outgoings o
+		JOIN
+			job_orders j
+		ON
+			o.job_order_id = j.id
+		LEFT JOIN
+			customers c
+		ON
+			j.customer_id = c.id
+		JOIN
+			inventory_products p
+		ON
+			o.product_id = p.id
+		WHERE
+			($1 = 0 OR j.id = $1)
+		AND
+			($2 = 0 OR j.customer_id = $2)
+		AND
+			($3 = '' OR j.status = $3)
+		AND
+			($4::TIMESTAMP IS NULL OR o.created_at >= $4::TIMESTAMP)
+		AND
+			($5::TIMESTAMP IS NULL OR o.created_at < $5::TIMESTAMP)
+		GROUP BY
+			j.id,
+			c.name,
+			p.id
+		ORDER BY
+			j.job_no,
+			p.code
+	`
+
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		queryStr,
+		jobOrderID,
+		filter.CustomerID,
+		filter.Status,
+		from,
+		until,
+	)
+	if err != nil {
+		slog.Error("Error querying job costing", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var job *models.JobCosting
+	for rows.Next() {
+		row := new(models.JobCosting)
+		line := new(models.JobCostingLine)
+		err := rows.Scan(
+			&row.JobOrderID,
+			&row.JobNo,
+			&row.CustomerName,
+			&row.SiteName,
+			&row.Status,
+			&line.ProductID,
+			&line.ProductCode,
+			&line.ProductName,
+			&line.StandardUnit,
+			&line.StandardQuantity,
+			&line.Cost,
+			&line.Outgoings,
+		)
+		if err != nil {
+			slog.Error("Error scanning job costing", "error", err)
+			return nil, err
+		}
+
+		if job == nil || job.JobOrderID != row.JobOrderID {
+			job = row
+			job.Lines = []*models.JobCostingLine{}
+			report.Jobs = append(report.Jobs, job)
+		}
+
+		job.Lines = append(job.Lines, line)
+		job.TotalCost += line.Cost
+		report.TotalCost += line.Cost
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating job costing", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried job costing", "jobs", len(report.Jobs))
+
+	return report, nil
+}
+
+// checkJobOrderOpen makes sure material is only issued to a job order that
+// exists and is still open. A zero id means the outgoing is not for a job.
+func checkJobOrderOpen(tx *sql.Tx, jobOrderID int) error {
+	if jobOrderID == 0 {
+		return nil
+	}
+
+	var status string
+	err := tx.QueryRowContext(
+		context.Background(),
+		`SELECT status FROM job_orders WHERE id = $1`,
+		jobOrderID,
+	).Scan(&status)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Message: "job order does not exist"}
+	}
+	if err != nil {
+		slog.Error("Error querying job order", "error", err, "id", jobOrderID)
+		return err
+	}
+	if status != models.JobOrderStatusOpen {
+		return &ValidationError{Message: "job order is " + status}
+	}
+
+	return nil
+}
@@ -0,0 +1,826 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/db"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+type PurchaseOrderService interface {
+	GetPurchaseOrders(status string) ([]*models.PurchaseOrder, error)
+	GetPurchaseOrder(id int) (*models.PurchaseOrder, error)
+	CreatePurchaseOrder(order *models.PurchaseOrder) (*models.PurchaseOrder, error)
+	UpdatePurchaseOrder(id int, order *models.PurchaseOrder) (*models.PurchaseOrder, error)
+	DeletePurchaseOrder(id int) error
+
+	ApprovePurchaseOrder(id int, by string) (*models.PurchaseOrder, error)
+	ReceivePurchaseOrder(id int, action *models.PurchaseOrderAction) (*models.PurchaseOrder, error)
+	ClosePurchaseOrder(id int, by string) (*models.PurchaseOrder, error)
+}
+
+type purchaseOrderService struct {
+	db        *sql.DB
+	inventory *inventoryService
+}
+
+func NewPurchaseOrderService() PurchaseOrderService {
+	return &purchaseOrderService{
+		db:        db.GetDB(),
+		inventory: newInventoryService(),
+	}
+}
+
+const purchaseOrderQuery = `
+	SELECT
+		po.id,
+		po.po_no,
+		COALESCE(po.supplier_id, 0),
+		po.supplier,
+		po.status,
+		po.order_date,
+		po.expected_at,
+		po.remarks,
+		po.approved_by,
+		po.approved_at,
+		po.created_by,
+		po.created_at,
+		po.updated_by,
+		po.updated_at,
+		COALESCE(t.total_cost, 0) AS total_cost
+	FROM
+		purchase_orders po
+	LEFT JOIN (
+		SELECT
+			purchase_order_id,
+			SUM(standard_quantity * unit_cost) AS total_cost
+		FROM
+			purchase_order_lines
+		GROUP BY
+			purchase_order_id
+		) t
+	ON
+		po.id = t.purchase_order_id
+	`
+
+// purchaseOrderLineQuery selects the lines of purchase order $1 with the
+// standard quantity received on each so far.
+const purchaseOrderLineQuery = `
+	SELECT
+		l.id,
+		l.purchase_order_id,
+		l.product_id,
+		l.standard_quantity,
+		l.unit_cost,
+		l.remarks,
+		COALESCE(r.received, 0) AS received_quantity,
+		p.code AS product_code,
+		p.name AS product_name,
+		p.standard_unit
+	FROM
+		purchase_order_lines l
+	JOIN
+		inventory_products p
+	ON
+		l.product_id = p.id
+	LEFT JOIN (
+		SELECT
+			purchase_order_line_id,
+			SUM(standard_quantity) AS received
+		FROM
+			inventory_incomings
+		WHERE
+			purchase_order_line_id IS NOT NULL
+		GROUP BY
+			purchase_order_line_id
+		) r
+	ON
+		r.purchase_order_line_id = l.id
+	WHERE
+		l.purchase_order_id = $1
+	ORDER BY
+		l.id
+	`
+
+func scanPurchaseOrder(row interface{ Scan(...any) error }, order *models.PurchaseOrder) error {
+	var expectedAt, approvedAt sql.NullString
+	err := row.Scan(
+		&order.ID,
+		&order.PONo,
+		&order.SupplierID,
+		&order.Supplier,
+		&order.Status,
+		&order.OrderDate,
+		&expectedAt,
+		&order.Remarks,
+		&order.ApprovedBy,
+		&approvedAt,
+		&order.CreatedBy,
+		&order.CreatedAt,
+		&order.UpdatedBy,
+		&order.UpdatedAt,
+		&order.TotalCost,
+	)
+	if err != nil {
+		return err
+	}
+
+	order.ExpectedAt = expectedAt.String
+	order.ApprovedAt = approvedAt.String
+
+	return nil
+}
+
+func (s *purchaseOrderService) GetPurchaseOrders(status string) ([]*models.PurchaseOrder, error) {
+	// execute query with context
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		purchaseOrderQuery+`
+		WHERE
+			$1 = '' OR po.status = $1
+		ORDER BY
+			po.order_date DESC,
+			po.id DESC
+		`,
+		status,
+	)
+	if err != nil {
+		slog.Error("Error querying purchase orders", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []*models.PurchaseOrder{}
+	for rows.Next() {
+		order := new(models.PurchaseOrder)
+		if err := scanPurchaseOrder(rows, order); err != nil {
+			slog.Error("Error scanning purchase order", "error", err)
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating purchase orders", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried purchase orders", "count", len(orders))
+
+	return orders, nil
+}
+
+func (s *purchaseOrderService) GetPurchaseOrder(id int) (*models.PurchaseOrder, error) {
+	order := new(models.PurchaseOrder)
+
+	// execute query with context
+	err := scanPurchaseOrder(s.db.QueryRowContext(
+		context.Background(),
+		purchaseOrderQuery+`
+		WHERE
+			po.id = $1
+		`,
+		id,
+	), order)
+	if err != nil {
+		slog.Error("Error querying purchase order", "error", err, "id", id)
+		return nil, err
+	}
+
+	order.Lines, err = s.getLines(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (s *purchaseOrderService) getLines(q queryer, id int) ([]*models.PurchaseOrderLine, error) {
+	rows, err := q.QueryContext(context.Background(), purchaseOrderLineQuery, id)
+	if err != nil {
+		slog.Error("Error querying purchase order lines", "error", err, "id", id)
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []*models.PurchaseOrderLine{}
+	for rows.Next() {
+		line := new(models.PurchaseOrderLine)
+		err := rows.Scan(
+			&line.ID,
+			&line.PurchaseOrderID,
+			&line.ProductID,
+			&line.StandardQuantity,
+			&line.UnitCost,
+			&line.Remarks,
+			&line.ReceivedQuantity,
+			&line.ProductCode,
+			&line.ProductName,
+			&line.StandardUnit,
+		)
+		if err != nil {
+			slog.Error("Error scanning purchase order line", "error", err)
+			return nil, err
+		}
+
+		line.OpenQuantity = line.StandardQuantity - line.ReceivedQuantity
+		if line.OpenQuantity < 0 {
+			line.OpenQuantity = 0
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating purchase order lines", "error", err)
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+func validatePurchaseOrder(order *models.PurchaseOrder) error {
+	order.PONo = strings.TrimSpace(order.PONo)
+	order.Supplier = strings.TrimSpace(order.Supplier)
+	order.Remarks = strings.TrimSpace(order.Remarks)
+
+	if order.PONo == "" {
+		return &ValidationError{Message: "PO no is required"}
+	}
+	if order.Supplier == "" {
+		return &ValidationError{Message: "supplier is required"}
+	}
+	if len(order.Lines) == 0 {
+		return &ValidationError{Message: "purchase order needs at least one line"}
+	}
+
+	for _, line := range order.Lines {
+		if line.ProductID == 0 {
+			return &ValidationError{Message: "product is required on every line"}
+		}
+		if line.StandardQuantity <= 0 {
+			return &ValidationError{Message: "line quantity must be greater than zero"}
+		}
+		if line.UnitCost < 0 {
+			return &ValidationError{Message: "line unit cost must not be negative"}
+		}
+		line.Remarks = strings.TrimSpace(line.Remarks)
+	}
+
+	return nil
+}
+
+// applySupplier takes the supplier name from the supplier record when the
+// order names one, and prices lines left without a unit cost from the
+// supplier's price list valid on the order date.
+func (s *purchaseOrderService) applySupplier(order *models.PurchaseOrder) error {
+	if order.SupplierID == 0 {
+		return nil
+	}
+
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`SELECT name FROM suppliers WHERE id = $1`,
+		order.SupplierID,
+	).Scan(&order.Supplier)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Message: "supplier does not exist"}
+	}
+	if err != nil {
+		slog.Error("Error querying supplier", "error", err, "supplier", order.SupplierID)
+		return err
+	}
+
+	for _, line := range order.Lines {
+		if line.UnitCost != 0 {
+			continue
+		}
+
+		err := s.db.QueryRowContext(
+			context.Background(),
+			`
+			SELECT
+				sp.unit_cost
+			FROM
+				supplier_prices sp
+			JOIN
+				product_suppliers ps
+			ON
+				sp.product_supplier_id = ps.id
+			WHERE
+				ps.supplier_id = $1
+			AND
+				ps.product_id = $2
+			AND
+				sp.min_quantity <= $3
+			AND
+				sp.valid_from <= COALESCE(NULLIF($4, '')::DATE, CURRENT_DATE)
+			AND
+				(sp.valid_to IS NULL OR sp.valid_to >= COALESCE(NULLIF($4, '')::DATE, CURRENT_DATE))
+			ORDER BY
+				sp.min_quantity DESC,
+				sp.valid_from DESC
+			LIMIT 1
+			`,
+			order.SupplierID,
+			line.ProductID,
+			line.StandardQuantity,
+			order.OrderDate,
+		).Scan(&line.UnitCost)
+		if err != nil && err != sql.ErrNoRows {
+			slog.Error("Error querying supplier price", "error", err, "product", line.ProductID)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *purchaseOrderService) insertLines(tx *sql.Tx, id int, lines []*models.PurchaseOrderLine) error {
+	for _, line := range lines {
+		_, err := tx.ExecContext(
+			context.Background(),
+			`
+			INSERT INTO purchase_order_lines (
+				purchase_order_id,
+				product_id,
+				standard_quantity,
+				unit_cost,
+				remarks
+			) VALUES (
+				$1, $2, $3, $4, $5
+			)
+			`,
+			id,
+			line.ProductID,
+			line.StandardQuantity,
+			line.UnitCost,
+			line.Remarks,
+		)
+		if err != nil {
+			slog.Error("Error inserting purchase order line", "error", err, "product", line.ProductID)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *purchaseOrderService) CreatePurchaseOrder(order *models.PurchaseOrder) (*models.PurchaseOrder, error) {
+	if err := s.applySupplier(order); err != nil {
+		return nil, err
+	}
+	if err := validatePurchaseOrder(order); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		INSERT INTO purchase_orders (
+			po_no,
+			supplier_id,
+			supplier,
+			status,
+			order_date,
+			expected_at,
+			remarks,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			$1, NULLIF($2, 0), $3, $4, COALESCE(NULLIF($5, '')::DATE, CURRENT_DATE), NULLIF($6, '')::DATE,
+			$7, $8, NOW(), $9, NOW()
+		)
+		RETURNING id
+		`,
+		order.PONo,
+		order.SupplierID,
+		order.Supplier,
+		models.PurchaseOrderStatusDraft,
+		order.OrderDate,
+		order.ExpectedAt,
+		order.Remarks,
+		order.CreatedBy,
+		order.UpdatedBy,
+	).Scan(&order.ID)
+	if err != nil {
+		slog.Error("Error inserting purchase order", "error", err)
+		return nil, err
+	}
+
+	if err := s.insertLines(tx, order.ID, order.Lines); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing purchase order", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully created purchase order", "id", order.ID, "poNo", order.PONo)
+
+	return s.GetPurchaseOrder(order.ID)
+}
+
+// UpdatePurchaseOrder replaces the header and lines of a draft order.
+func (s *purchaseOrderService) UpdatePurchaseOrder(id int, order *models.PurchaseOrder) (*models.PurchaseOrder, error) {
+	if err := s.applySupplier(order); err != nil {
+		return nil, err
+	}
+	if err := validatePurchaseOrder(order); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockPurchaseOrder(tx, id, models.PurchaseOrderStatusDraft); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			purchase_orders
+		SET
+			po_no = $1,
+			supplier_id = NULLIF($2, 0),
+			supplier = $3,
+			order_date = COALESCE(NULLIF($4, '')::DATE, order_date),
+			expected_at = NULLIF($5, '')::DATE,
+			remarks = $6,
+			updated_by = $7,
+			updated_at = NOW()
+		WHERE
+			id = $8
+		`,
+		order.PONo,
+		order.SupplierID,
+		order.Supplier,
+		order.OrderDate,
+		order.ExpectedAt,
+		order.Remarks,
+		order.UpdatedBy,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating purchase order", "error", err, "id", id)
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`DELETE FROM purchase_order_lines WHERE purchase_order_id = $1`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error deleting purchase order lines", "error", err, "id", id)
+		return nil, err
+	}
+
+	if err := s.insertLines(tx, id, order.Lines); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing purchase order", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully updated purchase order", "id", id)
+
+	return s.GetPurchaseOrder(id)
+}
+
+// DeletePurchaseOrder removes a draft order, placed orders are closed instead.
+func (s *purchaseOrderService) DeletePurchaseOrder(id int) error {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockPurchaseOrder(tx, id, models.PurchaseOrderStatusDraft); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`DELETE FROM purchase_orders WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error deleting purchase order", "error", err, "id", id)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing purchase order", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted purchase order", "id", id)
+
+	return nil
+}
+
+func (s *purchaseOrderService) ApprovePurchaseOrder(id int, by string) (*models.PurchaseOrder, error) {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockPurchaseOrder(tx, id, models.PurchaseOrderStatusDraft); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			purchase_orders
+		SET
+			status = $1,
+			approved_by = $2,
+			approved_at = NOW(),
+			updated_by = $2,
+			updated_at = NOW()
+		WHERE
+			id = $3
+		`,
+		models.PurchaseOrderStatusApproved,
+		by,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error approving purchase order", "error", err, "id", id)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing purchase order", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully approved purchase order", "id", id, "by", by)
+
+	return s.GetPurchaseOrder(id)
+}
+
+// ReceivePurchaseOrder books each receipt as an incoming through the
+// inventory service, carrying the line's product and cost and the order's
+// supplier, then moves the order to partially received or received, all in
+// one transaction. The order stays locked while receiving so two deliveries
+// cannot both fill the same open quantity.
+func (s *purchaseOrderService) ReceivePurchaseOrder(id int, action *models.PurchaseOrderAction) (*models.PurchaseOrder, error) {
+	if len(action.Receipts) == 0 {
+		return nil, &ValidationError{Message: "nothing to receive"}
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	order, err := lockPurchaseOrder(
+		tx,
+		id,
+		models.PurchaseOrderStatusApproved,
+		models.PurchaseOrderStatusPartiallyReceived,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := s.getLines(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	open := map[int]*models.PurchaseOrderLine{}
+	for _, line := range lines {
+		open[line.ID] = line
+	}
+
+	for _, receipt := range action.Receipts {
+		line, ok := open[receipt.PurchaseOrderLineID]
+		if !ok {
+			return nil, &ValidationError{Message: fmt.Sprintf("line %d is not on purchase order %s", receipt.PurchaseOrderLineID, order.PONo)}
+		}
+
+		receipt.ProductID = line.ProductID
+		if err := applyIncomingQuantity(tx, receipt); err != nil {
+			return nil, err
+		}
+		if receipt.StandardQuantity <= 0 {
+			return nil, &ValidationError{Message: "received quantity must be greater than zero"}
+		}
+		if receipt.StandardQuantity-line.OpenQuantity > stockTolerance {
+			return nil, &ValidationError{Message: fmt.Sprintf(
+				"line %d has %v %s open, cannot receive %v",
+				line.ID,
+				line.OpenQuantity,
+				line.StandardUnit,
+				receipt.StandardQuantity,
+			)}
+		}
+		line.OpenQuantity -= receipt.StandardQuantity
+	}
+
+	// every receipt is written in the transaction of the order, a failing one
+	// leaves the order and the stock as they were
+	for _, receipt := range action.Receipts {
+		line := open[receipt.PurchaseOrderLineID]
+
+		receipt.ProductID = line.ProductID
+		receipt.Cost = line.UnitCost
+		receipt.Supplier = order.Supplier
+		if strings.TrimSpace(receipt.RefNo) == "" {
+			receipt.RefNo = order.PONo
+		}
+		if receipt.Status == "" {
+			receipt.Status = models.IncomingStatusAvailable
+		}
+		if receipt.CreatedBy == "" {
+			receipt.CreatedBy = action.By
+		}
+		if receipt.UpdatedBy == "" {
+			receipt.UpdatedBy = action.By
+		}
+
+		if err := s.inventory.insertIncoming(tx, receipt); err != nil {
+			slog.Error("Error receiving purchase order line", "error", err, "line", line.ID)
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			purchase_orders po
+		SET
+			status = CASE
+				WHEN q.outstanding = 0 THEN $2
+				WHEN q.received > 0 THEN $3
+				ELSE po.status
+			END,
+			updated_by = $4,
+			updated_at = NOW()
+		FROM (
+			SELECT
+				COUNT(*) FILTER (WHERE COALESCE(r.received, 0) < l.standard_quantity - $5) AS outstanding,
+				COALESCE(SUM(r.received), 0) AS received
+			FROM
+				purchase_order_lines l
+			LEFT JOIN (
+				SELECT
+					purchase_order_line_id,
+					SUM(standard_quantity) AS received
+				FROM
+					inventory_incomings
+				WHERE
+					purchase_order_line_id IS NOT NULL
+				GROUP BY
+					purchase_order_line_id
+				) r
+			ON
+				r.purchase_order_line_id = l.id
+			WHERE
+				l.purchase_order_id = $1
+			) q
+		WHERE
+			po.id = $1
+		`,
+		id,
+		models.PurchaseOrderStatusReceived,
+		models.PurchaseOrderStatusPartiallyReceived,
+		action.By,
+		stockTolerance,
+	)
+	if err != nil {
+		slog.Error("Error updating purchase order status", "error", err, "id", id)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing purchase order", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully received purchase order", "id", id, "receipts", len(action.Receipts))
+
+	changed := map[int]bool{}
+	for _, receipt := range action.Receipts {
+		changed[receipt.ProductID] = true
+	}
+	for productID := range changed {
+		NotifyStockChanged(productID)
+	}
+
+	return s.GetPurchaseOrder(id)
+}
+
+// ClosePurchaseOrder stops an order from counting as on order, whether or
+// not everything on it arrived.
+func (s *purchaseOrderService) ClosePurchaseOrder(id int, by string) (*models.PurchaseOrder, error) {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = lockPurchaseOrder(
+		tx,
+		id,
+		models.PurchaseOrderStatusApproved,
+		models.PurchaseOrderStatusPartiallyReceived,
+		models.PurchaseOrderStatusReceived,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			purchase_orders
+		SET
+			status = $1,
+			updated_by = $2,
+			updated_at = NOW()
+		WHERE
+			id = $3
+		`,
+		models.PurchaseOrderStatusClosed,
+		by,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error closing purchase order", "error", err, "id", id)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing purchase order", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully closed purchase order", "id", id, "by", by)
+
+	return s.GetPurchaseOrder(id)
+}
+
+// lockPurchaseOrder locks an order for the rest of the transaction and
+// refuses it unless it is in one of the allowed statuses.
+func lockPurchaseOrder(tx *sql.Tx, id int, allowed ...string) (*models.PurchaseOrder, error) {
+	order := &models.PurchaseOrder{ID: id}
+	err := tx.QueryRowContext(
+		context.Background(),
+		`SELECT po_no, supplier, status FROM purchase_orders WHERE id = $1 FOR UPDATE`,
+		id,
+	).Scan(&order.PONo, &order.Supplier, &order.Status)
+	if err != nil {
+		slog.Error("Error locking purchase order", "error", err, "id", id)
+		return nil, err
+	}
+
+	for _, status := range allowed {
+		if order.Status == status {
+			return order, nil
+		}
+	}
+
+	return nil, &ValidationError{Message: fmt.Sprintf("purchase order %s is %s", order.PONo, order.Status)}
+}
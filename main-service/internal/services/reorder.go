@@ -0,0 +1,990 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/config"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/db"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/lib/pq"
+)
+
+type ReorderService interface {
+	GetReorderRules(productID int) ([]*models.ReorderRule, error)
+	GetReorderRule(id int) (*models.ReorderRule, error)
+	CreateReorderRule(rule *models.ReorderRule) (*models.ReorderRule, error)
+	UpdateReorderRule(id int, rule *models.ReorderRule) (*models.ReorderRule, error)
+	DeleteReorderRule(id int) error
+
+	GetAlerts(status, alertType string) ([]*models.InventoryAlert, error)
+	AcknowledgeAlert(id int, action *models.InventoryAlertAction) (*models.InventoryAlert, error)
+	EvaluateReorderRules(productID int) error
+	EvaluateExpiryAlerts(productID int) error
+
+	GetSuggestedPurchases(days int) (*models.SuggestedPurchaseReport, error)
+}
+
+type reorderService struct {
+	db *sql.DB
+}
+
+// defaultConsumptionDays is how far back consumption is averaged when the
+// caller does not say.
+const defaultConsumptionDays = 90
+
+func NewReorderService() ReorderService {
+	return &reorderService{
+		db: db.GetDB(),
+	}
+}
+
+// reorderRuleQuery reads rules with the balance they watch, the stock of the
+// product at the rule's location or everywhere for a rule without one.
+const reorderRuleQuery = `
+	SELECT
+		r.id,
+		r.product_id,
+		COALESCE(r.location_id, 0),
+		r.min_quantity,
+		r.max_quantity,
+		r.reorder_quantity,
+		r.is_exist,
+		r.created_by,
+		r.created_at,
+		r.updated_by,
+		r.updated_at,
+		p.code AS product_code,
+		p.name AS product_name,
+		p.standard_unit,
+		COALESCE(l.code, '') AS location_code,
+		COALESCE(l.name, '') AS location_name,
+		COALESCE((
+			SELECT
+				SUM(m.standard_quantity)
+			FROM
+				inventory_movements m
+			LEFT JOIN
+				inventory_incomings i
+			ON
+				m.incoming_id = i.id
+			WHERE
+				m.product_id = r.product_id
+			AND
+				(r.location_id IS NULL OR i.location_id = r.location_id)
+		), 0) AS balance
+	FROM
+		reorder_rules r
+	JOIN
+		inventory_products p
+	ON
+		r.product_id = p.id
+	LEFT JOIN
+		locations l
+	ON
+		r.location_id = l.id
+	`
+
+func scanReorderRule(row interface{ Scan(...any) error }, rule *models.ReorderRule) error {
+	return row.Scan(
+		&rule.ID,
+		&rule.ProductID,
+		&rule.LocationID,
+		&rule.MinQuantity,
+		&rule.MaxQuantity,
+		&rule.ReorderQuantity,
+		&rule.IsExist,
+		&rule.CreatedBy,
+		&rule.CreatedAt,
+		&rule.UpdatedBy,
+		&rule.UpdatedAt,
+		&rule.ProductCode,
+		&rule.ProductName,
+		&rule.StandardUnit,
+		&rule.LocationCode,
+		&rule.LocationName,
+		&rule.Balance,
+	)
+}
+
+// Reorder rule
+func (s *reorderService) GetReorderRules(productID int) ([]*models.ReorderRule, error) {
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		reorderRuleQuery+`
+		WHERE
+			($1 = 0 OR r.product_id = $1)
+		ORDER BY
+			p.code,
+			r.location_id NULLS FIRST
+		`,
+		productID,
+	)
+	if err != nil {
+		slog.Error("Error querying reorder rules", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*models.ReorderRule{}
+	for rows.Next() {
+		rule := new(models.ReorderRule)
+		if err := scanReorderRule(rows, rule); err != nil {
+			slog.Error("Error scanning reorder rule", "error", err)
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating reorder rules", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried reorder rules", "count", len(rules))
+
+	return rules, nil
+}
+
+func (s *reorderService) GetReorderRule(id int) (*models.ReorderRule, error) {
+	rule := new(models.ReorderRule)
+	err := scanReorderRule(s.db.QueryRowContext(
+		context.Background(),
+		reorderRuleQuery+`
+		WHERE
+			r.id = $1
+		`,
+		id,
+	), rule)
+	if err != nil {
+		slog.Error("Error querying reorder rule", "error", err, "id", id)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried reorder rule", "id", id)
+
+	return rule, nil
+}
+
+// validateReorderRule checks the levels and that the product does not already
+// have a rule for the same location, id is the rule being updated.
+func (s *reorderService) validateReorderRule(id int, rule *models.ReorderRule) error {
+	if rule.ProductID == 0 {
+		return &ValidationError{Message: "product is required"}
+	}
+	if rule.MinQuantity < 0 || rule.MaxQuantity < 0 || rule.ReorderQuantity < 0 {
+		return &ValidationError{Message: "stock levels cannot be negative"}
+	}
+	if rule.MaxQuantity > 0 && rule.MaxQuantity < rule.MinQuantity {
+		return &ValidationError{Message: "maximum quantity cannot be below the minimum"}
+	}
+
+	var existing int
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			COUNT(*)
+		FROM
+			reorder_rules
+		WHERE
+			product_id = $1
+		AND
+			COALESCE(location_id, 0) = $2
+		AND
+			id <> $3
+		`,
+		rule.ProductID,
+		rule.LocationID,
+		id,
+	).Scan(&existing)
+	if err != nil {
+		slog.Error("Error checking reorder rules", "error", err)
+		return err
+	}
+	if existing > 0 {
+		if rule.LocationID == 0 {
+			return &ValidationError{Message: "product already has a reorder rule"}
+		}
+		return &ValidationError{Message: "product already has a reorder rule for this location"}
+	}
+
+	return nil
+}
+
+func (s *reorderService) CreateReorderRule(rule *models.ReorderRule) (*models.ReorderRule, error) {
+	if err := s.validateReorderRule(0, rule); err != nil {
+		return nil, err
+	}
+
+	queryStr := `
+		INSERT INTO reorder_rules (
+			product_id,
+			location_id,
+			min_quantity,
+			max_quantity,
+			reorder_quantity,
+			is_exist,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			$1, NULLIF($2, 0), $3, $4, $5, $6, $7, NOW(), $8, NOW()
+		)
+		RETURNING id
+	`
+
+	err := s.db.QueryRowContext(
+		context.Background(),
+		queryStr,
+		rule.ProductID,
+		rule.LocationID,
+		rule.MinQuantity,
+		rule.MaxQuantity,
+		rule.ReorderQuantity,
+		rule.IsExist,
+		rule.CreatedBy,
+		rule.UpdatedBy,
+	).Scan(&rule.ID)
+	if err != nil {
+		slog.Error("Error inserting reorder rule", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted reorder rule", "id", rule.ID)
+
+	NotifyStockChanged(rule.ProductID)
+
+	return s.GetReorderRule(rule.ID)
+}
+
+func (s *reorderService) UpdateReorderRule(id int, rule *models.ReorderRule) (*models.ReorderRule, error) {
+	if err := s.validateReorderRule(id, rule); err != nil {
+		return nil, err
+	}
+
+	queryStr := `
+		UPDATE
+			reorder_rules
+		SET
+			product_id = $1,
+			location_id = NULLIF($2, 0),
+			min_quantity = $3,
+			max_quantity = $4,
+			reorder_quantity = $5,
+			is_exist = $6,
+			updated_by = $7,
+			updated_at = NOW()
+		WHERE
+			id = $8
+	`
+
+	result, err := s.db.ExecContext(
+		context.Background(),
+		queryStr,
+		rule.ProductID,
+		rule.LocationID,
+		rule.MinQuantity,
+		rule.MaxQuantity,
+		rule.ReorderQuantity,
+		rule.IsExist,
+		rule.UpdatedBy,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating reorder rule", "error", err)
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	slog.Info("Successfully updated reorder rule", "id", id)
+
+	NotifyStockChanged(rule.ProductID)
+
+	return s.GetReorderRule(id)
+}
+
+// DeleteReorderRule resolves the alerts of the rule before removing it, the
+// alerts themselves are kept as history.
+func (s *reorderService) DeleteReorderRule(id int) error {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := resolveRuleAlerts(tx, id); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`DELETE FROM reorder_rules WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error deleting reorder rule", "error", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing reorder rule", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted reorder rule", "id", id)
+
+	return nil
+}
+
+// Alert
+const alertQuery = `
+	SELECT
+		a.id,
+		a.type,
+		COALESCE(a.reorder_rule_id, 0),
+		COALESCE(a.incoming_id, 0),
+		a.product_id,
+		COALESCE(a.location_id, 0),
+		a.quantity,
+		a.threshold,
+		a.message,
+		a.status,
+		a.raised_at,
+		a.updated_at,
+		a.acknowledged_by,
+		a.acknowledged_at,
+		a.resolved_at,
+		p.code AS product_code,
+		p.name AS product_name,
+		p.standard_unit,
+		COALESCE(l.code, '') AS location_code,
+		COALESCE(l.name, '') AS location_name
+	FROM
+		inventory_alerts a
+	JOIN
+		inventory_products p
+	ON
+		a.product_id = p.id
+	LEFT JOIN
+		locations l
+	ON
+		a.location_id = l.id
+	`
+
+func scanAlert(row interface{ Scan(...any) error }, alert *models.InventoryAlert) error {
+	var acknowledgedAt, resolvedAt sql.NullString
+	err := row.Scan(
+		&alert.ID,
+		&alert.Type,
+		&alert.ReorderRuleID,
+		&alert.IncomingID,
+		&alert.ProductID,
+		&alert.LocationID,
+		&alert.Quantity,
+		&alert.Threshold,
+		&alert.Message,
+		&alert.Status,
+		&alert.RaisedAt,
+		&alert.UpdatedAt,
+		&alert.AcknowledgedBy,
+		&acknowledgedAt,
+		&resolvedAt,
+		&alert.ProductCode,
+		&alert.ProductName,
+		&alert.StandardUnit,
+		&alert.LocationCode,
+		&alert.LocationName,
+	)
+	alert.AcknowledgedAt = acknowledgedAt.String
+	alert.ResolvedAt = resolvedAt.String
+	return err
+}
+
+func (s *reorderService) GetAlerts(status, alertType string) ([]*models.InventoryAlert, error) {
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		alertQuery+`
+		WHERE
+			($1 = '' OR a.status = $1)
+		AND
+			($2 = '' OR a.type = $2)
+		ORDER BY
+			a.raised_at DESC,
+			a.id DESC
+		`,
+		status,
+		alertType,
+	)
+	if err != nil {
+		slog.Error("Error querying alerts", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []*models.InventoryAlert{}
+	for rows.Next() {
+		alert := new(models.InventoryAlert)
+		if err := scanAlert(rows, alert); err != nil {
+			slog.Error("Error scanning alert", "error", err)
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating alerts", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried alerts", "count", len(alerts))
+
+	return alerts, nil
+}
+
+// AcknowledgeAlert marks an open alert as being looked at. It still resolves
+// on its own once the stock recovers.
+func (s *reorderService) AcknowledgeAlert(id int, action *models.InventoryAlertAction) (*models.InventoryAlert, error) {
+	action.By = strings.TrimSpace(action.By)
+	if action.By == "" {
+		return nil, &ValidationError{Message: "acknowledged by is required"}
+	}
+
+	result, err := s.db.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_alerts
+		SET
+			status = $1,
+			acknowledged_by = $2,
+			acknowledged_at = NOW(),
+			updated_at = NOW()
+		WHERE
+			id = $3
+		AND
+			status = $4
+		`,
+		models.AlertStatusAcknowledged,
+		action.By,
+		id,
+		models.AlertStatusOpen,
+	)
+	if err != nil {
+		slog.Error("Error acknowledging alert", "error", err, "id", id)
+		return nil, err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		alert, err := s.getAlert(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &ValidationError{Message: fmt.Sprintf("alert %d is already %s", id, alert.Status)}
+	}
+
+	slog.Info("Successfully acknowledged alert", "id", id, "by", action.By)
+
+	return s.getAlert(id)
+}
+
+func (s *reorderService) getAlert(id int) (*models.InventoryAlert, error) {
+	alert := new(models.InventoryAlert)
+	err := scanAlert(s.db.QueryRowContext(
+		context.Background(),
+		alertQuery+`
+		WHERE
+			a.id = $1
+		`,
+		id,
+	), alert)
+	if err != nil {
+		slog.Error("Error querying alert", "error", err, "id", id)
+		return nil, err
+	}
+
+	return alert, nil
+}
+
+// EvaluateReorderRules raises a low stock alert for every active rule of the
+// product, or of all products when productID is zero, whose balance is below
+// its minimum, and resolves the alerts of the rules that recovered.
+func (s *reorderService) EvaluateReorderRules(productID int) error {
+	rules, err := s.GetReorderRules(productID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	low := 0
+	for _, rule := range rules {
+		if !rule.IsExist || rule.MinQuantity-rule.Balance <= stockTolerance {
+			if err := resolveRuleAlerts(tx, rule.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		low++
+		where := "all locations"
+		if rule.LocationID != 0 {
+			where = rule.LocationCode
+		}
+		message := fmt.Sprintf(
+			"%s is at %v %s in %s, below the minimum of %v",
+			rule.ProductCode,
+			math.Round(rule.Balance*1000)/1000,
+			rule.StandardUnit,
+			where,
+			rule.MinQuantity,
+		)
+
+		// one unresolved alert per rule, a repeated run only refreshes it
+		_, err := tx.ExecContext(
+			context.Background(),
+			`
+			INSERT INTO inventory_alerts (
+				type,
+				reorder_rule_id,
+				product_id,
+				location_id,
+				quantity,
+				threshold,
+				message,
+				status,
+				raised_at,
+				updated_at
+			) VALUES (
+				$1, $2, $3, NULLIF($4, 0), $5, $6, $7, $8, NOW(), NOW()
+			)
+			ON CONFLICT (reorder_rule_id) WHERE status <> 'resolved'
+			DO UPDATE SET
+				product_id = EXCLUDED.product_id,
+				location_id = EXCLUDED.location_id,
+				quantity = EXCLUDED.quantity,
+				threshold = EXCLUDED.threshold,
+				message = EXCLUDED.message,
+				updated_at = NOW()
+			`,
+			models.AlertTypeLowStock,
+			rule.ID,
+			rule.ProductID,
+			rule.LocationID,
+			rule.Balance,
+			rule.MinQuantity,
+			message,
+			models.AlertStatusOpen,
+		)
+		if err != nil {
+			slog.Error("Error raising low stock alert", "error", err, "rule", rule.ID)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing alerts", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully evaluated reorder rules", "product", productID, "rules", len(rules), "low", low)
+
+	return nil
+}
+
+func resolveRuleAlerts(tx *sql.Tx, ruleID int) error {
+	_, err := tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_alerts
+		SET
+			status = $1,
+			resolved_at = NOW(),
+			updated_at = NOW()
+		WHERE
+			reorder_rule_id = $2
+		AND
+			status <> $1
+		`,
+		models.AlertStatusResolved,
+		ruleID,
+	)
+	if err != nil {
+		slog.Error("Error resolving alerts", "error", err, "rule", ruleID)
+		return err
+	}
+
+	return nil
+}
+
+// EvaluateExpiryAlerts raises an expiry alert for every incoming of the
+// product, or of all products when productID is zero, with stock left that
+// expires within the largest configured horizon. An acknowledged alert opens
+// again when the lot moves into a nearer horizon. Alerts of lots used up or
+// no longer near expiry are resolved.
+func (s *reorderService) EvaluateExpiryAlerts(productID int) error {
+	horizons := config.Cfg.ExpiryAlertHorizons
+	if len(horizons) == 0 {
+		return nil
+	}
+
+	lines, err := queryNearExpiry(s.db, horizons[len(horizons)-1], productID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	incomingIDs := make([]int, 0, len(lines))
+	for _, line := range lines {
+		incomingIDs = append(incomingIDs, line.IncomingID)
+
+		horizon := expiryHorizon(line.DaysToExpiry, horizons)
+		lot := line.RefNo
+		if line.LotNo != "" {
+			lot = line.LotNo
+		}
+		when := "expires on"
+		if horizon == 0 {
+			when = "expired on"
+		}
+		message := fmt.Sprintf(
+			"%s lot %s %s %s with %v %s left",
+			line.ProductCode,
+			lot,
+			when,
+			line.ExpiresAt,
+			math.Round(line.StandardQuantity*1000)/1000,
+			line.StandardUnit,
+		)
+
+		// one unresolved alert per incoming, it opens again when the lot
+		// crosses into a nearer horizon
+		_, err := tx.ExecContext(
+			context.Background(),
+			`
+			INSERT INTO inventory_alerts (
+				type,
+				incoming_id,
+				product_id,
+				quantity,
+				threshold,
+				message,
+				status,
+				raised_at,
+				updated_at
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, NOW(), NOW()
+			)
+			ON CONFLICT (incoming_id) WHERE status <> 'resolved'
+			DO UPDATE SET
+				quantity = EXCLUDED.quantity,
+				message = EXCLUDED.message,
+				status = CASE
+					WHEN inventory_alerts.threshold <> EXCLUDED.threshold THEN EXCLUDED.status
+					ELSE inventory_alerts.status
+				END,
+				raised_at = CASE
+					WHEN inventory_alerts.threshold <> EXCLUDED.threshold THEN NOW()
+					ELSE inventory_alerts.raised_at
+				END,
+				threshold = EXCLUDED.threshold,
+				updated_at = NOW()
+			`,
+			models.AlertTypeExpiry,
+			line.IncomingID,
+			line.ProductID,
+			line.StandardQuantity,
+			horizon,
+			message,
+			models.AlertStatusOpen,
+		)
+		if err != nil {
+			slog.Error("Error raising expiry alert", "error", err, "incoming", line.IncomingID)
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_alerts
+		SET
+			status = $1,
+			resolved_at = NOW(),
+			updated_at = NOW()
+		WHERE
+			type = $2
+		AND
+			status <> $1
+		AND
+			($3 = 0 OR product_id = $3)
+		AND
+			NOT (COALESCE(incoming_id, 0) = ANY($4))
+		`,
+		models.AlertStatusResolved,
+		models.AlertTypeExpiry,
+		productID,
+		pq.Array(incomingIDs),
+	)
+	if err != nil {
+		slog.Error("Error resolving expiry alerts", "error", err, "product", productID)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing alerts", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully evaluated expiry alerts", "product", productID, "lots", len(lines))
+
+	return nil
+}
+
+// Suggested purchase
+
+// GetSuggestedPurchases lists what to order from whom. Only rules without a
+// location take part, stock short at one location is a matter for a transfer
+// before it is one for a purchase. Each product is bought from its preferred
+// supplier, or the first one linked, at its current price.
+func (s *reorderService) GetSuggestedPurchases(days int) (*models.SuggestedPurchaseReport, error) {
+	if days <= 0 {
+		days = defaultConsumptionDays
+	}
+
+	queryStr := `
+		SELECT
+			p.id,
+			p.code,
+			p.name,
+			p.standard_unit,
+			COALESCE(ps.supplier_id, 0),
+			COALESCE(sup.code, '') AS supplier_code,
+			COALESCE(sup.name, '') AS supplier_name,
+			COALESCE(ps.supplier_sku, ''),
+			COALESCE(ps.lead_time_days, sup.lead_time_days, 0) AS lead_time_days,
+			COALESCE(b.balance, 0) AS balance,
+			COALESCE(o.on_order, 0) AS on_order,
+			r.min_quantity,
+			r.max_quantity,
+			r.reorder_quantity,
+			COALESCE(c.consumed, 0) / $1::INTEGER AS daily_consumption,
+			COALESCE(sp.unit_cost, 0) AS unit_cost,
+			COALESCE(NULLIF(sp.currency, ''), sup.currency, '') AS currency
+		FROM
+			reorder_rules r
+		JOIN
+			inventory_products p
+		ON
+			r.product_id = p.id
+		LEFT JOIN (
+			SELECT
+				product_id,
+				SUM(standard_quantity) AS balance
+			FROM
+				inventory_movements
+			GROUP BY
+				product_id
+			) b
+		ON
+			b.product_id = p.id
+		LEFT JOIN (
+			SELECT
+				l.product_id,
+				SUM(GREATEST(l.standard_quantity - COALESCE(rc.received, 0), 0)) AS on_order
+			FROM
+				purchase_order_lines l
+			JOIN
+				purchase_orders po
+			ON
+				l.purchase_order_id = po.id
+			LEFT JOIN (
+				SELECT
+					purchase_order_line_id,
+					SUM(standard_quantity) AS received
+				FROM
+					inventory_incomings
+				WHERE
+					purchase_order_line_id IS NOT NULL
+				GROUP BY
+					purchase_order_line_id
+				) rc
+			ON
+				rc.purchase_order_line_id = l.id
+			WHERE
+				po.status IN ('approved', 'partially_received')
+			GROUP BY
+				l.product_id
+			) o
+		ON
+			o.product_id = p.id
+		LEFT JOIN (
+			SELECT
+				product_id,
+				SUM(standard_quantity) AS consumed
+			FROM
+				inventory_outgoings
+			WHERE
+				created_at >= CURRENT_DATE - $1::INTEGER
+			GROUP BY
+				product_id
+			) c
+		ON
+			c.product_id = p.id
+		LEFT JOIN LATERAL (
+			SELECT
+				id,
+				supplier_id,
+				supplier_sku,
+				lead_time_days
+			FROM
+				product_suppliers
+			WHERE
+				product_id = p.id
+			ORDER BY
+				is_preferred DESC,
+				id
+			LIMIT 1
+			) ps
+		ON
+			true
+		LEFT JOIN
+			suppliers sup
+		ON
+			ps.supplier_id = sup.id
+		LEFT JOIN LATERAL (
+			SELECT
+				unit_cost,
+				currency
+			FROM
+				supplier_prices
+			WHERE
+				product_supplier_id = ps.id
+			AND
+				valid_from <= CURRENT_DATE
+			AND
+				(valid_to IS NULL OR valid_to >= CURRENT_DATE)
+			ORDER BY
+				min_quantity,
+				valid_from DESC
+			LIMIT 1
+			) sp
+		ON
+			true
+		WHERE
+			r.location_id IS NULL
+		AND
+			r.is_exist
+		AND
+			p.is_exist
+		ORDER BY
+			sup.name NULLS LAST,
+			p.code
+	`
+
+	rows, err := s.db.QueryContext(context.Background(), queryStr, days)
+	if err != nil {
+		slog.Error("Error querying suggested purchases", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &models.SuggestedPurchaseReport{
+		Days:   days,
+		Groups: []*models.SuggestedPurchaseGroup{},
+	}
+	groups := map[int]*models.SuggestedPurchaseGroup{}
+	for rows.Next() {
+		line := new(models.SuggestedPurchaseLine)
+		group := new(models.SuggestedPurchaseGroup)
+		err := rows.Scan(
+			&line.ProductID,
+			&line.ProductCode,
+			&line.ProductName,
+			&line.StandardUnit,
+			&group.SupplierID,
+			&group.SupplierCode,
+			&group.SupplierName,
+			&line.SupplierSKU,
+			&line.LeadTimeDays,
+			&line.Balance,
+			&line.OnOrder,
+			&line.MinQuantity,
+			&line.MaxQuantity,
+			&line.ReorderQuantity,
+			&line.DailyConsumption,
+			&line.UnitCost,
+			&line.Currency,
+		)
+		if err != nil {
+			slog.Error("Error scanning suggested purchase", "error", err)
+			return nil, err
+		}
+
+		if !suggestPurchase(line) {
+			continue
+		}
+
+		if existing, ok := groups[group.SupplierID]; ok {
+			group = existing
+		} else {
+			groups[group.SupplierID] = group
+			report.Groups = append(report.Groups, group)
+		}
+		group.Lines = append(group.Lines, line)
+		group.EstimatedCost += line.EstimatedCost
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating suggested purchases", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully suggested purchases", "days", days, "suppliers", len(report.Groups))
+
+	return report, nil
+}
+
+// suggestPurchase works out the reorder point of line and, when the stock
+// position is below it, the quantity that brings the position back up to the
+// maximum plus lead time demand, never less than the reorder quantity.
+func suggestPurchase(line *models.SuggestedPurchaseLine) bool {
+	leadTimeDemand := line.DailyConsumption * float64(line.LeadTimeDays)
+	line.ReorderPoint = line.MinQuantity + leadTimeDemand
+
+	position := line.Balance + line.OnOrder
+	if line.ReorderPoint-position <= stockTolerance {
+		return false
+	}
+
+	target := line.ReorderPoint
+	if line.MaxQuantity > 0 {
+		target = line.MaxQuantity + leadTimeDemand
+	}
+
+	line.SuggestedQuantity = math.Max(target-position, line.ReorderQuantity)
+	line.EstimatedCost = line.SuggestedQuantity * line.UnitCost
+
+	return true
+}
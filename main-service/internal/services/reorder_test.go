@@ -0,0 +1,89 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+func TestSuggestPurchase(t *testing.T) {
+	tests := []struct {
+		name         string
+		line         models.SuggestedPurchaseLine
+		suggested    bool
+		reorderPoint float64
+		quantity     float64
+		cost         float64
+	}{
+		{
+			// 2 a day over 10 days lifts the reorder point from 50 to 70
+			name:         "lead time demand lifts the reorder point",
+			line:         models.SuggestedPurchaseLine{Balance: 60, MinQuantity: 50, DailyConsumption: 2, LeadTimeDays: 10, UnitCost: 1.5},
+			suggested:    true,
+			reorderPoint: 70,
+			quantity:     10,
+			cost:         15,
+		},
+		{
+			name:         "above the reorder point",
+			line:         models.SuggestedPurchaseLine{Balance: 80, MinQuantity: 50, DailyConsumption: 2, LeadTimeDays: 10},
+			reorderPoint: 70,
+		},
+		{
+			name:         "at the reorder point",
+			line:         models.SuggestedPurchaseLine{Balance: 70, MinQuantity: 50, DailyConsumption: 2, LeadTimeDays: 10},
+			reorderPoint: 70,
+		},
+		{
+			name:         "stock on order counts",
+			line:         models.SuggestedPurchaseLine{Balance: 40, OnOrder: 40, MinQuantity: 50, DailyConsumption: 2, LeadTimeDays: 10},
+			reorderPoint: 70,
+		},
+		{
+			name:         "tops up to the maximum plus lead time demand",
+			line:         models.SuggestedPurchaseLine{Balance: 30, OnOrder: 10, MinQuantity: 50, MaxQuantity: 200, DailyConsumption: 2, LeadTimeDays: 10, UnitCost: 2},
+			suggested:    true,
+			reorderPoint: 70,
+			quantity:     180,
+			cost:         360,
+		},
+		{
+			name:         "never less than the reorder quantity",
+			line:         models.SuggestedPurchaseLine{Balance: 65, MinQuantity: 50, ReorderQuantity: 100, DailyConsumption: 2, LeadTimeDays: 10},
+			suggested:    true,
+			reorderPoint: 70,
+			quantity:     100,
+		},
+		{
+			name:         "no lead time",
+			line:         models.SuggestedPurchaseLine{Balance: 20, MinQuantity: 50, DailyConsumption: 2},
+			suggested:    true,
+			reorderPoint: 50,
+			quantity:     30,
+		},
+		{
+			name:         "no consumption",
+			line:         models.SuggestedPurchaseLine{Balance: 60, MinQuantity: 50, LeadTimeDays: 30},
+			reorderPoint: 50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := tt.line
+			if got := suggestPurchase(&line); got != tt.suggested {
+				t.Fatalf("suggestPurchase = %v, want %v", got, tt.suggested)
+			}
+			if math.Abs(line.ReorderPoint-tt.reorderPoint) > 1e-9 {
+				t.Errorf("reorder point = %v, want %v", line.ReorderPoint, tt.reorderPoint)
+			}
+			if math.Abs(line.SuggestedQuantity-tt.quantity) > 1e-9 {
+				t.Errorf("suggested quantity = %v, want %v", line.SuggestedQuantity, tt.quantity)
+			}
+			if math.Abs(line.EstimatedCost-tt.cost) > 1e-9 {
+				t.Errorf("estimated cost = %v, want %v", line.EstimatedCost, tt.cost)
+			}
+		})
+	}
+}
@@ -0,0 +1,865 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/db"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+	"github.com/lib/pq"
+)
+
+type SupplierService interface {
+	GetSuppliers() ([]*models.Supplier, error)
+	GetSupplier(id int) (*models.Supplier, error)
+	CreateSupplier(supplier *models.Supplier) (*models.Supplier, error)
+	UpdateSupplier(id int, supplier *models.Supplier) (*models.Supplier, error)
+	DeleteSupplier(id int) error
+
+	GetSupplierProducts(supplierID int) ([]*models.ProductSupplier, error)
+	GetProductSuppliers(productID int) ([]*models.ProductSupplier, error)
+	SaveProductSupplier(link *models.ProductSupplier) (*models.ProductSupplier, error)
+	DeleteProductSupplier(supplierID, productID int) error
+
+	CreateSupplierPrice(supplierID, productID int, price *models.SupplierPrice) (*models.SupplierPrice, error)
+	DeleteSupplierPrice(supplierID, priceID int) error
+}
+
+type supplierService struct {
+	db *sql.DB
+}
+
+func NewSupplierService() SupplierService {
+	return &supplierService{
+		db: db.GetDB(),
+	}
+}
+
+// Supplier
+func (s *supplierService) GetSuppliers() ([]*models.Supplier, error) {
+	queryStr := `
+		SELECT
+			id,
+			code,
+			name,
+			country,
+			address,
+			currency,
+			payment_terms,
+			lead_time_days,
+			remarks,
+			is_exist,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		FROM
+			suppliers
+		ORDER BY
+			name
+	`
+
+	rows, err := s.db.QueryContext(context.Background(), queryStr)
+	if err != nil {
+		slog.Error("Error querying suppliers", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppliers := []*models.Supplier{}
+	for rows.Next() {
+		supplier := new(models.Supplier)
+		err := rows.Scan(
+			&supplier.ID,
+			&supplier.Code,
+			&supplier.Name,
+			&supplier.Country,
+			&supplier.Address,
+			&supplier.Currency,
+			&supplier.PaymentTerms,
+			&supplier.LeadTimeDays,
+			&supplier.Remarks,
+			&supplier.IsExist,
+			&supplier.CreatedBy,
+			&supplier.CreatedAt,
+			&supplier.UpdatedBy,
+			&supplier.UpdatedAt,
+		)
+		if err != nil {
+			slog.Error("Error scanning supplier", "error", err)
+			return nil, err
+		}
+
+		suppliers = append(suppliers, supplier)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating over suppliers", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried suppliers", "suppliers", len(suppliers))
+
+	return suppliers, nil
+}
+
+func (s *supplierService) GetSupplier(id int) (*models.Supplier, error) {
+	queryStr := `
+		SELECT
+			id,
+			code,
+			name,
+			country,
+			address,
+			currency,
+			payment_terms,
+			lead_time_days,
+			remarks,
+			is_exist,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		FROM
+			suppliers
+		WHERE
+			id = $1
+	`
+
+	supplier := new(models.Supplier)
+	err := s.db.QueryRowContext(context.Background(), queryStr, id).Scan(
+		&supplier.ID,
+		&supplier.Code,
+		&supplier.Name,
+		&supplier.Country,
+		&supplier.Address,
+		&supplier.Currency,
+		&supplier.PaymentTerms,
+		&supplier.LeadTimeDays,
+		&supplier.Remarks,
+		&supplier.IsExist,
+		&supplier.CreatedBy,
+		&supplier.CreatedAt,
+		&supplier.UpdatedBy,
+		&supplier.UpdatedAt,
+	)
+	if err != nil {
+		slog.Error("Error scanning supplier", "error", err)
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			id,
+			supplier_id,
+			name,
+			position,
+			email,
+			phone,
+			is_primary
+		FROM
+			supplier_contacts
+		WHERE
+			supplier_id = $1
+		ORDER BY
+			is_primary DESC,
+			id
+		`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error querying supplier contacts", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	supplier.Contacts = []*models.SupplierContact{}
+	for rows.Next() {
+		contact := new(models.SupplierContact)
+		err := rows.Scan(
+			&contact.ID,
+			&contact.SupplierID,
+			&contact.Name,
+			&contact.Position,
+			&contact.Email,
+			&contact.Phone,
+			&contact.IsPrimary,
+		)
+		if err != nil {
+			slog.Error("Error scanning supplier contact", "error", err)
+			return nil, err
+		}
+
+		supplier.Contacts = append(supplier.Contacts, contact)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating over supplier contacts", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried supplier", "supplier", id)
+
+	return supplier, nil
+}
+
+func validateSupplier(supplier *models.Supplier) error {
+	supplier.Code = strings.TrimSpace(supplier.Code)
+	supplier.Name = strings.TrimSpace(supplier.Name)
+	supplier.Currency = strings.ToUpper(strings.TrimSpace(supplier.Currency))
+
+	if supplier.Code == "" {
+		return &ValidationError{Message: "supplier code is required"}
+	}
+	if supplier.Name == "" {
+		return &ValidationError{Message: "supplier name is required"}
+	}
+	if supplier.LeadTimeDays < 0 {
+		return &ValidationError{Message: "lead time must not be negative"}
+	}
+
+	primary := 0
+	for _, contact := range supplier.Contacts {
+		contact.Name = strings.TrimSpace(contact.Name)
+		if contact.Name == "" {
+			return &ValidationError{Message: "contact name is required"}
+		}
+		if contact.IsPrimary {
+			primary++
+		}
+	}
+	if primary > 1 {
+		return &ValidationError{Message: "a supplier can only have one primary contact"}
+	}
+
+	return nil
+}
+
+func (s *supplierService) CreateSupplier(supplier *models.Supplier) (*models.Supplier, error) {
+	if err := validateSupplier(supplier); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	queryStr := `
+		INSERT INTO suppliers (
+			code,
+			name,
+			country,
+			address,
+			currency,
+			payment_terms,
+			lead_time_days,
+			remarks,
+			is_exist,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), $11, NOW()
+		)
+		RETURNING id
+	`
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		queryStr,
+		supplier.Code,
+		supplier.Name,
+		supplier.Country,
+		supplier.Address,
+		supplier.Currency,
+		supplier.PaymentTerms,
+		supplier.LeadTimeDays,
+		supplier.Remarks,
+		supplier.IsExist,
+		supplier.CreatedBy,
+		supplier.UpdatedBy,
+	).Scan(&supplier.ID)
+	if err != nil {
+		slog.Error("Error inserting supplier", "error", err)
+		return nil, err
+	}
+
+	if err := insertSupplierContacts(tx, supplier.ID, supplier.Contacts); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing supplier", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted supplier", "supplier", supplier.ID)
+
+	return s.GetSupplier(supplier.ID)
+}
+
+// UpdateSupplier replaces the supplier's details and contacts, and renames the
+// free text supplier on products that prefer it.
+func (s *supplierService) UpdateSupplier(id int, supplier *models.Supplier) (*models.Supplier, error) {
+	if err := validateSupplier(supplier); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	queryStr := `
+		UPDATE
+			suppliers
+		SET
+			code = $1,
+			name = $2,
+			country = $3,
+			address = $4,
+			currency = $5,
+			payment_terms = $6,
+			lead_time_days = $7,
+			remarks = $8,
+			is_exist = $9,
+			updated_by = $10,
+			updated_at = NOW()
+		WHERE
+			id = $11
+	`
+
+	result, err := tx.ExecContext(
+		context.Background(),
+		queryStr,
+		supplier.Code,
+		supplier.Name,
+		supplier.Country,
+		supplier.Address,
+		supplier.Currency,
+		supplier.PaymentTerms,
+		supplier.LeadTimeDays,
+		supplier.Remarks,
+		supplier.IsExist,
+		supplier.UpdatedBy,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating supplier", "error", err)
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		`DELETE FROM supplier_contacts WHERE supplier_id = $1`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error deleting supplier contacts", "error", err)
+		return nil, err
+	}
+
+	if err := insertSupplierContacts(tx, id, supplier.Contacts); err != nil {
+		return nil, err
+	}
+
+	if err := syncPreferredSupplier(tx, `ps.supplier_id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing supplier", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully updated supplier", "supplier", id)
+
+	return s.GetSupplier(id)
+}
+
+func (s *supplierService) DeleteSupplier(id int) error {
+	queryStr := `
+		DELETE FROM
+			suppliers
+		WHERE
+			id = $1
+	`
+
+	_, err := s.db.ExecContext(context.Background(), queryStr, id)
+	if err != nil {
+		slog.Error("Error deleting supplier", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted supplier", "supplier", id)
+
+	return nil
+}
+
+func insertSupplierContacts(tx *sql.Tx, supplierID int, contacts []*models.SupplierContact) error {
+	for _, contact := range contacts {
+		_, err := tx.ExecContext(
+			context.Background(),
+			`
+			INSERT INTO supplier_contacts (
+				supplier_id,
+				name,
+				position,
+				email,
+				phone,
+				is_primary
+			) VALUES (
+				$1, $2, $3, $4, $5, $6
+			)
+			`,
+			supplierID,
+			contact.Name,
+			contact.Position,
+			contact.Email,
+			contact.Phone,
+			contact.IsPrimary,
+		)
+		if err != nil {
+			slog.Error("Error inserting supplier contact", "error", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncPreferredSupplier copies the name of each product's preferred supplier
+// into inventory_products.supplier, which reports and older screens still
+// read. where filters product_suppliers ps on arg.
+func syncPreferredSupplier(tx *sql.Tx, where string, arg int) error {
+	_, err := tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_products p
+		SET
+			supplier = s.name
+		FROM
+			product_suppliers ps
+		JOIN
+			suppliers s
+		ON
+			ps.supplier_id = s.id
+		WHERE
+			ps.product_id = p.id
+		AND
+			ps.is_preferred = true
+		AND
+			`+where,
+		arg,
+	)
+	if err != nil {
+		slog.Error("Error syncing preferred supplier", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// Product supplier
+const productSupplierQuery = `
+	SELECT
+		ps.id,
+		ps.product_id,
+		ps.supplier_id,
+		ps.supplier_sku,
+		COALESCE(ps.lead_time_days, 0),
+		COALESCE(ps.lead_time_days, s.lead_time_days),
+		ps.is_preferred,
+		p.code AS product_code,
+		p.name AS product_name,
+		s.code AS supplier_code,
+		s.name AS supplier_name
+	FROM
+		product_suppliers ps
+	JOIN
+		inventory_products p
+	ON
+		ps.product_id = p.id
+	JOIN
+		suppliers s
+	ON
+		ps.supplier_id = s.id
+	`
+
+func (s *supplierService) GetSupplierProducts(supplierID int) ([]*models.ProductSupplier, error) {
+	return s.getProductSuppliers(productSupplierQuery+`
+		WHERE
+			ps.supplier_id = $1
+		ORDER BY
+			p.code
+	`, supplierID)
+}
+
+func (s *supplierService) GetProductSuppliers(productID int) ([]*models.ProductSupplier, error) {
+	return s.getProductSuppliers(productSupplierQuery+`
+		WHERE
+			ps.product_id = $1
+		ORDER BY
+			ps.is_preferred DESC,
+			s.name
+	`, productID)
+}
+
+// getProductSuppliers loads product supplier links with their price lists.
+// The current price is the one valid today with the lowest minimum quantity.
+func (s *supplierService) getProductSuppliers(queryStr string, id int) ([]*models.ProductSupplier, error) {
+	rows, err := s.db.QueryContext(context.Background(), queryStr, id)
+	if err != nil {
+		slog.Error("Error querying product suppliers", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*models.ProductSupplier{}
+	byID := map[int]*models.ProductSupplier{}
+	ids := []int{}
+	for rows.Next() {
+		link := new(models.ProductSupplier)
+		err := rows.Scan(
+			&link.ID,
+			&link.ProductID,
+			&link.SupplierID,
+			&link.SupplierSKU,
+			&link.LeadTimeDays,
+			&link.EffectiveLeadTimeDays,
+			&link.IsPreferred,
+			&link.ProductCode,
+			&link.ProductName,
+			&link.SupplierCode,
+			&link.SupplierName,
+		)
+		if err != nil {
+			slog.Error("Error scanning product supplier", "error", err)
+			return nil, err
+		}
+
+		link.Prices = []*models.SupplierPrice{}
+		links = append(links, link)
+		byID[link.ID] = link
+		ids = append(ids, link.ID)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating over product suppliers", "error", err)
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return links, nil
+	}
+
+	priceRows, err := s.db.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			id,
+			product_supplier_id,
+			unit_cost,
+			currency,
+			min_quantity,
+			valid_from,
+			valid_to,
+			valid_from <= CURRENT_DATE AND (valid_to IS NULL OR valid_to >= CURRENT_DATE) AS is_current,
+			created_by,
+			created_at
+		FROM
+			supplier_prices
+		WHERE
+			product_supplier_id = ANY($1)
+		ORDER BY
+			product_supplier_id,
+			min_quantity,
+			valid_from DESC
+		`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		slog.Error("Error querying supplier prices", "error", err)
+		return nil, err
+	}
+	defer priceRows.Close()
+
+	for priceRows.Next() {
+		price := new(models.SupplierPrice)
+		var validTo sql.NullString
+		err := priceRows.Scan(
+			&price.ID,
+			&price.ProductSupplierID,
+			&price.UnitCost,
+			&price.Currency,
+			&price.MinQuantity,
+			&price.ValidFrom,
+			&validTo,
+			&price.IsCurrent,
+			&price.CreatedBy,
+			&price.CreatedAt,
+		)
+		if err != nil {
+			slog.Error("Error scanning supplier price", "error", err)
+			return nil, err
+		}
+		price.ValidTo = validTo.String
+
+		link := byID[price.ProductSupplierID]
+		link.Prices = append(link.Prices, price)
+		if price.IsCurrent && link.CurrentPrice == nil {
+			link.CurrentPrice = price
+		}
+	}
+
+	if err := priceRows.Err(); err != nil {
+		slog.Error("Error iterating over supplier prices", "error", err)
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// SaveProductSupplier creates or updates the link between a product and a
+// supplier. Marking a link preferred takes the flag off the product's other
+// suppliers.
+func (s *supplierService) SaveProductSupplier(link *models.ProductSupplier) (*models.ProductSupplier, error) {
+	link.SupplierSKU = strings.TrimSpace(link.SupplierSKU)
+	if link.LeadTimeDays < 0 {
+		return nil, &ValidationError{Message: "lead time must not be negative"}
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		`
+		INSERT INTO product_suppliers (
+			product_id,
+			supplier_id,
+			supplier_sku,
+			lead_time_days,
+			is_preferred
+		) VALUES (
+			$1, $2, $3, NULLIF($4, 0), $5
+		)
+		ON CONFLICT (product_id, supplier_id) DO UPDATE
+		SET
+			supplier_sku = EXCLUDED.supplier_sku,
+			lead_time_days = EXCLUDED.lead_time_days,
+			is_preferred = EXCLUDED.is_preferred
+		RETURNING id
+		`,
+		link.ProductID,
+		link.SupplierID,
+		link.SupplierSKU,
+		link.LeadTimeDays,
+		link.IsPreferred,
+	).Scan(&link.ID)
+	if err != nil {
+		slog.Error("Error saving product supplier", "error", err)
+		return nil, err
+	}
+
+	if link.IsPreferred {
+		_, err = tx.ExecContext(
+			context.Background(),
+			`
+			UPDATE
+				product_suppliers
+			SET
+				is_preferred = false
+			WHERE
+				product_id = $1
+			AND
+				id <> $2
+			`,
+			link.ProductID,
+			link.ID,
+		)
+		if err != nil {
+			slog.Error("Error clearing preferred supplier", "error", err)
+			return nil, err
+		}
+
+		if err := syncPreferredSupplier(tx, `ps.product_id = $1`, link.ProductID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing product supplier", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully saved product supplier", "product", link.ProductID, "supplier", link.SupplierID)
+
+	links, err := s.getProductSuppliers(productSupplierQuery+`
+		WHERE
+			ps.id = $1
+	`, link.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return links[0], nil
+}
+
+func (s *supplierService) DeleteProductSupplier(supplierID, productID int) error {
+	queryStr := `
+		DELETE FROM
+			product_suppliers
+		WHERE
+			supplier_id = $1
+		AND
+			product_id = $2
+	`
+
+	_, err := s.db.ExecContext(context.Background(), queryStr, supplierID, productID)
+	if err != nil {
+		slog.Error("Error deleting product supplier", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted product supplier", "supplier", supplierID, "product", productID)
+
+	return nil
+}
+
+// Supplier price
+func (s *supplierService) CreateSupplierPrice(supplierID, productID int, price *models.SupplierPrice) (*models.SupplierPrice, error) {
+	price.Currency = strings.ToUpper(strings.TrimSpace(price.Currency))
+
+	if price.UnitCost < 0 {
+		return nil, &ValidationError{Message: "unit cost must not be negative"}
+	}
+	if price.MinQuantity < 0 {
+		return nil, &ValidationError{Message: "minimum quantity must not be negative"}
+	}
+
+	validFrom, err := parseOptionalDate(price.ValidFrom)
+	if err != nil {
+		return nil, &ValidationError{Message: "valid from must be YYYY-MM-DD"}
+	}
+	validTo, err := parseOptionalDate(price.ValidTo)
+	if err != nil {
+		return nil, &ValidationError{Message: "valid to must be YYYY-MM-DD"}
+	}
+	if validFrom != nil && validTo != nil && validTo.Before(*validFrom) {
+		return nil, &ValidationError{Message: "valid to must not be before valid from"}
+	}
+
+	queryStr := `
+		INSERT INTO supplier_prices (
+			product_supplier_id,
+			unit_cost,
+			currency,
+			min_quantity,
+			valid_from,
+			valid_to,
+			created_by
+		)
+		SELECT
+			ps.id,
+			$3,
+			COALESCE(NULLIF($4, ''), s.currency),
+			$5,
+			COALESCE(NULLIF($6, '')::DATE, CURRENT_DATE),
+			NULLIF($7, '')::DATE,
+			$8
+		FROM
+			product_suppliers ps
+		JOIN
+			suppliers s
+		ON
+			ps.supplier_id = s.id
+		WHERE
+			ps.supplier_id = $1
+		AND
+			ps.product_id = $2
+		RETURNING id, product_supplier_id, currency
+	`
+
+	err = s.db.QueryRowContext(
+		context.Background(),
+		queryStr,
+		supplierID,
+		productID,
+		price.UnitCost,
+		price.Currency,
+		price.MinQuantity,
+		price.ValidFrom,
+		price.ValidTo,
+		price.CreatedBy,
+	).Scan(&price.ID, &price.ProductSupplierID, &price.Currency)
+	if err == sql.ErrNoRows {
+		return nil, &ValidationError{Message: "product is not linked to the supplier"}
+	}
+	if err != nil {
+		slog.Error("Error inserting supplier price", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted supplier price", "price", price.ID)
+
+	return price, nil
+}
+
+func (s *supplierService) DeleteSupplierPrice(supplierID, priceID int) error {
+	queryStr := `
+		DELETE FROM
+			supplier_prices sp
+		USING
+			product_suppliers ps
+		WHERE
+			sp.product_supplier_id = ps.id
+		AND
+			ps.supplier_id = $1
+		AND
+			sp.id = $2
+	`
+
+	_, err := s.db.ExecContext(context.Background(), queryStr, supplierID, priceID)
+	if err != nil {
+		slog.Error("Error deleting supplier price", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted supplier price", "price", priceID)
+
+	return nil
+}
+
+// parseOptionalDate parses a YYYY-MM-DD date, returning nil for an empty one.
+func parseOptionalDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &date, nil
+}
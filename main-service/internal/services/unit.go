@@ -0,0 +1,732 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/config"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/db"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+type UnitService interface {
+	GetUnits() ([]*models.Unit, error)
+	CreateUnit(unit *models.Unit) (*models.Unit, error)
+	UpdateUnit(id int, unit *models.Unit) (*models.Unit, error)
+	DeleteUnit(id int) error
+
+	GetProductUnits(productID int) ([]*models.ProductUnitConversion, error)
+	SaveProductUnit(conversion *models.ProductUnitConversion) (*models.ProductUnitConversion, error)
+	DeleteProductUnit(productID int, unit string) error
+}
+
+type unitService struct {
+	db *sql.DB
+}
+
+func NewUnitService() UnitService {
+	return &unitService{
+		db: db.GetDB(),
+	}
+}
+
+const unitQuery = `
+	SELECT
+		id,
+		code,
+		name,
+		dimension,
+		base_factor,
+		created_by,
+		created_at,
+		updated_by,
+		updated_at
+	FROM
+		units
+	`
+
+func scanUnit(row interface{ Scan(...any) error }, unit *models.Unit) error {
+	return row.Scan(
+		&unit.ID,
+		&unit.Code,
+		&unit.Name,
+		&unit.Dimension,
+		&unit.BaseFactor,
+		&unit.CreatedBy,
+		&unit.CreatedAt,
+		&unit.UpdatedBy,
+		&unit.UpdatedAt,
+	)
+}
+
+// Unit
+func (s *unitService) GetUnits() ([]*models.Unit, error) {
+	rows, err := s.db.QueryContext(
+		context.Background(),
+		unitQuery+`
+		ORDER BY
+			dimension,
+			base_factor DESC,
+			code
+		`,
+	)
+	if err != nil {
+		slog.Error("Error querying units", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	units := []*models.Unit{}
+	for rows.Next() {
+		unit := new(models.Unit)
+		if err := scanUnit(rows, unit); err != nil {
+			slog.Error("Error scanning unit", "error", err)
+			return nil, err
+		}
+		units = append(units, unit)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating units", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried units", "count", len(units))
+
+	return units, nil
+}
+
+func (s *unitService) getUnit(id int) (*models.Unit, error) {
+	unit := new(models.Unit)
+	err := scanUnit(s.db.QueryRowContext(
+		context.Background(),
+		unitQuery+`
+		WHERE
+			id = $1
+		`,
+		id,
+	), unit)
+	if err != nil {
+		slog.Error("Error querying unit", "error", err, "id", id)
+		return nil, err
+	}
+
+	return unit, nil
+}
+
+func validateUnit(unit *models.Unit) error {
+	unit.Code = strings.ToLower(strings.TrimSpace(unit.Code))
+	unit.Name = strings.TrimSpace(unit.Name)
+
+	if unit.Code == "" {
+		return &ValidationError{Message: "unit code is required"}
+	}
+	if unit.Name == "" {
+		unit.Name = unit.Code
+	}
+
+	switch unit.Dimension {
+	case models.UnitDimensionArea,
+		models.UnitDimensionVolume,
+		models.UnitDimensionLength,
+		models.UnitDimensionMass,
+		models.UnitDimensionCount:
+		if unit.BaseFactor <= 0 {
+			return &ValidationError{Message: "base factor must be greater than zero"}
+		}
+	case models.UnitDimensionPackage, models.UnitDimensionOther:
+		if unit.BaseFactor != 0 {
+			return &ValidationError{Message: unit.Dimension + " units convert through product conversions only"}
+		}
+	default:
+		return &ValidationError{Message: "unknown unit dimension: " + unit.Dimension}
+	}
+
+	return nil
+}
+
+func (s *unitService) CreateUnit(unit *models.Unit) (*models.Unit, error) {
+	if err := validateUnit(unit); err != nil {
+		return nil, err
+	}
+
+	queryStr := `
+		INSERT INTO units (
+			code,
+			name,
+			dimension,
+			base_factor,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, NOW(), $6, NOW()
+		)
+		RETURNING id
+	`
+
+	err := s.db.QueryRowContext(
+		context.Background(),
+		queryStr,
+		unit.Code,
+		unit.Name,
+		unit.Dimension,
+		unit.BaseFactor,
+		unit.CreatedBy,
+		unit.UpdatedBy,
+	).Scan(&unit.ID)
+	if err != nil {
+		slog.Error("Error inserting unit", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted unit", "id", unit.ID, "code", unit.Code)
+
+	return s.getUnit(unit.ID)
+}
+
+// UpdateUnit changes the name, dimension and base factor of a unit. The code
+// is kept, products and stock refer to it.
+func (s *unitService) UpdateUnit(id int, unit *models.Unit) (*models.Unit, error) {
+	current, err := s.getUnit(id)
+	if err != nil {
+		return nil, err
+	}
+	unit.Code = current.Code
+
+	if err := validateUnit(unit); err != nil {
+		return nil, err
+	}
+
+	queryStr := `
+		UPDATE
+			units
+		SET
+			name = $1,
+			dimension = $2,
+			base_factor = $3,
+			updated_by = $4,
+			updated_at = NOW()
+		WHERE
+			id = $5
+	`
+
+	_, err = s.db.ExecContext(
+		context.Background(),
+		queryStr,
+		unit.Name,
+		unit.Dimension,
+		unit.BaseFactor,
+		unit.UpdatedBy,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating unit", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully updated unit", "id", id)
+
+	return s.getUnit(id)
+}
+
+// DeleteUnit refuses to remove a unit that is a product's standard unit or
+// part of a conversion.
+func (s *unitService) DeleteUnit(id int) error {
+	var used bool
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`
+		SELECT
+			EXISTS (SELECT 1 FROM inventory_products p WHERE p.standard_unit = u.code)
+			OR EXISTS (SELECT 1 FROM product_unit_conversions c WHERE u.code IN (c.unit, c.to_unit))
+		FROM
+			units u
+		WHERE
+			u.id = $1
+		`,
+		id,
+	).Scan(&used)
+	if err != nil {
+		slog.Error("Error checking unit usage", "error", err, "id", id)
+		return err
+	}
+	if used {
+		return &ValidationError{Message: "unit is in use"}
+	}
+
+	_, err = s.db.ExecContext(
+		context.Background(),
+		`DELETE FROM units WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error deleting unit", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted unit", "id", id)
+
+	return nil
+}
+
+// Product unit conversion
+func (s *unitService) GetProductUnits(productID int) ([]*models.ProductUnitConversion, error) {
+	converter, err := loadUnitConverter(s.db, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	conversions := []*models.ProductUnitConversion{}
+	for _, conversion := range converter.conversions {
+		// a conversion that no longer reaches the standard unit, because a
+		// unit changed dimension, is listed without a standard factor
+		conversion.StandardFactor, _ = converter.factor(conversion.Unit, converter.standardUnit)
+		conversions = append(conversions, conversion)
+	}
+	sort.Slice(conversions, func(i, j int) bool {
+		return conversions[i].Unit < conversions[j].Unit
+	})
+
+	slog.Info("Successfully queried product units", "product", productID, "count", len(conversions))
+
+	return conversions, nil
+}
+
+// SaveProductUnit adds or replaces the conversion of one unit of a product,
+// and refuses it unless the unit then converts to the standard unit.
+func (s *unitService) SaveProductUnit(conversion *models.ProductUnitConversion) (*models.ProductUnitConversion, error) {
+	conversion.Unit = strings.ToLower(strings.TrimSpace(conversion.Unit))
+	conversion.ToUnit = strings.ToLower(strings.TrimSpace(conversion.ToUnit))
+
+	if conversion.Unit == "" || conversion.ToUnit == "" {
+		return nil, &ValidationError{Message: "unit and to unit are required"}
+	}
+	if conversion.Unit == conversion.ToUnit {
+		return nil, &ValidationError{Message: "unit and to unit must differ"}
+	}
+	if conversion.Factor <= 0 {
+		return nil, &ValidationError{Message: "factor must be greater than zero"}
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	converter, err := loadUnitConverter(tx, conversion.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range []string{conversion.Unit, conversion.ToUnit} {
+		if _, ok := converter.units[code]; !ok {
+			return nil, &ValidationError{Message: "unknown unit: " + code}
+		}
+	}
+	if conversion.Unit == converter.standardUnit {
+		return nil, &ValidationError{Message: "the standard unit cannot be converted"}
+	}
+
+	converter.conversions[conversion.Unit] = conversion
+	conversion.StandardFactor, err = converter.factor(conversion.Unit, converter.standardUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	queryStr := `
+		INSERT INTO product_unit_conversions (
+			product_id,
+			unit,
+			factor,
+			to_unit,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, NOW(), $6, NOW()
+		)
+		ON CONFLICT (product_id, unit) DO UPDATE SET
+			factor = EXCLUDED.factor,
+			to_unit = EXCLUDED.to_unit,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING id, created_by, created_at, updated_at
+	`
+
+	err = tx.QueryRowContext(
+		context.Background(),
+		queryStr,
+		conversion.ProductID,
+		conversion.Unit,
+		conversion.Factor,
+		conversion.ToUnit,
+		conversion.CreatedBy,
+		conversion.UpdatedBy,
+	).Scan(
+		&conversion.ID,
+		&conversion.CreatedBy,
+		&conversion.CreatedAt,
+		&conversion.UpdatedAt,
+	)
+	if err != nil {
+		slog.Error("Error saving product unit", "error", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing product unit", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully saved product unit", "product", conversion.ProductID, "unit", conversion.Unit)
+
+	return conversion, nil
+}
+
+// DeleteProductUnit refuses to remove a conversion another one leads through.
+func (s *unitService) DeleteProductUnit(productID int, unit string) error {
+	var dependants int
+	err := s.db.QueryRowContext(
+		context.Background(),
+		`SELECT COUNT(*) FROM product_unit_conversions WHERE product_id = $1 AND to_unit = $2`,
+		productID,
+		unit,
+	).Scan(&dependants)
+	if err != nil {
+		slog.Error("Error counting dependent product units", "error", err)
+		return err
+	}
+	if dependants > 0 {
+		return &ValidationError{Message: "other units of the product convert through " + unit}
+	}
+
+	_, err = s.db.ExecContext(
+		context.Background(),
+		`DELETE FROM product_unit_conversions WHERE product_id = $1 AND unit = $2`,
+		productID,
+		unit,
+	)
+	if err != nil {
+		slog.Error("Error deleting product unit", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted product unit", "product", productID, "unit", unit)
+
+	return nil
+}
+
+// unitConverter converts quantities of one product between units, through
+// the product's own conversions and then the base factors of a dimension.
+type unitConverter struct {
+	standardUnit string
+	units        map[string]*models.Unit
+	conversions  map[string]*models.ProductUnitConversion
+}
+
+func loadUnitConverter(q queryer, productID int) (*unitConverter, error) {
+	converter := &unitConverter{
+		units:       map[string]*models.Unit{},
+		conversions: map[string]*models.ProductUnitConversion{},
+	}
+
+	err := q.QueryRowContext(
+		context.Background(),
+		`SELECT standard_unit FROM inventory_products WHERE id = $1`,
+		productID,
+	).Scan(&converter.standardUnit)
+	if err == sql.ErrNoRows {
+		return nil, &ValidationError{Message: "product does not exist"}
+	}
+	if err != nil {
+		slog.Error("Error querying product standard unit", "error", err, "product", productID)
+		return nil, err
+	}
+	converter.standardUnit = strings.TrimSpace(converter.standardUnit)
+
+	rows, err := q.QueryContext(context.Background(), unitQuery)
+	if err != nil {
+		slog.Error("Error querying units", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		unit := new(models.Unit)
+		if err := scanUnit(rows, unit); err != nil {
+			slog.Error("Error scanning unit", "error", err)
+			return nil, err
+		}
+		converter.units[unit.Code] = unit
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating units", "error", err)
+		return nil, err
+	}
+
+	rows, err = q.QueryContext(
+		context.Background(),
+		`
+		SELECT
+			id,
+			product_id,
+			unit,
+			factor,
+			to_unit,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		FROM
+			product_unit_conversions
+		WHERE
+			product_id = $1
+		ORDER BY
+			unit
+		`,
+		productID,
+	)
+	if err != nil {
+		slog.Error("Error querying product units", "error", err, "product", productID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		conversion := new(models.ProductUnitConversion)
+		err := rows.Scan(
+			&conversion.ID,
+			&conversion.ProductID,
+			&conversion.Unit,
+			&conversion.Factor,
+			&conversion.ToUnit,
+			&conversion.CreatedBy,
+			&conversion.CreatedAt,
+			&conversion.UpdatedBy,
+			&conversion.UpdatedAt,
+		)
+		if err != nil {
+			slog.Error("Error scanning product unit", "error", err)
+			return nil, err
+		}
+		converter.conversions[conversion.Unit] = conversion
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating product units", "error", err)
+		return nil, err
+	}
+
+	return converter, nil
+}
+
+// factor is how many to one from is, following the product's conversions
+// until a unit of the same dimension as to is reached.
+func (c *unitConverter) factor(from, to string) (float64, error) {
+	if _, ok := c.units[from]; !ok {
+		return 0, &ValidationError{Message: "unknown unit: " + from}
+	}
+
+	factor := 1.0
+	visited := map[string]bool{}
+	for unit := from; ; {
+		if unit == to {
+			return factor, nil
+		}
+		if ratio, ok := c.sameDimension(unit, to); ok {
+			return factor * ratio, nil
+		}
+
+		conversion, ok := c.conversions[unit]
+		if !ok || visited[unit] {
+			return 0, &ValidationError{Message: fmt.Sprintf("%s cannot be converted to %s for this product", from, to)}
+		}
+		visited[unit] = true
+
+		factor *= conversion.Factor
+		unit = conversion.ToUnit
+	}
+}
+
+func (c *unitConverter) sameDimension(from, to string) (float64, bool) {
+	a, ok := c.units[from]
+	if !ok || a.BaseFactor <= 0 {
+		return 0, false
+	}
+	b, ok := c.units[to]
+	if !ok || b.BaseFactor <= 0 || a.Dimension != b.Dimension {
+		return 0, false
+	}
+	return a.BaseFactor / b.BaseFactor, true
+}
+
+// applyQuantityUnit works out the standard quantity of a quantity keyed in
+// unit. Without a unit the caller converted by hand, so the standard quantity
+// is kept and a missing side is taken to be in the standard unit.
+func applyQuantityUnit(q queryer, productID int, unit *string, quantity, standardQuantity *float64) error {
+	converter, err := loadUnitConverter(q, productID)
+	if err != nil {
+		return err
+	}
+
+	return converter.apply(unit, quantity, standardQuantity)
+}
+
+func (c *unitConverter) apply(unit *string, quantity, standardQuantity *float64) error {
+	*unit = strings.TrimSpace(*unit)
+	if *unit == "" {
+		if *quantity == 0 {
+			*quantity = *standardQuantity
+		}
+		if *standardQuantity == 0 {
+			*standardQuantity = *quantity
+		}
+		if math.Abs(*quantity-*standardQuantity) <= stockTolerance {
+			*unit = c.standardUnit
+		}
+		return nil
+	}
+
+	factor, err := c.factor(*unit, c.standardUnit)
+	if err != nil {
+		return err
+	}
+	*standardQuantity = *quantity * factor
+
+	return nil
+}
+
+// litresPerCubicMetre links the length dimension to the volume dimension,
+// whose base unit is the litre.
+const litresPerCubicMetre = 1000
+
+// standardQuantityRounding lets a client supplied standard quantity be off by
+// rounding to two decimals without being flagged.
+const standardQuantityRounding = 0.005
+
+// applyIncomingQuantity works out the standard quantity of an incoming. A
+// piece count with dimensions is measured out in the product's standard unit,
+// and a standard quantity the client worked out as well has to agree with it
+// within the configured tolerance. Anything else converts by unit.
+func applyIncomingQuantity(q queryer, incoming *models.InventoryIncoming) error {
+	converter, err := loadUnitConverter(q, incoming.ProductID)
+	if err != nil {
+		return err
+	}
+
+	return converter.applyIncoming(incoming)
+}
+
+func (c *unitConverter) applyIncoming(incoming *models.InventoryIncoming) error {
+	incoming.QuantityUnit = strings.TrimSpace(incoming.QuantityUnit)
+	incoming.Unit = strings.TrimSpace(incoming.Unit)
+
+	measured, ok, err := c.measure(incoming)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return c.apply(&incoming.QuantityUnit, &incoming.Quantity, &incoming.StandardQuantity)
+	}
+
+	allowed := math.Max(measured*config.Cfg.StandardQuantityTolerance, standardQuantityRounding)
+	if incoming.StandardQuantity != 0 && math.Abs(incoming.StandardQuantity-measured) > allowed {
+		return &ValidationError{Message: fmt.Sprintf(
+			"standard quantity %v %s does not match %v %s of %v x %v x %v %s, which is %v %s",
+			incoming.StandardQuantity,
+			c.standardUnit,
+			incoming.Quantity,
+			incoming.QuantityUnit,
+			incoming.Length,
+			incoming.Width,
+			incoming.Height,
+			incoming.Unit,
+			math.Round(measured*1e6)/1e6,
+			c.standardUnit,
+		)}
+	}
+	incoming.StandardQuantity = measured
+
+	return nil
+}
+
+// measure works out the standard quantity of an incoming keyed in as a count
+// of pieces from the length, width and height of one piece. It reports false
+// when the quantity is not a count, no dimensions were given or the standard
+// unit is not a length, area or volume, leaving it to the unit conversions.
+func (c *unitConverter) measure(incoming *models.InventoryIncoming) (float64, bool, error) {
+	count, ok := c.units[incoming.QuantityUnit]
+	if !ok || count.Dimension != models.UnitDimensionCount {
+		return 0, false, nil
+	}
+	if incoming.Length == 0 && incoming.Width == 0 && incoming.Height == 0 {
+		return 0, false, nil
+	}
+
+	standard, ok := c.units[c.standardUnit]
+	if !ok || standard.BaseFactor <= 0 {
+		return 0, false, nil
+	}
+
+	var dimensions []float64
+	switch standard.Dimension {
+	case models.UnitDimensionLength:
+		dimensions = []float64{incoming.Length}
+	case models.UnitDimensionArea:
+		dimensions = []float64{incoming.Length, incoming.Width}
+	case models.UnitDimensionVolume:
+		dimensions = []float64{incoming.Length, incoming.Width, incoming.Height}
+	default:
+		return 0, false, nil
+	}
+
+	length, ok := c.units[incoming.Unit]
+	if !ok || length.Dimension != models.UnitDimensionLength {
+		return 0, false, &ValidationError{Message: "dimension unit must be a length unit such as mm"}
+	}
+
+	// one piece in metres, square metres or cubic metres
+	piece := 1.0
+	for _, dimension := range dimensions {
+		if dimension <= 0 {
+			return 0, false, &ValidationError{Message: fmt.Sprintf(
+				"%d dimensions are needed to measure %s",
+				len(dimensions),
+				c.standardUnit,
+			)}
+		}
+		piece *= dimension * length.BaseFactor
+	}
+	if standard.Dimension == models.UnitDimensionVolume {
+		piece *= litresPerCubicMetre
+	}
+
+	return incoming.Quantity * count.BaseFactor * piece / standard.BaseFactor, true, nil
+}
+
+// checkStandardUnit makes sure a product's standard unit is a known unit.
+func checkStandardUnit(q queryer, code string) error {
+	var exists bool
+	err := q.QueryRowContext(
+		context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM units WHERE code = $1)`,
+		code,
+	).Scan(&exists)
+	if err != nil {
+		slog.Error("Error checking standard unit", "error", err, "unit", code)
+		return err
+	}
+	if !exists {
+		return &ValidationError{Message: "unknown standard unit: " + code}
+	}
+
+	return nil
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/config"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+// testConverter is a sealant sold by the sausage, 600 ml each and 20 to a
+// carton, kept in litres.
+func testConverter(standardUnit string) *unitConverter {
+	units := []*models.Unit{
+		{Code: "sqm", Dimension: models.UnitDimensionArea, BaseFactor: 1},
+		{Code: "sqft", Dimension: models.UnitDimensionArea, BaseFactor: 0.09290304},
+		{Code: "cbm", Dimension: models.UnitDimensionVolume, BaseFactor: 1000},
+		{Code: "litre", Dimension: models.UnitDimensionVolume, BaseFactor: 1},
+		{Code: "ml", Dimension: models.UnitDimensionVolume, BaseFactor: 0.001},
+		{Code: "m", Dimension: models.UnitDimensionLength, BaseFactor: 1},
+		{Code: "mm", Dimension: models.UnitDimensionLength, BaseFactor: 0.001},
+		{Code: "ft", Dimension: models.UnitDimensionLength, BaseFactor: 0.3048},
+		{Code: "kg", Dimension: models.UnitDimensionMass, BaseFactor: 1},
+		{Code: "pcs", Dimension: models.UnitDimensionCount, BaseFactor: 1},
+		{Code: "sausage", Dimension: models.UnitDimensionPackage},
+		{Code: "carton", Dimension: models.UnitDimensionPackage},
+		{Code: "pallet", Dimension: models.UnitDimensionPackage},
+	}
+
+	converter := &unitConverter{
+		standardUnit: standardUnit,
+		units:        map[string]*models.Unit{},
+		conversions: map[string]*models.ProductUnitConversion{
+			"sausage": {Unit: "sausage", Factor: 600, ToUnit: "ml"},
+			"carton":  {Unit: "carton", Factor: 20, ToUnit: "sausage"},
+			"pallet":  {Unit: "pallet", Factor: 2, ToUnit: "pallet"},
+		},
+	}
+	for _, unit := range units {
+		converter.units[unit.Code] = unit
+	}
+
+	return converter
+}
+
+func TestUnitConverterFactor(t *testing.T) {
+	tests := []struct {
+		from    string
+		to      string
+		want    float64
+		invalid bool
+	}{
+		{from: "litre", to: "litre", want: 1},
+		{from: "ml", to: "litre", want: 0.001},
+		{from: "cbm", to: "litre", want: 1000},
+		{from: "sqft", to: "sqm", want: 0.09290304},
+		{from: "mm", to: "ft", want: 0.001 / 0.3048},
+		{from: "sausage", to: "litre", want: 0.6},
+		{from: "carton", to: "litre", want: 12},
+		{from: "carton", to: "cbm", want: 0.012},
+		{from: "sausage", to: "kg", invalid: true},
+		{from: "litre", to: "kg", invalid: true},
+		{from: "pallet", to: "litre", invalid: true},
+		{from: "drum", to: "litre", invalid: true},
+	}
+
+	converter := testConverter("litre")
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			got, err := converter.factor(tt.from, tt.to)
+			if tt.invalid {
+				var validation *ValidationError
+				if !errors.As(err, &validation) {
+					t.Fatalf("factor(%q, %q) error = %v, want a ValidationError", tt.from, tt.to, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("factor(%q, %q): %v", tt.from, tt.to, err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("factor(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnitConverterRoundTrip(t *testing.T) {
+	pairs := [][2]string{
+		{"ml", "cbm"},
+		{"sqft", "sqm"},
+		{"mm", "ft"},
+	}
+
+	converter := testConverter("litre")
+	for _, pair := range pairs {
+		there, err := converter.factor(pair[0], pair[1])
+		if err != nil {
+			t.Fatalf("factor(%q, %q): %v", pair[0], pair[1], err)
+		}
+		back, err := converter.factor(pair[1], pair[0])
+		if err != nil {
+			t.Fatalf("factor(%q, %q): %v", pair[1], pair[0], err)
+		}
+		if math.Abs(there*back-1) > 1e-9 {
+			t.Errorf("%s to %s and back is %v, want 1", pair[0], pair[1], there*back)
+		}
+	}
+}
+
+func TestUnitConverterApply(t *testing.T) {
+	tests := []struct {
+		name             string
+		unit             string
+		quantity         float64
+		standardQuantity float64
+		wantUnit         string
+		wantQuantity     float64
+		wantStandard     float64
+	}{
+		{
+			name:         "converts a keyed in unit",
+			unit:         " carton ",
+			quantity:     3,
+			wantUnit:     "carton",
+			wantQuantity: 3,
+			wantStandard: 36,
+		},
+		{
+			name:         "takes a bare quantity as the standard unit",
+			quantity:     5,
+			wantUnit:     "litre",
+			wantQuantity: 5,
+			wantStandard: 5,
+		},
+		{
+			name:             "keeps a hand conversion without a unit",
+			quantity:         2,
+			standardQuantity: 1.2,
+			wantQuantity:     2,
+			wantStandard:     1.2,
+		},
+	}
+
+	converter := testConverter("litre")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit, quantity, standardQuantity := tt.unit, tt.quantity, tt.standardQuantity
+			if err := converter.apply(&unit, &quantity, &standardQuantity); err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if unit != tt.wantUnit || quantity != tt.wantQuantity || math.Abs(standardQuantity-tt.wantStandard) > 1e-9 {
+				t.Errorf("apply = %q %v %v, want %q %v %v", unit, quantity, standardQuantity, tt.wantUnit, tt.wantQuantity, tt.wantStandard)
+			}
+		})
+	}
+}
+
+func TestUnitConverterMeasure(t *testing.T) {
+	tests := []struct {
+		name         string
+		standardUnit string
+		incoming     models.InventoryIncoming
+		want         float64
+		measured     bool
+		invalid      bool
+	}{
+		{
+			name:         "length",
+			standardUnit: "m",
+			incoming:     models.InventoryIncoming{Quantity: 4, QuantityUnit: "pcs", Length: 2500, Unit: "mm"},
+			want:         10,
+			measured:     true,
+		},
+		{
+			name:         "area",
+			standardUnit: "sqm",
+			incoming:     models.InventoryIncoming{Quantity: 10, QuantityUnit: "pcs", Length: 2440, Width: 1220, Unit: "mm"},
+			want:         29.7680,
+			measured:     true,
+		},
+		{
+			name:         "area in feet",
+			standardUnit: "sqft",
+			incoming:     models.InventoryIncoming{Quantity: 1, QuantityUnit: "pcs", Length: 1, Width: 1, Unit: "m"},
+			want:         1 / 0.09290304,
+			measured:     true,
+		},
+		{
+			name:         "volume",
+			standardUnit: "litre",
+			incoming:     models.InventoryIncoming{Quantity: 2, QuantityUnit: "pcs", Length: 1000, Width: 500, Height: 100, Unit: "mm"},
+			want:         100,
+			measured:     true,
+		},
+		{
+			name:         "not a count",
+			standardUnit: "sqm",
+			incoming:     models.InventoryIncoming{Quantity: 3, QuantityUnit: "sqm", Length: 1000, Width: 1000, Unit: "mm"},
+		},
+		{
+			name:         "no dimensions",
+			standardUnit: "sqm",
+			incoming:     models.InventoryIncoming{Quantity: 3, QuantityUnit: "pcs", Unit: "mm"},
+		},
+		{
+			name:         "standard unit not measurable",
+			standardUnit: "kg",
+			incoming:     models.InventoryIncoming{Quantity: 3, QuantityUnit: "pcs", Length: 1000, Unit: "mm"},
+		},
+		{
+			name:         "dimension unit not a length",
+			standardUnit: "sqm",
+			incoming:     models.InventoryIncoming{Quantity: 3, QuantityUnit: "pcs", Length: 1000, Width: 1000, Unit: "pcs"},
+			invalid:      true,
+		},
+		{
+			name:         "missing dimension",
+			standardUnit: "sqm",
+			incoming:     models.InventoryIncoming{Quantity: 3, QuantityUnit: "pcs", Length: 1000, Unit: "mm"},
+			invalid:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, measured, err := testConverter(tt.standardUnit).measure(&tt.incoming)
+			if tt.invalid {
+				var validation *ValidationError
+				if !errors.As(err, &validation) {
+					t.Fatalf("measure error = %v, want a ValidationError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("measure: %v", err)
+			}
+			if measured != tt.measured || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("measure = %v %v, want %v %v", got, measured, tt.want, tt.measured)
+			}
+		})
+	}
+}
+
+func TestApplyIncomingTolerance(t *testing.T) {
+	tolerance := config.Cfg.StandardQuantityTolerance
+	config.Cfg.StandardQuantityTolerance = 0.001
+	defer func() { config.Cfg.StandardQuantityTolerance = tolerance }()
+
+	tests := []struct {
+		name             string
+		quantity         float64
+		length           float64
+		standardQuantity float64
+		invalid          bool
+	}{
+		// 100 pieces of 2 x 1 m measure 200 sqm, 0.2 either way is allowed
+		{name: "worked out by the server", quantity: 100, length: 2000},
+		{name: "exact", quantity: 100, length: 2000, standardQuantity: 200},
+		{name: "at the upper bound", quantity: 100, length: 2000, standardQuantity: 200.2},
+		{name: "at the lower bound", quantity: 100, length: 2000, standardQuantity: 199.8},
+		{name: "over the bound", quantity: 100, length: 2000, standardQuantity: 200.21, invalid: true},
+		{name: "under the bound", quantity: 100, length: 2000, standardQuantity: 199.79, invalid: true},
+		// 1 piece of 0.1 x 1 m is 0.1 sqm, rounding to two decimals is allowed
+		{name: "rounded", quantity: 1, length: 100, standardQuantity: 0.104},
+		{name: "off by more than rounding", quantity: 1, length: 100, standardQuantity: 0.11, invalid: true},
+	}
+
+	converter := testConverter("sqm")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			incoming := &models.InventoryIncoming{
+				Quantity:         tt.quantity,
+				QuantityUnit:     "pcs",
+				Length:           tt.length,
+				Width:            1000,
+				Unit:             "mm",
+				StandardQuantity: tt.standardQuantity,
+			}
+			want := tt.quantity * tt.length / 1000
+
+			err := converter.applyIncoming(incoming)
+			if tt.invalid {
+				var validation *ValidationError
+				if !errors.As(err, &validation) {
+					t.Fatalf("applyIncoming error = %v, want a ValidationError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyIncoming: %v", err)
+			}
+			if math.Abs(incoming.StandardQuantity-want) > 1e-9 {
+				t.Errorf("standard quantity = %v, want the measured %v", incoming.StandardQuantity, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,711 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/db"
+	"github.com/kokweikhong/calvary-admin-system/main-service/internal/models"
+)
+
+type WarehouseService interface {
+	GetWarehouses() ([]*models.Warehouse, error)
+	GetWarehouse(id int) (*models.Warehouse, error)
+	CreateWarehouse(warehouse *models.Warehouse) (*models.Warehouse, error)
+	UpdateWarehouse(id int, warehouse *models.Warehouse) (*models.Warehouse, error)
+	DeleteWarehouse(id int) error
+	GetWarehouseStock(id int) ([]*models.LocationStock, error)
+
+	GetLocations(warehouseID int) ([]*models.Location, error)
+	GetLocation(id int) (*models.Location, error)
+	CreateLocation(location *models.Location) (*models.Location, error)
+	UpdateLocation(id int, location *models.Location) (*models.Location, error)
+	DeleteLocation(id int) error
+	GetLocationStock(id int) ([]*models.LocationStock, error)
+}
+
+type warehouseService struct {
+	db *sql.DB
+}
+
+func NewWarehouseService() WarehouseService {
+	return &warehouseService{
+		db: db.GetDB(),
+	}
+}
+
+// Warehouse
+func (s *warehouseService) GetWarehouses() ([]*models.Warehouse, error) {
+	queryStr := `
+		SELECT
+			id,
+			code,
+			name,
+			country,
+			address,
+			is_exist,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		FROM
+			warehouses
+		ORDER BY
+			country,
+			code
+	`
+
+	rows, err := s.db.QueryContext(context.Background(), queryStr)
+	if err != nil {
+		slog.Error("Error querying warehouses", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	warehouses := []*models.Warehouse{}
+	for rows.Next() {
+		warehouse := new(models.Warehouse)
+		err := rows.Scan(
+			&warehouse.ID,
+			&warehouse.Code,
+			&warehouse.Name,
+			&warehouse.Country,
+			&warehouse.Address,
+			&warehouse.IsExist,
+			&warehouse.CreatedBy,
+			&warehouse.CreatedAt,
+			&warehouse.UpdatedBy,
+			&warehouse.UpdatedAt,
+		)
+		if err != nil {
+			slog.Error("Error scanning warehouse", "error", err)
+			return nil, err
+		}
+
+		warehouses = append(warehouses, warehouse)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating over warehouses", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried warehouses", "warehouses", len(warehouses))
+
+	return warehouses, nil
+}
+
+func (s *warehouseService) GetWarehouse(id int) (*models.Warehouse, error) {
+	queryStr := `
+		SELECT
+			id,
+			code,
+			name,
+			country,
+			address,
+			is_exist,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		FROM
+			warehouses
+		WHERE
+			id = $1
+	`
+
+	warehouse := new(models.Warehouse)
+	err := s.db.QueryRowContext(context.Background(), queryStr, id).Scan(
+		&warehouse.ID,
+		&warehouse.Code,
+		&warehouse.Name,
+		&warehouse.Country,
+		&warehouse.Address,
+		&warehouse.IsExist,
+		&warehouse.CreatedBy,
+		&warehouse.CreatedAt,
+		&warehouse.UpdatedBy,
+		&warehouse.UpdatedAt,
+	)
+	if err != nil {
+		slog.Error("Error scanning warehouse", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried warehouse", "warehouse", warehouse)
+
+	return warehouse, nil
+}
+
+func (s *warehouseService) CreateWarehouse(warehouse *models.Warehouse) (*models.Warehouse, error) {
+	queryStr := `
+		INSERT INTO warehouses (
+			code,
+			name,
+			country,
+			address,
+			is_exist,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, NOW(), $7, NOW()
+		)
+		RETURNING id
+	`
+
+	err := s.db.QueryRowContext(
+		context.Background(),
+		queryStr,
+		warehouse.Code,
+		warehouse.Name,
+		warehouse.Country,
+		warehouse.Address,
+		warehouse.IsExist,
+		warehouse.CreatedBy,
+		warehouse.UpdatedBy,
+	).Scan(&warehouse.ID)
+	if err != nil {
+		slog.Error("Error inserting warehouse", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted warehouse", "warehouse", warehouse)
+
+	return warehouse, nil
+}
+
+func (s *warehouseService) UpdateWarehouse(id int, warehouse *models.Warehouse) (*models.Warehouse, error) {
+	queryStr := `
+		UPDATE
+			warehouses
+		SET
+			code = $1,
+			name = $2,
+			country = $3,
+			address = $4,
+			is_exist = $5,
+			updated_by = $6,
+			updated_at = NOW()
+		WHERE
+			id = $7
+	`
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		queryStr,
+		warehouse.Code,
+		warehouse.Name,
+		warehouse.Country,
+		warehouse.Address,
+		warehouse.IsExist,
+		warehouse.UpdatedBy,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating warehouse", "error", err)
+		return nil, err
+	}
+
+	// keep the country text on incomings in line with the warehouse
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_incomings i
+		SET
+			store_country = $1
+		FROM
+			locations l
+		WHERE
+			i.location_id = l.id
+		AND
+			l.warehouse_id = $2
+		`,
+		warehouse.Country,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating incoming country", "error", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing warehouse", "error", err)
+		return nil, err
+	}
+
+	warehouse.ID = id
+
+	slog.Info("Successfully updated warehouse", "warehouse", warehouse)
+
+	return warehouse, nil
+}
+
+func (s *warehouseService) DeleteWarehouse(id int) error {
+	queryStr := `
+		DELETE FROM
+			warehouses
+		WHERE
+			id = $1
+	`
+
+	_, err := s.db.ExecContext(context.Background(), queryStr, id)
+	if err != nil {
+		slog.Error("Error deleting warehouse", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted warehouse", "warehouse", id)
+
+	return nil
+}
+
+func (s *warehouseService) GetWarehouseStock(id int) ([]*models.LocationStock, error) {
+	return s.getStock(`
+		SELECT
+			id
+		FROM
+			locations
+		WHERE
+			warehouse_id = $1
+	`, id)
+}
+
+// Location
+func (s *warehouseService) GetLocations(warehouseID int) ([]*models.Location, error) {
+	queryStr := `
+		SELECT
+			l.id,
+			l.warehouse_id,
+			COALESCE(l.parent_id, 0),
+			l.type,
+			l.code,
+			l.name,
+			l.is_exist,
+			l.created_by,
+			l.created_at,
+			l.updated_by,
+			l.updated_at,
+			w.code AS warehouse_code,
+			w.name AS warehouse_name,
+			w.country
+		FROM
+			locations l
+		JOIN
+			warehouses w
+		ON
+			l.warehouse_id = w.id
+		WHERE
+			$1 = 0 OR l.warehouse_id = $1
+		ORDER BY
+			w.code,
+			l.code
+	`
+
+	rows, err := s.db.QueryContext(context.Background(), queryStr, warehouseID)
+	if err != nil {
+		slog.Error("Error querying locations", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []*models.Location{}
+	for rows.Next() {
+		location := new(models.Location)
+		err := rows.Scan(
+			&location.ID,
+			&location.WarehouseID,
+			&location.ParentID,
+			&location.Type,
+			&location.Code,
+			&location.Name,
+			&location.IsExist,
+			&location.CreatedBy,
+			&location.CreatedAt,
+			&location.UpdatedBy,
+			&location.UpdatedAt,
+			&location.WarehouseCode,
+			&location.WarehouseName,
+			&location.Country,
+		)
+		if err != nil {
+			slog.Error("Error scanning location", "error", err)
+			return nil, err
+		}
+
+		locations = append(locations, location)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating over locations", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried locations", "locations", len(locations))
+
+	return locations, nil
+}
+
+func (s *warehouseService) GetLocation(id int) (*models.Location, error) {
+	queryStr := `
+		SELECT
+			l.id,
+			l.warehouse_id,
+			COALESCE(l.parent_id, 0),
+			l.type,
+			l.code,
+			l.name,
+			l.is_exist,
+			l.created_by,
+			l.created_at,
+			l.updated_by,
+			l.updated_at,
+			w.code AS warehouse_code,
+			w.name AS warehouse_name,
+			w.country
+		FROM
+			locations l
+		JOIN
+			warehouses w
+		ON
+			l.warehouse_id = w.id
+		WHERE
+			l.id = $1
+	`
+
+	location := new(models.Location)
+	err := s.db.QueryRowContext(context.Background(), queryStr, id).Scan(
+		&location.ID,
+		&location.WarehouseID,
+		&location.ParentID,
+		&location.Type,
+		&location.Code,
+		&location.Name,
+		&location.IsExist,
+		&location.CreatedBy,
+		&location.CreatedAt,
+		&location.UpdatedBy,
+		&location.UpdatedAt,
+		&location.WarehouseCode,
+		&location.WarehouseName,
+		&location.Country,
+	)
+	if err != nil {
+		slog.Error("Error scanning location", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried location", "location", location)
+
+	return location, nil
+}
+
+func (s *warehouseService) CreateLocation(location *models.Location) (*models.Location, error) {
+	queryStr := `
+		INSERT INTO locations (
+			warehouse_id,
+			parent_id,
+			type,
+			code,
+			name,
+			is_exist,
+			created_by,
+			created_at,
+			updated_by,
+			updated_at
+		) VALUES (
+			$1, NULLIF($2, 0), $3, $4, $5, $6, $7, NOW(), $8, NOW()
+		)
+		RETURNING id
+	`
+
+	if err := s.validateLocation(0, location); err != nil {
+		return nil, err
+	}
+
+	err := s.db.QueryRowContext(
+		context.Background(),
+		queryStr,
+		location.WarehouseID,
+		location.ParentID,
+		location.Type,
+		location.Code,
+		location.Name,
+		location.IsExist,
+		location.CreatedBy,
+		location.UpdatedBy,
+	).Scan(&location.ID)
+	if err != nil {
+		slog.Error("Error inserting location", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully inserted location", "location", location)
+
+	return location, nil
+}
+
+func (s *warehouseService) UpdateLocation(id int, location *models.Location) (*models.Location, error) {
+	queryStr := `
+		UPDATE
+			locations
+		SET
+			warehouse_id = $1,
+			parent_id = NULLIF($2, 0),
+			type = $3,
+			code = $4,
+			name = $5,
+			is_exist = $6,
+			updated_by = $7,
+			updated_at = NOW()
+		WHERE
+			id = $8
+	`
+
+	if err := s.validateLocation(id, location); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		slog.Error("Error starting transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		context.Background(),
+		queryStr,
+		location.WarehouseID,
+		location.ParentID,
+		location.Type,
+		location.Code,
+		location.Name,
+		location.IsExist,
+		location.UpdatedBy,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating location", "error", err)
+		return nil, err
+	}
+
+	// keep the location text on incomings in line with the location
+	_, err = tx.ExecContext(
+		context.Background(),
+		`
+		UPDATE
+			inventory_incomings i
+		SET
+			store_location = l.name,
+			store_country = w.country
+		FROM
+			locations l
+		JOIN
+			warehouses w
+		ON
+			l.warehouse_id = w.id
+		WHERE
+			i.location_id = l.id
+		AND
+			l.id = $1
+		`,
+		id,
+	)
+	if err != nil {
+		slog.Error("Error updating incoming location", "error", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("Error committing location", "error", err)
+		return nil, err
+	}
+
+	location.ID = id
+
+	slog.Info("Successfully updated location", "location", location)
+
+	return location, nil
+}
+
+func (s *warehouseService) DeleteLocation(id int) error {
+	queryStr := `
+		DELETE FROM
+			locations
+		WHERE
+			id = $1
+	`
+
+	_, err := s.db.ExecContext(context.Background(), queryStr, id)
+	if err != nil {
+		slog.Error("Error deleting location", "error", err)
+		return err
+	}
+
+	slog.Info("Successfully deleted location", "location", id)
+
+	return nil
+}
+
+// GetLocationStock returns what is on hand at a location and every location
+// below it, per location and product.
+func (s *warehouseService) GetLocationStock(id int) ([]*models.LocationStock, error) {
+	return s.getStock(`
+		WITH RECURSIVE tree AS (
+			SELECT
+				id
+			FROM
+				locations
+			WHERE
+				id = $1
+			UNION ALL
+			SELECT
+				l.id
+			FROM
+				locations l
+			JOIN
+				tree t
+			ON
+				l.parent_id = t.id
+		)
+		SELECT
+			id
+		FROM
+			tree
+	`, id)
+}
+
+// getStock sums the balance of incomings per location and product for the
+// locations returned by locationQuery, which takes a single $1 argument.
+func (s *warehouseService) getStock(locationQuery string, id int) ([]*models.LocationStock, error) {
+	queryStr := `
+		SELECT
+			l.id,
+			l.code,
+			l.name,
+			p.id,
+			p.code,
+			p.name,
+			p.standard_unit,
+			SUM(m.standard_quantity) AS standard_quantity,
+			SUM(m.value) AS value
+		FROM
+			inventory_movements m
+		JOIN
+			inventory_incomings i
+		ON
+			m.incoming_id = i.id
+		JOIN
+			locations l
+		ON
+			i.location_id = l.id
+		JOIN
+			inventory_products p
+		ON
+			m.product_id = p.id
+		WHERE
+			l.id IN (` + locationQuery + `)
+		GROUP BY
+			l.id,
+			l.code,
+			l.name,
+			p.id,
+			p.code,
+			p.name,
+			p.standard_unit
+		HAVING
+			SUM(m.standard_quantity) <> 0
+		ORDER BY
+			l.code,
+			p.code
+	`
+
+	rows, err := s.db.QueryContext(context.Background(), queryStr, id)
+	if err != nil {
+		slog.Error("Error querying location stock", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	stock := []*models.LocationStock{}
+	for rows.Next() {
+		line := new(models.LocationStock)
+		err := rows.Scan(
+			&line.LocationID,
+			&line.LocationCode,
+			&line.LocationName,
+			&line.ProductID,
+			&line.ProductCode,
+			&line.ProductName,
+			&line.StandardUnit,
+			&line.StandardQuantity,
+			&line.Value,
+		)
+		if err != nil {
+			slog.Error("Error scanning location stock", "error", err)
+			return nil, err
+		}
+
+		stock = append(stock, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Error iterating over location stock", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Successfully queried location stock", "lines", len(stock))
+
+	return stock, nil
+}
+
+// validateLocation checks the type of a location and that its parent is a
+// zone of the same warehouse.
+func (s *warehouseService) validateLocation(id int, location *models.Location) error {
+	switch location.Type {
+	case models.LocationTypeZone:
+		if location.ParentID != 0 {
+			return &ValidationError{Message: "a zone cannot have a parent location"}
+		}
+		return nil
+	case models.LocationTypePallet, models.LocationTypeBin:
+	default:
+		return &ValidationError{Message: "unknown location type: " + location.Type}
+	}
+
+	if location.ParentID == 0 {
+		return nil
+	}
+
+	if location.ParentID == id {
+		return &ValidationError{Message: "a location cannot be its own parent"}
+	}
+
+	parent, err := s.GetLocation(location.ParentID)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Message: "parent location does not exist"}
+	}
+	if err != nil {
+		return err
+	}
+
+	if parent.WarehouseID != location.WarehouseID {
+		return &ValidationError{Message: "parent location belongs to another warehouse"}
+	}
+	if parent.Type != models.LocationTypeZone {
+		return &ValidationError{Message: "parent location must be a zone"}
+	}
+
+	return nil
+}
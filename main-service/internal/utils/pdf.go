@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page size in points.
+const (
+	PDFPageWidth  = 595.28
+	PDFPageHeight = 841.89
+)
+
+// helveticaWidths are the widths of the printable ASCII characters in the
+// standard Helvetica font, in thousandths of the font size.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// PDF is a minimal writer for A4 documents made of text and lines, using the
+// Helvetica fonts every PDF reader ships with. Coordinates are in points from
+// the top left corner of the page, text is placed by its baseline.
+type PDF struct {
+	pages []*bytes.Buffer
+}
+
+func NewPDF() *PDF {
+	return &PDF{}
+}
+
+// AddPage starts a new page, later drawing goes onto it.
+func (p *PDF) AddPage() {
+	p.pages = append(p.pages, new(bytes.Buffer))
+}
+
+func (p *PDF) current() *bytes.Buffer {
+	if len(p.pages) == 0 {
+		p.AddPage()
+	}
+	return p.pages[len(p.pages)-1]
+}
+
+// Text draws s starting at x.
+func (p *PDF) Text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(p.current(), "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, PDFPageHeight-y, pdfString(s))
+}
+
+// TextRight draws s so that it ends at x.
+func (p *PDF) TextRight(x, y, size float64, bold bool, s string) {
+	p.Text(x-TextWidth(s, size), y, size, bold, s)
+}
+
+// Line draws a thin line from x1, y1 to x2, y2.
+func (p *PDF) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(p.current(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, PDFPageHeight-y1, x2, PDFPageHeight-y2)
+}
+
+// TextWidth measures s in Helvetica at size. Bold text runs slightly wider.
+func TextWidth(s string, size float64) float64 {
+	width := 0
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			width += helveticaWidths[r-32]
+		} else {
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+// FitText cuts s down until it fits in width at size, marking the cut with
+// an ellipsis.
+func FitText(s string, size, width float64) string {
+	if TextWidth(s, size) <= width {
+		return s
+	}
+
+	runes := []rune(s)
+	for len(runes) > 0 && TextWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// pdfString escapes s for a PDF literal string. The fonts use WinAnsi
+// encoding, characters outside Latin-1 are printed as a question mark.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r < 32 || r > 255 || (r >= 127 && r < 160):
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+// Bytes assembles the document.
+func (p *PDF) Bytes() []byte {
+	if len(p.pages) == 0 {
+		p.AddPage()
+	}
+
+	var buf bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// pages are objects 5, 7, 9... each followed by its content stream
+	kids := make([]string, len(p.pages))
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range p.pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PDFPageWidth,
+			PDFPageHeight,
+			6+i*2,
+		))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPDFString(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"plain", "DO-2024-0001", "DO-2024-0001"},
+		{"parentheses", "Sealant (grey)", `Sealant \(grey\)`},
+		{"backslash", `C:\stock`, `C:\\stock`},
+		{"line breaks and tabs", "line\none\r\ttwo", "line one  two"},
+		{"latin-1", "Café", "Caf\xe9"},
+		{"outside latin-1", "€5 → 東京", "?5 ? ??"},
+		{"control characters", "a\x00b\x7fc\u0085d", "a?b?c?d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pdfString(tt.s); got != tt.want {
+				t.Errorf("pdfString(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitText(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		width float64
+		want  string
+	}{
+		{"fits", "Sealant", 100, "Sealant"},
+		{"cut with ellipsis", "Silicone sealant grey 600ml", 50, "Silicone ..."},
+		{"nothing fits", "Sealant", 1, "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FitText(tt.s, 10, tt.width); got != tt.want {
+				t.Errorf("FitText(%q, 10, %v) = %q, want %q", tt.s, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPDFTextEscapes(t *testing.T) {
+	p := NewPDF()
+	p.Text(10, 10, 12, false, "Total (SGD)")
+
+	if !bytes.Contains(p.Bytes(), []byte(`(Total \(SGD\)) Tj`)) {
+		t.Errorf("text is not escaped in the content stream")
+	}
+}
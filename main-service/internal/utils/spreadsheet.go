@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+
+	contentTypeCSV  = "text/csv"
+	contentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// Table is a header row plus data rows, ready to be written out as CSV or
+// XLSX. Cells may be strings or float64, anything else is printed with %v.
+type Table struct {
+	Headers []string
+	Rows    [][]any
+}
+
+// ExportFormat picks the output format of a report from the format query
+// parameter, falling back to the Accept header and then to JSON.
+func ExportFormat(r *http.Request) (string, error) {
+	switch format := strings.ToLower(r.URL.Query().Get("format")); format {
+	case "":
+	case ExportFormatJSON, ExportFormatCSV, ExportFormatXLSX:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, contentTypeCSV):
+		return ExportFormatCSV, nil
+	case strings.Contains(accept, contentTypeXLSX):
+		return ExportFormatXLSX, nil
+	}
+
+	return ExportFormatJSON, nil
+}
+
+// WriteTable sends table as an attachment named filename plus the extension
+// of format, which must be CSV or XLSX.
+func WriteTable(w http.ResponseWriter, format, filename string, table *Table) error {
+	switch format {
+	case ExportFormatCSV:
+		w.Header().Set("Content-Type", contentTypeCSV)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+		return WriteCSV(w, table)
+	case ExportFormatXLSX:
+		w.Header().Set("Content-Type", contentTypeXLSX)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".xlsx"))
+		return WriteXLSX(w, filename, table)
+	}
+
+	return fmt.Errorf("unsupported format: %s", format)
+}
+
+func WriteCSV(w io.Writer, table *Table) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(table.Headers); err != nil {
+		return err
+	}
+
+	for _, row := range table.Rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = formatCell(cell)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteXLSX writes table as a single sheet workbook. It only produces what
+// Excel needs to open the file: inline strings, plain numbers and no styles.
+func WriteXLSX(w io.Writer, sheetName string, table *Table) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, xmlEscape(xlsxSheetName(sheetName)))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/worksheets/sheet1.xml", xlsxSheet(table)},
+	}
+
+	for _, file := range files {
+		fw, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, file.content); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func formatCell(cell any) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	}
+
+	return fmt.Sprintf("%v", cell)
+}
+
+func xlsxSheet(table *Table) string {
+	var b strings.Builder
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	headers := make([]any, len(table.Headers))
+	for i, header := range table.Headers {
+		headers[i] = header
+	}
+
+	rows := append([][]any{headers}, table.Rows...)
+	for r, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := xlsxColumn(c) + strconv.Itoa(r+1)
+			switch v := cell.(type) {
+			case nil:
+			case float64, int:
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, formatCell(v))
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, xmlEscape(formatCell(v)))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+
+	return b.String()
+}
+
+// xlsxColumn turns a zero based column index into its letters, 0 -> A, 26 -> AA.
+func xlsxColumn(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxSheetName cuts a name to the 31 characters Excel allows, counting
+// characters rather than bytes, and drops the characters it refuses in sheet
+// names.
+func xlsxSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return -1
+		}
+		return r
+	}, name)
+
+	if name == "" {
+		name = "Sheet1"
+	}
+	if utf8.RuneCountInString(name) > 31 {
+		name = string([]rune(name)[:31])
+	}
+
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestXLSXColumn(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"},
+		{1, "B"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+		{16383, "XFD"},
+	}
+
+	for _, tt := range tests {
+		if got := xlsxColumn(tt.index); got != tt.want {
+			t.Errorf("xlsxColumn(%d) = %q, want %q", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestXLSXSheetName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"stock-valuation", "stock-valuation"},
+		{"", "Sheet1"},
+		{"[]:*?/\\", "Sheet1"},
+		{"a/b:c", "abc"},
+		{strings.Repeat("x", 40), strings.Repeat("x", 31)},
+		{"华南供应商", "华南供应商"},
+		{strings.Repeat("供", 40), strings.Repeat("供", 31)},
+		{strings.Repeat("x", 30) + "供应", strings.Repeat("x", 30) + "供"},
+	}
+
+	for _, tt := range tests {
+		got := xlsxSheetName(tt.name)
+		if got != tt.want {
+			t.Errorf("xlsxSheetName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("xlsxSheetName(%q) = %q is not valid UTF-8", tt.name, got)
+		}
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	wide := &Table{Headers: make([]string, 28)}
+	for i := range wide.Headers {
+		wide.Headers[i] = "h"
+	}
+	wide.Rows = [][]any{make([]any, 28)}
+	wide.Rows[0][27] = 1.5
+
+	tests := []struct {
+		name      string
+		sheetName string
+		table     *Table
+		workbook  []string
+		sheet     []string
+		notSheet  []string
+	}{
+		{
+			name:      "escapes text",
+			sheetName: "R&D <stock>",
+			table: &Table{
+				Headers: []string{"Code", "Name"},
+				Rows:    [][]any{{"A&B", `<tag> "quoted"`}},
+			},
+			workbook: []string{`name="R&amp;D &lt;stock&gt;"`},
+			sheet: []string{
+				`<c r="A2" t="inlineStr"><is><t>A&amp;B</t></is></c>`,
+				`<c r="B2" t="inlineStr"><is><t>&lt;tag&gt; &#34;quoted&#34;</t></is></c>`,
+			},
+		},
+		{
+			name:      "writes numbers and skips empty cells",
+			sheetName: "valuation",
+			table: &Table{
+				Headers: []string{"Quantity", "Unit", "Value"},
+				Rows:    [][]any{{2.5, nil, 10}},
+			},
+			sheet:    []string{`<c r="A2"><v>2.5</v></c>`, `<c r="C2"><v>10</v></c>`},
+			notSheet: []string{`r="B2"`},
+		},
+		{
+			name:      "names columns past Z",
+			sheetName: "wide",
+			table:     wide,
+			sheet:     []string{`<c r="AA1" t="inlineStr">`, `<c r="AB2"><v>1.5</v></c>`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteXLSX(&buf, tt.sheetName, tt.table); err != nil {
+				t.Fatalf("WriteXLSX: %v", err)
+			}
+
+			files := readZip(t, buf.Bytes())
+			for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/_rels/workbook.xml.rels"} {
+				if _, ok := files[name]; !ok {
+					t.Errorf("missing %s", name)
+				}
+			}
+			for _, want := range tt.workbook {
+				if !strings.Contains(files["xl/workbook.xml"], want) {
+					t.Errorf("workbook does not contain %s", want)
+				}
+			}
+			for _, want := range tt.sheet {
+				if !strings.Contains(files["xl/worksheets/sheet1.xml"], want) {
+					t.Errorf("sheet does not contain %s", want)
+				}
+			}
+			for _, unwanted := range tt.notSheet {
+				if strings.Contains(files["xl/worksheets/sheet1.xml"], unwanted) {
+					t.Errorf("sheet contains %s", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+
+	files := map[string]string{}
+	for _, file := range zr.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", file.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("reading %s: %v", file.Name, err)
+		}
+		files[file.Name] = string(content)
+	}
+
+	return files
+}
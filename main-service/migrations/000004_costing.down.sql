@@ -0,0 +1,12 @@
+-- Put back the cost outgoings had before they were repriced
+UPDATE inventory_outgoings
+SET
+    cost = original_cost
+WHERE
+    original_cost IS NOT NULL;
+
+ALTER TABLE inventory_outgoings
+    DROP COLUMN IF EXISTS original_cost,
+    DROP COLUMN IF EXISTS unit_cost;
+
+ALTER TABLE inventory_products DROP COLUMN IF EXISTS costing_method;
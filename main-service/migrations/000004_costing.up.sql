@@ -0,0 +1,20 @@
+-- Costing method used to price outgoings of each product
+ALTER TABLE inventory_products
+    ADD COLUMN IF NOT EXISTS costing_method VARCHAR(255) NOT NULL DEFAULT 'specific';
+
+-- Unit cost the outgoing was issued at, cost keeps the extended cost.
+-- original_cost keeps the cost an outgoing had before it was repriced
+ALTER TABLE inventory_outgoings
+    ADD COLUMN IF NOT EXISTS unit_cost NUMERIC NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS original_cost NUMERIC;
+
+-- Reprice existing outgoings from the incoming they were drawn from
+UPDATE inventory_outgoings o
+SET
+    original_cost = o.cost,
+    unit_cost = i.cost,
+    cost = o.standard_quantity * i.cost
+FROM
+    inventory_incomings i
+WHERE
+    o.incoming_id = i.id;
@@ -0,0 +1 @@
+ALTER TABLE inventory_incomings DROP COLUMN IF EXISTS received_at;
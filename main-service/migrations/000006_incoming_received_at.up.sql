@@ -0,0 +1,5 @@
+-- Date the stock physically arrived, used for FIFO order and aging
+ALTER TABLE inventory_incomings
+    ADD COLUMN IF NOT EXISTS received_at DATE NOT NULL DEFAULT CURRENT_DATE;
+
+UPDATE inventory_incomings SET received_at = created_at::DATE;
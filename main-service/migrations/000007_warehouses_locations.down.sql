@@ -0,0 +1,3 @@
+ALTER TABLE inventory_incomings DROP COLUMN IF EXISTS location_id;
+
+DROP TABLE IF EXISTS locations, warehouses;
@@ -0,0 +1,88 @@
+-- Create the warehouses table
+CREATE TABLE IF NOT EXISTS warehouses (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(255) NOT NULL UNIQUE DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    country VARCHAR(255) NOT NULL DEFAULT '',
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    is_exist BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create the locations table, zones hang off a warehouse and pallets or bins
+-- off a zone
+CREATE TABLE IF NOT EXISTS locations (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INTEGER REFERENCES warehouses(id) NOT NULL,
+    parent_id INTEGER REFERENCES locations(id),
+    type VARCHAR(255) NOT NULL DEFAULT 'pallet',
+    code VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    is_exist BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (warehouse_id, code)
+);
+
+ALTER TABLE inventory_incomings
+    ADD COLUMN IF NOT EXISTS location_id INTEGER REFERENCES locations(id);
+
+-- Normalise the free text locations, "LJX Pallet " and "LJX Pallet" are the
+-- same pallet
+UPDATE inventory_incomings
+SET
+    store_location = TRIM(store_location),
+    store_country = TRIM(store_country);
+
+-- One warehouse per country already in use
+INSERT INTO warehouses (code, name, country, created_by, updated_by)
+SELECT DISTINCT
+    UPPER(REGEXP_REPLACE(COALESCE(NULLIF(store_country, ''), 'Unassigned'), '\s+', '-', 'g')),
+    COALESCE(NULLIF(store_country, ''), 'Unassigned') || ' Warehouse',
+    store_country,
+    'migration',
+    'migration'
+FROM
+    inventory_incomings
+WHERE
+    store_location <> ''
+ON CONFLICT (code) DO NOTHING;
+
+-- One pallet location per distinct location text inside its warehouse
+INSERT INTO locations (warehouse_id, type, code, name, created_by, updated_by)
+SELECT DISTINCT ON (w.id, UPPER(REGEXP_REPLACE(i.store_location, '\s+', '-', 'g')))
+    w.id,
+    'pallet',
+    UPPER(REGEXP_REPLACE(i.store_location, '\s+', '-', 'g')),
+    i.store_location,
+    'migration',
+    'migration'
+FROM
+    inventory_incomings i
+JOIN
+    warehouses w
+ON
+    w.code = UPPER(REGEXP_REPLACE(COALESCE(NULLIF(i.store_country, ''), 'Unassigned'), '\s+', '-', 'g'))
+WHERE
+    i.store_location <> ''
+ON CONFLICT (warehouse_id, code) DO NOTHING;
+
+UPDATE inventory_incomings i
+SET
+    location_id = l.id,
+    store_location = l.name
+FROM
+    locations l
+JOIN
+    warehouses w
+ON
+    l.warehouse_id = w.id
+WHERE
+    l.code = UPPER(REGEXP_REPLACE(i.store_location, '\s+', '-', 'g'))
+AND
+    w.code = UPPER(REGEXP_REPLACE(COALESCE(NULLIF(i.store_country, ''), 'Unassigned'), '\s+', '-', 'g'));
@@ -0,0 +1,36 @@
+DROP VIEW IF EXISTS inventory_movements;
+
+CREATE VIEW inventory_movements AS
+SELECT
+    'incoming'::VARCHAR(255) AS movement_type,
+    i.id AS document_id,
+    i.id AS incoming_id,
+    i.product_id,
+    i.ref_no,
+    i.remarks,
+    i.created_at AS moved_at,
+    i.quantity,
+    i.standard_quantity,
+    i.cost AS unit_cost,
+    i.standard_quantity * i.cost AS value
+FROM
+    inventory_incomings i
+UNION ALL
+SELECT
+    'outgoing'::VARCHAR(255),
+    o.id,
+    o.incoming_id,
+    o.product_id,
+    o.ref_no,
+    o.remarks,
+    o.created_at,
+    -o.quantity,
+    -o.standard_quantity,
+    o.unit_cost,
+    -o.cost
+FROM
+    inventory_outgoings o;
+
+DROP TABLE IF EXISTS inventory_transfers;
+
+ALTER TABLE inventory_incomings DROP COLUMN IF EXISTS source_incoming_id;
@@ -0,0 +1,88 @@
+-- Incomings created by moving stock out of another incoming
+ALTER TABLE inventory_incomings
+    ADD COLUMN IF NOT EXISTS source_incoming_id INTEGER REFERENCES inventory_incomings(id);
+
+-- Create the inventory_transfers table
+CREATE TABLE IF NOT EXISTS inventory_transfers (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER REFERENCES inventory_products(id) NOT NULL,
+    from_incoming_id INTEGER REFERENCES inventory_incomings(id) NOT NULL,
+    to_incoming_id INTEGER REFERENCES inventory_incomings(id) NOT NULL,
+    from_location_id INTEGER REFERENCES locations(id),
+    to_location_id INTEGER REFERENCES locations(id),
+    quantity NUMERIC NOT NULL DEFAULT 0,
+    standard_quantity NUMERIC NOT NULL DEFAULT 0,
+    unit_cost NUMERIC NOT NULL DEFAULT 0,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    remarks VARCHAR(255) NOT NULL DEFAULT '',
+    transferred_by VARCHAR(255) NOT NULL DEFAULT '',
+    transferred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Incomings made by a transfer are not receipts, their stock arrives through
+-- the transfer_in movement
+DROP VIEW IF EXISTS inventory_movements;
+
+CREATE VIEW inventory_movements AS
+SELECT
+    'incoming'::VARCHAR(255) AS movement_type,
+    i.id AS document_id,
+    i.id AS incoming_id,
+    i.product_id,
+    i.ref_no,
+    i.remarks,
+    i.created_at AS moved_at,
+    i.quantity,
+    i.standard_quantity,
+    i.cost AS unit_cost,
+    i.standard_quantity * i.cost AS value
+FROM
+    inventory_incomings i
+WHERE
+    i.source_incoming_id IS NULL
+UNION ALL
+SELECT
+    'outgoing'::VARCHAR(255),
+    o.id,
+    o.incoming_id,
+    o.product_id,
+    o.ref_no,
+    o.remarks,
+    o.created_at,
+    -o.quantity,
+    -o.standard_quantity,
+    o.unit_cost,
+    -o.cost
+FROM
+    inventory_outgoings o
+UNION ALL
+SELECT
+    'transfer_out'::VARCHAR(255),
+    t.id,
+    t.from_incoming_id,
+    t.product_id,
+    ('TR-' || t.id)::VARCHAR(255),
+    t.reason,
+    t.transferred_at,
+    -t.quantity,
+    -t.standard_quantity,
+    t.unit_cost,
+    -t.standard_quantity * t.unit_cost
+FROM
+    inventory_transfers t
+UNION ALL
+SELECT
+    'transfer_in'::VARCHAR(255),
+    t.id,
+    t.to_incoming_id,
+    t.product_id,
+    ('TR-' || t.id)::VARCHAR(255),
+    t.reason,
+    t.transferred_at,
+    t.quantity,
+    t.standard_quantity,
+    t.unit_cost,
+    t.standard_quantity * t.unit_cost
+FROM
+    inventory_transfers t;
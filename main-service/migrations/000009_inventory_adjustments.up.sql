@@ -0,0 +1,138 @@
+-- Create the inventory_adjustment_reasons table
+CREATE TABLE IF NOT EXISTS inventory_adjustment_reasons (
+    code VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    is_exist BOOLEAN NOT NULL DEFAULT true
+);
+
+INSERT INTO inventory_adjustment_reasons (code, name) VALUES
+    ('shrinkage', 'Shrinkage'),
+    ('damage', 'Damage'),
+    ('sample', 'Sample'),
+    ('found', 'Found stock'),
+    ('count_correction', 'Count correction')
+ON CONFLICT (code) DO NOTHING;
+
+-- Create the cycle_counts table, one counting session per location
+CREATE TABLE IF NOT EXISTS cycle_counts (
+    id SERIAL PRIMARY KEY,
+    location_id INTEGER REFERENCES locations(id) NOT NULL,
+    status VARCHAR(255) NOT NULL DEFAULT 'open',
+    remarks VARCHAR(255) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    posted_by VARCHAR(255) NOT NULL DEFAULT '',
+    posted_at TIMESTAMP
+);
+
+-- Create the cycle_count_lines table, counted_quantity stays NULL until the
+-- incoming has been counted and system_quantity until the count is posted
+CREATE TABLE IF NOT EXISTS cycle_count_lines (
+    id SERIAL PRIMARY KEY,
+    cycle_count_id INTEGER REFERENCES cycle_counts(id) ON DELETE CASCADE NOT NULL,
+    incoming_id INTEGER REFERENCES inventory_incomings(id) NOT NULL,
+    counted_quantity NUMERIC,
+    system_quantity NUMERIC,
+    UNIQUE (cycle_count_id, incoming_id)
+);
+
+-- Create the inventory_adjustments table, standard_quantity is signed
+CREATE TABLE IF NOT EXISTS inventory_adjustments (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER REFERENCES inventory_products(id) NOT NULL,
+    incoming_id INTEGER REFERENCES inventory_incomings(id) NOT NULL,
+    reason_code VARCHAR(255) REFERENCES inventory_adjustment_reasons(code) NOT NULL,
+    cycle_count_id INTEGER REFERENCES cycle_counts(id),
+    quantity NUMERIC NOT NULL DEFAULT 0,
+    standard_quantity NUMERIC NOT NULL DEFAULT 0,
+    unit_cost NUMERIC NOT NULL DEFAULT 0,
+    status VARCHAR(255) NOT NULL DEFAULT 'pending',
+    remarks VARCHAR(255) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    approved_by VARCHAR(255) NOT NULL DEFAULT '',
+    approved_at TIMESTAMP
+);
+
+-- Only approved adjustments move stock
+DROP VIEW IF EXISTS inventory_movements;
+
+CREATE VIEW inventory_movements AS
+SELECT
+    'incoming'::VARCHAR(255) AS movement_type,
+    i.id AS document_id,
+    i.id AS incoming_id,
+    i.product_id,
+    i.ref_no,
+    i.remarks,
+    i.created_at AS moved_at,
+    i.quantity,
+    i.standard_quantity,
+    i.cost AS unit_cost,
+    i.standard_quantity * i.cost AS value
+FROM
+    inventory_incomings i
+WHERE
+    i.source_incoming_id IS NULL
+UNION ALL
+SELECT
+    'outgoing'::VARCHAR(255),
+    o.id,
+    o.incoming_id,
+    o.product_id,
+    o.ref_no,
+    o.remarks,
+    o.created_at,
+    -o.quantity,
+    -o.standard_quantity,
+    o.unit_cost,
+    -o.cost
+FROM
+    inventory_outgoings o
+UNION ALL
+SELECT
+    'transfer_out'::VARCHAR(255),
+    t.id,
+    t.from_incoming_id,
+    t.product_id,
+    ('TR-' || t.id)::VARCHAR(255),
+    t.reason,
+    t.transferred_at,
+    -t.quantity,
+    -t.standard_quantity,
+    t.unit_cost,
+    -t.standard_quantity * t.unit_cost
+FROM
+    inventory_transfers t
+UNION ALL
+SELECT
+    'transfer_in'::VARCHAR(255),
+    t.id,
+    t.to_incoming_id,
+    t.product_id,
+    ('TR-' || t.id)::VARCHAR(255),
+    t.reason,
+    t.transferred_at,
+    t.quantity,
+    t.standard_quantity,
+    t.unit_cost,
+    t.standard_quantity * t.unit_cost
+FROM
+    inventory_transfers t
+UNION ALL
+SELECT
+    'adjustment'::VARCHAR(255),
+    a.id,
+    a.incoming_id,
+    a.product_id,
+    ('ADJ-' || a.id)::VARCHAR(255),
+    a.reason_code,
+    a.approved_at,
+    a.quantity,
+    a.standard_quantity,
+    a.unit_cost,
+    a.standard_quantity * a.unit_cost
+FROM
+    inventory_adjustments a
+WHERE
+    a.status = 'approved';
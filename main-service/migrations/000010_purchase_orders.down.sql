@@ -0,0 +1,7 @@
+ALTER TABLE inventory_incomings
+    DROP COLUMN IF EXISTS purchase_order_line_id,
+    DROP COLUMN IF EXISTS supplier;
+
+DROP TABLE IF EXISTS purchase_order_lines;
+
+DROP TABLE IF EXISTS purchase_orders;
@@ -0,0 +1,32 @@
+-- Create the purchase_orders table
+CREATE TABLE IF NOT EXISTS purchase_orders (
+    id SERIAL PRIMARY KEY,
+    po_no VARCHAR(255) NOT NULL UNIQUE,
+    supplier VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(255) NOT NULL DEFAULT 'draft',
+    order_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    expected_at DATE,
+    remarks VARCHAR(255) NOT NULL DEFAULT '',
+    approved_by VARCHAR(255) NOT NULL DEFAULT '',
+    approved_at TIMESTAMP,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create the purchase_order_lines table, quantities are in the standard unit
+-- of the product
+CREATE TABLE IF NOT EXISTS purchase_order_lines (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER REFERENCES purchase_orders(id) ON DELETE CASCADE NOT NULL,
+    product_id INTEGER REFERENCES inventory_products(id) NOT NULL,
+    standard_quantity NUMERIC NOT NULL DEFAULT 0,
+    unit_cost NUMERIC NOT NULL DEFAULT 0,
+    remarks VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+-- Incomings received against a purchase order line
+ALTER TABLE inventory_incomings
+    ADD COLUMN IF NOT EXISTS purchase_order_line_id INTEGER REFERENCES purchase_order_lines(id),
+    ADD COLUMN IF NOT EXISTS supplier VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+ALTER TABLE purchase_orders DROP COLUMN IF EXISTS supplier_id;
+
+DROP TABLE IF EXISTS supplier_prices;
+
+DROP TABLE IF EXISTS product_suppliers;
+
+DROP TABLE IF EXISTS supplier_contacts;
+
+DROP TABLE IF EXISTS suppliers;
@@ -0,0 +1,94 @@
+-- Create the suppliers table
+CREATE TABLE IF NOT EXISTS suppliers (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(255) NOT NULL UNIQUE DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    country VARCHAR(255) NOT NULL DEFAULT '',
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    currency VARCHAR(255) NOT NULL DEFAULT '',
+    payment_terms VARCHAR(255) NOT NULL DEFAULT '',
+    lead_time_days INTEGER NOT NULL DEFAULT 0,
+    remarks VARCHAR(255) NOT NULL DEFAULT '',
+    is_exist BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create the supplier_contacts table
+CREATE TABLE IF NOT EXISTS supplier_contacts (
+    id SERIAL PRIMARY KEY,
+    supplier_id INTEGER REFERENCES suppliers(id) ON DELETE CASCADE NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    position VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(255) NOT NULL DEFAULT '',
+    is_primary BOOLEAN NOT NULL DEFAULT false
+);
+
+-- Create the product_suppliers table, lead_time_days overrides the supplier
+-- default when set
+CREATE TABLE IF NOT EXISTS product_suppliers (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER REFERENCES inventory_products(id) ON DELETE CASCADE NOT NULL,
+    supplier_id INTEGER REFERENCES suppliers(id) ON DELETE CASCADE NOT NULL,
+    supplier_sku VARCHAR(255) NOT NULL DEFAULT '',
+    lead_time_days INTEGER,
+    is_preferred BOOLEAN NOT NULL DEFAULT false,
+    UNIQUE (product_id, supplier_id)
+);
+
+-- Create the supplier_prices table, an open ended entry has no valid_to
+CREATE TABLE IF NOT EXISTS supplier_prices (
+    id SERIAL PRIMARY KEY,
+    product_supplier_id INTEGER REFERENCES product_suppliers(id) ON DELETE CASCADE NOT NULL,
+    unit_cost NUMERIC NOT NULL DEFAULT 0,
+    currency VARCHAR(255) NOT NULL DEFAULT '',
+    min_quantity NUMERIC NOT NULL DEFAULT 0,
+    valid_from DATE NOT NULL DEFAULT CURRENT_DATE,
+    valid_to DATE,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE purchase_orders
+    ADD COLUMN IF NOT EXISTS supplier_id INTEGER REFERENCES suppliers(id);
+
+-- Turn the free text supplier of products and purchase orders into supplier
+-- records, one per distinct trimmed name
+INSERT INTO suppliers (code, name, created_by, updated_by)
+SELECT
+    'SUP' || LPAD(ROW_NUMBER() OVER (ORDER BY name)::TEXT, 4, '0'),
+    name,
+    'migration',
+    'migration'
+FROM (
+    SELECT TRIM(supplier) AS name FROM inventory_products
+    UNION
+    SELECT TRIM(supplier) FROM purchase_orders
+) s
+WHERE
+    name <> ''
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO product_suppliers (product_id, supplier_id, is_preferred)
+SELECT
+    p.id,
+    s.id,
+    true
+FROM
+    inventory_products p
+JOIN
+    suppliers s
+ON
+    s.name = TRIM(p.supplier)
+ON CONFLICT (product_id, supplier_id) DO NOTHING;
+
+UPDATE purchase_orders po
+SET
+    supplier_id = s.id
+FROM
+    suppliers s
+WHERE
+    s.name = TRIM(po.supplier);
@@ -0,0 +1,5 @@
+ALTER TABLE inventory_outgoings DROP COLUMN IF EXISTS job_order_id;
+
+DROP TABLE IF EXISTS job_orders;
+
+DROP TABLE IF EXISTS customers;
@@ -0,0 +1,63 @@
+-- Create the customers table
+CREATE TABLE IF NOT EXISTS customers (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(255) NOT NULL UNIQUE DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    contact_name VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(255) NOT NULL DEFAULT '',
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    remarks VARCHAR(255) NOT NULL DEFAULT '',
+    is_exist BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create the job_orders table, status is one of open, completed or cancelled
+CREATE TABLE IF NOT EXISTS job_orders (
+    id SERIAL PRIMARY KEY,
+    job_no VARCHAR(255) NOT NULL UNIQUE DEFAULT '',
+    customer_id INTEGER REFERENCES customers(id),
+    site_name VARCHAR(255) NOT NULL DEFAULT '',
+    site_address VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(255) NOT NULL DEFAULT 'open',
+    started_at DATE,
+    completed_at DATE,
+    remarks VARCHAR(255) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE inventory_outgoings
+    ADD COLUMN IF NOT EXISTS job_order_id INTEGER REFERENCES job_orders(id);
+
+-- Turn the job numbers already keyed into outgoing ref_no into job orders,
+-- naming the site after the remarks of the first outgoing on the job
+INSERT INTO job_orders (job_no, site_name, started_at, created_by, updated_by)
+SELECT DISTINCT ON (UPPER(TRIM(ref_no)))
+    UPPER(TRIM(ref_no)),
+    remarks,
+    created_at::DATE,
+    'migration',
+    'migration'
+FROM
+    inventory_outgoings
+WHERE
+    UPPER(TRIM(ref_no)) LIKE 'JO-%'
+ORDER BY
+    UPPER(TRIM(ref_no)),
+    created_at,
+    id
+ON CONFLICT (job_no) DO NOTHING;
+
+UPDATE inventory_outgoings o
+SET
+    job_order_id = j.id
+FROM
+    job_orders j
+WHERE
+    j.job_no = UPPER(TRIM(o.ref_no));
@@ -0,0 +1,5 @@
+ALTER TABLE inventory_outgoings DROP COLUMN IF EXISTS delivery_order_id;
+
+DROP TABLE IF EXISTS delivery_orders;
+
+DROP TABLE IF EXISTS document_sequences;
@@ -0,0 +1,27 @@
+-- Create the document_sequences table, one running number per prefix and year
+CREATE TABLE IF NOT EXISTS document_sequences (
+    prefix VARCHAR(255) NOT NULL,
+    year INTEGER NOT NULL,
+    last_no INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (prefix, year)
+);
+
+-- Create the delivery_orders table, document is the path of the rendered PDF
+CREATE TABLE IF NOT EXISTS delivery_orders (
+    id SERIAL PRIMARY KEY,
+    do_no VARCHAR(255) NOT NULL UNIQUE DEFAULT '',
+    job_order_id INTEGER REFERENCES job_orders(id),
+    customer_id INTEGER REFERENCES customers(id),
+    deliver_to VARCHAR(255) NOT NULL DEFAULT '',
+    delivery_address VARCHAR(255) NOT NULL DEFAULT '',
+    delivered_at DATE NOT NULL DEFAULT CURRENT_DATE,
+    document VARCHAR(255) NOT NULL DEFAULT '',
+    remarks VARCHAR(255) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE inventory_outgoings
+    ADD COLUMN IF NOT EXISTS delivery_order_id INTEGER REFERENCES delivery_orders(id);
@@ -0,0 +1,181 @@
+-- Create the units table. Units of the same dimension convert through their
+-- base factor, package units have none and only convert through a product's
+-- own conversions
+CREATE TABLE IF NOT EXISTS units (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(255) NOT NULL UNIQUE DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    dimension VARCHAR(255) NOT NULL DEFAULT '',
+    base_factor NUMERIC NOT NULL DEFAULT 0,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create the product_unit_conversions table, one unit of the product is
+-- factor to_unit, such as a box of 10 pcs or a tube of 300 ml
+CREATE TABLE IF NOT EXISTS product_unit_conversions (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER REFERENCES inventory_products(id) ON DELETE CASCADE NOT NULL,
+    unit VARCHAR(255) NOT NULL DEFAULT '',
+    factor NUMERIC NOT NULL DEFAULT 0,
+    to_unit VARCHAR(255) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (product_id, unit)
+);
+
+INSERT INTO units (code, name, dimension, base_factor, created_by, updated_by) VALUES
+('sqm', 'Square metre', 'area', 1, 'migration', 'migration'),
+('sqft', 'Square foot', 'area', 0.09290304, 'migration', 'migration'),
+('cbm', 'Cubic metre', 'volume', 1000, 'migration', 'migration'),
+('litre', 'Litre', 'volume', 1, 'migration', 'migration'),
+('ml', 'Millilitre', 'volume', 0.001, 'migration', 'migration'),
+('m', 'Metre', 'length', 1, 'migration', 'migration'),
+('cm', 'Centimetre', 'length', 0.01, 'migration', 'migration'),
+('mm', 'Millimetre', 'length', 0.001, 'migration', 'migration'),
+('ft', 'Foot', 'length', 0.3048, 'migration', 'migration'),
+('kg', 'Kilogram', 'mass', 1, 'migration', 'migration'),
+('g', 'Gram', 'mass', 0.001, 'migration', 'migration'),
+('pcs', 'Pieces', 'count', 1, 'migration', 'migration'),
+('box', 'Box', 'package', 0, 'migration', 'migration'),
+('carton', 'Carton', 'package', 0, 'migration', 'migration'),
+('tube', 'Tube', 'package', 0, 'migration', 'migration'),
+('sausage', 'Sausage', 'package', 0, 'migration', 'migration'),
+('roll', 'Roll', 'package', 0, 'migration', 'migration'),
+('pallet', 'Pallet', 'package', 0, 'migration', 'migration')
+ON CONFLICT (code) DO NOTHING;
+
+-- Keep any other standard unit already in use, it converts to nothing until
+-- it is given a dimension
+INSERT INTO units (code, name, dimension, created_by, updated_by)
+SELECT DISTINCT
+    TRIM(standard_unit),
+    TRIM(standard_unit),
+    'other',
+    'migration',
+    'migration'
+FROM
+    inventory_products
+WHERE
+    TRIM(standard_unit) <> ''
+ON CONFLICT (code) DO NOTHING;
+
+-- quantity_unit is the unit quantity was entered in, standard_quantity is
+-- always in the product's standard unit
+ALTER TABLE inventory_incomings
+    ADD COLUMN IF NOT EXISTS quantity_unit VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE inventory_outgoings
+    ADD COLUMN IF NOT EXISTS quantity_unit VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Existing quantities were keyed in the standard unit
+UPDATE inventory_incomings i
+SET
+    quantity_unit = p.standard_unit
+FROM
+    inventory_products p
+WHERE
+    i.product_id = p.id;
+
+UPDATE inventory_outgoings o
+SET
+    quantity_unit = p.standard_unit
+FROM
+    inventory_products p
+WHERE
+    o.product_id = p.id;
+
+-- Outgoings may be keyed in any unit of the product, express their quantity
+-- in the unit of the incoming they draw from so balances keep adding up
+DROP VIEW IF EXISTS inventory_movements;
+
+CREATE VIEW inventory_movements AS
+SELECT
+    'incoming'::VARCHAR(255) AS movement_type,
+    i.id AS document_id,
+    i.id AS incoming_id,
+    i.product_id,
+    i.ref_no,
+    i.remarks,
+    i.created_at AS moved_at,
+    i.quantity,
+    i.standard_quantity,
+    i.cost AS unit_cost,
+    i.standard_quantity * i.cost AS value
+FROM
+    inventory_incomings i
+WHERE
+    i.source_incoming_id IS NULL
+UNION ALL
+SELECT
+    'outgoing'::VARCHAR(255),
+    o.id,
+    o.incoming_id,
+    o.product_id,
+    o.ref_no,
+    o.remarks,
+    o.created_at,
+    -CASE
+        WHEN i.standard_quantity <> 0 THEN o.standard_quantity * i.quantity / i.standard_quantity
+        ELSE o.quantity
+    END,
+    -o.standard_quantity,
+    o.unit_cost,
+    -o.cost
+FROM
+    inventory_outgoings o
+JOIN
+    inventory_incomings i
+ON
+    o.incoming_id = i.id
+UNION ALL
+SELECT
+    'transfer_out'::VARCHAR(255),
+    t.id,
+    t.from_incoming_id,
+    t.product_id,
+    ('TR-' || t.id)::VARCHAR(255),
+    t.reason,
+    t.transferred_at,
+    -t.quantity,
+    -t.standard_quantity,
+    t.unit_cost,
+    -t.standard_quantity * t.unit_cost
+FROM
+    inventory_transfers t
+UNION ALL
+SELECT
+    'transfer_in'::VARCHAR(255),
+    t.id,
+    t.to_incoming_id,
+    t.product_id,
+    ('TR-' || t.id)::VARCHAR(255),
+    t.reason,
+    t.transferred_at,
+    t.quantity,
+    t.standard_quantity,
+    t.unit_cost,
+    t.standard_quantity * t.unit_cost
+FROM
+    inventory_transfers t
+UNION ALL
+SELECT
+    'adjustment'::VARCHAR(255),
+    a.id,
+    a.incoming_id,
+    a.product_id,
+    ('ADJ-' || a.id)::VARCHAR(255),
+    a.reason_code,
+    a.approved_at,
+    a.quantity,
+    a.standard_quantity,
+    a.unit_cost,
+    a.standard_quantity * a.unit_cost
+FROM
+    inventory_adjustments a
+WHERE
+    a.status = 'approved';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS inventory_alerts;
+
+DROP TABLE IF EXISTS reorder_rules;
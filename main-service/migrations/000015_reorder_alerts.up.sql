@@ -0,0 +1,45 @@
+-- Create the reorder_rules table, a rule without a location covers the stock
+-- of the product everywhere
+CREATE TABLE IF NOT EXISTS reorder_rules (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER REFERENCES inventory_products(id) ON DELETE CASCADE NOT NULL,
+    location_id INTEGER REFERENCES locations(id) ON DELETE CASCADE,
+    min_quantity NUMERIC NOT NULL DEFAULT 0,
+    max_quantity NUMERIC NOT NULL DEFAULT 0,
+    reorder_quantity NUMERIC NOT NULL DEFAULT 0,
+    is_exist BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS reorder_rules_product_location_key
+    ON reorder_rules (product_id, COALESCE(location_id, 0));
+
+-- Create the inventory_alerts table, an alert stays open until the condition
+-- that raised it clears
+CREATE TABLE IF NOT EXISTS inventory_alerts (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(255) NOT NULL DEFAULT 'low_stock',
+    reorder_rule_id INTEGER REFERENCES reorder_rules(id) ON DELETE SET NULL,
+    product_id INTEGER REFERENCES inventory_products(id) ON DELETE CASCADE NOT NULL,
+    location_id INTEGER REFERENCES locations(id) ON DELETE SET NULL,
+    quantity NUMERIC NOT NULL DEFAULT 0,
+    threshold NUMERIC NOT NULL DEFAULT 0,
+    message VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(255) NOT NULL DEFAULT 'open',
+    raised_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    acknowledged_by VARCHAR(255) NOT NULL DEFAULT '',
+    acknowledged_at TIMESTAMP,
+    resolved_at TIMESTAMP
+);
+
+-- At most one unresolved alert per rule
+CREATE UNIQUE INDEX IF NOT EXISTS inventory_alerts_reorder_rule_key
+    ON inventory_alerts (reorder_rule_id)
+    WHERE status <> 'resolved';
+
+CREATE INDEX IF NOT EXISTS inventory_alerts_status_idx
+    ON inventory_alerts (status);
@@ -0,0 +1,5 @@
+DROP VIEW IF EXISTS inventory_reservation_balances;
+
+ALTER TABLE inventory_outgoings DROP COLUMN IF EXISTS reservation_id;
+
+DROP TABLE IF EXISTS inventory_reservations;
@@ -0,0 +1,57 @@
+-- Create the inventory_reservations table, a reservation holds stock of a
+-- product, or of one of its incomings, for a job until it expires. Only
+-- active and released are stored, fulfilled and expired follow from the
+-- outgoings that consumed it and the expiry date
+CREATE TABLE IF NOT EXISTS inventory_reservations (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER REFERENCES inventory_products(id) NOT NULL,
+    incoming_id INTEGER REFERENCES inventory_incomings(id),
+    job_order_id INTEGER REFERENCES job_orders(id),
+    standard_quantity NUMERIC NOT NULL DEFAULT 0,
+    expires_at DATE NOT NULL,
+    status VARCHAR(255) NOT NULL DEFAULT 'active',
+    ref_no VARCHAR(255) NOT NULL DEFAULT '',
+    remarks VARCHAR(255) NOT NULL DEFAULT '',
+    released_by VARCHAR(255) NOT NULL DEFAULT '',
+    released_at TIMESTAMP,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS inventory_reservations_product_idx
+    ON inventory_reservations (product_id);
+
+ALTER TABLE inventory_outgoings
+    ADD COLUMN IF NOT EXISTS reservation_id INTEGER REFERENCES inventory_reservations(id);
+
+-- What every reservation still holds, nothing once it is released or past
+-- its expiry date
+CREATE VIEW inventory_reservation_balances AS
+SELECT
+    r.id AS reservation_id,
+    r.product_id,
+    r.incoming_id,
+    r.standard_quantity,
+    COALESCE(c.consumed, 0) AS consumed_quantity,
+    CASE
+        WHEN r.status = 'active' AND r.expires_at >= CURRENT_DATE
+            THEN GREATEST(r.standard_quantity - COALESCE(c.consumed, 0), 0)
+        ELSE 0
+    END AS reserved_quantity
+FROM
+    inventory_reservations r
+LEFT JOIN (
+    SELECT
+        reservation_id,
+        SUM(standard_quantity) AS consumed
+    FROM
+        inventory_outgoings
+    WHERE
+        reservation_id IS NOT NULL
+    GROUP BY
+        reservation_id
+    ) c
+ON
+    c.reservation_id = r.id;
@@ -0,0 +1,92 @@
+DROP VIEW IF EXISTS inventory_movements;
+
+CREATE VIEW inventory_movements AS
+SELECT
+    'incoming'::VARCHAR(255) AS movement_type,
+    i.id AS document_id,
+    i.id AS incoming_id,
+    i.product_id,
+    i.ref_no,
+    i.remarks,
+    i.created_at AS moved_at,
+    i.quantity,
+    i.standard_quantity,
+    i.cost AS unit_cost,
+    i.standard_quantity * i.cost AS value
+FROM
+    inventory_incomings i
+WHERE
+    i.source_incoming_id IS NULL
+UNION ALL
+SELECT
+    'outgoing'::VARCHAR(255),
+    o.id,
+    o.incoming_id,
+    o.product_id,
+    o.ref_no,
+    o.remarks,
+    o.created_at,
+    -CASE
+        WHEN i.standard_quantity <> 0 THEN o.standard_quantity * i.quantity / i.standard_quantity
+        ELSE o.quantity
+    END,
+    -o.standard_quantity,
+    o.unit_cost,
+    -o.cost
+FROM
+    inventory_outgoings o
+JOIN
+    inventory_incomings i
+ON
+    o.incoming_id = i.id
+UNION ALL
+SELECT
+    'transfer_out'::VARCHAR(255),
+    t.id,
+    t.from_incoming_id,
+    t.product_id,
+    ('TR-' || t.id)::VARCHAR(255),
+    t.reason,
+    t.transferred_at,
+    -t.quantity,
+    -t.standard_quantity,
+    t.unit_cost,
+    -t.standard_quantity * t.unit_cost
+FROM
+    inventory_transfers t
+UNION ALL
+SELECT
+    'transfer_in'::VARCHAR(255),
+    t.id,
+    t.to_incoming_id,
+    t.product_id,
+    ('TR-' || t.id)::VARCHAR(255),
+    t.reason,
+    t.transferred_at,
+    t.quantity,
+    t.standard_quantity,
+    t.unit_cost,
+    t.standard_quantity * t.unit_cost
+FROM
+    inventory_transfers t
+UNION ALL
+SELECT
+    'adjustment'::VARCHAR(255),
+    a.id,
+    a.incoming_id,
+    a.product_id,
+    ('ADJ-' || a.id)::VARCHAR(255),
+    a.reason_code,
+    a.approved_at,
+    a.quantity,
+    a.standard_quantity,
+    a.unit_cost,
+    a.standard_quantity * a.unit_cost
+FROM
+    inventory_adjustments a
+WHERE
+    a.status = 'approved';
+
+DROP TABLE IF EXISTS inventory_returns;
+
+DROP TABLE IF EXISTS inventory_return_reasons;
@@ -0,0 +1,163 @@
+-- Create the inventory_return_reasons table
+CREATE TABLE IF NOT EXISTS inventory_return_reasons (
+    code VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    is_exist BOOLEAN NOT NULL DEFAULT true
+);
+
+INSERT INTO inventory_return_reasons (code, name) VALUES
+    ('defective', 'Defective'),
+    ('damaged_in_transit', 'Damaged in transit'),
+    ('wrong_item', 'Wrong item'),
+    ('excess', 'Excess quantity'),
+    ('quality', 'Quality issue')
+ON CONFLICT (code) DO NOTHING;
+
+-- Create the inventory_returns table. A customer return puts stock back into
+-- incoming_id against the outgoing it was issued on, a supplier return takes
+-- it out of incoming_id. standard_quantity is always positive, the type gives
+-- the direction
+CREATE TABLE IF NOT EXISTS inventory_returns (
+    id SERIAL PRIMARY KEY,
+    return_no VARCHAR(255) NOT NULL UNIQUE,
+    type VARCHAR(255) NOT NULL,
+    product_id INTEGER REFERENCES inventory_products(id) NOT NULL,
+    incoming_id INTEGER REFERENCES inventory_incomings(id) NOT NULL,
+    outgoing_id INTEGER REFERENCES inventory_outgoings(id),
+    purchase_order_id INTEGER REFERENCES purchase_orders(id),
+    reason_code VARCHAR(255) REFERENCES inventory_return_reasons(code) NOT NULL,
+    disposition VARCHAR(255) NOT NULL DEFAULT '',
+    quantity NUMERIC NOT NULL DEFAULT 0,
+    standard_quantity NUMERIC NOT NULL DEFAULT 0,
+    unit_cost NUMERIC NOT NULL DEFAULT 0,
+    ref_doc VARCHAR(255) NOT NULL DEFAULT '',
+    remarks VARCHAR(255) NOT NULL DEFAULT '',
+    returned_by VARCHAR(255) NOT NULL DEFAULT '',
+    returned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS inventory_returns_outgoing_id_idx ON inventory_returns (outgoing_id);
+
+-- Returns move stock like any other document
+DROP VIEW IF EXISTS inventory_movements;
+
+CREATE VIEW inventory_movements AS
+SELECT
+    'incoming'::VARCHAR(255) AS movement_type,
+    i.id AS document_id,
+    i.id AS incoming_id,
+    i.product_id,
+    i.ref_no,
+    i.remarks,
+    i.created_at AS moved_at,
+    i.quantity,
+    i.standard_quantity,
+    i.cost AS unit_cost,
+    i.standard_quantity * i.cost AS value
+FROM
+    inventory_incomings i
+WHERE
+    i.source_incoming_id IS NULL
+UNION ALL
+SELECT
+    'outgoing'::VARCHAR(255),
+    o.id,
+    o.incoming_id,
+    o.product_id,
+    o.ref_no,
+    o.remarks,
+    o.created_at,
+    -CASE
+        WHEN i.standard_quantity <> 0 THEN o.standard_quantity * i.quantity / i.standard_quantity
+        ELSE o.quantity
+    END,
+    -o.standard_quantity,
+    o.unit_cost,
+    -o.cost
+FROM
+    inventory_outgoings o
+JOIN
+    inventory_incomings i
+ON
+    o.incoming_id = i.id
+UNION ALL
+SELECT
+    'transfer_out'::VARCHAR(255),
+    t.id,
+    t.from_incoming_id,
+    t.product_id,
+    ('TR-' || t.id)::VARCHAR(255),
+    t.reason,
+    t.transferred_at,
+    -t.quantity,
+    -t.standard_quantity,
+    t.unit_cost,
+    -t.standard_quantity * t.unit_cost
+FROM
+    inventory_transfers t
+UNION ALL
+SELECT
+    'transfer_in'::VARCHAR(255),
+    t.id,
+    t.to_incoming_id,
+    t.product_id,
+    ('TR-' || t.id)::VARCHAR(255),
+    t.reason,
+    t.transferred_at,
+    t.quantity,
+    t.standard_quantity,
+    t.unit_cost,
+    t.standard_quantity * t.unit_cost
+FROM
+    inventory_transfers t
+UNION ALL
+SELECT
+    'adjustment'::VARCHAR(255),
+    a.id,
+    a.incoming_id,
+    a.product_id,
+    ('ADJ-' || a.id)::VARCHAR(255),
+    a.reason_code,
+    a.approved_at,
+    a.quantity,
+    a.standard_quantity,
+    a.unit_cost,
+    a.standard_quantity * a.unit_cost
+FROM
+    inventory_adjustments a
+WHERE
+    a.status = 'approved'
+UNION ALL
+SELECT
+    'customer_return'::VARCHAR(255),
+    r.id,
+    r.incoming_id,
+    r.product_id,
+    r.return_no,
+    r.reason_code,
+    r.returned_at,
+    r.quantity,
+    r.standard_quantity,
+    r.unit_cost,
+    r.standard_quantity * r.unit_cost
+FROM
+    inventory_returns r
+WHERE
+    r.type = 'customer'
+UNION ALL
+SELECT
+    'supplier_return'::VARCHAR(255),
+    r.id,
+    r.incoming_id,
+    r.product_id,
+    r.return_no,
+    r.reason_code,
+    r.returned_at,
+    -r.quantity,
+    -r.standard_quantity,
+    r.unit_cost,
+    -r.standard_quantity * r.unit_cost
+FROM
+    inventory_returns r
+WHERE
+    r.type = 'supplier';
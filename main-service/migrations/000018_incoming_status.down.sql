@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS inventory_incoming_status_history;
+
+DROP INDEX IF EXISTS inventory_incomings_ref_no_idx;
+
+ALTER TABLE inventory_incomings
+    DROP CONSTRAINT IF EXISTS inventory_incomings_status_check,
+    ALTER COLUMN status SET DEFAULT '';
+
+UPDATE inventory_incomings
+SET
+    status = CASE status
+        WHEN 'in-transit' THEN 'incoming'
+        WHEN 'qc-hold' THEN 'quarantine'
+        ELSE 'in-stock'
+    END;
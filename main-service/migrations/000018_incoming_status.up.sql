@@ -0,0 +1,43 @@
+-- Bring the free text statuses of incomings onto the status state machine,
+-- stock already in the system stays issuable
+UPDATE inventory_incomings
+SET
+    status = CASE
+        WHEN LOWER(TRIM(status)) IN ('in-transit', 'received', 'qc-hold', 'available', 'depleted', 'closed') THEN LOWER(TRIM(status))
+        WHEN LOWER(TRIM(status)) = 'incoming' THEN 'in-transit'
+        WHEN LOWER(TRIM(status)) IN ('quarantine', 'returned') THEN 'qc-hold'
+        ELSE 'available'
+    END;
+
+ALTER TABLE inventory_incomings
+    ALTER COLUMN status SET DEFAULT 'available',
+    ADD CONSTRAINT inventory_incomings_status_check CHECK (
+        status IN ('in-transit', 'received', 'qc-hold', 'available', 'depleted', 'closed')
+    );
+
+-- Shipments are transitioned together by their ref no
+CREATE INDEX IF NOT EXISTS inventory_incomings_ref_no_idx ON inventory_incomings (ref_no);
+
+-- Create the inventory_incoming_status_history table, from_status is empty
+-- for the status an incoming was created with
+CREATE TABLE IF NOT EXISTS inventory_incoming_status_history (
+    id SERIAL PRIMARY KEY,
+    incoming_id INTEGER REFERENCES inventory_incomings(id) ON DELETE CASCADE NOT NULL,
+    from_status VARCHAR(255) NOT NULL DEFAULT '',
+    to_status VARCHAR(255) NOT NULL,
+    remarks VARCHAR(255) NOT NULL DEFAULT '',
+    changed_by VARCHAR(255) NOT NULL DEFAULT '',
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS inventory_incoming_status_history_incoming_id_idx ON inventory_incoming_status_history (incoming_id);
+
+-- Start the history of existing incomings at their current status
+INSERT INTO inventory_incoming_status_history (incoming_id, to_status, changed_by, changed_at)
+SELECT
+    id,
+    status,
+    'migration',
+    created_at
+FROM
+    inventory_incomings;
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS inventory_serials;
+
+ALTER TABLE inventory_outgoings DROP COLUMN IF EXISTS expiry_override_by;
+
+DROP INDEX IF EXISTS inventory_incomings_lot_no_idx;
+
+ALTER TABLE inventory_incomings
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS manufactured_at,
+    DROP COLUMN IF EXISTS lot_no;
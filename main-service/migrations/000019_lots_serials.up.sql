@@ -0,0 +1,27 @@
+-- Lot or batch of the manufacturer with its manufacture and expiry dates
+ALTER TABLE inventory_incomings
+    ADD COLUMN IF NOT EXISTS lot_no VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS manufactured_at DATE,
+    ADD COLUMN IF NOT EXISTS expires_at DATE;
+
+CREATE INDEX IF NOT EXISTS inventory_incomings_lot_no_idx ON inventory_incomings (lot_no);
+
+-- Who let an outgoing issue an expired lot
+ALTER TABLE inventory_outgoings
+    ADD COLUMN IF NOT EXISTS expiry_override_by VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Create the inventory_serials table, one row per serialised item received.
+-- outgoing_id is set once the item is issued
+CREATE TABLE IF NOT EXISTS inventory_serials (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER REFERENCES inventory_products(id) NOT NULL,
+    incoming_id INTEGER REFERENCES inventory_incomings(id) ON DELETE CASCADE NOT NULL,
+    outgoing_id INTEGER REFERENCES inventory_outgoings(id) ON DELETE SET NULL,
+    serial_no VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (product_id, serial_no)
+);
+
+CREATE INDEX IF NOT EXISTS inventory_serials_incoming_id_idx ON inventory_serials (incoming_id);
+
+CREATE INDEX IF NOT EXISTS inventory_serials_outgoing_id_idx ON inventory_serials (outgoing_id);
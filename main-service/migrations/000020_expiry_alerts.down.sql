@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS inventory_incomings_expires_at_idx;
+
+DROP INDEX IF EXISTS inventory_alerts_incoming_key;
+
+ALTER TABLE inventory_alerts DROP COLUMN IF EXISTS incoming_id;
@@ -0,0 +1,12 @@
+-- Expiry alerts are raised per incoming, the lot running out of date
+ALTER TABLE inventory_alerts
+    ADD COLUMN IF NOT EXISTS incoming_id INTEGER REFERENCES inventory_incomings(id) ON DELETE SET NULL;
+
+-- At most one unresolved alert per incoming
+CREATE UNIQUE INDEX IF NOT EXISTS inventory_alerts_incoming_key
+    ON inventory_alerts (incoming_id)
+    WHERE status <> 'resolved';
+
+CREATE INDEX IF NOT EXISTS inventory_incomings_expires_at_idx
+    ON inventory_incomings (expires_at)
+    WHERE expires_at IS NOT NULL;
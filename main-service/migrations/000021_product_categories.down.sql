@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS inventory_product_attributes;
+
+DROP INDEX IF EXISTS inventory_products_tags_idx;
+
+DROP INDEX IF EXISTS inventory_products_category_id_idx;
+
+ALTER TABLE inventory_products
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS category_id;
+
+DROP TABLE IF EXISTS product_category_attributes;
+
+DROP VIEW IF EXISTS product_category_paths;
+
+DROP VIEW IF EXISTS product_category_tree;
+
+DROP TABLE IF EXISTS product_categories;
@@ -0,0 +1,93 @@
+-- Create the product_categories table, a category without a parent is a root
+-- of the tree
+CREATE TABLE IF NOT EXISTS product_categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INTEGER REFERENCES product_categories(id),
+    code VARCHAR(255) NOT NULL UNIQUE DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    is_exist BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS product_categories_parent_id_idx ON product_categories (parent_id);
+
+-- Every category paired with itself and each category below it
+CREATE OR REPLACE VIEW product_category_tree AS
+WITH RECURSIVE tree AS (
+    SELECT
+        id AS ancestor_id,
+        id AS category_id,
+        0 AS depth
+    FROM
+        product_categories
+    UNION ALL
+    SELECT
+        t.ancestor_id,
+        c.id,
+        t.depth + 1
+    FROM
+        product_categories c
+    JOIN
+        tree t
+    ON
+        c.parent_id = t.category_id
+)
+SELECT
+    ancestor_id,
+    category_id,
+    depth
+FROM
+    tree;
+
+-- Full name of every category from its root, "Adhesives / Sealants"
+CREATE OR REPLACE VIEW product_category_paths AS
+SELECT
+    t.category_id AS id,
+    STRING_AGG(a.name, ' / ' ORDER BY t.depth DESC) AS path
+FROM
+    product_category_tree t
+JOIN
+    product_categories a
+ON
+    t.ancestor_id = a.id
+GROUP BY
+    t.category_id;
+
+-- Create the product_category_attributes table, an attribute applies to the
+-- products of its category and of every category below it
+CREATE TABLE IF NOT EXISTS product_category_attributes (
+    id SERIAL PRIMARY KEY,
+    category_id INTEGER REFERENCES product_categories(id) ON DELETE CASCADE NOT NULL,
+    code VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    type VARCHAR(255) NOT NULL DEFAULT 'text'
+        CHECK (type IN ('text', 'number', 'boolean', 'option')),
+    unit VARCHAR(255) NOT NULL DEFAULT '',
+    options TEXT[] NOT NULL DEFAULT '{}',
+    is_required BOOLEAN NOT NULL DEFAULT false,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (category_id, code)
+);
+
+ALTER TABLE inventory_products
+    ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES product_categories(id),
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS inventory_products_category_id_idx ON inventory_products (category_id);
+
+CREATE INDEX IF NOT EXISTS inventory_products_tags_idx ON inventory_products USING GIN (tags);
+
+-- Create the inventory_product_attributes table, number_value is kept for
+-- number attributes so they can be compared as numbers
+CREATE TABLE IF NOT EXISTS inventory_product_attributes (
+    product_id INTEGER REFERENCES inventory_products(id) ON DELETE CASCADE NOT NULL,
+    attribute_id INTEGER REFERENCES product_category_attributes(id) ON DELETE CASCADE NOT NULL,
+    value VARCHAR(255) NOT NULL DEFAULT '',
+    number_value NUMERIC,
+    PRIMARY KEY (product_id, attribute_id)
+);
+
+CREATE INDEX IF NOT EXISTS inventory_product_attributes_attribute_id_idx ON inventory_product_attributes (attribute_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS inventory_products_parent_id_idx;
+
+ALTER TABLE inventory_products
+    DROP COLUMN IF EXISTS variant_axes,
+    DROP COLUMN IF EXISTS parent_id;
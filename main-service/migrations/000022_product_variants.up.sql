@@ -0,0 +1,8 @@
+-- A variant points at its parent product, which names the attributes of its
+-- category the variants differ by. Products keep their ids, so incomings and
+-- outgoings are untouched when a product is linked as a variant
+ALTER TABLE inventory_products
+    ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES inventory_products(id),
+    ADD COLUMN IF NOT EXISTS variant_axes TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS inventory_products_parent_id_idx ON inventory_products (parent_id);
@@ -0,0 +1,2 @@
+-- the thumbnail already holds the primary image
+DROP TABLE IF EXISTS inventory_product_media;
@@ -0,0 +1,38 @@
+-- The media gallery of a product: ordered images, one of them primary, and
+-- documents such as datasheets and installation guides. Files are uploaded
+-- through the file system service, path is where it put them
+CREATE TABLE IF NOT EXISTS inventory_product_media (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES inventory_products(id) ON DELETE CASCADE,
+    type VARCHAR(30) NOT NULL CHECK (type IN ('image', 'datasheet', 'installation_guide')),
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    path VARCHAR(255) NOT NULL,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    size BIGINT NOT NULL DEFAULT 0,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (NOT is_primary OR type = 'image')
+);
+
+CREATE INDEX IF NOT EXISTS inventory_product_media_product_id_idx ON inventory_product_media (product_id, sort_order);
+CREATE UNIQUE INDEX IF NOT EXISTS inventory_product_media_primary_key ON inventory_product_media (product_id) WHERE is_primary;
+
+-- the thumbnail becomes the primary image, and from now on follows it
+INSERT INTO inventory_product_media (product_id, type, path, filename, is_primary, sort_order, created_by, created_at)
+SELECT
+    p.id,
+    'image',
+    p.thumbnail,
+    REGEXP_REPLACE(p.thumbnail, '^.*/', ''),
+    TRUE,
+    0,
+    p.created_by,
+    p.created_at
+FROM
+    inventory_products p
+WHERE
+    p.thumbnail <> ''
+    AND NOT EXISTS (SELECT 1 FROM inventory_product_media m WHERE m.product_id = p.id AND m.is_primary);